@@ -509,6 +509,78 @@ func createAndPushPlainImage(ctx context.Context, imageName string, patch patchF
 	return ctx, ref.String(), nil
 }
 
+// createAndPushImageIndex creates an image index with a random image for each
+// of the provided platforms, e.g. "linux/amd64", and pushes it to the stub
+// registry. Each platform specific image is also tagged with the platform, e.g.
+// "linux-amd64", so its digest is available to the tests.
+func createAndPushImageIndex(ctx context.Context, imageName string, platforms *godog.Table) (context.Context, error) {
+	var state *imageState
+	ctx, err := testenv.SetupState(ctx, &state)
+	if err != nil {
+		return ctx, err
+	}
+
+	if state.Images[imageName] != "" {
+		// we already created the image index
+		return ctx, nil
+	}
+
+	if platforms == nil || len(platforms.Rows) == 0 {
+		return ctx, errors.New("at least one platform needs to be provided for the image index")
+	}
+
+	index := mutate.IndexMediaType(empty.Index, types.OCIImageIndex)
+	for _, row := range platforms.Rows {
+		platform, err := v1.ParsePlatform(row.Cells[0].Value)
+		if err != nil {
+			return ctx, err
+		}
+
+		img, err := random.Image(4096, 2)
+		if err != nil {
+			return ctx, err
+		}
+
+		img, err = mutate.Config(img, v1.Config{
+			Labels: map[string]string{
+				"org.opencontainers.image.title": imageName,
+			},
+		})
+		if err != nil {
+			return ctx, err
+		}
+
+		ref, err := registry.ImageReferenceInStubRegistry(ctx, imageName+":%s", strings.ReplaceAll(platform.String(), "/", "-"))
+		if err != nil {
+			return ctx, err
+		}
+
+		if err := remote.Write(ref, img); err != nil {
+			return ctx, err
+		}
+
+		index = mutate.AppendManifests(index, mutate.IndexAddendum{
+			Add: img,
+			Descriptor: v1.Descriptor{
+				Platform: platform,
+			},
+		})
+	}
+
+	ref, err := registry.ImageReferenceInStubRegistry(ctx, imageName)
+	if err != nil {
+		return ctx, err
+	}
+
+	if err := remote.WriteIndex(ref, index); err != nil {
+		return ctx, err
+	}
+
+	state.Images[imageName] = ref.String()
+
+	return ctx, nil
+}
+
 // resolveRefDigest returns an image reference that is guaranteed to have a digest.
 func resolveRefDigest(url string) (string, error) {
 	ref, err := name.ParseReference(url)
@@ -986,6 +1058,7 @@ func copyAllImages(ctx context.Context, source, destination string) (context.Con
 func AddStepsTo(sc *godog.ScenarioContext) {
 	sc.Step(`^an image named "([^"]*)"$`, CreateAndPushImageWithParent)
 	sc.Step(`^an image named "([^"]*)" containing a layer with:$`, createAndPushImageWithLayer)
+	sc.Step(`^an image index named "([^"]*)" with platforms:$`, createAndPushImageIndex)
 	sc.Step(`^the image "([^"]*)" has labels:$`, labelImage)
 	sc.Step(`^a valid image signature of "([^"]*)" image signed by the "([^"]*)" key$`, CreateAndPushImageSignature)
 	sc.Step(`^a valid attestation of "([^"]*)" signed by the "([^"]*)" key$`, CreateAndPushAttestation)
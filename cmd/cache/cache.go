@@ -0,0 +1,77 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cache
+
+import (
+	hd "github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/enterprise-contract/ec-cli/internal/policy/cache"
+)
+
+var CacheCmd *cobra.Command
+
+func init() {
+	CacheCmd = NewCacheCmd()
+	CacheCmd.AddCommand(cacheListCmd())
+	CacheCmd.AddCommand(cachePruneCmd())
+	CacheCmd.AddCommand(cacheClearCmd())
+}
+
+func NewCacheCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the persistent policy source cache",
+
+		Long: hd.Doc(`
+			Manage the persistent policy source cache.
+
+			Policy sources downloaded from git repositories and OCI registries can be
+			kept in a cache directory shared between invocations. The cache is disabled
+			by default, set the EC_POLICY_CACHE environment variable to true to use the
+			ec/policies directory within the user cache directory, e.g. $XDG_CACHE_HOME,
+			or to the path of the directory to use.
+
+			Sources pinned to a git commit SHA or an image digest are reused for as long
+			as they are cached. Sources referring to a branch or a tag are fetched again
+			once they were cached longer than EC_POLICY_CACHE_TTL, 1h by default. When
+			the cache grows beyond EC_POLICY_CACHE_MAX_SIZE, 1Gi by default, the least
+			recently used sources are removed.
+		`),
+	}
+}
+
+// addDirFlag adds the --dir flag shared by the cache sub-commands.
+func addDirFlag(flags *pflag.FlagSet, dir *string) {
+	flags.StringVar(dir, "dir", "", "cache directory to use. if not set, the value of EC_POLICY_CACHE or the ec/policies directory within the user cache directory is used")
+}
+
+// openStore returns the store configured via the environment, using dir
+// instead of the default directory when it is set.
+func openStore(dir string) (*cache.Store, error) {
+	s, err := cache.ConfiguredStore()
+	if err != nil {
+		return nil, err
+	}
+
+	if dir != "" {
+		s.Dir = dir
+	}
+
+	return s, nil
+}
@@ -0,0 +1,58 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cache
+
+import (
+	hd "github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+)
+
+func cacheClearCmd() *cobra.Command {
+	var dir string
+
+	cmd := &cobra.Command{
+		Use:   "clear",
+		Short: "Remove all policy sources from the persistent cache",
+
+		Long: hd.Doc(`
+			Remove all policy sources from the persistent cache.
+
+			It is safe to run while other ec processes are using the cache, the cache is
+			locked while it is being cleared.
+		`),
+
+		Example: hd.Doc(`
+			Clear the cache:
+
+			  ec cache clear
+		`),
+
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			s, err := openStore(dir)
+			if err != nil {
+				return err
+			}
+
+			return s.Clear()
+		},
+	}
+
+	addDirFlag(cmd.Flags(), &dir)
+
+	return cmd
+}
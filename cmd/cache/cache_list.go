@@ -0,0 +1,110 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	hd "github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/slices"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/enterprise-contract/ec-cli/internal/policy/cache"
+)
+
+func cacheListCmd() *cobra.Command {
+	var (
+		dir          string
+		outputFormat string
+	)
+
+	validFormats := []string{"text", "json"}
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the policy sources in the persistent cache",
+
+		Long: hd.Doc(`
+			List the policy sources in the persistent cache.
+
+			Each entry shows the policy source URL, the git commit SHA or image digest
+			it resolved to, the size of the downloaded content and when the entry
+			expires. Entries for sources pinned to a commit SHA or a digest never
+			expire.
+		`),
+
+		Example: hd.Doc(`
+			List the cached policy sources:
+
+			  ec cache list
+
+			List the cached policy sources as JSON:
+
+			  ec cache list --output json
+		`),
+
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if !slices.Contains(validFormats, outputFormat) {
+				return fmt.Errorf("invalid value for --output '%s'. accepted values: %s", outputFormat, strings.Join(validFormats, ", "))
+			}
+
+			s, err := openStore(dir)
+			if err != nil {
+				return err
+			}
+
+			entries, err := s.List()
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if outputFormat == "json" {
+				if entries == nil {
+					entries = []cache.Entry{}
+				}
+				return json.NewEncoder(out).Encode(entries)
+			}
+
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "URL\tREVISION\tSIZE\tFETCHED\tEXPIRES")
+			now := time.Now()
+			for _, e := range entries {
+				expires := "never"
+				if e.Expired(now, s.TTL) {
+					expires = "expired"
+				} else if !e.Immutable {
+					expires = e.Fetched.Add(s.TTL).Format(time.RFC3339)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", e.URL, e.Revision, resource.NewQuantity(e.Size, resource.BinarySI), e.Fetched.Format(time.RFC3339), expires)
+			}
+
+			return w.Flush()
+		},
+	}
+
+	addDirFlag(cmd.Flags(), &dir)
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "text", fmt.Sprintf("output format. one of: %s", strings.Join(validFormats, ", ")))
+
+	return cmd
+}
@@ -0,0 +1,93 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cache
+
+import (
+	"fmt"
+	"time"
+
+	hd "github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"github.com/enterprise-contract/ec-cli/internal/policy/cache"
+)
+
+func cachePruneCmd() *cobra.Command {
+	var (
+		dir     string
+		ttl     time.Duration
+		maxSize string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove expired policy sources from the persistent cache",
+
+		Long: hd.Doc(`
+			Remove expired policy sources from the persistent cache.
+
+			Entries for sources not pinned to a git commit SHA or an image digest that
+			were fetched longer ago than the TTL are removed. If the remaining content is
+			larger than the maximum size, the least recently used content is removed
+			until it fits.
+
+			The TTL and maximum size default to the values of the EC_POLICY_CACHE_TTL and
+			EC_POLICY_CACHE_MAX_SIZE environment variables, or to 1h and 1Gi.
+		`),
+
+		Example: hd.Doc(`
+			Remove expired entries from the cache:
+
+			  ec cache prune
+
+			Remove entries fetched more than a day ago and trim the cache to 100Mi:
+
+			  ec cache prune --ttl 24h --max-size 100Mi
+		`),
+
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			s, err := openStore(dir)
+			if err != nil {
+				return err
+			}
+
+			if cmd.Flags().Changed("ttl") {
+				s.TTL = ttl
+			}
+
+			if cmd.Flags().Changed("max-size") {
+				if s.MaxSize, err = cache.ParseSize(maxSize); err != nil {
+					return fmt.Errorf("invalid value for --max-size '%s': %w", maxSize, err)
+				}
+			}
+
+			removed, err := s.Prune(s.TTL, s.MaxSize)
+			for _, e := range removed {
+				fmt.Fprintf(cmd.OutOrStdout(), "Removed %s\n", e.URL)
+			}
+
+			return err
+		},
+	}
+
+	addDirFlag(cmd.Flags(), &dir)
+	cmd.Flags().DurationVar(&ttl, "ttl", cache.DefaultTTL, "remove entries for mutable references fetched longer ago than this")
+	cmd.Flags().StringVar(&maxSize, "max-size", "1Gi", "maximum size of the cache, e.g. 512Mi")
+
+	return cmd
+}
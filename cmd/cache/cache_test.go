@@ -0,0 +1,131 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	gitMetadata "github.com/conforma/go-gather/gather/git"
+	"github.com/conforma/go-gather/metadata"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/cmd/root"
+	"github.com/enterprise-contract/ec-cli/internal/policy/cache"
+)
+
+const commit = "6b0ae8c8f2d1d1f5a2c9d8c4b6fcd2a4a6b8e0f1"
+
+func setUpCobra(command *cobra.Command) *cobra.Command {
+	cacheCmd := NewCacheCmd()
+	cacheCmd.AddCommand(command)
+	cmd := root.NewRootCmd()
+	cmd.AddCommand(cacheCmd)
+	return cmd
+}
+
+// populate adds entries for a mutable and a pinned git reference to the store
+// in dir.
+func populate(t *testing.T, dir string) {
+	s := &cache.Store{Dir: dir, TTL: cache.DefaultTTL, MaxSize: cache.DefaultMaxSize}
+	fs := afero.NewMemMapFs()
+
+	for _, u := range []string{"git::https://github.com/org/repo?ref=main", "git::https://github.com/org/repo?ref=" + commit} {
+		_, err := s.Fetch(fs, u, filepath.Join("/work", u), func(dest string) (metadata.Metadata, error) {
+			if err := fs.MkdirAll(dest, 0755); err != nil {
+				return nil, err
+			}
+			return &gitMetadata.GitMetadata{LatestCommit: commit}, afero.WriteFile(fs, filepath.Join(dest, "policy.rego"), []byte("package main"), 0644)
+		})
+		require.NoError(t, err)
+	}
+}
+
+func run(t *testing.T, command *cobra.Command, args ...string) (string, error) {
+	cmd := setUpCobra(command)
+	cmd.SetContext(context.Background())
+	out := bytes.Buffer{}
+	cmd.SetOut(&out)
+	cmd.SetArgs(append([]string{"cache"}, args...))
+
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestCacheList(t *testing.T) {
+	dir := t.TempDir()
+	populate(t, dir)
+
+	out, err := run(t, cacheListCmd(), "list", "--dir", dir)
+	require.NoError(t, err)
+	assert.Contains(t, out, "URL")
+	assert.Regexp(t, `git::https://github.com/org/repo\?ref=main\s+`+commit+`\s+12\s+\S+\s+\d{4}-`, out)
+	assert.Regexp(t, `git::https://github.com/org/repo\?ref=`+commit+`\s+`+commit+`\s+12\s+\S+\s+never`, out)
+
+	out, err = run(t, cacheListCmd(), "list", "--dir", dir, "--output", "json")
+	require.NoError(t, err)
+	var entries []cache.Entry
+	require.NoError(t, json.Unmarshal([]byte(out), &entries))
+	assert.Len(t, entries, 2)
+
+	out, err = run(t, cacheListCmd(), "list", "--dir", t.TempDir(), "--output", "json")
+	require.NoError(t, err)
+	assert.JSONEq(t, "[]", out)
+
+	_, err = run(t, cacheListCmd(), "list", "--dir", dir, "--output", "yaml")
+	assert.EqualError(t, err, "invalid value for --output 'yaml'. accepted values: text, json")
+}
+
+func TestCachePrune(t *testing.T) {
+	dir := t.TempDir()
+	populate(t, dir)
+
+	out, err := run(t, cachePruneCmd(), "prune", "--dir", dir)
+	require.NoError(t, err)
+	assert.Empty(t, out)
+
+	out, err = run(t, cachePruneCmd(), "prune", "--dir", dir, "--ttl", "0s")
+	require.NoError(t, err)
+	assert.Equal(t, "Removed git::https://github.com/org/repo?ref=main\n", out)
+
+	out, err = run(t, cachePruneCmd(), "prune", "--dir", dir, "--max-size", "0")
+	require.NoError(t, err)
+	assert.Equal(t, "Removed git::https://github.com/org/repo?ref="+commit+"\n", out)
+
+	_, err = run(t, cachePruneCmd(), "prune", "--dir", dir, "--max-size", "huge")
+	assert.ErrorContains(t, err, "invalid value for --max-size 'huge'")
+}
+
+func TestCacheClear(t *testing.T) {
+	dir := t.TempDir()
+	populate(t, dir)
+
+	_, err := run(t, cacheClearCmd(), "clear", "--dir", dir)
+	require.NoError(t, err)
+
+	entries, err := (&cache.Store{Dir: dir}).List()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -19,6 +19,7 @@ package inspect
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -44,9 +45,13 @@ func inspectPolicyCmd() *cobra.Command {
 		ruleFilter       string
 		packageFilter    string
 		collectionFilter string
+		explainConfig    bool
+		effectiveTime    string
+		policyConfig     policy.Policy
 	)
 
 	validFormats := []string{"json", "text", "names", "short-names"}
+	validExplainFormats := []string{"json", "text"}
 
 	cmd := &cobra.Command{
 		Use:   "policy --source <source-url>",
@@ -75,18 +80,26 @@ func inspectPolicyCmd() *cobra.Command {
 			Display details about the latest release policy in json format:
 
 			  ec inspect policy --source quay.io/enterprise-contract/ec-release-policy -o json | jq
+
+			Explain which rules are included or excluded by the policy configuration:
+
+			  ec inspect policy --policy my-policy.yaml --explain-config
 		`),
 
 		Args: cobra.NoArgs,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if policyRef == "" {
+				if explainConfig {
+					return errors.New("the --explain-config flag requires the --policy flag")
+				}
 				return nil
 			}
 
-			p, err := policy.NewInertPolicy(cmd.Context(), policyRef)
+			p, err := policy.NewInputPolicy(cmd.Context(), policyRef, effectiveTime)
 			if err != nil {
 				return err
 			}
+			policyConfig = p
 
 			// clear the sourceUrls slice
 			sourceUrls = make([]string, 0, 10)
@@ -98,8 +111,12 @@ func inspectPolicyCmd() *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if !slices.Contains(validFormats, outputFormat) {
-				return fmt.Errorf("invalid value for --output '%s'. accepted values: %s", outputFormat, strings.Join(validFormats, ", "))
+			formats := validFormats
+			if explainConfig {
+				formats = validExplainFormats
+			}
+			if !slices.Contains(formats, outputFormat) {
+				return fmt.Errorf("invalid value for --output '%s'. accepted values: %s", outputFormat, strings.Join(formats, ", "))
 			}
 
 			ctx := cmd.Context()
@@ -116,6 +133,10 @@ func inspectPolicyCmd() *cobra.Command {
 				defer utils.CleanupWorkDir(fs, workDir)
 			}
 
+			if explainConfig {
+				return explainPolicyConfig(cmd, policyConfig, destDir, outputFormat, ruleFilter, packageFilter, collectionFilter)
+			}
+
 			allResults := make(map[string][]*ast.AnnotationsRef)
 			for _, url := range sourceUrls {
 				s := &source.PolicyUrl{Url: url, Kind: source.PolicyKind}
@@ -159,6 +180,14 @@ func inspectPolicyCmd() *cobra.Command {
 	flags.StringVar(&ruleFilter, "rule", ruleFilter, "display results matching rule name")
 	flags.StringVar(&packageFilter, "package", packageFilter, "display results matching package name")
 	flags.StringVar(&collectionFilter, "collection", collectionFilter, "display rules included in given collection")
+	flags.BoolVar(&explainConfig, "explain-config", explainConfig, hd.Doc(`
+		for every rule in the sources of the policy configuration display which include
+		and exclude entries match the rule, their scores and if the rule is included.
+		Requires --policy. Supported output formats are: json, text`))
+	flags.StringVar(&effectiveTime, "effective-time", policy.Now, hd.Doc(`
+		the time used to determine which volatile include and exclude entries are in
+		effect when using --explain-config. The value can be "now" (default) - for
+		current time, or a RFC3339 formatted value, e.g. 2022-11-18T00:00:00Z.`))
 
 	cmd.MarkFlagsMutuallyExclusive("policy", "source")
 
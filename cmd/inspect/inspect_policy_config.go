@@ -0,0 +1,162 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inspect
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/spf13/cobra"
+
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/opa"
+	opaRule "github.com/enterprise-contract/ec-cli/internal/opa/rule"
+	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+// sourceGroupInclusion holds the explanation of the include and exclude
+// configuration for each rule within a policy source group
+type sourceGroupInclusion struct {
+	Name  string                    `json:"name,omitempty"`
+	Rules []evaluator.RuleInclusion `json:"rules"`
+}
+
+// explainPolicyConfig downloads the policy sources of each source group in the
+// policy configuration and outputs, for each rule, the include and exclude
+// entries that match the rule and if the rule is included in the evaluation
+func explainPolicyConfig(cmd *cobra.Command, p policy.Policy, destDir, outputFormat, ruleFilter, packageFilter, collectionFilter string) error {
+	ctx := cmd.Context()
+	fs := utils.FS(ctx)
+
+	groups := make([]sourceGroupInclusion, 0, len(p.Spec().Sources))
+	for _, src := range p.Spec().Sources {
+		urls := make([]string, 0, len(src.Policy))
+		annotations := make(map[string][]*ast.AnnotationsRef)
+		for _, url := range src.Policy {
+			s := &source.PolicyUrl{Url: url, Kind: source.PolicyKind}
+
+			policyDir, err := s.GetPolicy(ctx, destDir, false)
+			if err != nil {
+				return err
+			}
+
+			result, err := opa.InspectDir(fs, policyDir)
+			if err != nil {
+				return err
+			}
+
+			urls = append(urls, s.PolicyUrl())
+			annotations[s.PolicyUrl()] = result
+		}
+
+		annotations, err := filterResults(annotations, ruleFilter, packageFilter, collectionFilter)
+		if err != nil {
+			return err
+		}
+
+		var rules []opaRule.Info
+		for _, url := range urls {
+			for _, a := range annotations[url] {
+				if a.Annotations == nil || a.Annotations.Scope != "rule" {
+					continue
+				}
+
+				info := opaRule.RuleInfo(a)
+				if info.ShortName == "" {
+					// the evaluator ignores rules without a short name
+					continue
+				}
+				info.Source = url
+
+				rules = append(rules, info)
+			}
+		}
+
+		groups = append(groups, sourceGroupInclusion{
+			Name:  src.Name,
+			Rules: evaluator.ExplainInclusion(src, p, rules),
+		})
+	}
+
+	out := cmd.OutOrStdout()
+	if outputFormat == "json" {
+		return json.NewEncoder(out).Encode(groups)
+	}
+
+	return outputInclusionText(out, groups)
+}
+
+func outputInclusionText(out io.Writer, groups []sourceGroupInclusion) error {
+	for i, group := range groups {
+		name := group.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		if _, err := fmt.Fprintf(out, "# Source group: %s\n\n", name); err != nil {
+			return err
+		}
+
+		for _, r := range group.Rules {
+			if _, err := fmt.Fprintf(out, "%s (%s)\n", r.Code, r.Source); err != nil {
+				return err
+			}
+
+			for _, v := range r.Verdicts {
+				verdict := "Included"
+				if !v.Included {
+					verdict = "Excluded"
+				}
+				if v.ImageDigest != "" {
+					verdict = fmt.Sprintf("For image %s: %s", v.ImageDigest, verdict)
+				}
+
+				if _, err := fmt.Fprintf(out, "  %s (include score %d, exclude score %d)\n    Include: %s\n    Exclude: %s\n",
+					verdict, v.IncludeScore, v.ExcludeScore, criteriaText(v.Include), criteriaText(v.Exclude)); err != nil {
+					return err
+				}
+			}
+
+			if _, err := fmt.Fprintln(out, "--"); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func criteriaText(matches []evaluator.CriteriaMatch) string {
+	if len(matches) == 0 {
+		return "(none)"
+	}
+
+	texts := make([]string, 0, len(matches))
+	for _, m := range matches {
+		if m.ImageDigest != "" {
+			texts = append(texts, fmt.Sprintf("%s (%d, image specific)", m.Value, m.Score))
+		} else {
+			texts = append(texts, fmt.Sprintf("%s (%d)", m.Value, m.Score))
+		}
+	}
+
+	return strings.Join(texts, ", ")
+}
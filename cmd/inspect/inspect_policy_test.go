@@ -181,3 +181,119 @@ func setUpCobra(command *cobra.Command) *cobra.Command {
 	cmd.AddCommand(inspectCmd)
 	return cmd
 }
+
+func TestExplainConfig(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	ctx := utils.WithFS(context.Background(), fs)
+
+	downloader := mockDownloader{}
+	ctx = context.WithValue(ctx, source.DownloaderFuncKey, &downloader)
+
+	createDir := func(args mock.Arguments) {
+		dir := args.String(0)
+
+		if err := fs.MkdirAll(dir, 0755); err != nil {
+			panic(err)
+		}
+		if err := afero.WriteFile(fs, fmt.Sprintf("%s/foo.rego", args.String(0)), []byte(`package foo
+
+import rego.v1
+
+# METADATA
+# title: Bar
+# custom:
+#   short_name: bar
+#   collections:
+#   - minimal
+deny contains "bar" if {
+	true
+}
+
+# METADATA
+# title: Baz
+# custom:
+#   short_name: baz
+deny contains "baz" if {
+	true
+}
+`), 0644); err != nil {
+			panic(err)
+		}
+	}
+
+	downloader.On("Download", mock.Anything, "one", false).Return(&fileMetadata.FSMetadata{}, nil).Run(createDir)
+
+	cases := []struct {
+		name     string
+		output   string
+		expected string
+	}{
+		{
+			name:   "text",
+			output: "text",
+			expected: `# Source group: default
+
+foo.bar (file::one)
+  Included (include score 11, exclude score 10)
+    Include: * (1), @minimal (10)
+    Exclude: foo (10)
+  For image sha256:0000000000000000000000000000000000000000000000000000000000000000: Excluded (include score 11, exclude score 120)
+    Include: * (1), @minimal (10)
+    Exclude: foo.bar (110, image specific), foo (10)
+--
+foo.baz (file::one)
+  Excluded (include score 1, exclude score 10)
+    Include: * (1)
+    Exclude: foo (10)
+--
+`,
+		},
+		{
+			name:     "json",
+			output:   "json",
+			expected: `[{"name":"default","rules":[{"code":"foo.bar","source":"file::one","verdicts":[{"include":[{"value":"*","score":1},{"value":"@minimal","score":10}],"exclude":[{"value":"foo","score":10}],"includeScore":11,"excludeScore":10,"included":true},{"imageDigest":"sha256:0000000000000000000000000000000000000000000000000000000000000000","include":[{"value":"*","score":1},{"value":"@minimal","score":10}],"exclude":[{"value":"foo.bar","score":110,"imageDigest":"sha256:0000000000000000000000000000000000000000000000000000000000000000"},{"value":"foo","score":10}],"includeScore":11,"excludeScore":120,"included":false}]},{"code":"foo.baz","source":"file::one","verdicts":[{"include":[{"value":"*","score":1}],"exclude":[{"value":"foo","score":10}],"includeScore":1,"excludeScore":10,"included":false}]}]}]` + "\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			inspectPolicyCmd := inspectPolicyCmd()
+			cmd := setUpCobra(inspectPolicyCmd)
+			cmd.SetContext(ctx)
+			buffy := bytes.Buffer{}
+			cmd.SetOut(&buffy)
+
+			cmd.SetArgs([]string{
+				"inspect",
+				"policy",
+				"--explain-config",
+				"--output",
+				c.output,
+				"--policy",
+				`{"sources":[{"name":"default","policy":["one"],"config":{"include":["*","@minimal"],"exclude":["foo"]},"volatileConfig":{"exclude":[{"value":"foo.bar","imageRef":"sha256:0000000000000000000000000000000000000000000000000000000000000000"}]}}]}`,
+			})
+
+			err := cmd.Execute()
+			assert.NoError(t, err)
+
+			assert.Equal(t, c.expected, buffy.String())
+		})
+	}
+}
+
+func TestExplainConfigRequiresPolicy(t *testing.T) {
+	inspectPolicyCmd := inspectPolicyCmd()
+	cmd := setUpCobra(inspectPolicyCmd)
+	cmd.SetContext(context.Background())
+
+	cmd.SetArgs([]string{
+		"inspect",
+		"policy",
+		"--explain-config",
+		"--source",
+		"one",
+	})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "the --explain-config flag requires the --policy flag")
+}
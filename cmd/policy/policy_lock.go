@@ -0,0 +1,101 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"fmt"
+
+	hd "github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/policy/lock"
+	validate_utils "github.com/enterprise-contract/ec-cli/internal/validate"
+)
+
+func policyLockCmd() *cobra.Command {
+	var (
+		policyConfiguration string
+		output              string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "lock --policy <policy-configuration>",
+		Short: "Pin the policy sources to immutable references",
+
+		Long: hd.Doc(`
+			Pin the policy sources to immutable references.
+
+			Every policy and data source URL of each source group in the policy
+			configuration is fetched and resolved to the git commit SHA or the image
+			digest it currently refers to. The resolved references are written to a lock
+			file which can be passed to "ec validate image" or "ec validate input" using
+			the --policy-lock flag, so each validation evaluates exactly the same policy
+			regardless of branches or tags being updated.
+		`),
+
+		Example: hd.Doc(`
+			Create a lock file for a policy configuration:
+
+			  ec policy lock --policy policy.yaml --output policy.lock.yaml
+
+			Validate an image using the pinned policy sources:
+
+			  ec validate image --image registry/name:tag --policy policy.yaml \
+			    --public-key key.pub --policy-lock policy.lock.yaml
+		`),
+
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+
+			policyConfiguration, err := validate_utils.GetPolicyConfig(ctx, policyConfiguration)
+			if err != nil {
+				return err
+			}
+
+			p, err := policy.NewInertPolicy(ctx, policyConfiguration)
+			if err != nil {
+				return err
+			}
+
+			l, err := lock.Resolve(ctx, p.Spec())
+			if err != nil {
+				return err
+			}
+
+			if err := l.Write(ctx, output); err != nil {
+				return fmt.Errorf("unable to write the policy lock file: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&policyConfiguration, "policy", "p", "", hd.Doc(`
+		Policy configuration as:
+		* file (policy.yaml)
+		* git reference (github.com/user/repo//default?ref=main), or
+		* inline JSON ('{sources: {...}}')`))
+	cmd.Flags().StringVarP(&output, "output", "o", "policy.lock.yaml", "path of the policy lock file to write")
+
+	if err := cmd.MarkFlagRequired("policy"); err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
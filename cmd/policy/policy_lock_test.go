@@ -0,0 +1,96 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package policy
+
+import (
+	"context"
+	"testing"
+
+	hd "github.com/MakeNowJust/heredoc"
+	gitMetadata "github.com/conforma/go-gather/gather/git"
+	"github.com/conforma/go-gather/metadata"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/cmd/root"
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+type mockDownloader struct {
+	mock.Mock
+}
+
+func (m *mockDownloader) Download(_ context.Context, dest string, sourceUrl string, showMsg bool) (metadata.Metadata, error) {
+	args := m.Called(dest, sourceUrl, showMsg)
+
+	return args.Get(0).(metadata.Metadata), args.Error(1)
+}
+
+func setUpCobra(command *cobra.Command) *cobra.Command {
+	policyCmd := NewPolicyCmd()
+	policyCmd.AddCommand(command)
+	cmd := root.NewRootCmd()
+	cmd.AddCommand(policyCmd)
+	return cmd
+}
+
+func TestPolicyLock(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	ctx := utils.WithFS(context.Background(), fs)
+
+	dl := mockDownloader{}
+	dl.On("Download", mock.Anything, "git::https://example.com/org/lock-cmd//policy?ref=main", false).Return(&gitMetadata.GitMetadata{LatestCommit: "6b0ae8c8f2d1d1f5a2c9d8c4b6fcd2a4a6b8e0f1"}, nil)
+	ctx = context.WithValue(ctx, source.DownloaderFuncKey, &dl)
+
+	require.NoError(t, afero.WriteFile(fs, "/policy.yaml", []byte(hd.Doc(`
+		sources:
+		  - name: default
+		    policy:
+		      - git::https://example.com/org/lock-cmd//policy?ref=main
+	`)), 0644))
+
+	cmd := setUpCobra(policyLockCmd())
+	cmd.SetContext(ctx)
+	cmd.SetArgs([]string{"policy", "lock", "--policy", "/policy.yaml", "--output", "/policy.lock.yaml"})
+
+	require.NoError(t, cmd.Execute())
+
+	b, err := afero.ReadFile(fs, "/policy.lock.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, hd.Doc(`
+		sources:
+		- name: default
+		  policy:
+		  - pinned: git::example.com/org/lock-cmd//policy?ref=6b0ae8c8f2d1d1f5a2c9d8c4b6fcd2a4a6b8e0f1
+		    url: git::https://example.com/org/lock-cmd//policy?ref=main
+		version: 1
+	`), string(b))
+}
+
+func TestPolicyLockRequiresPolicy(t *testing.T) {
+	cmd := setUpCobra(policyLockCmd())
+	cmd.SetContext(utils.WithFS(context.Background(), afero.NewMemMapFs()))
+	cmd.SetArgs([]string{"policy", "lock"})
+
+	assert.EqualError(t, cmd.Execute(), `required flag(s) "policy" not set`)
+}
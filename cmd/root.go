@@ -21,11 +21,14 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/enterprise-contract/ec-cli/cmd/cache"
 	"github.com/enterprise-contract/ec-cli/cmd/fetch"
 	"github.com/enterprise-contract/ec-cli/cmd/initialize"
 	"github.com/enterprise-contract/ec-cli/cmd/inspect"
 	"github.com/enterprise-contract/ec-cli/cmd/opa"
+	"github.com/enterprise-contract/ec-cli/cmd/policy"
 	"github.com/enterprise-contract/ec-cli/cmd/root"
+	"github.com/enterprise-contract/ec-cli/cmd/serve"
 	"github.com/enterprise-contract/ec-cli/cmd/sigstore"
 	"github.com/enterprise-contract/ec-cli/cmd/test"
 	"github.com/enterprise-contract/ec-cli/cmd/track"
@@ -51,6 +54,7 @@ func Execute() {
 }
 
 func init() {
+	RootCmd.AddCommand(cache.CacheCmd)
 	RootCmd.AddCommand(fetch.FetchCmd)
 	RootCmd.AddCommand(initialize.InitCmd)
 	RootCmd.AddCommand(inspect.InspectCmd)
@@ -58,6 +62,8 @@ func init() {
 	RootCmd.AddCommand(validate.ValidateCmd)
 	RootCmd.AddCommand(version.VersionCmd)
 	RootCmd.AddCommand(opa.OPACmd)
+	RootCmd.AddCommand(policy.PolicyCmd)
+	RootCmd.AddCommand(serve.ServeCmd)
 	RootCmd.AddCommand(sigstore.SigstoreCmd)
 	if utils.Experimental() {
 		RootCmd.AddCommand(test.TestCmd)
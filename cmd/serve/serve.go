@@ -0,0 +1,211 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package serve
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	hd "github.com/MakeNowJust/heredoc"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/enterprise-contract/ec-cli/internal/image"
+	_ "github.com/enterprise-contract/ec-cli/internal/rego"
+	"github.com/enterprise-contract/ec-cli/internal/server"
+)
+
+var ServeCmd *cobra.Command
+
+func init() {
+	ServeCmd = serveCmd(image.ValidateImage)
+}
+
+func serveCmd(validate server.ImageValidationFunc) *cobra.Command {
+	var (
+		address     string
+		tlsCertFile string
+		tlsKeyFile  string
+		opts        = server.Options{
+			AdmissionCacheTTL: 10 * time.Minute,
+			AdmissionPolicy:   "default",
+			MaxConcurrency:    10,
+			MaxPolicies:       50,
+			PolicyRefresh:     15 * time.Minute,
+			Timeout:           5 * time.Minute,
+			Workers:           5,
+		}
+	)
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Run the validation service",
+
+		Long: hd.Doc(`
+			Run the validation service.
+
+			The service validates images over HTTP, so that other services, e.g.
+			admission or release controllers, can use the Enterprise Contract without
+			running a new process for each validation. The policies, along with the
+			policy sources they use, are prepared on the first request using them and
+			reused by the following requests for the duration set by --policy-refresh.
+			At most --max-policies prepared policies are kept, and the ones not used
+			for the duration set by --policy-refresh are removed.
+
+			The following endpoints are provided:
+
+			  POST /v1/validate/image  validates the image or the ApplicationSnapshot spec
+			                           in the JSON request body and responds with the
+			                           same JSON report "ec validate image" outputs
+			  POST /v1/admission       Kubernetes validating admission webhook
+			  GET  /healthz            responds with 200 while the service is running
+			  GET  /readyz             responds with 200 while the service accepts requests
+
+			The validation request body is a JSON object with the following fields, the
+			"image" or the "snapshot" field is required, the remaining fields have the
+			same meaning as the flags of "ec validate image": "image", "snapshot",
+			"policy", "publicKey", "rekorUrl", "ignoreRekor", "certificateIdentity",
+			"certificateIdentityRegExp", "certificateOIDCIssuer",
+			"certificateOIDCIssuerRegExp", "effectiveTime", "info", "showSuccesses" and
+			"nestImageManifests". The "policy" and "publicKey" fields are only accepted
+			with --allow-request-policy, otherwise the requests are validated using the
+			policy set by --policy.
+
+			The admission webhook admits Pods, and Deployments, ReplicaSets,
+			StatefulSets, DaemonSets, Jobs and CronJobs creating them, only if all of
+			their container images conform to the policy. Images referenced by tag are
+			resolved to, and validated by, their current digests. As the tag could refer
+			to a different image by the time the container is created, use
+			--admission-require-digest to deny the images not referenced by their
+			digests. The images are validated using
+			the EnterpriseContractPolicy named by --admission-policy in the namespace of
+			the object, or, if there is none, the policy set by --policy. The outcome of the validation of each image digest is
+			reused for the duration set by --admission-cache-ttl. Use --admission-audit
+			to admit all objects, reporting the violations as warnings and audit
+			annotations instead. Kubernetes requires webhooks to be served over HTTPS,
+			see --tls-cert-file and --tls-key-file.
+
+			The policy configuration and the public key in the requests allowed by
+			--allow-request-policy are read, and the policy sources fetched, with the
+			permissions and the credentials of the service, e.g. local files or
+			Kubernetes resources, so only allow them if the service is only available to
+			trusted clients. The global --timeout flag doesn't apply
+			to the service, use --request-timeout to limit the duration of each request.
+		`),
+
+		Example: hd.Doc(`
+			Run the service on port 8080 validating with a policy from a file by default:
+
+			  ec serve --address :8080 --policy policy.yaml
+
+			Validate an image using the service:
+
+			  curl -X POST http://localhost:8080/v1/validate/image \
+			    -d '{"image": "registry/name:tag"}'
+		`),
+
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			if (tlsCertFile == "") != (tlsKeyFile == "") {
+				return errors.New("both --tls-cert-file and --tls-key-file need to be provided")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			// The service is running until stopped, not bound by the global timeout
+			ctx := context.WithoutCancel(cmd.Context())
+
+			stop, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+			defer cancel()
+
+			listener, err := net.Listen("tcp", address)
+			if err != nil {
+				return err
+			}
+
+			s := server.New(ctx, opts, validate)
+			defer s.Close()
+
+			httpServer := &http.Server{
+				Handler:           s.Handler(),
+				ReadHeaderTimeout: 10 * time.Second,
+				BaseContext: func(net.Listener) context.Context {
+					return ctx
+				},
+			}
+
+			errs := make(chan error, 1)
+			go func() {
+				if tlsCertFile != "" {
+					errs <- httpServer.ServeTLS(listener, tlsCertFile, tlsKeyFile)
+				} else {
+					errs <- httpServer.Serve(listener)
+				}
+			}()
+			s.SetReady(true)
+			log.Infof("Listening on %s", listener.Addr())
+
+			select {
+			case err := <-errs:
+				return err
+			case <-stop.Done():
+			}
+
+			log.Info("Shutting down")
+			s.SetReady(false)
+			shutdownCtx, cancelShutdown := context.WithTimeout(ctx, opts.Timeout)
+			defer cancelShutdown()
+			if err := httpServer.Shutdown(shutdownCtx); err != nil {
+				return err
+			}
+
+			if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&address, "address", ":8080", "address to listen on")
+	cmd.Flags().StringVarP(&opts.DefaultPolicy, "policy", "p", opts.DefaultPolicy, hd.Doc(`
+		Policy configuration used by requests not providing one, as:
+		  * Kubernetes reference ([<namespace>/]<name>)
+		  * file (policy.yaml)
+		  * git reference (github.com/user/repo//default?ref=main), or
+		  * inline JSON ('{sources: {...}, identity: {...}}')`))
+	cmd.Flags().BoolVar(&opts.AllowRequestPolicy, "allow-request-policy", opts.AllowRequestPolicy, "allow the requests to provide the policy configuration and the public key, read with the permissions and the credentials of the service")
+	cmd.Flags().IntVar(&opts.MaxConcurrency, "max-concurrency", opts.MaxConcurrency, "maximum number of requests validated at the same time, other requests wait until they time out")
+	cmd.Flags().DurationVar(&opts.Timeout, "request-timeout", opts.Timeout, "maximum duration of a request")
+	cmd.Flags().DurationVar(&opts.PolicyRefresh, "policy-refresh", opts.PolicyRefresh, "duration a prepared policy is reused before it is prepared again, and unused before it is removed, 0 to reuse it indefinitely")
+	cmd.Flags().IntVar(&opts.MaxPolicies, "max-policies", opts.MaxPolicies, "maximum number of prepared policies kept, the least recently used one is removed to make room for a new one")
+	cmd.Flags().IntVar(&opts.Workers, "workers", opts.Workers, "number of components of a request validated at the same time")
+	cmd.Flags().StringVar(&tlsCertFile, "tls-cert-file", "", "path to the TLS certificate used to serve HTTPS")
+	cmd.Flags().StringVar(&tlsKeyFile, "tls-key-file", "", "path to the private key of the TLS certificate")
+	cmd.Flags().StringVar(&opts.AdmissionPolicy, "admission-policy", opts.AdmissionPolicy, "name of the EnterpriseContractPolicy, in the namespace of the admitted object, used by the admission webhook")
+	cmd.Flags().BoolVar(&opts.AdmissionAudit, "admission-audit", opts.AdmissionAudit, "admit all objects, reporting the policy violations as warnings and audit annotations")
+	cmd.Flags().BoolVar(&opts.AdmissionRequireDigest, "admission-require-digest", opts.AdmissionRequireDigest, "deny the images not referenced by their digests instead of resolving their tags")
+	cmd.Flags().DurationVar(&opts.AdmissionCacheTTL, "admission-cache-ttl", opts.AdmissionCacheTTL, "duration the outcome of the validation of an image digest is reused by the admission webhook, 0 to disable")
+
+	return cmd
+}
@@ -18,6 +18,8 @@ package validate
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -27,6 +29,7 @@ import (
 
 	hd "github.com/MakeNowJust/heredoc"
 	app "github.com/konflux-ci/application-api/api/v1alpha1"
+	cosignoptions "github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
 	"github.com/sigstore/cosign/v2/pkg/cosign"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -34,12 +37,17 @@ import (
 
 	"github.com/enterprise-contract/ec-cli/internal/applicationsnapshot"
 	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/evidence"
 	"github.com/enterprise-contract/ec-cli/internal/format"
 	"github.com/enterprise-contract/ec-cli/internal/output"
 	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/policy/cache"
+	"github.com/enterprise-contract/ec-cli/internal/policy/lock"
 	"github.com/enterprise-contract/ec-cli/internal/policy/source"
 	"github.com/enterprise-contract/ec-cli/internal/utils"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
 	validate_utils "github.com/enterprise-contract/ec-cli/internal/validate"
+	"github.com/enterprise-contract/ec-cli/internal/vsa"
 )
 
 type imageValidationFunc func(context.Context, app.SnapshotComponent, *app.SnapshotSpec, policy.Policy, []evaluator.Evaluator, bool) (*output.Output, error)
@@ -49,37 +57,65 @@ var newOPAEvaluator = evaluator.NewOPAEvaluator
 
 func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 	data := struct {
+		attachVSA                   bool
 		certificateIdentity         string
 		certificateIdentityRegExp   string
 		certificateOIDCIssuer       string
 		certificateOIDCIssuerRegExp string
 		effectiveTime               string
+		explain                     bool
+		exportDir                   string
 		extraRuleData               []string
 		filePath                    string // Deprecated: images replaced this
 		imageRef                    string
 		info                        bool
 		input                       string // Deprecated: images replaced this
 		ignoreRekor                 bool
+		ociLayouts                  []string
+		layoutClient                *oci.LayoutClient
 		output                      []string
 		outputFile                  string
 		policy                      policy.Policy
+		policyCache                 *cache.PolicyCache
 		policyConfiguration         string
+		policyDigest                string
+		policyLock                  string
+		policyURI                   string
 		publicKey                   string
+		recordResults               bool
 		rekorURL                    string
+		reportSourceErrors          bool
+		resultsConfigMap            string
 		snapshot                    string
 		spec                        *app.SnapshotSpec
+		manifests                   applicationsnapshot.ImageManifests
+		nestImageManifests          bool
 		strict                      bool
 		images                      string
 		noColor                     bool
 		forceColor                  bool
 		workers                     int
+		vsaOptions                  vsa.Options
+		vsaFormat                   string
+		vsaVerifiedLevels           []string
 	}{
-		strict:  true,
-		workers: 5,
+		strict:    true,
+		workers:   5,
+		vsaFormat: vsa.FormatEC,
+		vsaOptions: vsa.Options{
+			FulcioURL:  cosignoptions.DefaultFulcioURL,
+			RekorURL:   cosignoptions.DefaultRekorURL,
+			OIDCIssuer: cosignoptions.DefaultOIDCIssuerURL,
+		},
 	}
 
 	validOutputFormats := applicationsnapshot.OutputFormats
 
+	// resultFlags are the flags affecting the result of the validation, when set
+	// they're included in the command re-running the validation from the exported
+	// evidence
+	resultFlags := []string{"ignore-rekor", "strict", "info", "explain", "show-successes", "nest-image-manifests"}
+
 	cmd := &cobra.Command{
 		Use:   "image",
 		Short: "Validate conformance of container images with the provided policies",
@@ -170,6 +206,10 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 
 			  ec validate image --image registry/name:tag --output yaml --output appstudio=<path>
 
+			Explain how each violation and warning came to be
+
+			  ec validate image --image registry/name:tag --explain --output text
+
 
 			Validate a single image with keyless workflow.
 
@@ -184,6 +224,19 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 			    --certificate-identity-regexp '^https://github\.com' \
 			    --certificate-oidc-issuer-regexp 'githubusercontent' \
 			    --rekor-url 'https://rekor.sigstore.dev'
+
+			Validate an image saved with "cosign save" without network access:
+
+			  cosign save --dir image-layout registry/name@sha256:<digest>
+
+			  ec validate image --image registry/name@sha256:<digest> --policy policy.yaml \
+			    --public-key key.pub --oci-layout image-layout
+
+			Validate the Snapshot from the cluster and record the outcome on it, along with
+			the report in the "my-snapshot-ec-results" ConfigMap:
+
+			  ec validate image --snapshot my-namespace/my-snapshot --policy my-policy \
+			    --record-results --results-configmap my-snapshot-ec-results
 		`),
 
 		PreRunE: func(cmd *cobra.Command, args []string) (allErrors error) {
@@ -195,7 +248,48 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 				cmd.SetContext(ctx)
 			}
 
-			if s, err := applicationsnapshot.DetermineInputSpec(ctx, applicationsnapshot.Input{
+			if (data.recordResults || data.resultsConfigMap != "") && data.snapshot == "" {
+				return errors.New("--record-results and --results-configmap require --snapshot")
+			}
+
+			if err := vsa.ValidateFormat(data.vsaFormat); err != nil {
+				return err
+			}
+
+			data.policyURI = validate_utils.PolicyURI(data.policyConfiguration)
+			if data.policyURI == "" {
+				if data.attachVSA && data.vsaFormat == vsa.FormatSLSA {
+					return errors.New("--vsa-format slsa requires the --policy to be provided as a file or a URL")
+				}
+				for _, o := range data.output {
+					formatAndPath, _, _ := strings.Cut(o, "?")
+					if f, _, _ := strings.Cut(formatAndPath, "="); f == applicationsnapshot.SLSAVSA {
+						return fmt.Errorf("--output %s requires the --policy to be provided as a file or a URL", applicationsnapshot.SLSAVSA)
+					}
+				}
+			}
+
+			if len(data.ociLayouts) > 0 {
+				c, err := oci.NewLayoutClient(ctx, data.ociLayouts...)
+				if err != nil {
+					return err
+				}
+				data.layoutClient = c
+				defer func() {
+					if allErrors != nil {
+						c.Close()
+					}
+				}()
+				ctx = oci.WithClient(ctx, c)
+				cmd.SetContext(ctx)
+			}
+
+			if data.reportSourceErrors {
+				ctx = source.WithFetchErrorsReported(ctx)
+				cmd.SetContext(ctx)
+			}
+
+			if s, m, err := applicationsnapshot.DetermineInputSpec(ctx, applicationsnapshot.Input{
 				File:     data.filePath,
 				JSON:     data.input,
 				Image:    data.imageRef,
@@ -205,6 +299,7 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 				allErrors = errors.Join(allErrors, err)
 			} else {
 				data.spec = s
+				data.manifests = m
 			}
 
 			policyConfiguration, err := validate_utils.GetPolicyConfig(ctx, data.policyConfiguration)
@@ -213,6 +308,18 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 				return
 			}
 			data.policyConfiguration = policyConfiguration
+			if data.policyURI != "" {
+				digest := sha256.Sum256([]byte(policyConfiguration))
+				data.policyDigest = hex.EncodeToString(digest[:])
+			}
+
+			var policyLock *lock.Lock
+			if data.policyLock != "" {
+				if policyLock, err = lock.Read(ctx, data.policyLock); err != nil {
+					allErrors = errors.Join(allErrors, err)
+					return
+				}
+			}
 
 			policyOptions := policy.Options{
 				EffectiveTime: data.effectiveTime,
@@ -223,14 +330,15 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 					SubjectRegExp: data.certificateIdentityRegExp,
 				},
 				IgnoreRekor: data.ignoreRekor,
+				PolicyLock:  policyLock,
 				PolicyRef:   data.policyConfiguration,
 				PublicKey:   data.publicKey,
 				RekorURL:    data.rekorURL,
 			}
 
-			// We're not currently using the policyCache returned from PreProcessPolicy, but we could
-			// use it to cache the policy for future use.
-			if p, _, err := policy.PreProcessPolicy(ctx, policyOptions); err != nil {
+			// The policyCache holds the directories the pinned sources were
+			// fetched to, the evidence export copies the sources from them
+			if p, policyCache, err := policy.PreProcessPolicy(ctx, policyOptions); err != nil {
 				allErrors = errors.Join(allErrors, err)
 			} else {
 				// inject extra variables into rule data per source
@@ -284,6 +392,7 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 					p = p.WithSpec(policySpec)
 				}
 				data.policy = p
+				data.policyCache = policyCache
 			}
 
 			return
@@ -296,6 +405,14 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 				defer task.End()
 			}
 
+			if data.layoutClient != nil {
+				defer data.layoutClient.Close()
+			}
+
+			if data.explain {
+				cmd.SetContext(evaluator.WithExplain(cmd.Context()))
+			}
+
 			type result struct {
 				err         error
 				component   applicationsnapshot.Component
@@ -305,11 +422,11 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 			appComponents := data.spec.Components
 			evaluators := []evaluator.Evaluator{}
 
-			// Return an evaluator for each of these
-			for _, sourceGroup := range data.policy.Spec().Sources {
-				// Todo: Make each fetch run concurrently
+			// Return an evaluator for each of these, the policy sources have
+			// already been fetched concurrently by PreProcessPolicy
+			for i, sourceGroup := range data.policy.Spec().Sources {
 				log.Debugf("Fetching policy source group '%s'", sourceGroup.Name)
-				policySources := source.PolicySourcesFrom(sourceGroup)
+				policySources := data.policy.PolicySources(i)
 
 				for _, policySource := range policySources {
 					log.Debugf("policySource: %#v", policySource)
@@ -318,13 +435,13 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 				var c evaluator.Evaluator
 				var err error
 				if utils.IsOpaEnabled() {
-					c, err = newOPAEvaluator()
+					c, err = newOPAEvaluator(cmd.Context(), policySources, data.policy, sourceGroup)
 				} else {
 					c, err = newConftestEvaluator(cmd.Context(), policySources, data.policy, sourceGroup)
 				}
 
 				if err != nil {
-					log.Debug("Failed to initialize the conftest evaluator!")
+					log.Debug("Failed to initialize the policy evaluator!")
 					return err
 				}
 
@@ -411,6 +528,7 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 
 			var components []applicationsnapshot.Component
 			var manyPolicyInput [][]byte
+			var evidenceComponents []evidence.Component
 			var allErrors error = nil
 			for i := 0; i < numComponents; i++ {
 				r := <-results
@@ -420,6 +538,10 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 				} else {
 					components = append(components, r.component)
 					manyPolicyInput = append(manyPolicyInput, r.policyInput)
+					evidenceComponents = append(evidenceComponents, evidence.Component{
+						SnapshotComponent: r.component.SnapshotComponent,
+						PolicyInput:       r.policyInput,
+					})
 				}
 			}
 			close(results)
@@ -427,6 +549,10 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 				return allErrors
 			}
 
+			if data.nestImageManifests {
+				components = applicationsnapshot.NestImageManifests(components, data.manifests)
+			}
+
 			// Ensure some consistency in output.
 			sort.Slice(components, func(i, j int) bool {
 				return components[i].ContainerImage > components[j].ContainerImage
@@ -440,12 +566,55 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 			if err != nil {
 				return err
 			}
+			report.VerifiedLevels = data.vsaVerifiedLevels
+			report.PolicyURI = data.policyURI
+			report.PolicyDigest = data.policyDigest
 			p := format.NewTargetParser(applicationsnapshot.JSON, format.Options{ShowSuccesses: showSuccesses}, cmd.OutOrStdout(), utils.FS(cmd.Context()))
 			utils.SetColorEnabled(data.noColor, data.forceColor)
 			if err := report.WriteAll(data.output, p); err != nil {
 				return err
 			}
 
+			if data.recordResults || data.resultsConfigMap != "" {
+				if err := applicationsnapshot.Record(cmd.Context(), report, applicationsnapshot.RecordOptions{
+					ConfigMap: data.resultsConfigMap,
+				}); err != nil {
+					return fmt.Errorf("recording the results in the cluster: %w", err)
+				}
+			}
+
+			if data.exportDir != "" {
+				sort.Slice(evidenceComponents, func(i, j int) bool {
+					return evidenceComponents[i].ContainerImage < evidenceComponents[j].ContainerImage
+				})
+				var flags []string
+				for _, name := range resultFlags {
+					if f := cmd.Flags().Lookup(name); f != nil && f.Changed {
+						flags = append(flags, fmt.Sprintf("--%s=%s", name, f.Value))
+					}
+				}
+				if err := evidence.Export(cmd.Context(), data.exportDir, evidence.Evidence{
+					Policy:     data.policy,
+					Sources:    data.policyCache,
+					Snapshot:   data.spec,
+					Components: evidenceComponents,
+					Flags:      flags,
+				}); err != nil {
+					return fmt.Errorf("exporting evidence: %w", err)
+				}
+			}
+
+			if data.attachVSA {
+				signer, err := vsa.NewSigner(cmd.Context(), data.vsaOptions)
+				if err != nil {
+					return err
+				}
+
+				if err := vsa.Attach(cmd.Context(), signer, report, data.vsaFormat); err != nil {
+					return err
+				}
+			}
+
 			if data.strict && !report.Success {
 				return errors.New("success criteria not met")
 			}
@@ -509,6 +678,13 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 	cmd.Flags().BoolVarP(&data.strict, "strict", "s", data.strict,
 		"Return non-zero status on non-successful validation. Defaults to true. Use --strict=false to return a zero status code.")
 
+	cmd.Flags().StringVar(&data.policyLock, "policy-lock", "", hd.Doc(`
+		Path to a policy lock file created by "ec policy lock". The policy and data
+		sources are fetched using the commit SHAs and image digests recorded in the
+		lock file, and validation fails if a source is missing from the lock file or
+		isn't locked to a commit SHA or an image digest.
+	`))
+
 	cmd.Flags().StringVar(&data.effectiveTime, "effective-time", policy.Now, hd.Doc(`
 		Run policy checks with the provided time. Useful for testing rules with
 		effective dates in the future. The value can be "now" (default) - for
@@ -529,15 +705,99 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 		violations, include the title and the description of the failed policy
 		rule.`))
 
+	cmd.Flags().BoolVar(&data.explain, "explain", data.explain, hd.Doc(`
+		Include an explanation of how the policy rule was evaluated for each
+		violation and warning, listing the rule's expressions, whether each
+		evaluated to true or false, and the input values they referenced.`))
+
+	cmd.Flags().BoolVar(&data.reportSourceErrors, "report-source-errors", data.reportSourceErrors, hd.Doc(`
+		Report policy sources that can't be fetched as violations of their source
+		group and continue evaluating the remaining source groups, instead of
+		failing the validation right away. The validation still fails.`))
+
 	cmd.Flags().BoolVar(&data.noColor, "no-color", data.info, hd.Doc(`
 		Disable color when using text output even when the current terminal supports it`))
 
 	cmd.Flags().BoolVar(&data.forceColor, "color", data.info, hd.Doc(`
 		Enable color when using text output even when the current terminal does not support it`))
 
+	cmd.Flags().BoolVar(&data.nestImageManifests, "nest-image-manifests", data.nestImageManifests, hd.Doc(`
+		Report the results of the platform specific images of an image index nested
+		under the result of the image index, labeled with their platform. The image
+		index is successful only if all of its platform specific images are.`))
+
 	cmd.Flags().IntVar(&data.workers, "workers", data.workers, hd.Doc(`
 		Number of workers to use for validation. Defaults to 5.`))
 
+	cmd.Flags().BoolVar(&data.attachVSA, "attach-vsa", data.attachVSA, hd.Doc(`
+		Sign a Verification Summary Attestation (VSA) of the validation for each
+		image and attach it to the image as a cosign attestation, replacing any VSA
+		attached previously.`))
+
+	cmd.Flags().StringVar(&data.vsaOptions.KeyRef, "vsa-signing-key", data.vsaOptions.KeyRef, hd.Doc(`
+		Private key used to sign the VSA, a path to a file or a KMS URI. The password
+		of an encrypted key is read from the COSIGN_PASSWORD environment variable. If
+		not provided the VSA is signed keyless, using a certificate issued by Fulcio.`))
+
+	cmd.Flags().StringVar(&data.vsaOptions.FulcioURL, "vsa-fulcio-url", data.vsaOptions.FulcioURL, hd.Doc(`
+		URL of the Fulcio instance issuing the certificate for keyless signing of the VSA`))
+
+	cmd.Flags().StringVar(&data.vsaOptions.RekorURL, "vsa-rekor-url", data.vsaOptions.RekorURL, hd.Doc(`
+		URL of the Rekor instance the VSA signature is recorded in. Use an empty string
+		to not record the signature in a transparency log.`))
+
+	cmd.Flags().StringVar(&data.vsaOptions.OIDCIssuer, "vsa-oidc-issuer", data.vsaOptions.OIDCIssuer, hd.Doc(`
+		URL of the OIDC issuer of the identity for keyless signing of the VSA`))
+
+	cmd.Flags().StringVar(&data.vsaOptions.IdentityToken, "vsa-identity-token", data.vsaOptions.IdentityToken, hd.Doc(`
+		OIDC identity token for keyless signing of the VSA. If not provided, it is
+		obtained from the environment when possible.`))
+
+	cmd.Flags().StringVar(&data.vsaFormat, "vsa-format", data.vsaFormat, hd.Doc(`
+		Format of the VSA attached to the images, one of: `+strings.Join(vsa.Formats, ", ")+`.
+		The "ec" format holds the validation report as the predicate, the "slsa"
+		format uses the SLSA Verification Summary predicate
+		(https://slsa.dev/verification_summary/v1) and requires the policy
+		configuration to be provided as a file or a URL.`))
+
+	cmd.Flags().StringSliceVar(&data.vsaVerifiedLevels, "vsa-verified-level", data.vsaVerifiedLevels, hd.Doc(`
+		SLSA level, e.g. SLSA_BUILD_LEVEL_3, claimed as verified in the SLSA
+		Verification Summary of images that pass the policy. Can be repeated.`))
+
+	cmd.Flags().StringVar(&data.exportDir, "export-dir", data.exportDir, hd.Doc(`
+		Export the inputs of the validation to the directory, or to a gzip compressed tar
+		archive if the path ends with .tar.gz or .tgz, so the validation can be re-run
+		offline. The export holds the policy with the sources it was evaluated from, the
+		validated image digests with their signatures, attestations and Sigstore
+		bundles, the policy input of each image, and a manifest.json file with the
+		digests of the exported files and the command to re-run the validation from
+		within the export directory.`))
+
+	cmd.Flags().BoolVar(&data.recordResults, "record-results", data.recordResults, hd.Doc(`
+		Record the outcome of the validation in the cluster the Snapshot provided via
+		--snapshot was fetched from. The Snapshot is annotated with the result, a
+		summary, the digest of the policy, and the time of the validation, and a
+		Warning Event is recorded for each component violating the policy.`))
+
+	cmd.Flags().StringVar(&data.resultsConfigMap, "results-configmap", data.resultsConfigMap, hd.Doc(`
+		Name of the ConfigMap, in the namespace of the Snapshot provided via
+		--snapshot, to create or replace with the validation report in JSON format.
+		The ConfigMap is owned by the Snapshot, and the outcome of the validation is
+		recorded as with --record-results. An existing ConfigMap is only replaced if
+		it was created by ec. If the report exceeds the 1 MiB limit of a ConfigMap,
+		its summary is stored instead and the command reports an error.`))
+
+	cmd.Flags().StringSliceVar(&data.ociLayouts, "oci-layout", data.ociLayouts, hd.Doc(`
+		Read the images, their signatures and attestations from the OCI image layout
+		instead of the registry, as written by "cosign save". Can be a directory, a
+		tar archive of it, or a directory containing multiple OCI image layouts. Can be
+		repeated. Images are looked up by their digest, or by the full image reference
+		in the "org.opencontainers.image.ref.name" annotation of the layout, so they
+		are best referenced by digest. Signatures and attestations are verified
+		offline using the transparency log bundles stored with them. For the keyless
+		workflow the Sigstore trust root needs to be available locally, e.g. via a
+		previously initialized TUF_ROOT.`))
+
 	if len(data.input) > 0 || len(data.filePath) > 0 || len(data.images) > 0 {
 		if err := cmd.MarkFlagRequired("image"); err != nil {
 			panic(err)
@@ -21,9 +21,13 @@ package validate
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -31,22 +35,32 @@ import (
 	hd "github.com/MakeNowJust/heredoc"
 	ociMetadata "github.com/conforma/go-gather/gather/oci"
 	"github.com/gkampitakis/go-snaps/snaps"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/random"
 	app "github.com/konflux-ci/application-api/api/v1alpha1"
 	"github.com/sigstore/cosign/v2/pkg/cosign"
+	cosignoci "github.com/sigstore/cosign/v2/pkg/oci"
+	"github.com/sigstore/cosign/v2/pkg/oci/layout"
+	"github.com/sigstore/cosign/v2/pkg/oci/signed"
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
 	log "github.com/sirupsen/logrus"
 	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"github.com/enterprise-contract/ec-cli/internal/applicationsnapshot"
 	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/evidence"
+	"github.com/enterprise-contract/ec-cli/internal/kubernetes"
 	"github.com/enterprise-contract/ec-cli/internal/output"
 	"github.com/enterprise-contract/ec-cli/internal/policy"
 	"github.com/enterprise-contract/ec-cli/internal/policy/source"
 	"github.com/enterprise-contract/ec-cli/internal/utils"
 	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
 	"github.com/enterprise-contract/ec-cli/internal/utils/oci/fake"
+	"github.com/enterprise-contract/ec-cli/internal/vsa"
 )
 
 type data struct {
@@ -280,7 +294,7 @@ func Test_determineInputSpec(t *testing.T) {
 	ctx := oci.WithClient(context.Background(), &client)
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			s, err := applicationsnapshot.DetermineInputSpec(ctx, applicationsnapshot.Input{
+			s, _, err := applicationsnapshot.DetermineInputSpec(ctx, applicationsnapshot.Input{
 				File:   c.arguments.filePath,
 				JSON:   c.arguments.input,
 				Image:  c.arguments.imageRef,
@@ -344,6 +358,329 @@ func Test_ValidateImageCommand(t *testing.T) {
 	  }`, effectiveTimeTest, utils.TestPublicKeyJSON, utils.TestPublicKeyJSON), out.String())
 }
 
+type stubVSASigner struct {
+	signed []any
+}
+
+func (s *stubVSASigner) Sign(_ context.Context, _ string, statement any) (cosignoci.Signature, error) {
+	s.signed = append(s.signed, statement)
+	return static.NewAttestation([]byte("{}"))
+}
+
+func Test_ValidateImageCommandAttachVSA(t *testing.T) {
+	validateImageCmd := validateImageCmd(happyValidator())
+	cmd := setUpCobra(validateImageCmd)
+
+	image := "registry/image@sha256:" + strings.Repeat("a", 64)
+	ref, err := name.NewDigest(image)
+	require.NoError(t, err)
+
+	client := fake.FakeClient{}
+	commonMockClient(&client)
+	client.On("AttachAttestation", ref, mock.Anything, applicationsnapshot.PredicateVSAProvenance).Return(nil)
+
+	signer := &stubVSASigner{}
+	ctx := utils.WithFS(context.Background(), afero.NewMemMapFs())
+	ctx = oci.WithClient(ctx, &client)
+	ctx = vsa.WithSigner(ctx, signer)
+	cmd.SetContext(ctx)
+
+	cmd.SetArgs(append(rootArgs, []string{
+		"--image",
+		image,
+		"--policy",
+		fmt.Sprintf(`{"publicKey": %s}`, utils.TestPublicKeyJSON),
+		"--attach-vsa",
+	}...))
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+
+	utils.SetTestRekorPublicKey(t)
+
+	require.NoError(t, cmd.Execute())
+
+	client.AssertCalled(t, "AttachAttestation", ref, mock.Anything, applicationsnapshot.PredicateVSAProvenance)
+	require.Len(t, signer.signed, 1)
+	signed, ok := signer.signed[0].(applicationsnapshot.ProvenanceStatementVSA)
+	require.True(t, ok)
+	require.Len(t, signed.Subject, 1)
+	assert.Equal(t, "index.docker.io/registry/image", signed.Subject[0].Name)
+	assert.Equal(t, strings.Repeat("a", 64), signed.Subject[0].Digest["sha256"])
+	assert.True(t, signed.Predicate.Success)
+}
+
+func Test_ValidateImageCommandAttachSLSAVSA(t *testing.T) {
+	validateImageCmd := validateImageCmd(happyValidator())
+	cmd := setUpCobra(validateImageCmd)
+
+	image := "registry/image@sha256:" + strings.Repeat("a", 64)
+	ref, err := name.NewDigest(image)
+	require.NoError(t, err)
+
+	client := fake.FakeClient{}
+	commonMockClient(&client)
+	client.On("AttachAttestation", ref, mock.Anything, applicationsnapshot.PredicateSLSAVSA).Return(nil)
+
+	signer := &stubVSASigner{}
+	fs := afero.NewMemMapFs()
+	policyConfig := []byte(fmt.Sprintf(`{"publicKey": %s}`, utils.TestPublicKeyJSON))
+	require.NoError(t, afero.WriteFile(fs, "/policy.json", policyConfig, 0644))
+	ctx := utils.WithFS(context.Background(), fs)
+	ctx = oci.WithClient(ctx, &client)
+	ctx = vsa.WithSigner(ctx, signer)
+	cmd.SetContext(ctx)
+
+	cmd.SetArgs(append(rootArgs, []string{
+		"--image",
+		image,
+		"--policy",
+		"/policy.json",
+		"--attach-vsa",
+		"--vsa-format",
+		"slsa",
+		"--vsa-verified-level",
+		"SLSA_BUILD_LEVEL_3",
+	}...))
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+
+	utils.SetTestRekorPublicKey(t)
+
+	require.NoError(t, cmd.Execute())
+
+	client.AssertCalled(t, "AttachAttestation", ref, mock.Anything, applicationsnapshot.PredicateSLSAVSA)
+	require.Len(t, signer.signed, 1)
+	signed, ok := signer.signed[0].(applicationsnapshot.SLSAProvenanceStatementVSA)
+	require.True(t, ok)
+	require.Len(t, signed.Subject, 1)
+	assert.Equal(t, "index.docker.io/registry/image", signed.Subject[0].Name)
+	assert.Equal(t, image, signed.Predicate.ResourceURI)
+	assert.Equal(t, applicationsnapshot.VerificationPassed, signed.Predicate.VerificationResult)
+	assert.Equal(t, []string{"SLSA_BUILD_LEVEL_3"}, signed.Predicate.VerifiedLevels)
+	assert.Equal(t, "file:///policy.json", signed.Predicate.Policy.URI)
+	// the digest is of the policy configuration as read from the file
+	policyDigest := sha256.Sum256(policyConfig)
+	assert.Equal(t, hex.EncodeToString(policyDigest[:]), signed.Predicate.Policy.Digest["sha256"])
+}
+
+func Test_ValidateImageCommandVSAFormatErrors(t *testing.T) {
+	cases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "unknown format",
+			args:     []string{"--vsa-format", "bogus"},
+			expected: `"bogus" is not a valid VSA format, expected one of: ec, slsa`,
+		},
+		{
+			name:     "slsa with inline policy",
+			args:     []string{"--attach-vsa", "--vsa-format", "slsa"},
+			expected: "--vsa-format slsa requires the --policy to be provided as a file or a URL",
+		},
+		{
+			name:     "slsa-vsa output with inline policy",
+			args:     []string{"--output", "json", "--output", "slsa-vsa=vsa.jsonl"},
+			expected: "--output slsa-vsa requires the --policy to be provided as a file or a URL",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			validate := func(context.Context, app.SnapshotComponent, *app.SnapshotSpec, policy.Policy, []evaluator.Evaluator, bool) (*output.Output, error) {
+				t.Fatal("the image should not be validated")
+				return nil, nil
+			}
+			cmd := setUpCobra(validateImageCmd(validate))
+			cmd.SetContext(utils.WithFS(context.Background(), afero.NewMemMapFs()))
+
+			cmd.SetArgs(append(append(rootArgs, []string{
+				"--image",
+				"registry/image@sha256:" + strings.Repeat("a", 64),
+				"--policy",
+				fmt.Sprintf(`{"publicKey": %s}`, utils.TestPublicKeyJSON),
+			}...), c.args...))
+
+			var out bytes.Buffer
+			cmd.SetOut(&out)
+
+			assert.EqualError(t, cmd.Execute(), c.expected)
+		})
+	}
+}
+
+func Test_ValidateImageCommandOCILayout(t *testing.T) {
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+	h, err := img.Digest()
+	require.NoError(t, err)
+	image := "registry/image@" + h.String()
+
+	dir := t.TempDir()
+	require.NoError(t, layout.WriteSignedImage(dir, signed.Image(img)))
+
+	var resolved []string
+	validator := func(ctx context.Context, component app.SnapshotComponent, spec *app.SnapshotSpec, p policy.Policy, e []evaluator.Evaluator, info bool) (*output.Output, error) {
+		client := oci.NewClient(ctx)
+		require.IsType(t, &oci.LayoutClient{}, client)
+
+		ref, err := name.ParseReference(component.ContainerImage)
+		require.NoError(t, err)
+		digest, err := client.ResolveDigest(ref)
+		require.NoError(t, err)
+		resolved = append(resolved, digest)
+
+		return happyValidator()(ctx, component, spec, p, e, info)
+	}
+
+	cmd := setUpCobra(validateImageCmd(validator))
+	cmd.SetContext(utils.WithFS(context.Background(), afero.NewMemMapFs()))
+
+	cmd.SetArgs(append(rootArgs, []string{
+		"--image",
+		image,
+		"--policy",
+		fmt.Sprintf(`{"publicKey": %s}`, utils.TestPublicKeyJSON),
+		"--oci-layout",
+		dir,
+	}...))
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+
+	utils.SetTestRekorPublicKey(t)
+
+	require.NoError(t, cmd.Execute())
+	assert.Equal(t, []string{h.String()}, resolved)
+}
+
+func Test_ValidateImageCommandExportDir(t *testing.T) {
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+	h, err := img.Digest()
+	require.NoError(t, err)
+	image := "registry/image@" + h.String()
+
+	dir := t.TempDir()
+	require.NoError(t, layout.WriteSignedImage(dir, signed.Image(img)))
+
+	cmd := setUpCobra(validateImageCmd(happyValidator()))
+	cmd.SetContext(utils.WithFS(context.Background(), afero.NewOsFs()))
+
+	exportDir := filepath.Join(t.TempDir(), "evidence")
+	cmd.SetArgs(append(rootArgs, []string{
+		"--image",
+		image,
+		"--policy",
+		fmt.Sprintf(`{"publicKey": %s}`, utils.TestPublicKeyJSON),
+		"--oci-layout",
+		dir,
+		"--export-dir",
+		exportDir,
+		"--strict=false",
+	}...))
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+
+	utils.SetTestRekorPublicKey(t)
+
+	require.NoError(t, cmd.Execute())
+
+	data, err := os.ReadFile(filepath.Join(exportDir, evidence.ManifestFile))
+	require.NoError(t, err)
+	var manifest evidence.Manifest
+	require.NoError(t, json.Unmarshal(data, &manifest))
+	require.Len(t, manifest.Components, 1)
+	assert.Equal(t, "index.docker.io/"+image, manifest.Components[0].ContainerImage)
+	assert.FileExists(t, filepath.Join(exportDir, manifest.Components[0].Image, "index.json"))
+	// the flags affecting the result are included in the command re-running
+	// the validation
+	assert.True(t, strings.HasSuffix(manifest.Command, " --strict=false"), manifest.Command)
+}
+
+func Test_ValidateImageCommandRecordResults(t *testing.T) {
+	validateImageCmd := validateImageCmd(happyValidator())
+	cmd := setUpCobra(validateImageCmd)
+
+	client := fake.FakeClient{}
+	commonMockClient(&client)
+	k8s := &policy.FakeKubernetesClient{
+		Snapshot: app.SnapshotSpec{
+			Components: []app.SnapshotComponent{
+				{Name: "image", ContainerImage: "registry/image:tag"},
+			},
+		},
+	}
+	ctx := utils.WithFS(context.Background(), afero.NewMemMapFs())
+	ctx = oci.WithClient(ctx, &client)
+	ctx = kubernetes.WithClient(ctx, k8s)
+	cmd.SetContext(ctx)
+
+	cmd.SetArgs(append(rootArgs, []string{
+		"--snapshot",
+		"snapshot",
+		"--policy",
+		fmt.Sprintf(`{"publicKey": %s}`, utils.TestPublicKeyJSON),
+		"--record-results",
+		"--results-configmap",
+		"results",
+	}...))
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+
+	utils.SetTestRekorPublicKey(t)
+
+	require.NoError(t, cmd.Execute())
+
+	assert.Equal(t, "success", k8s.Annotations[applicationsnapshot.ResultAnnotation])
+	assert.Equal(t, "results", k8s.Annotations[applicationsnapshot.ResultsConfigMapAnnotation])
+	assert.Empty(t, k8s.Events)
+	require.Len(t, k8s.ConfigMaps, 1)
+	assert.JSONEq(t, out.String(), k8s.ConfigMaps[0].Data[applicationsnapshot.ReportKey])
+}
+
+func Test_ValidateImageCommandRecordResultsWithoutSnapshot(t *testing.T) {
+	cmd := setUpCobra(validateImageCmd(happyValidator()))
+	cmd.SetContext(utils.WithFS(context.Background(), afero.NewMemMapFs()))
+
+	cmd.SetArgs(append(rootArgs, []string{
+		"--image",
+		"registry/image:tag",
+		"--policy",
+		fmt.Sprintf(`{"publicKey": %s}`, utils.TestPublicKeyJSON),
+		"--record-results",
+	}...))
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+
+	assert.EqualError(t, cmd.Execute(), "--record-results and --results-configmap require --snapshot")
+}
+
+func Test_ValidateImageCommandOCILayoutMissing(t *testing.T) {
+	cmd := setUpCobra(validateImageCmd(happyValidator()))
+	cmd.SetContext(utils.WithFS(context.Background(), afero.NewMemMapFs()))
+
+	cmd.SetArgs(append(rootArgs, []string{
+		"--image",
+		"registry/image:tag",
+		"--policy",
+		fmt.Sprintf(`{"publicKey": %s}`, utils.TestPublicKeyJSON),
+		"--oci-layout",
+		t.TempDir(),
+	}...))
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+
+	assert.ErrorContains(t, cmd.Execute(), "no signed images found in OCI layouts")
+}
+
 func Test_ValidateImageCommandImages(t *testing.T) {
 	validateImageCmd := validateImageCmd(happyValidator())
 	cmd := setUpCobra(validateImageCmd)
@@ -1349,3 +1686,86 @@ func TestContainsAttestation(t *testing.T) {
 		assert.Equal(t, test.expected, result, test.name)
 	}
 }
+
+func Test_ValidateImageCommandReportSourceErrors(t *testing.T) {
+	evaluatingValidator := func(ctx context.Context, component app.SnapshotComponent, _ *app.SnapshotSpec, _ policy.Policy, evaluators []evaluator.Evaluator, _ bool) (*output.Output, error) {
+		out, err := happyValidator()(ctx, component, nil, nil, nil, false)
+		if err != nil {
+			return nil, err
+		}
+		out.PolicyCheck = nil
+		for _, e := range evaluators {
+			results, err := e.Evaluate(ctx, evaluator.EvaluationTarget{Inputs: []string{"/input.json"}})
+			if err != nil {
+				return nil, err
+			}
+			out.PolicyCheck = append(out.PolicyCheck, results...)
+		}
+		return out, nil
+	}
+
+	cases := []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{
+			name: "fails right away",
+			err:  `unable to fetch policy source "registry/flaky-policy:latest": connection refused`,
+		},
+		{
+			name: "reports source errors",
+			args: []string{"--report-source-errors"},
+			err:  "success criteria not met",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cmd := setUpCobra(validateImageCmd(evaluatingValidator))
+
+			fs := afero.NewMemMapFs()
+			ctx := utils.WithFS(context.Background(), fs)
+			client := fake.FakeClient{}
+			commonMockClient(&client)
+			ctx = oci.WithClient(ctx, &client)
+
+			mdl := MockDownloader{}
+			mdl.On("Download", mock.Anything, "registry/flaky-policy:latest", false).Return(&ociMetadata.OCIMetadata{}, errors.New("connection refused"))
+			ctx = context.WithValue(ctx, source.DownloaderFuncKey, &mdl)
+			cmd.SetContext(ctx)
+
+			require.NoError(t, afero.WriteFile(fs, "/policy.yaml", []byte(`{"sources": [{"name": "flaky", "policy": ["registry/flaky-policy:latest"]}]}`), 0644))
+
+			cmd.SetArgs(append(append(rootArgs, []string{
+				"--image", "registry/image:tag",
+				"--public-key", utils.TestPublicKey,
+				"--policy", "/policy.yaml",
+			}...), c.args...))
+
+			var out bytes.Buffer
+			cmd.SetOut(&out)
+
+			utils.SetTestRekorPublicKey(t)
+
+			err := cmd.Execute()
+			assert.EqualError(t, err, c.err)
+			if len(c.args) == 0 {
+				return
+			}
+
+			var report struct {
+				Success    bool `json:"success"`
+				Components []struct {
+					Violations []evaluator.Result `json:"violations"`
+				} `json:"components"`
+			}
+			require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+			assert.False(t, report.Success)
+			require.Len(t, report.Components, 1)
+			require.Len(t, report.Components[0].Violations, 1)
+			assert.Equal(t, `Unable to fetch the policy source "registry/flaky-policy:latest" of the source group "flaky": connection refused`, report.Components[0].Violations[0].Message)
+			assert.Equal(t, "builtin.policy.source_accessible", report.Components[0].Violations[0].Metadata["code"])
+		})
+	}
+}
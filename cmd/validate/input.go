@@ -28,32 +28,78 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
-	"github.com/enterprise-contract/ec-cli/internal/applicationsnapshot"
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
 	"github.com/enterprise-contract/ec-cli/internal/format"
 	"github.com/enterprise-contract/ec-cli/internal/input"
-	"github.com/enterprise-contract/ec-cli/internal/output"
 	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/policy/lock"
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
 	"github.com/enterprise-contract/ec-cli/internal/utils"
 	validate_utils "github.com/enterprise-contract/ec-cli/internal/validate"
 )
 
-type InputValidationFunc func(context.Context, string, policy.Policy, bool) (*output.Output, error)
+type InputValidationFunc func(context.Context, string, policy.Policy, bool) (*input.Output, error)
+
+// inputResult is the outcome of validating a single input file path, holding
+// an input for each file, or document, validated for the path
+type inputResult struct {
+	err         error
+	filePath    string
+	inputs      []input.Input
+	policyInput []byte
+}
 
 func validateInputCmd(validate InputValidationFunc) *cobra.Command {
 	data := struct {
 		effectiveTime       string
+		exclude             []string
+		explain             bool
 		filePaths           []string
+		filters             input.Filters
+		include             []string
 		info                bool
 		namespaces          []string
 		output              []string
 		policy              policy.Policy
 		policyConfiguration string
+		policyLock          string
+		reportSourceErrors  bool
 		strict              bool
+		watch               bool
 		workers             int
 	}{
 		strict:  true,
 		workers: 5,
 	}
+
+	// loadPolicy reads the policy configuration and applies the policy lock
+	loadPolicy := func(ctx context.Context) (policy.Policy, error) {
+		policyConfiguration, err := validate_utils.GetPolicyConfig(ctx, data.policyConfiguration)
+		if err != nil {
+			return nil, err
+		}
+
+		p, err := policy.NewInputPolicy(ctx, policyConfiguration, data.effectiveTime)
+		if err != nil {
+			return nil, err
+		}
+
+		if data.policyLock != "" {
+			l, err := lock.Read(ctx, data.policyLock)
+			if err != nil {
+				return nil, err
+			}
+
+			spec, err := l.Apply(ctx, p.Spec())
+			if err != nil {
+				return nil, err
+			}
+			p = p.WithSpec(spec)
+		}
+
+		return p, nil
+	}
+
 	cmd := &cobra.Command{
 		Use:   "input",
 		Short: "Validate arbitrary JSON or yaml file input conformance with the provided policies",
@@ -85,21 +131,26 @@ func validateInputCmd(validate InputValidationFunc) *cobra.Command {
 
 			  ec validate input --file /path/to/file.yaml --policy github.com/user/repo
 
+			Validate the Kubernetes manifests in a directory and its subdirectories, skipping
+			the templates. Each document of a multi-document YAML file is reported separately.
+			ec validate input --file /path/to/manifests --include "*.yaml" --exclude "templates" --policy my-policy.yaml
+
+			Validate the files again whenever they, or the policy with local sources, change
+			while writing the policy
+			ec validate input --file /path/to/dir --policy my-policy.yaml --watch
+
 `),
 		PreRunE: func(cmd *cobra.Command, args []string) (allErrors error) {
-			ctx := cmd.Context()
-
-			policyConfiguration, err := validate_utils.GetPolicyConfig(ctx, data.policyConfiguration)
+			p, err := loadPolicy(cmd.Context())
 			if err != nil {
 				allErrors = errors.Join(allErrors, err)
 				return
 			}
-			data.policyConfiguration = policyConfiguration
 
-			if p, err := policy.NewInputPolicy(cmd.Context(), data.policyConfiguration, data.effectiveTime); err != nil {
+			data.policy = p
+
+			if data.filters, err = input.NewFilters(data.include, data.exclude); err != nil {
 				allErrors = errors.Join(allErrors, err)
-			} else {
-				data.policy = p
 			}
 			return
 		},
@@ -110,104 +161,142 @@ func validateInputCmd(validate InputValidationFunc) *cobra.Command {
 				defer task.End()
 			}
 
-			type result struct {
-				err         error
-				input       input.Input
-				policyInput []byte
+			if data.explain {
+				cmd.SetContext(evaluator.WithExplain(cmd.Context()))
 			}
 
+			if data.reportSourceErrors {
+				cmd.SetContext(source.WithFetchErrorsReported(cmd.Context()))
+			}
+
+			cmd.SetContext(input.WithFilters(cmd.Context(), data.filters))
+
 			showSuccesses, _ := cmd.Flags().GetBool("show-successes")
 
-			// Set numWorkers to the value from our flag. The default is 5.
-			numWorkers := data.workers
-
-			jobs := make(chan string, len(data.filePaths))
-			results := make(chan result, len(data.filePaths))
-
-			// worker function processes one file path at a time.
-			worker := func(id int, jobs <-chan string, results chan<- result) {
-				log.Debugf("Starting worker %d", id)
-				for fpath := range jobs {
-					ctx := cmd.Context()
-					var task *trace.Task
-					if trace.IsEnabled() {
-						ctx, task = trace.NewTask(ctx, "ec:validate-input")
-						trace.Logf(ctx, "", "workerID=%d, file=%s", id, fpath)
-					}
+			// validateFiles validates the files using a pool of workers, the
+			// results are keyed by the file path
+			validateFiles := func(ctx context.Context, filePaths []string) map[string]inputResult {
+				// Set numWorkers to the value from our flag. The default is 5.
+				numWorkers := data.workers
+
+				jobs := make(chan string, len(filePaths))
+				results := make(chan inputResult, len(filePaths))
+
+				// worker function processes one file path at a time.
+				worker := func(id int, jobs <-chan string, results chan<- inputResult) {
+					log.Debugf("Starting worker %d", id)
+					for fpath := range jobs {
+						ctx := ctx
+						var task *trace.Task
+						if trace.IsEnabled() {
+							ctx, task = trace.NewTask(ctx, "ec:validate-input")
+							trace.Logf(ctx, "", "workerID=%d, file=%s", id, fpath)
+						}
 
-					out, err := validate(ctx, fpath, data.policy, data.info)
-					res := result{
-						err: err,
-						input: input.Input{
-							FilePath: fpath,
-							Success:  err == nil,
-						},
-					}
+						out, err := validate(ctx, fpath, data.policy, data.info)
+						res := inputResult{
+							err:      err,
+							filePath: fpath,
+						}
 
-					if err == nil {
-						res.input.Violations = out.Violations()
-						res.input.Warnings = out.Warnings()
+						if err == nil {
+							res.inputs = input.NewInputs(fpath, out, showSuccesses)
+							res.policyInput = out.PolicyInput
+						}
 
-						successes := out.Successes()
-						res.input.SuccessCount = len(successes)
-						if showSuccesses {
-							res.input.Successes = successes
+						if task != nil {
+							task.End()
 						}
-						res.input.Success = (len(res.input.Violations) == 0)
-						res.policyInput = out.PolicyInput
+						results <- res
 					}
+					log.Debugf("Done with worker %d", id)
+				}
 
-					if task != nil {
-						task.End()
-					}
-					results <- res
+				// Start the worker pool
+				for i := 0; i < numWorkers; i++ {
+					go worker(i, jobs, results)
 				}
-				log.Debugf("Done with worker %d", id)
-			}
 
-			// Start the worker pool
-			for i := 0; i < numWorkers; i++ {
-				go worker(i, jobs, results)
-			}
+				// Push all jobs (file paths) to the jobs channel
+				for _, f := range filePaths {
+					jobs <- f
+				}
+				close(jobs)
 
-			// Push all jobs (file paths) to the jobs channel
-			for _, f := range data.filePaths {
-				jobs <- f
-			}
-			close(jobs)
-
-			var inputs []input.Input
-			var manyPolicyInput [][]byte
-			var allErrors error = nil
-
-			// Collect all results
-			for i := 0; i < len(data.filePaths); i++ {
-				r := <-results
-				if r.err != nil {
-					e := fmt.Errorf("error validating file %s: %w", r.input.FilePath, r.err)
-					allErrors = errors.Join(allErrors, e)
-				} else {
-					inputs = append(inputs, r.input)
-					manyPolicyInput = append(manyPolicyInput, r.policyInput)
+				// Collect all results
+				collected := make(map[string]inputResult, len(filePaths))
+				for i := 0; i < len(filePaths); i++ {
+					r := <-results
+					collected[r.filePath] = r
 				}
+				close(results)
+
+				return collected
 			}
-			close(results)
 
-			if allErrors != nil {
-				return allErrors
+			// newReport returns the report of the results, failing if any
+			// of the files could not be validated
+			newReport := func(results map[string]inputResult) (input.Report, error) {
+				var inputs []input.Input
+				var manyPolicyInput [][]byte
+				var allErrors error = nil
+
+				for _, f := range data.filePaths {
+					r, ok := results[f]
+					if !ok {
+						continue
+					}
+					if r.err != nil {
+						e := fmt.Errorf("error validating file %s: %w", r.filePath, r.err)
+						allErrors = errors.Join(allErrors, e)
+					} else {
+						inputs = append(inputs, r.inputs...)
+						manyPolicyInput = append(manyPolicyInput, r.policyInput)
+					}
+				}
+
+				if allErrors != nil {
+					return input.Report{}, allErrors
+				}
+
+				// Sort inputs for consistent output
+				sort.SliceStable(inputs, func(i, j int) bool {
+					if inputs[i].FilePath != inputs[j].FilePath {
+						return inputs[i].FilePath > inputs[j].FilePath
+					}
+					return inputs[i].Document < inputs[j].Document
+				})
+
+				return input.NewReport(inputs, data.policy, manyPolicyInput)
 			}
 
-			// Sort inputs for consistent output
-			sort.Slice(inputs, func(i, j int) bool {
-				return inputs[i].FilePath > inputs[j].FilePath
-			})
+			p := format.NewTargetParser(input.JSON, format.Options{ShowSuccesses: showSuccesses}, cmd.OutOrStdout(), utils.FS(cmd.Context()))
+
+			if data.watch {
+				return inputWatch{
+					filePaths:    data.filePaths,
+					policyConfig: data.policyConfiguration,
+					policyLock:   data.policyLock,
+					policy:       data.policy,
+					reloadPolicy: func(ctx context.Context) (policy.Policy, error) {
+						p, err := loadPolicy(ctx)
+						if err == nil {
+							data.policy = p
+						}
+						return p, err
+					},
+					validate: validateFiles,
+					report:   newReport,
+					output:   data.output,
+					parser:   p,
+				}.run(cmd)
+			}
 
-			report, err := input.NewReport(inputs, data.policy, manyPolicyInput)
+			report, err := newReport(validateFiles(cmd.Context(), data.filePaths))
 			if err != nil {
 				return err
 			}
 
-			p := format.NewTargetParser(input.JSON, format.Options{ShowSuccesses: showSuccesses}, cmd.OutOrStdout(), utils.FS(cmd.Context()))
 			if err := report.WriteAll(data.output, p); err != nil {
 				return err
 			}
@@ -220,7 +309,20 @@ func validateInputCmd(validate InputValidationFunc) *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringSliceVarP(&data.filePaths, "file", "f", data.filePaths, "path to input YAML/JSON file (required)")
+	cmd.Flags().StringSliceVarP(&data.filePaths, "file", "f", data.filePaths, hd.Doc(`
+		path to input YAML/JSON file, or to a directory in which all files of a supported
+		type, e.g. YAML, JSON, TOML, HCL or Dockerfile, are validated recursively (required)`))
+
+	cmd.Flags().StringSliceVar(&data.include, "include", data.include, hd.Doc(`
+		Validate only the files matching the glob pattern within directories provided with
+		--file. A pattern without a slash matches the file name, e.g. "*.yaml", otherwise
+		the path relative to the directory, where "**" matches any number of directories,
+		e.g. "overlays/**/*.yaml". May be used multiple times.`))
+
+	cmd.Flags().StringSliceVar(&data.exclude, "exclude", data.exclude, hd.Doc(`
+		Skip the files and directories matching the glob pattern within directories
+		provided with --file, matched the same way as with --include, e.g. "vendor" or
+		"*.tmpl.yaml". Takes precedence over --include. May be used multiple times.`))
 
 	cmd.Flags().StringVarP(&data.policyConfiguration, "policy", "p", data.policyConfiguration, hd.Doc(`
 		Policy configuration as:
@@ -228,7 +330,7 @@ func validateInputCmd(validate InputValidationFunc) *cobra.Command {
 		* git reference (github.com/user/repo//default?ref=main), or
 		* inline JSON ('{sources: {...}}')")`))
 
-	validOutputFormats := applicationsnapshot.OutputFormats
+	validOutputFormats := input.OutputFormats
 	cmd.Flags().StringSliceVarP(&data.output, "output", "o", data.output, hd.Doc(`
 		Write output to a file in a specific format, e.g. yaml=/tmp/output.yaml. Use empty string
 		path for stdout, e.g. yaml. May be used multiple times. Possible formats are:
@@ -240,6 +342,12 @@ func validateInputCmd(validate InputValidationFunc) *cobra.Command {
 	cmd.Flags().BoolVarP(&data.strict, "strict", "s", data.strict,
 		"Return non-zero status on non-successful validation")
 
+	cmd.Flags().StringVar(&data.policyLock, "policy-lock", "", hd.Doc(`
+		Path to a policy lock file created by "ec policy lock". The policy and data
+		sources are fetched using the commit SHAs and image digests recorded in the
+		lock file, and validation fails if a source is missing from the lock file or
+		isn't locked to a commit SHA or an image digest.`))
+
 	cmd.Flags().StringVar(&data.effectiveTime, "effective-time", policy.Now, hd.Doc(`
 		Run policy checks with the provided time. Useful for testing rules with
 		effective dates in the future. The value can be "now" (default) - for
@@ -250,9 +358,27 @@ func validateInputCmd(validate InputValidationFunc) *cobra.Command {
 		violations, include the title and the description of the failed policy
 		rule.`))
 
+	cmd.Flags().BoolVar(&data.explain, "explain", data.explain, hd.Doc(`
+		Include an explanation of how the policy rule was evaluated for each
+		violation and warning, listing the rule's expressions, whether each
+		evaluated to true or false, and the input values they referenced.`))
+
+	cmd.Flags().BoolVar(&data.reportSourceErrors, "report-source-errors", data.reportSourceErrors, hd.Doc(`
+		Report policy sources that can't be fetched as violations of their source
+		group and continue evaluating the remaining source groups, instead of
+		failing the validation right away. The validation still fails.`))
+
 	cmd.Flags().IntVar(&data.workers, "workers", data.workers, hd.Doc(`
 		Number of workers to use for validation. Defaults to 5.`))
 
+	cmd.Flags().BoolVar(&data.watch, "watch", data.watch, hd.Doc(`
+		Keep running and validate again whenever the input files, or the local policy
+		and data sources, e.g. "./policy" or "file::/path/to/policy", change. Only the
+		changed inputs are validated again, unless the policy changed. The report is
+		written again after each validation, in the text format if no --output is
+		provided, followed by the rules that started or stopped failing. The global
+		--timeout doesn't apply, stop with Ctrl+C.`))
+
 	if err := cmd.MarkFlagRequired("file"); err != nil {
 		panic(err)
 	}
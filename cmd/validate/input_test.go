@@ -23,17 +23,26 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	gitMetadata "github.com/conforma/go-gather/gather/git"
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/input"
 	"github.com/enterprise-contract/ec-cli/internal/output"
 	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
 	"github.com/enterprise-contract/ec-cli/internal/utils"
 	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
 	"github.com/enterprise-contract/ec-cli/internal/utils/oci/fake"
@@ -41,9 +50,12 @@ import (
 
 // mockValidate is a helper function that returns a specified Output and error for testing.
 func mockValidate(out *output.Output, err error) InputValidationFunc {
-	return func(_ context.Context, fpath string, _ policy.Policy, _ bool) (*output.Output, error) {
+	return func(_ context.Context, fpath string, _ policy.Policy, _ bool) (*input.Output, error) {
 		// This function ignores the actual file content and always returns the provided output and error.
-		return out, err
+		if out == nil {
+			return nil, err
+		}
+		return input.NewOutput(*out), err
 	}
 }
 
@@ -159,6 +171,93 @@ func Test_ValidateInputCmd_SuccessMultipleFiles(t *testing.T) {
 	assert.Equal(t, sorted, filePaths)
 }
 
+func Test_ValidateInputCmd_Documents(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/dir/multi.yaml", []byte("kind: A\n---\nkind: B"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/dir/single.json", []byte(`{"kind": "C"}`), 0644))
+
+	// Mock validator: returns the results of each file, and document, of the
+	// directory
+	validate := func(_ context.Context, _ string, _ policy.Policy, _ bool) (*input.Output, error) {
+		return &input.Output{
+			Outcomes: []input.Outcome{
+				{Outcome: evaluator.Outcome{FileName: "/dir/single.json", Successes: []evaluator.Result{{Message: "Pass"}}}},
+				{Outcome: evaluator.Outcome{FileName: "/dir/multi.yaml", Failures: []evaluator.Result{{Message: "Fail"}}}, Document: 2},
+				{Outcome: evaluator.Outcome{FileName: "/dir/multi.yaml", Successes: []evaluator.Result{{Message: "Pass"}}}, Document: 1},
+			},
+		}, nil
+	}
+
+	cmd, buf := setUpValidateInputCmd(validate, fs)
+	cmd.SetArgs([]string{
+		"input",
+		"--file", "/dir",
+		"--policy", `{"publicKey": "testkey"}`,
+		"--strict=false",
+		"--output", "summary",
+	})
+
+	utils.SetTestRekorPublicKey(t)
+	require.NoError(t, cmd.Execute())
+
+	assert.JSONEq(t, `{
+		"success": false,
+		"key": "",
+		"filepaths": [
+			{
+				"name": "/dir/single.json",
+				"success": true,
+				"violations": {},
+				"warnings": {},
+				"successes": {},
+				"total_violations": 0,
+				"total_warnings": 0,
+				"total_successes": 1
+			},
+			{
+				"name": "/dir/multi.yaml",
+				"document": 1,
+				"success": true,
+				"violations": {},
+				"warnings": {},
+				"successes": {},
+				"total_violations": 0,
+				"total_warnings": 0,
+				"total_successes": 1
+			},
+			{
+				"name": "/dir/multi.yaml",
+				"document": 2,
+				"success": false,
+				"violations": {},
+				"warnings": {},
+				"successes": {},
+				"total_violations": 1,
+				"total_warnings": 0,
+				"total_successes": 0
+			}
+		]
+	}`, buf.String())
+}
+
+func Test_ValidateInputCmd_InvalidFilePattern(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/dir/file.yaml", []byte("some: data"), 0644))
+
+	cmd, _ := setUpValidateInputCmd(mockValidate(&output.Output{}, nil), fs)
+	cmd.SetArgs([]string{
+		"input",
+		"--file", "/dir",
+		"--policy", `{"publicKey": "testkey"}`,
+		"--include", "*.yaml",
+		"--exclude", "[unterminated",
+	})
+
+	utils.SetTestRekorPublicKey(t)
+	err := cmd.Execute()
+	assert.ErrorContains(t, err, `invalid file pattern "[unterminated"`)
+}
+
 func Test_ValidateInputCmd_Failure(t *testing.T) {
 	fs := afero.NewMemMapFs()
 	require.NoError(t, afero.WriteFile(fs, "/bad.yaml", []byte("invalid"), 0644))
@@ -307,3 +406,175 @@ func Test_ValidateInputCmd_EmptyPolicyFile(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "file /policy.yaml is empty")
 }
+
+func Test_ValidateInputCmd_PolicyLock(t *testing.T) {
+	const (
+		sourceURL = "git::https://example.com/org/input-lock//policy?ref=main"
+		pinnedURL = "git::example.com/org/input-lock//policy?ref=6b0ae8c8f2d1d1f5a2c9d8c4b6fcd2a4a6b8e0f1"
+		branchURL = "git::example.com/org/input-lock//policy?ref=main"
+	)
+
+	cases := []struct {
+		name   string
+		pinned string
+		err    string
+	}{
+		{
+			name:   "pinned",
+			pinned: pinnedURL,
+		},
+		{
+			name:   "mutable",
+			pinned: branchURL,
+			err:    fmt.Sprintf("policy source %q of the policy source group \"default\" is locked to %q, which isn't a commit SHA or an image digest", sourceURL, branchURL),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			require.NoError(t, afero.WriteFile(fs, "/input.yaml", []byte("some: data"), 0644))
+			require.NoError(t, afero.WriteFile(fs, "/policy.yaml", []byte(`{"sources": [{"name": "default", "policy": ["`+sourceURL+`"]}]}`), 0644))
+			require.NoError(t, afero.WriteFile(fs, "/policy.lock.yaml", []byte(`{"version": 1, "sources": [{"name": "default", "policy": [{"url": "`+sourceURL+`", "pinned": "`+c.pinned+`"}]}]}`), 0644))
+
+			var evaluated []string
+			validate := func(_ context.Context, _ string, p policy.Policy, _ bool) (*input.Output, error) {
+				evaluated = p.Spec().Sources[0].Policy
+				return &input.Output{}, nil
+			}
+
+			cmd, _ := setUpValidateInputCmd(validate, fs)
+			mdl := MockDownloader{}
+			mdl.On("Download", mock.Anything, c.pinned, false).Return(&gitMetadata.GitMetadata{LatestCommit: "6b0ae8c8f2d1d1f5a2c9d8c4b6fcd2a4a6b8e0f1"}, nil)
+			cmd.SetContext(context.WithValue(cmd.Context(), source.DownloaderFuncKey, &mdl))
+			cmd.SetArgs([]string{
+				"input",
+				"--file", "/input.yaml",
+				"--policy", "/policy.yaml",
+				"--policy-lock", "/policy.lock.yaml",
+			})
+
+			err := cmd.Execute()
+			if c.err != "" {
+				assert.EqualError(t, err, c.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, []string{pinnedURL}, evaluated)
+		})
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func Test_ValidateInputCmd_Watch(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/input.yaml", []byte("ok: false"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/other.yaml", []byte("ok: true"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/policy.yaml", []byte(`{"sources": [{"policy": ["/policies"]}]}`), 0644))
+
+	// the validation fails for the files that are not ok
+	calls := map[string]*atomic.Int32{"/input.yaml": {}, "/other.yaml": {}}
+	validate := func(ctx context.Context, fpath string, _ policy.Policy, _ bool) (*input.Output, error) {
+		calls[fpath].Add(1)
+		content, err := afero.ReadFile(utils.FS(ctx), fpath)
+		if err != nil {
+			return nil, err
+		}
+		out := &output.Output{}
+		if strings.Contains(string(content), "ok: false") {
+			out.PolicyCheck = []evaluator.Outcome{
+				{Failures: []evaluator.Result{{Message: "Not ok", Metadata: map[string]any{"code": "main.ok"}}}},
+			}
+		}
+		return input.NewOutput(*out), nil
+	}
+
+	interval := watchInterval
+	watchInterval = 5 * time.Millisecond
+	stopCtx, stop := context.WithCancel(context.Background())
+	watch := watchContext
+	watchContext = func(ctx context.Context) (context.Context, context.CancelFunc) {
+		ctx, cancel := context.WithCancel(ctx)
+		context.AfterFunc(stopCtx, cancel)
+		return ctx, cancel
+	}
+	t.Cleanup(func() {
+		stop()
+		watchInterval = interval
+		watchContext = watch
+	})
+
+	cmd, _ := setUpValidateInputCmd(validate, fs)
+	out := &syncBuffer{}
+	cmd.SetOut(out)
+	cmd.SetArgs([]string{
+		"input",
+		"--file", "/input.yaml",
+		"--file", "/other.yaml",
+		"--policy", "/policy.yaml",
+		"--watch",
+	})
+
+	utils.SetTestRekorPublicKey(t)
+	done := make(chan error)
+	go func() {
+		done <- cmd.Execute()
+	}()
+
+	eventually := func(condition func() bool) {
+		require.Eventually(t, condition, 5*time.Second, time.Millisecond, out.String())
+	}
+	eventually(func() bool {
+		return strings.Contains(out.String(), "watching for changes")
+	})
+	assert.Contains(t, out.String(), "Success: false")
+	assert.Contains(t, out.String(), "[Violation] main.ok")
+
+	// only the changed file is validated again
+	require.NoError(t, afero.WriteFile(fs, "/input.yaml", []byte("ok: true"), 0644))
+	eventually(func() bool {
+		return strings.Contains(out.String(), "Newly passing: [main.ok] /input.yaml")
+	})
+	assert.Equal(t, int32(2), calls["/input.yaml"].Load())
+	assert.Equal(t, int32(1), calls["/other.yaml"].Load())
+
+	require.NoError(t, afero.WriteFile(fs, "/other.yaml", []byte("ok: false"), 0644))
+	eventually(func() bool {
+		return strings.Contains(out.String(), "Newly failing: [main.ok] /other.yaml")
+	})
+
+	// all files are validated again when the policy changes
+	require.NoError(t, afero.WriteFile(fs, "/policy.yaml", []byte(`{"sources": [{"policy": ["/policies", "/more"]}]}`), 0644))
+	eventually(func() bool {
+		return calls["/input.yaml"].Load() == 3 && calls["/other.yaml"].Load() == 3
+	})
+	eventually(func() bool {
+		return strings.Contains(out.String(), "No rules started or stopped failing since the previous evaluation")
+	})
+
+	stop()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch mode did not stop")
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package validate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	isatty "github.com/mattn/go-isatty"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/enterprise-contract/ec-cli/internal/format"
+	"github.com/enterprise-contract/ec-cli/internal/input"
+	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+	"github.com/enterprise-contract/ec-cli/internal/watch"
+)
+
+// watchInterval is how often the watched files are checked for changes
+var watchInterval = time.Second
+
+// watchContext returns the context of the watch mode, done when interrupted
+// and not bound by the global timeout
+var watchContext = func(ctx context.Context) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.WithoutCancel(ctx), os.Interrupt, syscall.SIGTERM)
+}
+
+// inputWatch validates the inputs again whenever they, or the local policy
+// sources, change
+type inputWatch struct {
+	filePaths    []string
+	policyConfig string
+	policyLock   string
+	policy       policy.Policy
+	// reloadPolicy loads the policy again and uses it for the subsequent
+	// validations
+	reloadPolicy func(context.Context) (policy.Policy, error)
+	validate     func(context.Context, []string) map[string]inputResult
+	report       func(map[string]inputResult) (input.Report, error)
+	output       []string
+	parser       format.TargetParser
+}
+
+func (w inputWatch) run(cmd *cobra.Command) error {
+	ctx, stop := watchContext(cmd.Context())
+	defer stop()
+
+	if len(w.output) == 0 {
+		w.output = []string{input.Text}
+	}
+
+	out := cmd.OutOrStdout()
+	results := w.validate(ctx, w.filePaths)
+	previous := w.render(out, results, nil)
+
+	policyPaths := w.policyPaths(ctx)
+	watcher := watch.New(utils.FS(ctx), watchInterval, w.watchedPaths(policyPaths)...)
+	for {
+		changed, err := watcher.Wait(ctx)
+		if err != nil {
+			// interrupted
+			return nil
+		}
+		log.Debugf("Changed: %v", changed)
+
+		if !slices.ContainsFunc(changed, func(p string) bool { return slices.Contains(policyPaths, p) }) {
+			for path, r := range w.validate(ctx, changed) {
+				results[path] = r
+			}
+			previous = w.render(out, results, previous)
+			continue
+		}
+
+		p, err := w.reloadPolicy(ctx)
+		if err != nil {
+			clearScreen(out)
+			fmt.Fprintf(out, "Error: unable to load the policy: %v\n", err)
+			footer(out)
+			continue
+		}
+		w.policy = p
+
+		// the local sources are copied when fetched
+		source.ClearDownloadCache()
+		results = w.validate(ctx, w.filePaths)
+		previous = w.render(out, results, previous)
+
+		// the policy might now use different local sources
+		policyPaths = w.policyPaths(ctx)
+		watcher = watch.New(utils.FS(ctx), watchInterval, w.watchedPaths(policyPaths)...)
+	}
+}
+
+// render writes the report of the results in place of the previous one,
+// followed by the rules that started or stopped failing since the previous
+// report. Returns the report to compare the next one to.
+func (w inputWatch) render(out io.Writer, results map[string]inputResult, previous *input.Report) *input.Report {
+	clearScreen(out)
+	defer footer(out)
+
+	report, err := w.report(results)
+	if err != nil {
+		fmt.Fprintf(out, "Error: %v\n", err)
+		return previous
+	}
+
+	if err := report.WriteAll(w.output, w.parser); err != nil {
+		fmt.Fprintf(out, "Error: %v\n", err)
+	}
+
+	if previous != nil {
+		changes, err := input.RenderChanges(input.Changes(*previous, report))
+		if err != nil {
+			fmt.Fprintf(out, "Error: %v\n", err)
+		} else {
+			fmt.Fprintf(out, "\n%s", changes)
+		}
+	}
+
+	return &report
+}
+
+// policyPaths returns the local paths of the policy configuration, the policy
+// lock and the policy and data sources
+func (w inputWatch) policyPaths(ctx context.Context) []string {
+	var paths []string
+	add := func(p string) {
+		if !slices.Contains(paths, p) {
+			paths = append(paths, p)
+		}
+	}
+
+	for _, f := range []string{w.policyConfig, w.policyLock} {
+		if path, ok := source.LocalPath(f); ok {
+			add(path)
+		} else if exists, _ := utils.IsFile(ctx, f); exists {
+			add(f)
+		}
+	}
+
+	for _, s := range w.policy.Spec().Sources {
+		for _, url := range slices.Concat(s.Policy, s.Data) {
+			if path, ok := source.LocalPath(url); ok {
+				add(path)
+			}
+		}
+	}
+
+	return paths
+}
+
+// watchedPaths returns the paths of the input files followed by the policy
+// paths, without duplicates
+func (w inputWatch) watchedPaths(policyPaths []string) []string {
+	paths := make([]string, 0, len(w.filePaths)+len(policyPaths))
+	for _, p := range slices.Concat(w.filePaths, policyPaths) {
+		if !slices.Contains(paths, p) {
+			paths = append(paths, p)
+		}
+	}
+
+	return paths
+}
+
+// clearScreen clears the terminal the output is written to, if any
+func clearScreen(out io.Writer) {
+	if f, ok := out.(*os.File); ok && (isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())) {
+		fmt.Fprint(out, "\x1b[H\x1b[2J")
+	}
+}
+
+func footer(out io.Writer) {
+	fmt.Fprintf(out, "\nValidated at %s, watching for changes. Press Ctrl+C to stop.\n", time.Now().Format(time.TimeOnly))
+}
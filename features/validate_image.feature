@@ -1167,3 +1167,29 @@ Feature: evaluate enterprise contract
     Then the exit status should be 1
      And the output should match the snapshot
      And the "${TMPDIR}/output.json" file should match the snapshot
+
+  Scenario: image index with nested image manifests
+    Given a key pair named "known"
+      And an image index named "acceptance/ec-multi-arch" with platforms:
+      | linux/amd64 |
+      | linux/arm64 |
+      And a git repository named "happy-day-policy" with
+      | main.rego | examples/happy_day.rego |
+      And policy configuration named "ec-policy" with specification
+    """
+    {
+      "sources": [
+        {
+          "policy": [
+            "git::https://${GITHOST}/git/happy-day-policy.git"
+          ]
+        }
+      ]
+    }
+    """
+    When ec command is run with "validate image --image ${REGISTRY}/acceptance/ec-multi-arch --policy acceptance/ec-policy --public-key ${known_PUBLIC_KEY} --ignore-rekor --nest-image-manifests --output summary"
+    Then the exit status should be 1
+     And the standard output should contain
+    """
+    "components":\[\{"name":"Unnamed","success":false,.*"manifests":\[\{"name":"Unnamed-sha256:${REGISTRY_acceptance/ec-multi-arch:linux-amd64_DIGEST}-amd64","platform":"linux/amd64","success":false,.*\},\{"name":"Unnamed-sha256:${REGISTRY_acceptance/ec-multi-arch:linux-arm64_DIGEST}-arm64","platform":"linux/arm64","success":false,.*\}\]\}\],"success":false
+    """
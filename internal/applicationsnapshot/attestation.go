@@ -53,9 +53,9 @@ func NewAttestationResult(att attestation.Attestation) AttestationResult {
 }
 
 func (r *Report) renderAttestations() ([]byte, error) {
-	byts := make([][]byte, 0, len(r.Components)*2)
+	byts := make([][]byte, 0, len(r.AllComponents())*2)
 
-	for _, c := range r.Components {
+	for _, c := range r.AllComponents() {
 		for _, a := range c.Attestations {
 			byts = append(byts, a.Statement)
 		}
@@ -66,7 +66,7 @@ func (r *Report) renderAttestations() ([]byte, error) {
 
 func (r *Report) attestations() ([]in_toto.Statement, error) {
 	var statements []in_toto.Statement
-	for _, c := range r.Components {
+	for _, c := range r.AllComponents() {
 		for _, a := range c.Attestations {
 			var statement in_toto.Statement
 			err := json.Unmarshal(a.Statement, &statement)
@@ -0,0 +1,247 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package applicationsnapshot
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	app "github.com/konflux-ci/application-api/api/v1alpha1"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/enterprise-contract/ec-cli/internal/kubernetes"
+)
+
+// Annotations of the Snapshot recording the outcome of its validation
+const (
+	// ResultAnnotation is either "success" or "failure"
+	ResultAnnotation = "enterprisecontract.dev/result"
+	// SummaryAnnotation holds the number of conforming components,
+	// violations and warnings
+	SummaryAnnotation = "enterprisecontract.dev/summary"
+	// PolicyDigestAnnotation holds the digest of the policy the Snapshot was
+	// validated against
+	PolicyDigestAnnotation = "enterprisecontract.dev/policy-digest"
+	// ValidatedAtAnnotation holds the time of the validation
+	ValidatedAtAnnotation = "enterprisecontract.dev/validated-at"
+	// VersionAnnotation holds the version of ec performing the validation
+	VersionAnnotation = "enterprisecontract.dev/ec-version"
+	// ResultsConfigMapAnnotation holds the name of the ConfigMap with the
+	// validation report
+	ResultsConfigMapAnnotation = "enterprisecontract.dev/results-configmap"
+)
+
+const (
+	// SnapshotLabel is set on the results ConfigMap to the name of the
+	// validated Snapshot
+	SnapshotLabel = "enterprisecontract.dev/snapshot"
+	// ReportKey is the key of the validation report in JSON format within the
+	// results ConfigMap
+	ReportKey = "report.json"
+	// SummaryKey is the key of the summary of the validation report in JSON
+	// format within the results ConfigMap, used instead of the report when it
+	// doesn't fit in the ConfigMap
+	SummaryKey = "summary.json"
+	// maxConfigMapSize is the limit Kubernetes imposes on the data of a
+	// ConfigMap
+	maxConfigMapSize = 1024 * 1024
+	// ViolationReason is the reason of the Events recorded for components
+	// violating the policy
+	ViolationReason = "PolicyViolation"
+	eventSource     = "ec"
+)
+
+// RecordOptions configure how the outcome of a validation is recorded in the
+// cluster
+type RecordOptions struct {
+	// ConfigMap is the name of the ConfigMap to create, or replace, with the
+	// validation report in the namespace of the Snapshot. No ConfigMap is
+	// created if empty.
+	ConfigMap string
+}
+
+// Record writes the outcome of the validation of a Snapshot fetched from the
+// cluster back to the cluster: the Snapshot is annotated with the verdict, a
+// Warning Event is recorded for each component violating the policy, and, when
+// requested, the report is stored in a ConfigMap owned by the Snapshot.
+func Record(ctx context.Context, r Report, opts RecordOptions) error {
+	if r.Snapshot == "" {
+		return errors.New("recording the results requires the Snapshot to be fetched from the cluster")
+	}
+
+	client, err := kubernetes.NewClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	annotations, err := r.annotations()
+	if err != nil {
+		return err
+	}
+	if opts.ConfigMap != "" {
+		annotations[ResultsConfigMapAnnotation] = opts.ConfigMap
+	}
+
+	snapshot, err := client.AnnotateSnapshot(ctx, r.Snapshot, annotations)
+	if err != nil {
+		return fmt.Errorf("unable to annotate the Snapshot %q: %w", r.Snapshot, err)
+	}
+
+	var allErrors error
+	if opts.ConfigMap != "" {
+		if err := r.applyConfigMap(ctx, client, snapshot, opts.ConfigMap); err != nil {
+			allErrors = errors.Join(allErrors, fmt.Errorf("unable to store the report in the ConfigMap %q: %w", opts.ConfigMap, err))
+		}
+	}
+
+	now := time.Now()
+	for i, c := range r.Components {
+		if c.Success {
+			continue
+		}
+
+		if err := client.CreateEvent(ctx, violationEvent(snapshot, c, now, i)); err != nil {
+			allErrors = errors.Join(allErrors, fmt.Errorf("unable to record the event for the component %q: %w", c.Name, err))
+		}
+	}
+
+	if allErrors == nil {
+		log.Debugf("Recorded the results of the validation of the Snapshot %q", r.Snapshot)
+	}
+
+	return allErrors
+}
+
+// annotations returns the annotations summarizing the verdict of the report
+func (r *Report) annotations() (map[string]string, error) {
+	policy, err := json.Marshal(r.Policy)
+	if err != nil {
+		return nil, err
+	}
+
+	result := "failure"
+	if r.Success {
+		result = "success"
+	}
+
+	conforming, violations, warnings := 0, 0, 0
+	for _, c := range r.Components {
+		if c.Success {
+			conforming++
+		}
+	}
+	for _, c := range r.AllComponents() {
+		violations += len(c.Violations)
+		warnings += len(c.Warnings)
+	}
+
+	validatedAt := r.created
+	if validatedAt.IsZero() {
+		validatedAt = time.Now().UTC()
+	}
+
+	return map[string]string{
+		ResultAnnotation:       result,
+		SummaryAnnotation:      fmt.Sprintf("%d of %d components conform, %d violations, %d warnings", conforming, len(r.Components), violations, warnings),
+		PolicyDigestAnnotation: fmt.Sprintf("sha256:%x", sha256.Sum256(policy)),
+		ValidatedAtAnnotation:  validatedAt.Format(time.RFC3339),
+		VersionAnnotation:      r.EcVersion,
+	}, nil
+}
+
+// applyConfigMap stores the report in the ConfigMap, or, if the report exceeds
+// the size limit of a ConfigMap, its summary, reporting that the report was not
+// stored
+func (r *Report) applyConfigMap(ctx context.Context, client kubernetes.Client, snapshot *app.Snapshot, name string) error {
+	report, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	data := map[string]string{ReportKey: string(report)}
+	var tooLarge error
+	if len(ReportKey)+len(report) > maxConfigMapSize {
+		summary, err := json.Marshal(r.toSummary())
+		if err != nil {
+			return err
+		}
+		if len(SummaryKey)+len(summary) > maxConfigMapSize {
+			return fmt.Errorf("neither the report of %d bytes nor its summary of %d bytes fit within the %d bytes limit of a ConfigMap", len(report), len(summary), maxConfigMapSize)
+		}
+
+		data = map[string]string{SummaryKey: string(summary)}
+		tooLarge = fmt.Errorf("the report of %d bytes exceeds the %d bytes limit of a ConfigMap, stored its summary under %q instead", len(report), maxConfigMapSize, SummaryKey)
+	}
+
+	if err := client.ApplyConfigMap(ctx, corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: snapshot.Namespace,
+			Labels: map[string]string{
+				SnapshotLabel: snapshot.Name,
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: app.GroupVersion.String(),
+					Kind:       "Snapshot",
+					Name:       snapshot.Name,
+					UID:        snapshot.UID,
+				},
+			},
+		},
+		Data: data,
+	}); err != nil {
+		return err
+	}
+
+	return tooLarge
+}
+
+// violationEvent returns the Warning Event for the component violating the
+// policy, named after the Snapshot the same way client-go names events
+func violationEvent(snapshot *app.Snapshot, c Component, now time.Time, i int) corev1.Event {
+	timestamp := metav1.NewTime(now)
+
+	return corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%x", snapshot.Name, now.UnixNano()+int64(i)),
+			Namespace: snapshot.Namespace,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion:      app.GroupVersion.String(),
+			Kind:            "Snapshot",
+			Name:            snapshot.Name,
+			Namespace:       snapshot.Namespace,
+			UID:             snapshot.UID,
+			ResourceVersion: snapshot.ResourceVersion,
+		},
+		Reason:              ViolationReason,
+		Message:             fmt.Sprintf("Component %s (%s) %s", c.Name, c.ContainerImage, ViolationsSummary([]Component{c})),
+		Type:                corev1.EventTypeWarning,
+		Source:              corev1.EventSource{Component: eventSource},
+		ReportingController: eventSource,
+		FirstTimestamp:      timestamp,
+		LastTimestamp:       timestamp,
+		Count:               1,
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package applicationsnapshot
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	app "github.com/konflux-ci/application-api/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/kubernetes"
+	"github.com/enterprise-contract/ec-cli/internal/policy"
+)
+
+func recordedReport(t *testing.T) Report {
+	ctx := context.Background()
+	report, err := NewReport("snapshot", []Component{
+		{
+			SnapshotComponent: app.SnapshotComponent{Name: "A", ContainerImage: "registry.io/repository/a"},
+			Success:           true,
+			Warnings:          []evaluator.Result{{Message: "careful"}},
+		},
+		{
+			SnapshotComponent: app.SnapshotComponent{Name: "B", ContainerImage: "registry.io/repository/b"},
+			Violations: []evaluator.Result{
+				{Message: "no", Metadata: map[string]any{"code": "main.one"}},
+				{Message: "nope"},
+			},
+		},
+		{
+			SnapshotComponent: app.SnapshotComponent{Name: "C", ContainerImage: "registry.io/repository/c"},
+			Manifests: []Component{
+				{
+					SnapshotComponent: app.SnapshotComponent{Name: "C-amd64", ContainerImage: "registry.io/repository/c-amd64"},
+					Violations:        []evaluator.Result{{Message: "wrong platform"}},
+				},
+			},
+		},
+	}, createTestPolicy(t, ctx), nil, true)
+	require.NoError(t, err)
+
+	return report
+}
+
+func TestRecord(t *testing.T) {
+	report := recordedReport(t)
+	client := &policy.FakeKubernetesClient{}
+	ctx := kubernetes.WithClient(context.Background(), client)
+
+	require.NoError(t, Record(ctx, report, RecordOptions{ConfigMap: "results"}))
+
+	spec, err := json.Marshal(report.Policy)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		ResultAnnotation:           "failure",
+		SummaryAnnotation:          "1 of 3 components conform, 3 violations, 1 warnings",
+		PolicyDigestAnnotation:     fmt.Sprintf("sha256:%x", sha256.Sum256(spec)),
+		ValidatedAtAnnotation:      report.created.Format(time.RFC3339),
+		VersionAnnotation:          report.EcVersion,
+		ResultsConfigMapAnnotation: "results",
+	}, client.Annotations)
+
+	require.Len(t, client.Events, 2)
+	messages := []string{}
+	for _, e := range client.Events {
+		assert.Equal(t, corev1.EventTypeWarning, e.Type)
+		assert.Equal(t, ViolationReason, e.Reason)
+		assert.Equal(t, "Snapshot", e.InvolvedObject.Kind)
+		assert.Equal(t, "snapshot", e.InvolvedObject.Name)
+		assert.Equal(t, "test", e.Namespace)
+		messages = append(messages, e.Message)
+	}
+	assert.Equal(t, []string{
+		"Component B (registry.io/repository/b) violates the policy: [main.one] no, nope",
+		"Component C (registry.io/repository/c) violates the policy: wrong platform",
+	}, messages)
+	assert.NotEqual(t, client.Events[0].Name, client.Events[1].Name)
+
+	require.Len(t, client.ConfigMaps, 1)
+	cm := client.ConfigMaps[0]
+	assert.Equal(t, "results", cm.Name)
+	assert.Equal(t, "test", cm.Namespace)
+	assert.Equal(t, map[string]string{SnapshotLabel: "snapshot"}, cm.Labels)
+	require.Len(t, cm.OwnerReferences, 1)
+	assert.Equal(t, "snapshot-uid", string(cm.OwnerReferences[0].UID))
+	expected, err := json.Marshal(report)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expected), cm.Data[ReportKey])
+}
+
+func TestRecordSuccess(t *testing.T) {
+	report := recordedReport(t)
+	report.Success = true
+	report.Components = report.Components[:1]
+	client := &policy.FakeKubernetesClient{}
+	ctx := kubernetes.WithClient(context.Background(), client)
+
+	require.NoError(t, Record(ctx, report, RecordOptions{}))
+
+	assert.Equal(t, "success", client.Annotations[ResultAnnotation])
+	assert.Equal(t, "1 of 1 components conform, 0 violations, 1 warnings", client.Annotations[SummaryAnnotation])
+	assert.NotContains(t, client.Annotations, ResultsConfigMapAnnotation)
+	assert.Empty(t, client.Events)
+	assert.Empty(t, client.ConfigMaps)
+}
+
+func TestRecordLargeReport(t *testing.T) {
+	report := recordedReport(t)
+	for i := 0; i < 200; i++ {
+		report.Components[1].Violations = append(report.Components[1].Violations, evaluator.Result{
+			Message:  strings.Repeat("x", 10*1024),
+			Metadata: map[string]any{"code": "main.large"},
+		})
+	}
+	client := &policy.FakeKubernetesClient{}
+	ctx := kubernetes.WithClient(context.Background(), client)
+
+	err := Record(ctx, report, RecordOptions{ConfigMap: "results"})
+	assert.ErrorContains(t, err, `unable to store the report in the ConfigMap "results": the report of`)
+	assert.ErrorContains(t, err, `exceeds the 1048576 bytes limit of a ConfigMap, stored its summary under "summary.json" instead`)
+
+	require.Len(t, client.ConfigMaps, 1)
+	cm := client.ConfigMaps[0]
+	assert.NotContains(t, cm.Data, ReportKey)
+	expected, err := json.Marshal(report.toSummary())
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expected), cm.Data[SummaryKey])
+
+	// the events are recorded regardless
+	assert.Len(t, client.Events, 2)
+
+	report.Components[1].Violations = []evaluator.Result{{
+		Message:  strings.Repeat("x", 2*1024*1024),
+		Metadata: map[string]any{"code": "main.large"},
+	}}
+	client = &policy.FakeKubernetesClient{}
+	ctx = kubernetes.WithClient(context.Background(), client)
+
+	err = Record(ctx, report, RecordOptions{ConfigMap: "results"})
+	assert.ErrorContains(t, err, "fit within the 1048576 bytes limit of a ConfigMap")
+	assert.Empty(t, client.ConfigMaps)
+}
+
+func TestRecordErrors(t *testing.T) {
+	report := recordedReport(t)
+
+	ctx := kubernetes.WithClient(context.Background(), &policy.FakeKubernetesClient{WriteError: true})
+	assert.EqualError(t, Record(ctx, report, RecordOptions{}), `unable to annotate the Snapshot "snapshot": no writing for you`)
+
+	report.Snapshot = ""
+	assert.EqualError(t, Record(ctx, report, RecordOptions{}), "recording the results requires the Snapshot to be fetched from the cluster")
+}
+
+func TestViolationsSummary(t *testing.T) {
+	violations := func(n int) []evaluator.Result {
+		results := make([]evaluator.Result, 0, n)
+		for i := 0; i < n; i++ {
+			results = append(results, evaluator.Result{Message: fmt.Sprintf("violation %d", i)})
+		}
+		return results
+	}
+
+	assert.Equal(t, "does not conform to the policy", ViolationsSummary([]Component{{}}))
+	assert.Equal(t, "violates the policy: violation 0, violation 1, violation 2", ViolationsSummary([]Component{{Violations: violations(3)}}))
+	assert.Equal(t, "violates the policy: violation 0, violation 1, violation 2 and 2 more", ViolationsSummary([]Component{{Violations: violations(5)}}))
+}
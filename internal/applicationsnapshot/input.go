@@ -24,6 +24,7 @@ import (
 	"runtime/trace"
 	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/google/go-containerregistry/pkg/name"
 	app "github.com/konflux-ci/application-api/api/v1alpha1"
@@ -52,6 +53,39 @@ type Input struct {
 	Images   string
 }
 
+// Platform describes the platform of an image manifest within an image index
+type Platform struct {
+	OS           string `json:"os,omitempty"`
+	Architecture string `json:"architecture,omitempty"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+// String returns the platform in the os/architecture/variant form
+func (p Platform) String() string {
+	parts := make([]string, 0, 3)
+	for _, part := range []string{p.OS, p.Architecture, p.Variant} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	return strings.Join(parts, "/")
+}
+
+// ImageManifest relates the component of a platform specific image manifest to
+// the component of the image index containing it
+type ImageManifest struct {
+	// Index is the name of the component of the image index
+	Index string
+	// Platform is the platform of the image manifest, nil if the image index
+	// doesn't specify it
+	Platform *Platform
+}
+
+// ImageManifests holds the image manifests of image indexes, by the name of
+// the component of the image manifest
+type ImageManifests map[string]ImageManifest
+
 type snapshot struct {
 	app.SnapshotSpec
 }
@@ -89,7 +123,11 @@ func (s *snapshot) merge(snap app.SnapshotSpec) {
 	}
 }
 
-func DetermineInputSpec(ctx context.Context, input Input) (*app.SnapshotSpec, error) {
+// DetermineInputSpec returns the Snapshot from the input, with the components
+// of image indexes expanded to also include the component of each platform
+// specific image manifest of the image index. The returned ImageManifests
+// relates the expanded components to the image indexes containing them.
+func DetermineInputSpec(ctx context.Context, input Input) (*app.SnapshotSpec, ImageManifests, error) {
 	var snapshot snapshot
 	provided := false
 
@@ -106,7 +144,7 @@ func DetermineInputSpec(ctx context.Context, input Input) (*app.SnapshotSpec, er
 
 		file, err := readSnapshotSource(content)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		snapshot.merge(file)
 		provided = true
@@ -117,11 +155,11 @@ func DetermineInputSpec(ctx context.Context, input Input) (*app.SnapshotSpec, er
 		fs := utils.FS(ctx)
 		content, err := afero.ReadFile(fs, input.File)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		file, err := readSnapshotSource(content)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		snapshot.merge(file)
 		provided = true
@@ -131,7 +169,7 @@ func DetermineInputSpec(ctx context.Context, input Input) (*app.SnapshotSpec, er
 	if input.JSON != "" {
 		json, err := readSnapshotSource([]byte(input.JSON))
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		snapshot.merge(json)
 		provided = true
@@ -156,13 +194,13 @@ func DetermineInputSpec(ctx context.Context, input Input) (*app.SnapshotSpec, er
 		client, err := kubernetes.NewClient(ctx)
 		if err != nil {
 			log.Debugf("Unable to initialize Kubernetes Client: %v", err)
-			return nil, err
+			return nil, nil, err
 		}
 
 		cluster, err := client.FetchSnapshot(ctx, input.Snapshot)
 		if err != nil {
 			log.Debugf("Unable to fetch snapshot %s from Kubernetes cluster: %v", input.Snapshot, err)
-			return nil, err
+			return nil, nil, err
 		}
 		snapshot.merge(cluster.Spec)
 		provided = true
@@ -170,11 +208,11 @@ func DetermineInputSpec(ctx context.Context, input Input) (*app.SnapshotSpec, er
 
 	if !provided {
 		log.Debug("No application snapshot available")
-		return nil, errors.New("neither Snapshot nor image reference provided to validate")
+		return nil, nil, errors.New("neither Snapshot nor image reference provided to validate")
 	}
-	expandImageIndex(ctx, &snapshot.SnapshotSpec)
+	manifests := expandImageIndex(ctx, &snapshot.SnapshotSpec)
 
-	return &snapshot.SnapshotSpec, nil
+	return &snapshot.SnapshotSpec, manifests, nil
 }
 
 func readSnapshotSource(input []byte) (app.SnapshotSpec, error) {
@@ -189,15 +227,24 @@ func readSnapshotSource(input []byte) (app.SnapshotSpec, error) {
 	return file, nil
 }
 
+// expandedComponent holds the component and, for an image index, the
+// components of its image manifests
+type expandedComponent struct {
+	components []app.SnapshotComponent
+	manifests  ImageManifests
+}
+
 // For an image index, remove the original component and replace it with an expanded component with all its image manifests
 // Do not raise an error if the image is inaccessible, it will be handled as a violation when evaluated against the policy
 // This is to retain the original behavior of the `ec validate` command.
-func imageIndexWorker(client oci.Client, component app.SnapshotComponent, componentChan chan<- []app.SnapshotComponent, errorsChan chan<- error) {
-	var components []app.SnapshotComponent
-	components = append(components, component)
+func imageIndexWorker(client oci.Client, component app.SnapshotComponent, componentChan chan<- expandedComponent, errorsChan chan<- error) {
+	expanded := expandedComponent{
+		components: []app.SnapshotComponent{component},
+		manifests:  ImageManifests{},
+	}
 	// to avoid adding to componentsChan before each return
 	defer func() {
-		componentChan <- components
+		componentChan <- expanded
 	}()
 
 	ref, err := name.ParseReference(component.ContainerImage)
@@ -240,11 +287,24 @@ func imageIndexWorker(client oci.Client, component app.SnapshotComponent, compon
 		archComponent := component
 		archComponent.Name = fmt.Sprintf("%s-%s-%s", component.Name, manifest.Digest, arch)
 		archComponent.ContainerImage = fmt.Sprintf("%s@%s", ref.Context().Name(), manifest.Digest)
-		components = append(components, archComponent)
+		expanded.components = append(expanded.components, archComponent)
+
+		imageManifest := ImageManifest{Index: component.Name}
+		if manifest.Platform != nil {
+			imageManifest.Platform = &Platform{
+				OS:           manifest.Platform.OS,
+				Architecture: manifest.Platform.Architecture,
+				Variant:      manifest.Platform.Variant,
+			}
+		}
+		expanded.manifests[archComponent.Name] = imageManifest
 	}
 }
 
-func expandImageIndex(ctx context.Context, snap *app.SnapshotSpec) {
+// expandImageIndex adds the components of the image manifests of each image
+// index to the snapshot and returns the relation of the added components to
+// the image indexes
+func expandImageIndex(ctx context.Context, snap *app.SnapshotSpec) ImageManifests {
 	if trace.IsEnabled() {
 		region := trace.StartRegion(ctx, "ec:expand-image-index")
 		defer region.End()
@@ -252,7 +312,7 @@ func expandImageIndex(ctx context.Context, snap *app.SnapshotSpec) {
 
 	client := oci.NewClient(ctx)
 
-	componentChan := make(chan []app.SnapshotComponent, len(snap.Components))
+	componentChan := make(chan expandedComponent, len(snap.Components))
 	errorsChan := make(chan error, len(snap.Components))
 	g, _ := errgroup.WithContext(ctx)
 	g.SetLimit(imageWorkers())
@@ -271,8 +331,12 @@ func expandImageIndex(ctx context.Context, snap *app.SnapshotSpec) {
 	}()
 
 	var components []app.SnapshotComponent
-	for component := range componentChan {
-		components = append(components, component...)
+	manifests := ImageManifests{}
+	for expanded := range componentChan {
+		components = append(components, expanded.components...)
+		for name, manifest := range expanded.manifests {
+			manifests[name] = manifest
+		}
 	}
 	snap.Components = components
 
@@ -289,6 +353,8 @@ func expandImageIndex(ctx context.Context, snap *app.SnapshotSpec) {
 		log.Warnf("Encountered error while checking for Image Index: %v", allErrors)
 	}
 	log.Debugf("Snap component after expanding the image index is %v", snap.Components)
+
+	return manifests
 }
 
 func imageWorkers() int {
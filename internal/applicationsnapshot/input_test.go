@@ -177,7 +177,7 @@ func Test_DetermineInputSpec(t *testing.T) {
 					panic(err)
 				}
 			}
-			got, err := DetermineInputSpec(ctx, tc.input)
+			got, _, err := DetermineInputSpec(ctx, tc.input)
 			// expect an error so check for nil
 			if tc.want != nil {
 				assert.NoError(t, err)
@@ -275,8 +275,13 @@ func TestExpandImageIndex(t *testing.T) {
 		},
 	}
 
-	expandImageIndex(ctx, snap)
+	manifests := expandImageIndex(ctx, snap)
 	assert.True(t, len(snap.Components) == 4, "Image Index should NOT be removed")
+	assert.Equal(t, ImageManifests{
+		"some-image-name-sha256:digest1-amd64":    {Index: "some-image-name", Platform: &Platform{Architecture: "amd64"}},
+		"some-image-name-sha256:digest2-arm64":    {Index: "some-image-name", Platform: &Platform{Architecture: "arm64"}},
+		"some-image-name-sha256:digest3-noarch-2": {Index: "some-image-name"},
+	}, manifests)
 
 	indexImage, amd64Image, arm64Image, noarchImage := false, false, false, false
 	for _, component := range snap.Components {
@@ -67,7 +67,7 @@ func asTestCase(r evaluator.Result) junit.Testcase {
 func (r *Report) toJUnit() junit.Testsuites {
 	report := junit.Testsuites{}
 
-	for _, component := range r.Components {
+	for _, component := range r.AllComponents() {
 		properties := []junit.Property{
 			{
 				Name:  "image",
@@ -81,6 +81,13 @@ func (r *Report) toJUnit() junit.Testsuites {
 			},
 		}
 
+		if component.Platform != nil {
+			properties = append(properties, junit.Property{
+				Name:  "platform",
+				Value: component.Platform.String(),
+			})
+		}
+
 		for _, s := range component.Signatures {
 			properties = append(properties, junit.Property{
 				Name:  "keyId",
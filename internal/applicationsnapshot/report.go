@@ -23,6 +23,8 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
@@ -32,6 +34,7 @@ import (
 	"github.com/enterprise-contract/ec-cli/internal/evaluator"
 	"github.com/enterprise-contract/ec-cli/internal/format"
 	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/report/sarif"
 	"github.com/enterprise-contract/ec-cli/internal/signature"
 	"github.com/enterprise-contract/ec-cli/internal/utils"
 	"github.com/enterprise-contract/ec-cli/internal/version"
@@ -46,6 +49,115 @@ type Component struct {
 	SuccessCount int                         `json:"-"`
 	Signatures   []signature.EntitySignature `json:"signatures,omitempty"`
 	Attestations []AttestationResult         `json:"attestations,omitempty"`
+	// Platform is set for the component of an image manifest nested within
+	// the component of an image index
+	Platform *Platform `json:"platform,omitempty"`
+	// Manifests holds the components of the platform specific image
+	// manifests of an image index
+	Manifests []Component `json:"manifests,omitempty"`
+}
+
+// NestImageManifests nests the components of image manifests under the
+// component of the image index containing them, as related by the manifests.
+// The component of an image index is successful only if the components of all
+// its image manifests are successful. Components of image manifests are not
+// nested when the component of their image index is not present.
+func NestImageManifests(components []Component, manifests ImageManifests) []Component {
+	indexes := map[string]bool{}
+	for _, c := range components {
+		indexes[c.Name] = true
+	}
+
+	nested := map[string][]Component{}
+	result := make([]Component, 0, len(components))
+	for _, c := range components {
+		if m, ok := manifests[c.Name]; ok && indexes[m.Index] {
+			c.Platform = m.Platform
+			nested[m.Index] = append(nested[m.Index], c)
+			continue
+		}
+		result = append(result, c)
+	}
+
+	for i := range result {
+		children, ok := nested[result[i].Name]
+		if !ok {
+			continue
+		}
+		sort.Slice(children, func(i, j int) bool {
+			if pi, pj := platformString(children[i].Platform), platformString(children[j].Platform); pi != pj {
+				return pi < pj
+			}
+			return children[i].ContainerImage < children[j].ContainerImage
+		})
+		for _, child := range children {
+			result[i].Success = result[i].Success && child.Success
+		}
+		result[i].Manifests = children
+	}
+
+	return result
+}
+
+func platformString(p *Platform) string {
+	if p == nil {
+		return ""
+	}
+
+	return p.String()
+}
+
+// flattenComponents returns the components along with the components of image
+// manifests nested within them
+func flattenComponents(components []Component) []Component {
+	flattened := make([]Component, 0, len(components))
+	for _, c := range components {
+		flattened = append(flattened, c)
+		flattened = append(flattened, flattenComponents(c.Manifests)...)
+	}
+
+	return flattened
+}
+
+// AllComponents returns all components of the report, including the
+// components of image manifests nested within the components of image indexes
+func (r *Report) AllComponents() []Component {
+	return flattenComponents(r.Components)
+}
+
+// maxSummarizedViolations limits the number of violations included in the
+// summary of violations
+const maxSummarizedViolations = 3
+
+// ViolationsSummary returns a concise description of the violations of the
+// components, including the components of image manifests nested within them,
+// to complete a sentence with the component as the subject, e.g. "violates
+// the policy: [code] message and 2 more"
+func ViolationsSummary(components []Component) string {
+	var violations []evaluator.Result
+	for _, c := range flattenComponents(components) {
+		violations = append(violations, c.Violations...)
+	}
+
+	if len(violations) == 0 {
+		return "does not conform to the policy"
+	}
+
+	messages := make([]string, 0, maxSummarizedViolations)
+	for _, v := range violations[:min(len(violations), maxSummarizedViolations)] {
+		if code, ok := v.Metadata["code"].(string); ok && code != "" {
+			messages = append(messages, fmt.Sprintf("[%s] %s", code, v.Message))
+		} else {
+			messages = append(messages, v.Message)
+		}
+	}
+
+	summary := fmt.Sprintf("violates the policy: %s", strings.Join(messages, ", "))
+	if more := len(violations) - maxSummarizedViolations; more > 0 {
+		summary += fmt.Sprintf(" and %d more", more)
+	}
+
+	return summary
 }
 
 type Report struct {
@@ -60,6 +172,15 @@ type Report struct {
 	EffectiveTime time.Time                        `json:"effective-time"`
 	PolicyInput   [][]byte                         `json:"-"`
 	ShowSuccesses bool                             `json:"-"`
+	// VerifiedLevels are the SLSA levels claimed in the SLSA Verification
+	// Summary of successful components
+	VerifiedLevels []string `json:"-"`
+	// PolicyURI is the URI of the policy configuration, required by the SLSA
+	// Verification Summary
+	PolicyURI string `json:"-"`
+	// PolicyDigest is the hex encoded sha256 digest of the policy configuration
+	// as fetched from PolicyURI
+	PolicyDigest string `json:"-"`
 }
 
 type summary struct {
@@ -71,6 +192,7 @@ type summary struct {
 
 type componentSummary struct {
 	Name            string              `json:"name"`
+	Platform        string              `json:"platform,omitempty"`
 	Success         bool                `json:"success"`
 	Violations      map[string][]string `json:"violations"`
 	Warnings        map[string][]string `json:"warnings"`
@@ -78,6 +200,19 @@ type componentSummary struct {
 	TotalViolations int                 `json:"total_violations"`
 	TotalWarnings   int                 `json:"total_warnings"`
 	TotalSuccesses  int                 `json:"total_successes"`
+	Manifests       []componentSummary  `json:"manifests,omitempty"`
+}
+
+// flattenSummaries returns the component summaries along with the summaries of
+// image manifests nested within them
+func flattenSummaries(summaries []componentSummary) []componentSummary {
+	flattened := make([]componentSummary, 0, len(summaries))
+	for _, s := range summaries {
+		flattened = append(flattened, s)
+		flattened = append(flattened, flattenSummaries(s.Manifests)...)
+	}
+
+	return flattened
 }
 
 // TestReport represents the standardized TEST_OUTPUT format.
@@ -106,6 +241,8 @@ const (
 	Attestation     = "attestation"
 	PolicyInput     = "policy-input"
 	VSA             = "vsa"
+	SLSAVSA         = "slsa-vsa"
+	SARIF           = "sarif"
 	// Deprecated old version of appstudio. Remove some day.
 	HACBS = "hacbs"
 )
@@ -121,6 +258,8 @@ var OutputFormats = []string{
 	Attestation,
 	PolicyInput,
 	VSA,
+	SLSAVSA,
+	SARIF,
 }
 
 // WriteReport returns a new instance of Report representing the state of
@@ -212,6 +351,10 @@ func (r *Report) toFormat(format string) (data []byte, err error) {
 		data = bytes.Join(r.PolicyInput, []byte("\n"))
 	case VSA:
 		data, err = r.toVSA()
+	case SLSAVSA:
+		data, err = r.toSLSAVSA()
+	case SARIF:
+		data, err = json.Marshal(r.toSARIF())
 	default:
 		return nil, fmt.Errorf("%q is not a valid report format", format)
 	}
@@ -226,6 +369,39 @@ func (r *Report) toVSA() ([]byte, error) {
 	return json.Marshal(vsa)
 }
 
+// toSLSAVSA returns the SLSA Verification Summary of each component, one
+// statement per line
+func (r *Report) toSLSAVSA() ([]byte, error) {
+	components := r.AllComponents()
+	byts := make([][]byte, 0, len(components))
+	for _, c := range components {
+		vsa, err := NewComponentSLSAVSA(*r, c)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := json.Marshal(vsa)
+		if err != nil {
+			return nil, err
+		}
+		byts = append(byts, data)
+	}
+
+	return bytes.Join(byts, []byte{'\n'}), nil
+}
+
+// toSARIF returns the violations and warnings of all components in the SARIF
+// format, located at the image reference of the component
+func (r *Report) toSARIF() sarif.Log {
+	b := sarif.NewBuilder(r.EcVersion)
+	for _, c := range r.AllComponents() {
+		b.AddImage(c.ContainerImage, sarif.Error, c.Violations)
+		b.AddImage(c.ContainerImage, sarif.Warning, c.Warnings)
+	}
+
+	return b.Log()
+}
+
 // toSummary returns a condensed version of the report.
 func (r *Report) toSummary() summary {
 	pr := summary{
@@ -235,26 +411,35 @@ func (r *Report) toSummary() summary {
 		if !cmp.Success {
 			pr.Success = false
 		}
-		c := componentSummary{
-			TotalViolations: len(cmp.Violations),
-			TotalWarnings:   len(cmp.Warnings),
-
-			// Because cmp.Successes does not get populated unless the --show-successes
-			// flag was set, cmp.SuccessCount is used here instead of len(cmp.Successes)
-			TotalSuccesses: cmp.SuccessCount,
-
-			Success:    cmp.Success,
-			Name:       cmp.Name,
-			Violations: condensedMsg(cmp.Violations),
-			Warnings:   condensedMsg(cmp.Warnings),
-			Successes:  condensedMsg(cmp.Successes),
-		}
-		pr.Components = append(pr.Components, c)
+		pr.Components = append(pr.Components, toComponentSummary(cmp))
 	}
 	pr.Key = r.Key
 	return pr
 }
 
+func toComponentSummary(cmp Component) componentSummary {
+	c := componentSummary{
+		TotalViolations: len(cmp.Violations),
+		TotalWarnings:   len(cmp.Warnings),
+
+		// Because cmp.Successes does not get populated unless the --show-successes
+		// flag was set, cmp.SuccessCount is used here instead of len(cmp.Successes)
+		TotalSuccesses: cmp.SuccessCount,
+
+		Success:    cmp.Success,
+		Name:       cmp.Name,
+		Platform:   platformString(cmp.Platform),
+		Violations: condensedMsg(cmp.Violations),
+		Warnings:   condensedMsg(cmp.Warnings),
+		Successes:  condensedMsg(cmp.Successes),
+	}
+	for _, m := range cmp.Manifests {
+		c.Manifests = append(c.Manifests, toComponentSummary(m))
+	}
+
+	return c
+}
+
 func (r *Report) applyOptions(opts format.Options) {
 	r.ShowSuccesses = opts.ShowSuccesses
 }
@@ -290,7 +475,7 @@ func generateMarkdownSummary(r *Report) ([]byte, error) {
 
 	var totalViolations, totalWarnings, totalSuccesses int
 	pr := r.toSummary()
-	for _, component := range pr.Components {
+	for _, component := range flattenSummaries(pr.Components) {
 		totalViolations += component.TotalViolations
 		totalWarnings += component.TotalWarnings
 		totalSuccesses += component.TotalSuccesses
@@ -347,7 +532,7 @@ func (r *Report) toAppstudioReport() TestReport {
 	}
 
 	hasFailures := false
-	for _, component := range r.toSummary().Components {
+	for _, component := range flattenSummaries(r.toSummary().Components) {
 		result.Failures += component.TotalViolations
 		result.Warnings += component.TotalWarnings
 		result.Successes += component.TotalSuccesses
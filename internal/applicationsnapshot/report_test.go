@@ -100,6 +100,57 @@ func Test_ReportJson(t *testing.T) {
 	assert.False(t, report.Success)
 }
 
+func Test_ReportSARIF(t *testing.T) {
+	var snapshot app.SnapshotSpec
+	err := json.Unmarshal([]byte(testSnapshot), &snapshot)
+	assert.NoError(t, err)
+
+	components := testComponentsFor(snapshot)
+
+	ctx := context.Background()
+	report, err := NewReport("snappy", components, createTestPolicy(t, ctx), nil, true)
+	assert.NoError(t, err)
+
+	reportSARIF, err := report.toFormat(SARIF)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"version": "2.1.0",
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"runs": [
+			{
+				"tool": {
+					"driver": {
+						"name": "ec",
+						"version": "development",
+						"informationUri": "https://enterprisecontract.dev",
+						"rules": []
+					}
+				},
+				"results": [
+					{
+						"level": "error",
+						"message": {"text": "violation1"},
+						"locations": [{"logicalLocations": [{"name": "quay.io/caf/spam@sha256:123…", "kind": "image"}]}],
+						"properties": {"image": "quay.io/caf/spam@sha256:123…"}
+					},
+					{
+						"level": "warning",
+						"message": {"text": "warning1"},
+						"locations": [{"logicalLocations": [{"name": "quay.io/caf/spam@sha256:123…", "kind": "image"}]}],
+						"properties": {"image": "quay.io/caf/spam@sha256:123…"}
+					},
+					{
+						"level": "error",
+						"message": {"text": "violation2"},
+						"locations": [{"logicalLocations": [{"name": "quay.io/caf/bacon@sha256:234…", "kind": "image"}]}],
+						"properties": {"image": "quay.io/caf/bacon@sha256:234…"}
+					}
+				]
+			}
+		]
+	}`, string(reportSARIF))
+}
+
 func Test_ReportYaml(t *testing.T) {
 	var snapshot *app.SnapshotSpec
 	err := json.Unmarshal([]byte(testSnapshot), &snapshot)
@@ -921,6 +972,75 @@ func Test_TextReport(t *testing.T) {
 				},
 			},
 		}},
+		{"explained", Report{
+			Components: []Component{
+				{
+					SnapshotComponent: app.SnapshotComponent{
+						ContainerImage: "registry.io/repository/component-1:tag",
+					},
+					Violations: []evaluator.Result{
+						{
+							Metadata: map[string]interface{}{
+								"code": "violation-1",
+							},
+							Message: "Violation 1 message",
+							Explanation: &evaluator.Explanation{
+								Expressions: []evaluator.ExpressionExplanation{
+									{
+										Location:   "policy/main.rego:10",
+										Expression: "input.kind != \"Allowed\"",
+										Result:     true,
+										Input:      map[string]any{"input.kind": "Denied"},
+									},
+									{
+										Location:   "policy/main.rego:11",
+										Expression: "input.spec.replicas > 1",
+										Result:     true,
+										Input:      map[string]any{"input.spec.replicas": 3},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}},
+		{"image index", Report{
+			Components: []Component{
+				{
+					SnapshotComponent: app.SnapshotComponent{
+						Name:           "index",
+						ContainerImage: "registry.io/repository/image@sha256:index",
+					},
+					SuccessCount: 1,
+					Manifests: []Component{
+						{
+							SnapshotComponent: app.SnapshotComponent{
+								Name:           "index-sha256:amd64-amd64",
+								ContainerImage: "registry.io/repository/image@sha256:amd64",
+							},
+							Platform: &Platform{OS: "linux", Architecture: "amd64"},
+							Violations: []evaluator.Result{
+								{
+									Metadata: map[string]interface{}{
+										"code": "violation-1",
+									},
+									Message: "Violation 1 message",
+								},
+							},
+						},
+						{
+							SnapshotComponent: app.SnapshotComponent{
+								Name:           "index-sha256:arm64-arm64",
+								ContainerImage: "registry.io/repository/image@sha256:arm64",
+							},
+							Platform:     &Platform{OS: "linux", Architecture: "arm64", Variant: "v8"},
+							SuccessCount: 1,
+						},
+					},
+				},
+			},
+		}},
 	}
 
 	for _, c := range cases {
@@ -998,3 +1118,44 @@ func createTestPolicy(t *testing.T, ctx context.Context) policy.Policy {
 	assert.NoError(t, err)
 	return p
 }
+
+func TestNestImageManifests(t *testing.T) {
+	amd64 := &Platform{OS: "linux", Architecture: "amd64"}
+	arm64 := &Platform{OS: "linux", Architecture: "arm64"}
+
+	components := []Component{
+		{SnapshotComponent: app.SnapshotComponent{Name: "index-arm64", ContainerImage: "registry.io/image@sha256:arm64"}, Success: false},
+		{SnapshotComponent: app.SnapshotComponent{Name: "other", ContainerImage: "registry.io/other:tag"}, Success: true},
+		{SnapshotComponent: app.SnapshotComponent{Name: "index", ContainerImage: "registry.io/image@sha256:index"}, Success: true},
+		{SnapshotComponent: app.SnapshotComponent{Name: "index-amd64", ContainerImage: "registry.io/image@sha256:amd64"}, Success: true},
+		{SnapshotComponent: app.SnapshotComponent{Name: "orphan", ContainerImage: "registry.io/orphan@sha256:abc"}, Success: true},
+	}
+
+	manifests := ImageManifests{
+		"index-amd64": {Index: "index", Platform: amd64},
+		"index-arm64": {Index: "index", Platform: arm64},
+		"orphan":      {Index: "missing"},
+	}
+
+	nested := NestImageManifests(components, manifests)
+
+	assert.Equal(t, []Component{
+		{SnapshotComponent: app.SnapshotComponent{Name: "other", ContainerImage: "registry.io/other:tag"}, Success: true},
+		{
+			SnapshotComponent: app.SnapshotComponent{Name: "index", ContainerImage: "registry.io/image@sha256:index"},
+			Success:           false,
+			Manifests: []Component{
+				{SnapshotComponent: app.SnapshotComponent{Name: "index-amd64", ContainerImage: "registry.io/image@sha256:amd64"}, Success: true, Platform: amd64},
+				{SnapshotComponent: app.SnapshotComponent{Name: "index-arm64", ContainerImage: "registry.io/image@sha256:arm64"}, Success: false, Platform: arm64},
+			},
+		},
+		{SnapshotComponent: app.SnapshotComponent{Name: "orphan", ContainerImage: "registry.io/orphan@sha256:abc"}, Success: true},
+	}, nested)
+
+	r := Report{Components: nested}
+	names := []string{}
+	for _, c := range r.AllComponents() {
+		names = append(names, c.Name)
+	}
+	assert.Equal(t, []string{"other", "index", "index-amd64", "index-arm64", "orphan"}, names)
+}
@@ -17,13 +17,29 @@
 package applicationsnapshot
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
 )
 
 const (
 	// Make it visible elsewhere
 	PredicateVSAProvenance = "https://enterprisecontract.dev/verification_summary/v1"
 	StatmentVSA            = "https://in-toto.io/Statement/v1"
+	// PredicateSLSAVSA is the predicate type of the SLSA Verification Summary
+	PredicateSLSAVSA = "https://slsa.dev/verification_summary/v1"
+	// VerifierID identifies ec as the verifier in the SLSA Verification Summary
+	VerifierID = "https://enterprisecontract.dev/ec-cli"
+
+	VerificationPassed = "PASSED"
+	VerificationFailed = "FAILED"
 )
 
 type ProvenanceStatementVSA struct {
@@ -47,6 +63,135 @@ func NewVSA(report Report) (ProvenanceStatementVSA, error) {
 	}, nil
 }
 
+// NewComponentVSA returns the VSA for a single image, the subject of the VSA is
+// the image and the predicate is the report holding only the component of the
+// image
+func NewComponentVSA(report Report, component Component) (ProvenanceStatementVSA, error) {
+	subject, err := componentSubject(component)
+	if err != nil {
+		return ProvenanceStatementVSA{}, err
+	}
+
+	report.Components = []Component{component}
+	report.Success = component.Success
+
+	return ProvenanceStatementVSA{
+		StatementHeader: in_toto.StatementHeader{
+			Type:          StatmentVSA,
+			PredicateType: PredicateVSAProvenance,
+			Subject:       []in_toto.Subject{subject},
+		},
+		Predicate: report,
+	}, nil
+}
+
+// SLSAProvenanceStatementVSA is an in-toto statement with the SLSA
+// Verification Summary predicate, see
+// https://slsa.dev/spec/v1.0/verification_summary
+type SLSAProvenanceStatementVSA struct {
+	in_toto.StatementHeader
+	Predicate SLSAVerificationSummary `json:"predicate"`
+}
+
+type SLSAVerificationSummary struct {
+	Verifier           Verifier             `json:"verifier"`
+	TimeVerified       time.Time            `json:"timeVerified"`
+	ResourceURI        string               `json:"resourceUri"`
+	Policy             ResourceDescriptor   `json:"policy"`
+	InputAttestations  []ResourceDescriptor `json:"inputAttestations,omitempty"`
+	VerificationResult string               `json:"verificationResult"`
+	VerifiedLevels     []string             `json:"verifiedLevels"`
+	SLSAVersion        string               `json:"slsaVersion,omitempty"`
+}
+
+type Verifier struct {
+	ID      string            `json:"id"`
+	Version map[string]string `json:"version,omitempty"`
+}
+
+// ResourceDescriptor describes a resource by its digest, see
+// https://github.com/in-toto/attestation/blob/main/spec/v1/resource_descriptor.md
+type ResourceDescriptor struct {
+	URI    string           `json:"uri,omitempty"`
+	Digest common.DigestSet `json:"digest,omitempty"`
+}
+
+// NewComponentSLSAVSA returns the SLSA Verification Summary for a single image,
+// the subject of the VSA is the image. The policy is described by the URI and,
+// if known, the digest of the policy configuration, and the input attestations
+// by the digests of their statements. The verified levels of the report are claimed only when
+// the component is successful.
+func NewComponentSLSAVSA(report Report, component Component) (SLSAProvenanceStatementVSA, error) {
+	subject, err := componentSubject(component)
+	if err != nil {
+		return SLSAProvenanceStatementVSA{}, err
+	}
+
+	if report.PolicyURI == "" {
+		return SLSAProvenanceStatementVSA{}, errors.New("the SLSA Verification Summary requires the URI of the policy, provide the policy configuration as a file or a URL")
+	}
+
+	policy := ResourceDescriptor{URI: report.PolicyURI}
+	if report.PolicyDigest != "" {
+		policy.Digest = common.DigestSet{"sha256": report.PolicyDigest}
+	}
+
+	var inputs []ResourceDescriptor
+	for _, a := range component.Attestations {
+		if len(a.Statement) == 0 {
+			continue
+		}
+		inputs = append(inputs, ResourceDescriptor{Digest: sha256DigestSet(a.Statement)})
+	}
+
+	result := VerificationFailed
+	levels := []string{}
+	if component.Success {
+		result = VerificationPassed
+		levels = append(levels, report.VerifiedLevels...)
+	}
+
+	return SLSAProvenanceStatementVSA{
+		StatementHeader: in_toto.StatementHeader{
+			Type:          StatmentVSA,
+			PredicateType: PredicateSLSAVSA,
+			Subject:       []in_toto.Subject{subject},
+		},
+		Predicate: SLSAVerificationSummary{
+			Verifier: Verifier{
+				ID:      VerifierID,
+				Version: map[string]string{"ec-cli": report.EcVersion},
+			},
+			TimeVerified:       report.created,
+			ResourceURI:        component.ContainerImage,
+			Policy:             policy,
+			InputAttestations:  inputs,
+			VerificationResult: result,
+			VerifiedLevels:     levels,
+			SLSAVersion:        "1.0",
+		},
+	}, nil
+}
+
+func componentSubject(component Component) (in_toto.Subject, error) {
+	ref, err := name.NewDigest(component.ContainerImage)
+	if err != nil {
+		return in_toto.Subject{}, fmt.Errorf("image reference %q is not pinned to a digest: %w", component.ContainerImage, err)
+	}
+
+	algorithm, digest, _ := strings.Cut(ref.DigestStr(), ":")
+
+	return in_toto.Subject{
+		Name:   ref.Repository.Name(),
+		Digest: common.DigestSet{algorithm: digest},
+	}, nil
+}
+
+func sha256DigestSet(data []byte) common.DigestSet {
+	sum := sha256.Sum256(data)
+	return common.DigestSet{"sha256": hex.EncodeToString(sum[:])}
+}
+
 func getSubjects(report Report) ([]in_toto.Subject, error) {
 	statements, err := report.attestations()
 	if err != nil {
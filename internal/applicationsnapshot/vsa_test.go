@@ -19,10 +19,14 @@
 package applicationsnapshot
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"testing"
+	"time"
 
 	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
 	"github.com/in-toto/in-toto-golang/in_toto"
@@ -114,6 +118,171 @@ func TestSubjects(t *testing.T) {
 	assert.Equal(t, expected, subjects)
 }
 
+func TestNewComponentVSA(t *testing.T) {
+	passing := Component{
+		SnapshotComponent: app.SnapshotComponent{
+			Name:           "passing",
+			ContainerImage: "registry.io/repository/image@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		},
+		Success: true,
+	}
+	failing := Component{
+		SnapshotComponent: app.SnapshotComponent{
+			Name:           "failing",
+			ContainerImage: "registry.io/repository/other@sha256:abcdef0123456789abcdef0123456789abcdef0123456789abcdef0123456789",
+		},
+		Violations: []evaluator.Result{{Message: "violation1"}},
+	}
+
+	report := Report{
+		Success:    false,
+		Snapshot:   "snappy",
+		Components: []Component{passing, failing},
+	}
+
+	vsa, err := NewComponentVSA(report, passing)
+	assert.NoError(t, err)
+	assert.Equal(t, ProvenanceStatementVSA{
+		StatementHeader: in_toto.StatementHeader{
+			Type:          "https://in-toto.io/Statement/v1",
+			PredicateType: "https://enterprisecontract.dev/verification_summary/v1",
+			Subject: []in_toto.Subject{
+				{
+					Name:   "registry.io/repository/image",
+					Digest: map[string]string{"sha256": "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"},
+				},
+			},
+		},
+		Predicate: Report{
+			Success:    true,
+			Snapshot:   "snappy",
+			Components: []Component{passing},
+		},
+	}, vsa)
+
+	// the report given is not modified
+	assert.Len(t, report.Components, 2)
+
+	_, err = NewComponentVSA(report, Component{
+		SnapshotComponent: app.SnapshotComponent{ContainerImage: "registry.io/repository/image:tag"},
+	})
+	assert.ErrorContains(t, err, `image reference "registry.io/repository/image:tag" is not pinned to a digest`)
+}
+
+func TestNewComponentSLSAVSA(t *testing.T) {
+	statement := []byte(`{"_type": "https://in-toto.io/Statement/v0.1"}`)
+	passing := Component{
+		SnapshotComponent: app.SnapshotComponent{
+			Name:           "passing",
+			ContainerImage: "registry.io/repository/image@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		},
+		Attestations: []AttestationResult{{Statement: statement}},
+		Success:      true,
+	}
+	failing := Component{
+		SnapshotComponent: app.SnapshotComponent{
+			Name:           "failing",
+			ContainerImage: "registry.io/repository/other@sha256:abcdef0123456789abcdef0123456789abcdef0123456789abcdef0123456789",
+		},
+		Violations: []evaluator.Result{{Message: "violation1"}},
+	}
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	report := Report{
+		created:        created,
+		EcVersion:      "v1.2.3",
+		Policy:         ecc.EnterpriseContractPolicySpec{PublicKey: "key"},
+		Components:     []Component{passing, failing},
+		VerifiedLevels: []string{"SLSA_BUILD_LEVEL_3"},
+		PolicyURI:      "github.com/org/repo//policy.yaml",
+	}
+
+	policyDigest := sha256.Sum256([]byte("publicKey: key\n"))
+	report.PolicyDigest = hex.EncodeToString(policyDigest[:])
+	statementDigest := sha256.Sum256(statement)
+
+	vsa, err := NewComponentSLSAVSA(report, passing)
+	assert.NoError(t, err)
+	assert.Equal(t, SLSAProvenanceStatementVSA{
+		StatementHeader: in_toto.StatementHeader{
+			Type:          "https://in-toto.io/Statement/v1",
+			PredicateType: "https://slsa.dev/verification_summary/v1",
+			Subject: []in_toto.Subject{
+				{
+					Name:   "registry.io/repository/image",
+					Digest: map[string]string{"sha256": "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"},
+				},
+			},
+		},
+		Predicate: SLSAVerificationSummary{
+			Verifier: Verifier{
+				ID:      "https://enterprisecontract.dev/ec-cli",
+				Version: map[string]string{"ec-cli": "v1.2.3"},
+			},
+			TimeVerified: created,
+			ResourceURI:  passing.ContainerImage,
+			Policy: ResourceDescriptor{
+				URI:    "github.com/org/repo//policy.yaml",
+				Digest: map[string]string{"sha256": hex.EncodeToString(policyDigest[:])},
+			},
+			InputAttestations: []ResourceDescriptor{
+				{Digest: map[string]string{"sha256": hex.EncodeToString(statementDigest[:])}},
+			},
+			VerificationResult: "PASSED",
+			VerifiedLevels:     []string{"SLSA_BUILD_LEVEL_3"},
+			SLSAVersion:        "1.0",
+		},
+	}, vsa)
+
+	vsa, err = NewComponentSLSAVSA(report, failing)
+	assert.NoError(t, err)
+	assert.Equal(t, "FAILED", vsa.Predicate.VerificationResult)
+	assert.Empty(t, vsa.Predicate.InputAttestations)
+	assert.Equal(t, []string{}, vsa.Predicate.VerifiedLevels)
+
+	data, err := report.toFormat(SLSAVSA)
+	assert.NoError(t, err)
+	lines := bytes.Split(data, []byte{'\n'})
+	assert.Len(t, lines, 2)
+	assert.JSONEq(t, `{
+		"_type": "https://in-toto.io/Statement/v1",
+		"predicateType": "https://slsa.dev/verification_summary/v1",
+		"subject": [
+			{
+				"name": "registry.io/repository/other",
+				"digest": {"sha256": "abcdef0123456789abcdef0123456789abcdef0123456789abcdef0123456789"}
+			}
+		],
+		"predicate": {
+			"verifier": {"id": "https://enterprisecontract.dev/ec-cli", "version": {"ec-cli": "v1.2.3"}},
+			"timeVerified": "2024-01-02T03:04:05Z",
+			"resourceUri": "registry.io/repository/other@sha256:abcdef0123456789abcdef0123456789abcdef0123456789abcdef0123456789",
+			"policy": {
+				"uri": "github.com/org/repo//policy.yaml",
+				"digest": {"sha256": "`+hex.EncodeToString(policyDigest[:])+`"}
+			},
+			"verificationResult": "FAILED",
+			"verifiedLevels": [],
+			"slsaVersion": "1.0"
+		}
+	}`, string(lines[1]))
+
+	_, err = NewComponentSLSAVSA(report, Component{
+		SnapshotComponent: app.SnapshotComponent{ContainerImage: "registry.io/repository/image:tag"},
+	})
+	assert.ErrorContains(t, err, `image reference "registry.io/repository/image:tag" is not pinned to a digest`)
+
+	// without the digest of the policy configuration only its URI is included
+	report.PolicyDigest = ""
+	vsa, err = NewComponentSLSAVSA(report, passing)
+	assert.NoError(t, err)
+	assert.Equal(t, ResourceDescriptor{URI: "github.com/org/repo//policy.yaml"}, vsa.Predicate.Policy)
+
+	report.PolicyURI = ""
+	_, err = NewComponentSLSAVSA(report, passing)
+	assert.ErrorContains(t, err, "the SLSA Verification Summary requires the URI of the policy")
+}
+
 func toJson(policy any) string {
 	newInline, err := json.Marshal(policy)
 	if err != nil {
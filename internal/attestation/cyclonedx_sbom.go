@@ -0,0 +1,97 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package attestation
+
+import (
+	"encoding/json"
+
+	"github.com/sigstore/cosign/v2/pkg/oci"
+)
+
+const (
+	PredicateCycloneDX = "https://cyclonedx.org/bom"
+
+	// CycloneDX is the format of CycloneDX SBOMs
+	CycloneDX = "cyclonedx"
+)
+
+// cyclonedxBOM holds the parts of a CycloneDX 1.4+ BOM needed to list its
+// components, see https://cyclonedx.org/specification/overview/
+type cyclonedxBOM struct {
+	Components []cyclonedxComponent `json:"components"`
+}
+
+type cyclonedxComponent struct {
+	Name       string               `json:"name"`
+	Version    string               `json:"version"`
+	PURL       string               `json:"purl"`
+	Licenses   []cyclonedxLicense   `json:"licenses"`
+	Components []cyclonedxComponent `json:"components"`
+}
+
+// cyclonedxLicense is either a license or a SPDX license expression
+type cyclonedxLicense struct {
+	License *struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"license"`
+	Expression string `json:"expression"`
+}
+
+// CycloneDXSBOMFromSignature parses the CycloneDX SBOM from the provided OCI
+// layer. Expects that the layer contains DSSE JSON with the embedded CycloneDX
+// BOM as the predicate.
+func CycloneDXSBOMFromSignature(sig oci.Signature) (SBOM, error) {
+	return sbomFromSignature(sig, PredicateCycloneDX, CycloneDX, cyclonedxPackages)
+}
+
+func cyclonedxPackages(predicate json.RawMessage) ([]Package, error) {
+	var bom cyclonedxBOM
+	if err := json.Unmarshal(predicate, &bom); err != nil {
+		return nil, err
+	}
+
+	return appendCycloneDXPackages([]Package{}, bom.Components), nil
+}
+
+// appendCycloneDXPackages appends the packages of the components including
+// the components nested within them
+func appendCycloneDXPackages(packages []Package, components []cyclonedxComponent) []Package {
+	for _, c := range components {
+		pkg := Package{
+			Name:    c.Name,
+			Version: c.Version,
+			PURL:    c.PURL,
+		}
+
+		for _, l := range c.Licenses {
+			switch {
+			case l.Expression != "":
+				pkg.Licenses = appendLicense(pkg.Licenses, l.Expression)
+			case l.License != nil && l.License.ID != "":
+				pkg.Licenses = appendLicense(pkg.Licenses, l.License.ID)
+			case l.License != nil:
+				pkg.Licenses = appendLicense(pkg.Licenses, l.License.Name)
+			}
+		}
+
+		packages = append(packages, pkg)
+		packages = appendCycloneDXPackages(packages, c.Components)
+	}
+
+	return packages
+}
@@ -0,0 +1,157 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package attestation
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+
+	"github.com/enterprise-contract/ec-cli/internal/signature"
+)
+
+// Package is a package listed in a SBOM, normalized from the SBOM format
+type Package struct {
+	Name     string   `json:"name"`
+	Version  string   `json:"version,omitempty"`
+	PURL     string   `json:"purl,omitempty"`
+	Licenses []string `json:"licenses,omitempty"`
+}
+
+// SBOM is a SBOM attestation providing the packages listed in the SBOM
+type SBOM interface {
+	Attestation
+	// Format is the format of the SBOM, e.g. spdx or cyclonedx
+	Format() string
+	Packages() []Package
+}
+
+// parsePackages parses the packages from the predicate of a SBOM attestation
+type parsePackages func(predicate json.RawMessage) ([]Package, error)
+
+func sbomFromSignature(sig oci.Signature, predicateType, format string, parse parsePackages) (SBOM, error) {
+	payload, err := payloadFromSig(sig)
+	if err != nil {
+		return nil, err
+	}
+
+	embedded, err := decodedPayload(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var statement struct {
+		in_toto.StatementHeader
+		Predicate json.RawMessage `json:"predicate"`
+	}
+	if err := json.Unmarshal(embedded, &statement); err != nil {
+		return nil, fmt.Errorf("malformed attestation data: %w", err)
+	}
+
+	if statement.Type != in_toto.StatementInTotoV01 && statement.Type != StatementInTotoV1 {
+		return nil, fmt.Errorf("unsupported attestation type: %s", statement.Type)
+	}
+
+	if statement.PredicateType != predicateType {
+		return nil, fmt.Errorf("unsupported attestation predicate type: %s", statement.PredicateType)
+	}
+
+	packages, err := parse(statement.Predicate)
+	if err != nil {
+		return nil, fmt.Errorf("malformed %s SBOM: %w", format, err)
+	}
+
+	signatures, err := createEntitySignatures(sig, payload)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create signed entity: %w", err)
+	}
+
+	return sbom{
+		header:     statement.StatementHeader,
+		format:     format,
+		packages:   packages,
+		data:       embedded,
+		signatures: signatures,
+	}, nil
+}
+
+type sbom struct {
+	header     in_toto.StatementHeader
+	format     string
+	packages   []Package
+	data       []byte
+	signatures []signature.EntitySignature
+}
+
+func (s sbom) Type() string {
+	return s.header.Type
+}
+
+func (s sbom) PredicateType() string {
+	return s.header.PredicateType
+}
+
+func (s sbom) Statement() []byte {
+	return s.data
+}
+
+func (s sbom) Signatures() []signature.EntitySignature {
+	return s.signatures
+}
+
+func (s sbom) Subject() []in_toto.Subject {
+	return s.header.Subject
+}
+
+func (s sbom) Format() string {
+	return s.format
+}
+
+func (s sbom) Packages() []Package {
+	return s.packages
+}
+
+func (s sbom) MarshalJSON() ([]byte, error) {
+	val := struct {
+		Type          string                      `json:"type"`
+		PredicateType string                      `json:"predicateType"`
+		Signatures    []signature.EntitySignature `json:"signatures"`
+	}{
+		Type:          s.header.Type,
+		PredicateType: s.header.PredicateType,
+		Signatures:    s.signatures,
+	}
+
+	return json.Marshal(val)
+}
+
+// appendLicense appends the license unless it is empty or already present
+func appendLicense(licenses []string, license string) []string {
+	if license == "" {
+		return licenses
+	}
+
+	for _, l := range licenses {
+		if l == license {
+			return licenses
+		}
+	}
+
+	return append(licenses, license)
+}
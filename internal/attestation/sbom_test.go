@@ -0,0 +1,188 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package attestation
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gkampitakis/go-snaps/snaps"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	ct "github.com/sigstore/cosign/v2/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const spdxStatement = `{
+	"_type": "https://in-toto.io/Statement/v0.1",
+	"subject": [{"name": "registry.io/repository/image", "digest": {"sha256": "abcdef"}}],
+	"predicateType": "https://spdx.dev/Document",
+	"predicate": {
+		"spdxVersion": "SPDX-2.3",
+		"packages": [
+			{
+				"name": "openssl",
+				"versionInfo": "3.0.7-1",
+				"licenseConcluded": "Apache-2.0",
+				"licenseDeclared": "Apache-2.0",
+				"externalRefs": [
+					{"referenceCategory": "SECURITY", "referenceType": "cpe23Type", "referenceLocator": "cpe:2.3:a:openssl:openssl:3.0.7:*:*:*:*:*:*:*"},
+					{"referenceCategory": "PACKAGE-MANAGER", "referenceType": "purl", "referenceLocator": "pkg:rpm/redhat/openssl@3.0.7-1"}
+				]
+			},
+			{
+				"name": "unknown",
+				"licenseConcluded": "NOASSERTION",
+				"licenseDeclared": "NONE"
+			}
+		]
+	}
+}`
+
+const cyclonedxStatement = `{
+	"_type": "https://in-toto.io/Statement/v1",
+	"subject": [{"name": "registry.io/repository/image", "digest": {"sha256": "abcdef"}}],
+	"predicateType": "https://cyclonedx.org/bom",
+	"predicate": {
+		"bomFormat": "CycloneDX",
+		"specVersion": "1.5",
+		"components": [
+			{
+				"type": "library",
+				"name": "lodash",
+				"version": "4.17.21",
+				"purl": "pkg:npm/lodash@4.17.21",
+				"licenses": [{"license": {"id": "MIT"}}, {"license": {"name": "Custom"}}],
+				"components": [
+					{
+						"type": "library",
+						"name": "nested",
+						"licenses": [{"expression": "MIT OR Apache-2.0"}]
+					}
+				]
+			}
+		]
+	}
+}`
+
+func sbomSignature(data string) mockSignature {
+	sig := mockSignature{&mock.Mock{}}
+	sig.On("MediaType").Return(types.MediaType(ct.DssePayloadType), nil)
+	sig.On("Uncompressed").Return(buffy(
+		fmt.Sprintf(`{"payload": "%s", "signatures": [{"keyid": "key-id-1", "sig": "sig-1"}]}`, encode(data)),
+	), nil)
+	sig.On("Base64Signature").Return("", nil)
+	sig.On("Cert").Return(&x509.Certificate{}, nil)
+	sig.On("Chain").Return([]*x509.Certificate{}, nil)
+
+	return sig
+}
+
+func TestSPDXSBOMFromSignature(t *testing.T) {
+	sbom, err := SPDXSBOMFromSignature(sbomSignature(spdxStatement))
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://in-toto.io/Statement/v0.1", sbom.Type())
+	assert.Equal(t, "https://spdx.dev/Document", sbom.PredicateType())
+	assert.Equal(t, "spdx", sbom.Format())
+	assert.JSONEq(t, spdxStatement, string(sbom.Statement()))
+	assert.Equal(t, "registry.io/repository/image", sbom.Subject()[0].Name)
+	assert.Equal(t, []Package{
+		{
+			Name:     "openssl",
+			Version:  "3.0.7-1",
+			PURL:     "pkg:rpm/redhat/openssl@3.0.7-1",
+			Licenses: []string{"Apache-2.0"},
+		},
+		{
+			Name: "unknown",
+		},
+	}, sbom.Packages())
+
+	j, err := json.Marshal(sbom)
+	require.NoError(t, err)
+	snaps.MatchJSON(t, j)
+}
+
+func TestCycloneDXSBOMFromSignature(t *testing.T) {
+	sbom, err := CycloneDXSBOMFromSignature(sbomSignature(cyclonedxStatement))
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://in-toto.io/Statement/v1", sbom.Type())
+	assert.Equal(t, "https://cyclonedx.org/bom", sbom.PredicateType())
+	assert.Equal(t, "cyclonedx", sbom.Format())
+	assert.Equal(t, []Package{
+		{
+			Name:     "lodash",
+			Version:  "4.17.21",
+			PURL:     "pkg:npm/lodash@4.17.21",
+			Licenses: []string{"MIT", "Custom"},
+		},
+		{
+			Name:     "nested",
+			Licenses: []string{"MIT OR Apache-2.0"},
+		},
+	}, sbom.Packages())
+}
+
+func TestSBOMFromSignatureErrors(t *testing.T) {
+	cases := []struct {
+		name  string
+		parse func(sig mockSignature) (SBOM, error)
+		data  string
+		err   error
+	}{
+		{
+			name:  "unsupported statement type",
+			parse: func(sig mockSignature) (SBOM, error) { return SPDXSBOMFromSignature(sig) },
+			data:  `{"_type": "kaboom", "predicateType": "https://spdx.dev/Document"}`,
+			err:   errors.New("unsupported attestation type: kaboom"),
+		},
+		{
+			name:  "unexpected predicate type",
+			parse: func(sig mockSignature) (SBOM, error) { return CycloneDXSBOMFromSignature(sig) },
+			data:  `{"_type": "https://in-toto.io/Statement/v1", "predicateType": "https://spdx.dev/Document"}`,
+			err:   errors.New("unsupported attestation predicate type: https://spdx.dev/Document"),
+		},
+		{
+			name:  "malformed SPDX",
+			parse: func(sig mockSignature) (SBOM, error) { return SPDXSBOMFromSignature(sig) },
+			data:  `{"_type": "https://in-toto.io/Statement/v1", "predicateType": "https://spdx.dev/Document", "predicate": {"packages": {}}}`,
+			err:   errors.New("malformed spdx SBOM: json: cannot unmarshal object into Go struct field spdxDocument.packages of type []attestation.spdxPackage"),
+		},
+		{
+			name:  "malformed CycloneDX",
+			parse: func(sig mockSignature) (SBOM, error) { return CycloneDXSBOMFromSignature(sig) },
+			data:  `{"_type": "https://in-toto.io/Statement/v1", "predicateType": "https://cyclonedx.org/bom", "predicate": "bom"}`,
+			err:   errors.New("malformed cyclonedx SBOM: json: cannot unmarshal string into Go value of type attestation.cyclonedxBOM"),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sbom, err := c.parse(sbomSignature(c.data))
+			assert.Nil(t, sbom)
+			assert.EqualError(t, err, c.err.Error())
+		})
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package attestation
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/in-toto/in-toto-golang/in_toto"
+	v1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+
+	"github.com/enterprise-contract/ec-cli/internal/signature"
+)
+
+const (
+	// Make it visible elsewhere
+	PredicateSLSAProvenanceV1 = v1.PredicateSLSAProvenance
+	// StatementInTotoV1 is the statement type of in-toto attestation
+	// framework v1, SLSA Provenance v1.0 can be provided in either the v0.1 or
+	// the v1 statement
+	StatementInTotoV1 = "https://in-toto.io/Statement/v1"
+)
+
+// SLSAProvenanceV1 is a SLSA Provenance v1.0 attestation providing access to
+// the commonly used parts of the predicate
+type SLSAProvenanceV1 interface {
+	Attestation
+	PredicateBuildType() string
+	BuilderID() string
+	InvocationID() string
+	ResolvedDependencies() []v1.ResourceDescriptor
+}
+
+// SLSAProvenanceV1FromSignature parses the SLSA Provenance v1.0 from the
+// provided OCI layer. Expects that the layer contains DSSE JSON with the
+// embedded SLSA Provenance v1.0 payload.
+func SLSAProvenanceV1FromSignature(sig oci.Signature) (SLSAProvenanceV1, error) {
+	payload, err := payloadFromSig(sig)
+	if err != nil {
+		return nil, err
+	}
+
+	embedded, err := decodedPayload(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var statement in_toto.ProvenanceStatementSLSA1
+	if err := json.Unmarshal(embedded, &statement); err != nil {
+		return nil, fmt.Errorf("malformed attestation data: %w", err)
+	}
+
+	if statement.Type != in_toto.StatementInTotoV01 && statement.Type != StatementInTotoV1 {
+		return nil, fmt.Errorf("unsupported attestation type: %s", statement.Type)
+	}
+
+	if statement.PredicateType != v1.PredicateSLSAProvenance {
+		return nil, fmt.Errorf("unsupported attestation predicate type: %s", statement.PredicateType)
+	}
+
+	signatures, err := createEntitySignatures(sig, payload)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create signed entity: %w", err)
+	}
+
+	return slsaProvenanceV1{statement: statement, data: embedded, signatures: signatures}, nil
+}
+
+type slsaProvenanceV1 struct {
+	statement  in_toto.ProvenanceStatementSLSA1
+	data       []byte
+	signatures []signature.EntitySignature
+}
+
+func (a slsaProvenanceV1) Type() string {
+	return a.statement.Type
+}
+
+func (a slsaProvenanceV1) PredicateType() string {
+	return v1.PredicateSLSAProvenance
+}
+
+func (a slsaProvenanceV1) Statement() []byte {
+	return a.data
+}
+
+func (a slsaProvenanceV1) PredicateBuildType() string {
+	return a.statement.Predicate.BuildDefinition.BuildType
+}
+
+func (a slsaProvenanceV1) BuilderID() string {
+	return a.statement.Predicate.RunDetails.Builder.ID
+}
+
+func (a slsaProvenanceV1) InvocationID() string {
+	return a.statement.Predicate.RunDetails.BuildMetadata.InvocationID
+}
+
+func (a slsaProvenanceV1) ResolvedDependencies() []v1.ResourceDescriptor {
+	return a.statement.Predicate.BuildDefinition.ResolvedDependencies
+}
+
+func (a slsaProvenanceV1) Signatures() []signature.EntitySignature {
+	return a.signatures
+}
+
+func (a slsaProvenanceV1) Subject() []in_toto.Subject {
+	return a.statement.Subject
+}
+
+func (a slsaProvenanceV1) MarshalJSON() ([]byte, error) {
+	val := struct {
+		Type               string                      `json:"type"`
+		PredicateType      string                      `json:"predicateType"`
+		PredicateBuildType string                      `json:"predicateBuildType"`
+		Signatures         []signature.EntitySignature `json:"signatures"`
+	}{
+		Type:               a.statement.Type,
+		PredicateType:      a.statement.PredicateType,
+		PredicateBuildType: a.statement.Predicate.BuildDefinition.BuildType,
+		Signatures:         a.signatures,
+	}
+
+	return json.Marshal(val)
+}
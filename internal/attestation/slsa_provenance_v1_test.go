@@ -0,0 +1,152 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package attestation
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gkampitakis/go-snaps/snaps"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	v1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+	ct "github.com/sigstore/cosign/v2/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/signature"
+)
+
+const provenanceV1 = `{
+	"_type": "https://in-toto.io/Statement/v1",
+	"subject": [{"name": "registry.io/repository/image", "digest": {"sha256": "abcdef"}}],
+	"predicateType": "https://slsa.dev/provenance/v1",
+	"predicate": {
+		"buildDefinition": {
+			"buildType": "https://tekton.dev/chains/v2/slsa",
+			"externalParameters": {"runSpec": {}},
+			"resolvedDependencies": [
+				{"uri": "git+https://github.com/org/repo.git", "digest": {"sha1": "0123456789abcdef"}},
+				{"name": "pipelineTask", "uri": "oci://registry.io/task", "digest": {"sha256": "fedcba"}}
+			]
+		},
+		"runDetails": {
+			"builder": {"id": "https://tekton.dev/chains/v2"},
+			"metadata": {"invocationID": "pipelinerun-123"}
+		}
+	}
+}`
+
+func TestSLSAProvenanceV1FromSignature(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+		err  error
+	}{
+		{
+			name: "in-toto v1 statement",
+			data: provenanceV1,
+		},
+		{
+			name: "in-toto v0.1 statement",
+			data: `{
+				"_type": "https://in-toto.io/Statement/v0.1",
+				"predicateType": "https://slsa.dev/provenance/v1",
+				"predicate": {"buildDefinition": {"buildType": "https://my.build.type"}}
+			}`,
+		},
+		{
+			name: "empty statement",
+			data: `{}`,
+			err:  errors.New("unsupported attestation type: "),
+		},
+		{
+			name: "unexpected predicate type",
+			data: `{
+				"_type": "https://in-toto.io/Statement/v1",
+				"predicateType": "https://slsa.dev/provenance/v0.2"
+			}`,
+			err: errors.New("unsupported attestation predicate type: https://slsa.dev/provenance/v0.2"),
+		},
+		{
+			name: "invalid predicate",
+			data: `{
+				"_type": "https://in-toto.io/Statement/v1",
+				"predicateType": "https://slsa.dev/provenance/v1",
+				"predicate": {"buildDefinition": []}
+			}`,
+			err: errors.New("malformed attestation data: json: cannot unmarshal array into Go struct field ProvenanceStatementSLSA1.predicate.buildDefinition of type v1.ProvenanceBuildDefinition"),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sig := mockSignature{&mock.Mock{}}
+			sig.On("MediaType").Return(types.MediaType(ct.DssePayloadType), nil)
+			sig.On("Uncompressed").Return(buffy(
+				fmt.Sprintf(`{"payload": "%s", "signatures": [{"keyid": "key-id-1", "sig": "sig-1"}]}`, encode(c.data)),
+			), nil)
+			sig.On("Base64Signature").Return("", nil)
+			sig.On("Cert").Return(&x509.Certificate{}, nil)
+			sig.On("Chain").Return([]*x509.Certificate{}, nil)
+
+			sp, err := SLSAProvenanceV1FromSignature(sig)
+			if c.err != nil {
+				require.Nil(t, sp)
+				assert.EqualError(t, err, c.err.Error())
+				return
+			}
+
+			require.NoError(t, err)
+			assert.JSONEq(t, c.data, string(sp.Statement()))
+			assert.Equal(t, "https://slsa.dev/provenance/v1", sp.PredicateType())
+			assert.Equal(t, []signature.EntitySignature{{KeyID: "key-id-1", Signature: "sig-1", Metadata: map[string]string{}}}, sp.Signatures())
+		})
+	}
+}
+
+func TestSLSAProvenanceV1Accessors(t *testing.T) {
+	sig := mockSignature{&mock.Mock{}}
+	sig.On("MediaType").Return(types.MediaType(ct.DssePayloadType), nil)
+	sig.On("Uncompressed").Return(buffy(fmt.Sprintf(`{"payload": "%s"}`, encode(provenanceV1))), nil)
+	sig.On("Base64Signature").Return("sig-from-cert", nil)
+	sig.On("Cert").Return(signature.ParseChainguardReleaseCert(), nil)
+	sig.On("Chain").Return(signature.ParseSigstoreChainCert(), nil)
+
+	sp, err := SLSAProvenanceV1FromSignature(sig)
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://in-toto.io/Statement/v1", sp.Type())
+	assert.Equal(t, "https://tekton.dev/chains/v2/slsa", sp.PredicateBuildType())
+	assert.Equal(t, "https://tekton.dev/chains/v2", sp.BuilderID())
+	assert.Equal(t, "pipelinerun-123", sp.InvocationID())
+	assert.Equal(t, []v1.ResourceDescriptor{
+		{URI: "git+https://github.com/org/repo.git", Digest: map[string]string{"sha1": "0123456789abcdef"}},
+		{Name: "pipelineTask", URI: "oci://registry.io/task", Digest: map[string]string{"sha256": "fedcba"}},
+	}, sp.ResolvedDependencies())
+	require.Len(t, sp.Subject(), 1)
+	assert.Equal(t, "registry.io/repository/image", sp.Subject()[0].Name)
+
+	j, err := json.Marshal(sp)
+	require.NoError(t, err)
+	snaps.MatchJSON(t, j)
+}
@@ -16,9 +16,75 @@
 
 package attestation
 
+import (
+	"encoding/json"
+
+	"github.com/sigstore/cosign/v2/pkg/oci"
+)
+
 const (
 	PredicateSpdxDocument = "https://spdx.dev/Document"
+
+	// SPDX is the format of SPDX SBOMs
+	SPDX = "spdx"
 )
 
-// Todo: More code here in future probably, e.g. for unmarshaling an SPDX
-// document to confirm it is formatted correctly
+// spdxDocument holds the parts of a SPDX 2.3 document needed to list its
+// packages, see https://spdx.github.io/spdx-spec/v2.3/
+type spdxDocument struct {
+	Packages []spdxPackage `json:"packages"`
+}
+
+type spdxPackage struct {
+	Name             string            `json:"name"`
+	VersionInfo      string            `json:"versionInfo"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs"`
+}
+
+type spdxExternalRef struct {
+	ReferenceType    string `json:"referenceType"`
+	ReferenceLocator string `json:"referenceLocator"`
+}
+
+// SPDXSBOMFromSignature parses the SPDX SBOM from the provided OCI layer.
+// Expects that the layer contains DSSE JSON with the embedded SPDX document as
+// the predicate.
+func SPDXSBOMFromSignature(sig oci.Signature) (SBOM, error) {
+	return sbomFromSignature(sig, PredicateSpdxDocument, SPDX, spdxPackages)
+}
+
+func spdxPackages(predicate json.RawMessage) ([]Package, error) {
+	var doc spdxDocument
+	if err := json.Unmarshal(predicate, &doc); err != nil {
+		return nil, err
+	}
+
+	packages := make([]Package, 0, len(doc.Packages))
+	for _, p := range doc.Packages {
+		pkg := Package{
+			Name:    p.Name,
+			Version: p.VersionInfo,
+		}
+
+		for _, ref := range p.ExternalRefs {
+			if ref.ReferenceType == "purl" {
+				pkg.PURL = ref.ReferenceLocator
+				break
+			}
+		}
+
+		for _, license := range []string{p.LicenseConcluded, p.LicenseDeclared} {
+			// NOASSERTION and NONE are not licenses
+			if license == "NOASSERTION" || license == "NONE" {
+				continue
+			}
+			pkg.Licenses = appendLicense(pkg.Licenses, license)
+		}
+
+		packages = append(packages, pkg)
+	}
+
+	return packages, nil
+}
@@ -27,6 +27,7 @@ import (
 	"runtime/trace"
 
 	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
 	app "github.com/konflux-ci/application-api/api/v1alpha1"
 	"github.com/santhosh-tekuri/jsonschema/v5"
 	"github.com/sigstore/cosign/v2/pkg/cosign"
@@ -46,6 +47,8 @@ import (
 
 var attestationSchemas = map[string]*jsonschema.Schema{
 	"https://slsa.dev/provenance/v0.2": schema.SLSA_Provenance_v0_2,
+	"https://slsa.dev/provenance/v1":   schema.SLSA_Provenance_v1,
+	"https://cyclonedx.org/bom":        schema.CycloneDX,
 }
 
 // ApplicationSnapshotImage represents the structure needed to evaluate an Application Snapshot Image
@@ -192,13 +195,26 @@ func (a *ApplicationSnapshotImage) ValidateAttestationSignature(ctx context.Cont
 			}
 			a.attestations = append(a.attestations, sp)
 
+		case attestation.PredicateSLSAProvenanceV1:
+			sp, err := attestation.SLSAProvenanceV1FromSignature(sig)
+			if err != nil {
+				return fmt.Errorf("unable to parse as SLSA v1.0: %w", err)
+			}
+			a.attestations = append(a.attestations, sp)
+
 		case attestation.PredicateSpdxDocument:
-			// It's an SPDX format SBOM
-			// Todo maybe: We could unmarshal it into a suitable SPDX struct
-			// similar to how it's done for SLSA above
-			a.attestations = append(a.attestations, att)
+			sbom, err := attestation.SPDXSBOMFromSignature(sig)
+			if err != nil {
+				return fmt.Errorf("unable to parse as SPDX SBOM: %w", err)
+			}
+			a.attestations = append(a.attestations, sbom)
 
-		// Todo: CycloneDX format SBOM
+		case attestation.PredicateCycloneDX:
+			sbom, err := attestation.CycloneDXSBOMFromSignature(sig)
+			if err != nil {
+				return fmt.Errorf("unable to parse as CycloneDX SBOM: %w", err)
+			}
+			a.attestations = append(a.attestations, sbom)
 
 		default:
 			// It's some other kind of attestation
@@ -280,6 +296,49 @@ func (a *ApplicationSnapshotImage) ResolveDigest(ctx context.Context) (string, e
 type attestationData struct {
 	Statement  json.RawMessage             `json:"statement"`
 	Signatures []signature.EntitySignature `json:"signatures,omitempty"`
+	Provenance *provenance                 `json:"provenance,omitempty"`
+	SBOM       *sbom                       `json:"sbom,omitempty"`
+}
+
+// sbom holds the packages listed in a SBOM, normalized across the SBOM
+// formats, so policy rules don't need to parse the SBOM document
+type sbom struct {
+	Format   string                `json:"format"`
+	Packages []attestation.Package `json:"packages"`
+}
+
+// provenance holds the commonly used parts of the SLSA Provenance v1.0
+// predicate, so policy rules don't need to navigate the predicate structure
+type provenance struct {
+	BuildType            string                  `json:"buildType"`
+	BuilderID            string                  `json:"builderId"`
+	InvocationID         string                  `json:"invocationId,omitempty"`
+	ResolvedDependencies []v1.ResourceDescriptor `json:"resolvedDependencies,omitempty"`
+}
+
+func newAttestationData(att attestation.Attestation) attestationData {
+	data := attestationData{
+		Statement:  att.Statement(),
+		Signatures: att.Signatures(),
+	}
+
+	if sp, ok := att.(attestation.SLSAProvenanceV1); ok {
+		data.Provenance = &provenance{
+			BuildType:            sp.PredicateBuildType(),
+			BuilderID:            sp.BuilderID(),
+			InvocationID:         sp.InvocationID(),
+			ResolvedDependencies: sp.ResolvedDependencies(),
+		}
+	}
+
+	if s, ok := att.(attestation.SBOM); ok {
+		data.SBOM = &sbom{
+			Format:   s.Format(),
+			Packages: s.Packages(),
+		}
+	}
+
+	return data
 }
 
 // MarshalJSON returns a JSON representation of the attestationData. It is customized to take into
@@ -317,6 +376,18 @@ func (a attestationData) MarshalJSON() ([]byte, error) {
 		}
 	}
 
+	if a.Provenance != nil {
+		if err := writeAttribute(&buffy, "provenance", a.Provenance); err != nil {
+			return nil, err
+		}
+	}
+
+	if a.SBOM != nil {
+		if err := writeAttribute(&buffy, "sbom", a.SBOM); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := buffy.WriteByte('}'); err != nil {
 		return nil, fmt.Errorf("close json: %w", err)
 	}
@@ -324,6 +395,23 @@ func (a attestationData) MarshalJSON() ([]byte, error) {
 	return buffy.Bytes(), nil
 }
 
+// writeAttribute writes the attribute with the given key and the value in JSON
+// format, following a previous attribute of the JSON object
+func writeAttribute(buffy *bytes.Buffer, key string, value any) error {
+	if _, err := fmt.Fprintf(buffy, `, %q:`, key); err != nil {
+		return fmt.Errorf("write %s key: %w", key, err)
+	}
+	j, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("marshal json %s: %w", key, err)
+	}
+	if _, err := buffy.Write(j); err != nil {
+		return fmt.Errorf("write %s value: %w", key, err)
+	}
+
+	return nil
+}
+
 type image struct {
 	Ref        string                      `json:"ref"`
 	Signatures []signature.EntitySignature `json:"signatures,omitempty"`
@@ -345,10 +433,7 @@ func (a *ApplicationSnapshotImage) WriteInputFile(ctx context.Context) (string,
 
 	var attestations []attestationData
 	for _, a := range a.attestations {
-		attestations = append(attestations, attestationData{
-			Statement:  a.Statement(),
-			Signatures: a.Signatures(),
-		})
+		attestations = append(attestations, newAttestationData(a))
 	}
 
 	input := Input{
@@ -37,6 +37,7 @@ import (
 	"github.com/in-toto/in-toto-golang/in_toto"
 	"github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
 	v02 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
+	slsav1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
 	app "github.com/konflux-ci/application-api/api/v1alpha1"
 	"github.com/secure-systems-lab/go-securesystemslib/dsse"
 	"github.com/sigstore/cosign/v2/pkg/cosign"
@@ -117,6 +118,118 @@ func createSimpleAttestation(statement *in_toto.ProvenanceStatementSLSA02, o ...
 	return a
 }
 
+func slsaProvenanceV1Signature(t *testing.T, builderID string) oci.Signature {
+	statement, err := json.Marshal(in_toto.ProvenanceStatementSLSA1{
+		StatementHeader: in_toto.StatementHeader{
+			Type:          attestation.StatementInTotoV1,
+			PredicateType: slsav1.PredicateSLSAProvenance,
+			Subject: []in_toto.Subject{
+				{
+					Name: "registry.io/repository/image",
+					Digest: common.DigestSet{
+						"sha256": "abcdef0123456789",
+					},
+				},
+			},
+		},
+		Predicate: slsav1.ProvenancePredicate{
+			BuildDefinition: slsav1.ProvenanceBuildDefinition{
+				BuildType:          "https://tekton.dev/chains/v2/slsa",
+				ExternalParameters: map[string]any{},
+				ResolvedDependencies: []slsav1.ResourceDescriptor{
+					{
+						URI:    "git+https://github.com/org/repo.git",
+						Digest: common.DigestSet{"sha1": "0123456789abcdef"},
+					},
+				},
+			},
+			RunDetails: slsav1.ProvenanceRunDetails{
+				Builder: slsav1.Builder{
+					ID: builderID,
+				},
+				BuildMetadata: slsav1.BuildMetadata{
+					InvocationID: "pipelinerun-123",
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	return dsseSignature(t, statement)
+}
+
+func dsseSignature(t *testing.T, statement []byte) oci.Signature {
+	envelope, err := json.Marshal(dsse.Envelope{
+		PayloadType: "application/vnd.in-toto+json",
+		Payload:     base64.StdEncoding.EncodeToString(statement),
+		Signatures:  []dsse.Signature{{KeyID: "keyId", Sig: "signature"}},
+	})
+	require.NoError(t, err)
+
+	sig, err := static.NewAttestation(envelope, static.WithLayerMediaType(cosignTypes.DssePayloadType))
+	require.NoError(t, err)
+
+	return sig
+}
+
+func createSLSAProvenanceV1Attestation(t *testing.T, builderID string) attestation.Attestation {
+	att, err := attestation.SLSAProvenanceV1FromSignature(slsaProvenanceV1Signature(t, builderID))
+	require.NoError(t, err)
+
+	return att
+}
+
+const spdxStatement = `{
+	"_type": "https://in-toto.io/Statement/v0.1",
+	"subject": [{"name": "registry.io/repository/image", "digest": {"sha256": "abcdef0123456789"}}],
+	"predicateType": "https://spdx.dev/Document",
+	"predicate": {
+		"spdxVersion": "SPDX-2.3",
+		"dataLicense": "CC0-1.0",
+		"SPDXID": "SPDXRef-DOCUMENT",
+		"name": "image",
+		"documentNamespace": "https://example.com/image",
+		"creationInfo": {"created": "2024-01-02T03:04:05Z", "creators": ["Tool: syft"]},
+		"packages": [
+			{
+				"SPDXID": "SPDXRef-Package-openssl",
+				"name": "openssl",
+				"versionInfo": "3.0.7",
+				"downloadLocation": "NOASSERTION",
+				"licenseConcluded": "Apache-2.0",
+				"externalRefs": [{"referenceCategory": "PACKAGE-MANAGER", "referenceType": "purl", "referenceLocator": "pkg:rpm/openssl@3.0.7"}]
+			}
+		]
+	}
+}`
+
+const cyclonedxStatement = `{
+	"_type": "https://in-toto.io/Statement/v0.1",
+	"subject": [{"name": "registry.io/repository/image", "digest": {"sha256": "abcdef0123456789"}}],
+	"predicateType": "https://cyclonedx.org/bom",
+	"predicate": {
+		"bomFormat": "CycloneDX",
+		"specVersion": "1.5",
+		"version": 1,
+		"components": [{"type": "library", "name": "lodash", "version": "4.17.21", "purl": "pkg:npm/lodash@4.17.21"}]
+	}
+}`
+
+func createSBOMAttestation(t *testing.T, statement string) attestation.Attestation {
+	sig := dsseSignature(t, []byte(statement))
+
+	var att attestation.Attestation
+	var err error
+	if strings.Contains(statement, attestation.PredicateSpdxDocument) {
+		att, err = attestation.SPDXSBOMFromSignature(sig)
+	} else {
+		att, err = attestation.CycloneDXSBOMFromSignature(sig)
+	}
+	require.NoError(t, err)
+
+	return att
+}
+
 func TestWriteInputFile(t *testing.T) {
 	cases := []struct {
 		name     string
@@ -192,6 +305,23 @@ func TestWriteInputFile(t *testing.T) {
 				})},
 			},
 		},
+		{
+			name: "SLSA Provenance v1.0 attestation",
+			snapshot: ApplicationSnapshotImage{
+				reference:    name.MustParseReference("registry.io/repository/image:tag"),
+				attestations: []attestation.Attestation{createSLSAProvenanceV1Attestation(t, "https://tekton.dev/chains/v2")},
+			},
+		},
+		{
+			name: "SBOM attestations",
+			snapshot: ApplicationSnapshotImage{
+				reference: name.MustParseReference("registry.io/repository/image:tag"),
+				attestations: []attestation.Attestation{
+					createSBOMAttestation(t, spdxStatement),
+					createSBOMAttestation(t, cyclonedxStatement),
+				},
+			},
+		},
 		{
 			name: "component with source",
 			snapshot: ApplicationSnapshotImage{
@@ -416,6 +546,37 @@ func TestSyntaxValidation(t *testing.T) {
 	}
 }
 
+func TestSyntaxValidationSLSAProvenanceV1(t *testing.T) {
+	valid := ApplicationSnapshotImage{
+		attestations: []attestation.Attestation{createSLSAProvenanceV1Attestation(t, "https://tekton.dev/chains/v2")},
+	}
+	assert.NoError(t, valid.ValidateAttestationSyntax(context.TODO()))
+
+	invalid := ApplicationSnapshotImage{
+		attestations: []attestation.Attestation{createSLSAProvenanceV1Attestation(t, "invalid")},
+	}
+	err := invalid.ValidateAttestationSyntax(context.TODO())
+	assert.EqualError(t, err, "attestation syntax validation failed: jsonschema: '/predicate/runDetails/builder/id' does not validate with https://slsa.dev/provenance/v1#/properties/predicate/properties/runDetails/properties/builder/properties/id/format: 'invalid' is not valid 'uri'")
+}
+
+func TestSyntaxValidationSBOM(t *testing.T) {
+	valid := ApplicationSnapshotImage{
+		attestations: []attestation.Attestation{
+			createSBOMAttestation(t, spdxStatement),
+			createSBOMAttestation(t, cyclonedxStatement),
+		},
+	}
+	assert.NoError(t, valid.ValidateAttestationSyntax(context.TODO()))
+
+	invalid := ApplicationSnapshotImage{
+		attestations: []attestation.Attestation{
+			createSBOMAttestation(t, strings.Replace(cyclonedxStatement, `"specVersion": "1.5"`, `"specVersion": "1.2"`, 1)),
+		},
+	}
+	err := invalid.ValidateAttestationSyntax(context.TODO())
+	assert.EqualError(t, err, `attestation syntax validation failed: jsonschema: '/predicate/specVersion' does not validate with https://cyclonedx.org/bom#/properties/predicate/properties/specVersion/enum: value must be one of "1.4", "1.5", "1.6"`)
+}
+
 func TestValidateImageSignatureClaims(t *testing.T) {
 	ref := name.MustParseReference("registry.io/repository/image:tag")
 	a := ApplicationSnapshotImage{
@@ -654,6 +815,51 @@ func TestValidateAttestationSignatureClaims(t *testing.T) {
 	}
 }
 
+func TestValidateAttestationSignatureSLSAProvenanceV1(t *testing.T) {
+	ref := name.MustParseReference("registry.io/repository/image:tag")
+	a := ApplicationSnapshotImage{
+		reference: ref,
+	}
+
+	c := fake.FakeClient{}
+	ctx := o.WithClient(context.Background(), &c)
+
+	c.On("VerifyImageAttestations", ref, mock.Anything).Return([]oci.Signature{slsaProvenanceV1Signature(t, "https://tekton.dev/chains/v2")}, false, nil)
+
+	require.NoError(t, a.ValidateAttestationSignature(ctx))
+	require.Len(t, a.Attestations(), 1)
+
+	sp, ok := a.Attestations()[0].(attestation.SLSAProvenanceV1)
+	require.True(t, ok)
+	assert.Equal(t, "https://tekton.dev/chains/v2", sp.BuilderID())
+	assert.Equal(t, "https://tekton.dev/chains/v2/slsa", sp.PredicateBuildType())
+}
+
+func TestValidateAttestationSignatureSBOM(t *testing.T) {
+	ref := name.MustParseReference("registry.io/repository/image:tag")
+	a := ApplicationSnapshotImage{
+		reference: ref,
+	}
+
+	c := fake.FakeClient{}
+	ctx := o.WithClient(context.Background(), &c)
+
+	c.On("VerifyImageAttestations", ref, mock.Anything).Return([]oci.Signature{
+		dsseSignature(t, []byte(spdxStatement)),
+		dsseSignature(t, []byte(cyclonedxStatement)),
+	}, false, nil)
+
+	require.NoError(t, a.ValidateAttestationSignature(ctx))
+	require.Len(t, a.Attestations(), 2)
+
+	for i, format := range []string{attestation.SPDX, attestation.CycloneDX} {
+		sbom, ok := a.Attestations()[i].(attestation.SBOM)
+		require.True(t, ok)
+		assert.Equal(t, format, sbom.Format())
+		assert.Len(t, sbom.Packages(), 1)
+	}
+}
+
 func TestValidateImageSignatureWithCertificates(t *testing.T) {
 	ref := name.MustParseReference("registry.io/repository/image:tag")
 	a := ApplicationSnapshotImage{
@@ -23,10 +23,11 @@ import (
 
 	"github.com/enterprise-contract/ec-cli/internal/evaluator"
 	"github.com/enterprise-contract/ec-cli/internal/policy"
-	"github.com/enterprise-contract/ec-cli/internal/policy/source"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
 )
 
 var newConftestEvaluator = evaluator.NewConftestEvaluator
+var newOPAEvaluator = evaluator.NewOPAEvaluator
 
 // Input represents the structure needed to evaluate a generic file input
 type Input struct {
@@ -40,21 +41,27 @@ func NewInput(ctx context.Context, paths []string, p policy.Policy) (*Input, err
 		Paths: paths,
 	}
 
-	for _, sourceGroup := range p.Spec().Sources {
+	for idx, sourceGroup := range p.Spec().Sources {
 		// Todo: Make each fetch run concurrently
-		policySources := source.PolicySourcesFrom(sourceGroup)
+		policySources := p.PolicySources(idx)
 
 		for _, policySource := range policySources {
 			log.Debugf("policySource: %#v", policySource)
 		}
 
-		c, err := newConftestEvaluator(ctx, policySources, p, sourceGroup)
+		var c evaluator.Evaluator
+		var err error
+		if utils.IsOpaEnabled() {
+			c, err = newOPAEvaluator(ctx, policySources, p, sourceGroup)
+		} else {
+			c, err = newConftestEvaluator(ctx, policySources, p, sourceGroup)
+		}
 		if err != nil {
-			log.Debug("Failed to initialize the conftest evaluator!")
+			log.Debug("Failed to initialize the policy evaluator!")
 			return nil, err
 		}
 
-		log.Debug("Conftest evaluator initialized")
+		log.Debug("Policy evaluator initialized")
 		i.Evaluators = append(i.Evaluators, c)
 
 	}
@@ -19,6 +19,7 @@ package evaluator
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
@@ -26,13 +27,14 @@ import (
 	"path/filepath"
 	"runtime/trace"
 	"strings"
+	"sync"
 	"time"
 
 	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
-	"github.com/open-policy-agent/conftest/output"
 	conftest "github.com/open-policy-agent/conftest/policy"
 	"github.com/open-policy-agent/conftest/runner"
 	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
 	"github.com/open-policy-agent/opa/storage"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
@@ -42,7 +44,6 @@ import (
 	"github.com/enterprise-contract/ec-cli/internal/opa/rule"
 	"github.com/enterprise-contract/ec-cli/internal/policy"
 	"github.com/enterprise-contract/ec-cli/internal/policy/source"
-	"github.com/enterprise-contract/ec-cli/internal/tracing"
 	"github.com/enterprise-contract/ec-cli/internal/utils"
 )
 
@@ -54,51 +55,108 @@ const (
 	effectiveTimeKey contextKey = "ec.evaluator.effective_time"
 )
 
-// trim removes all failure, warning, success or skipped results that depend on
-// a result reported as failure, warning or skipped. Dependencies are declared
-// by setting the metadata via metadataDependsOn.
-func trim(results *[]Outcome) {
-	// holds codes for all failures, warnings or skipped rules, as a map to ease
-	// the lookup, any rule that depends on a reported code will be removed from
-	// the results
-	reported := map[string]bool{}
+// trim moves all failure, warning or success results that depend on a rule
+// with unmet dependency to the skipped results, with the message describing
+// which dependencies were not met. A dependency is not met if the rule it
+// refers to was reported as failure, warning or skipped, or if it was excluded
+// from the evaluation, i.e. its identity is within excluded and it produced no
+// results. Dependencies are declared by setting the metadata via
+// metadataDependsOn, and refer to the rules from the same policy source.
+func trim(results *[]Outcome, excluded map[ruleID]bool) {
+	// holds the reason for each rule, by identity, not meeting the dependency,
+	// as a map to ease the lookup, any rule that depends on a rule present
+	// here will be skipped
+	unmet := map[ruleID]string{}
+	succeeded := map[ruleID]bool{}
 
 	for _, checks := range *results {
-		for _, results := range [][]Result{checks.Failures, checks.Warnings, checks.Skipped} {
-			for _, result := range results {
-				if code, ok := result.Metadata[metadataCode].(string); ok {
-					reported[code] = true
+		for _, r := range []struct {
+			results []Result
+			reason  string
+		}{
+			// ordered from the least to the most relevant reason
+			{checks.Skipped, "was skipped"},
+			{checks.Warnings, "produced a warning"},
+			{checks.Failures, "failed"},
+		} {
+			for _, result := range r.results {
+				if id, ok := resultRuleID(result); ok {
+					unmet[id] = r.reason
 				}
 			}
 		}
+
+		for _, result := range checks.Successes {
+			if id, ok := resultRuleID(result); ok {
+				succeeded[id] = true
+			}
+		}
+	}
+
+	for id := range excluded {
+		if _, ok := unmet[id]; !ok && !succeeded[id] {
+			unmet[id] = "was excluded"
+		}
+	}
+
+	// helper function inlined for ecapsulation, returns the message describing
+	// the dependencies that were not met, or an empty string if all are met
+	unmetDependencies := func(result Result) string {
+		dependencies, ok := result.Metadata[metadataDependsOn].([]string)
+		if !ok {
+			return ""
+		}
+
+		source, _ := result.Metadata[metadataSource].(string)
+		reasons := make([]string, 0, len(dependencies))
+		for _, d := range dependencies {
+			if reason, ok := unmet[ruleID{source: source, code: d}]; ok {
+				reasons = append(reasons, fmt.Sprintf("%s which %s", d, reason))
+			}
+		}
+
+		if len(reasons) == 0 {
+			return ""
+		}
+
+		return fmt.Sprintf("Skipped because it depends on %s", strings.Join(reasons, ", "))
 	}
 
-	// helper function inlined for ecapsulation, removes any results that depend
-	// on a reported rule, by code
-	trimOutput := func(what []Result) []Result {
+	// helper function inlined for ecapsulation, removes any results that have
+	// unmet dependencies, returning the leftover results and the results that
+	// should be skipped, one per rule code
+	trimOutput := func(what []Result) (trimmed []Result, skipped []Result) {
 		if what == nil {
 			// nil might get passed in, while this would not cause an issue, the
 			// function would return empty array and that would needlessly
 			// change the output
-			return nil
+			return nil, nil
 		}
 
-		// holds leftover results, i.e. the ones that do not depend on a rule
-		// reported as failure, warning or skipped
-		trimmed := make([]Result, 0, len(what))
+		// holds leftover results, i.e. the ones that have their dependencies
+		// met
+		trimmed = make([]Result, 0, len(what))
+		seen := map[ruleID]bool{}
 		for _, result := range what {
-			if dependency, ok := result.Metadata[metadataDependsOn].([]string); ok {
-				for _, d := range dependency {
-					if !reported[d] {
-						trimmed = append(trimmed, result)
-					}
-				}
-			} else {
+			reason := unmetDependencies(result)
+			if reason == "" {
 				trimmed = append(trimmed, result)
+				continue
+			}
+
+			id, _ := resultRuleID(result)
+			if seen[id] {
+				continue
 			}
+			seen[id] = true
+
+			skipped = append(skipped, Result{
+				Message:  reason,
+				Metadata: result.Metadata,
+			})
 		}
 
-		return trimmed
+		return trimmed, skipped
 	}
 
 	addNote := func(results []Result) []Result {
@@ -119,12 +177,55 @@ func trim(results *[]Outcome) {
 		return results
 	}
 
-	for i, checks := range *results {
-		(*results)[i].Failures = addNote(trimOutput(checks.Failures))
-		(*results)[i].Warnings = trimOutput(checks.Warnings)
-		(*results)[i].Skipped = trimOutput(checks.Skipped)
-		(*results)[i].Successes = trimOutput(checks.Successes)
+	// skipping a rule can cause the rules depending on it to be skipped, so
+	// repeat until no more rules are skipped
+	for changed := true; changed; {
+		changed = false
+		for i := range *results {
+			o := &(*results)[i]
+			for _, what := range []*[]Result{&o.Failures, &o.Warnings, &o.Successes} {
+				trimmed, skipped := trimOutput(*what)
+				if len(skipped) == 0 {
+					continue
+				}
+
+				*what = trimmed
+				o.Skipped = append(o.Skipped, skipped...)
+				for _, s := range skipped {
+					if id, ok := resultRuleID(s); ok {
+						if _, ok := unmet[id]; !ok {
+							unmet[id] = "was skipped"
+							changed = true
+						}
+					}
+				}
+			}
+		}
+	}
+
+	for i := range *results {
+		(*results)[i].Failures = addNote((*results)[i].Failures)
+	}
+}
+
+// ruleID identifies a rule by its code qualified by the URL of the policy
+// source the rule was loaded from, as the same code can be used by rules from
+// different policy sources
+type ruleID struct {
+	source string
+	code   string
+}
+
+// resultRuleID returns the identity of the rule that produced the result, from
+// the code and the source recorded in the result's metadata
+func resultRuleID(result Result) (ruleID, bool) {
+	code, ok := result.Metadata[metadataCode].(string)
+	if !ok {
+		return ruleID{}, false
 	}
+	source, _ := result.Metadata[metadataSource].(string)
+
+	return ruleID{source: source, code: code}, true
 }
 
 // Used above to suggest what to exclude to skip a certain violation.
@@ -176,10 +277,15 @@ const (
 	metadataSeverity    = "severity"
 	metadataEffectiveOn = "effective_on"
 	metadataSolution    = "solution"
+	metadataSource      = "source"
 	metadataTerm        = "term"
 	metadataTitle       = "title"
 )
 
+// sourceSeparator separates the policy source from the matcher in the include
+// and exclude criteria qualified by the policy source
+const sourceSeparator = "#"
+
 const (
 	severityWarning = "warning"
 	severityFailure = "failure"
@@ -205,81 +311,82 @@ type conftestEvaluator struct {
 	exclude       *Criteria
 	fs            afero.Fs
 	namespace     []string
+	sourceGroup   string
+	source        ecc.Source
+	prepared      *preparedPolicy
+}
+
+// preparedPolicy holds the state computed from the policy sources on the first
+// successful evaluation and reused by all subsequent evaluations. This way the
+// sources are downloaded, inspected and compiled only once per source group
+// regardless of the number of components being evaluated. A failure to prepare
+// is not retained, the next evaluation attempts to prepare again.
+type preparedPolicy struct {
+	mu     sync.Mutex
+	rules  policyRules
+	runner testRunner
 }
 
+// conftestRunner evaluates policies using the Conftest policy engine. The
+// policies and data are loaded into the engine, and the queries for the rules
+// prepared, on the first successful run and reused by all subsequent runs.
+// Conftest records the name and the directory of the input file in the
+// engine's store (data.conftest.file) before checking it, which is not safe
+// with concurrent runs. Instead, each input file is checked, the same way as
+// Conftest checks it, with the information of the input file placed over the
+// engine's data for the evaluation only.
 type conftestRunner struct {
 	runner.TestRunner
+	mu       sync.Mutex
+	prepared *query
 }
 
-func (r conftestRunner) Run(ctx context.Context, fileList []string) (result []Outcome, err error) {
-	r.Trace = tracing.FromContext(ctx).Enabled(tracing.Opa)
+// load loads the policies and data into the Conftest engine and prepares the
+// queries of the rules within the evaluated namespaces
+func (r *conftestRunner) load(ctx context.Context) (*query, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	var conftestResult []output.CheckResult
-	conftestResult, err = r.TestRunner.Run(ctx, fileList)
-	if err != nil {
-		return
+	if r.prepared != nil {
+		return r.prepared, nil
 	}
 
-	for _, res := range conftestResult {
-		if log.IsLevelEnabled(log.TraceLevel) {
-			for _, q := range res.Queries {
-				for _, t := range q.Traces {
-					log.Tracef("[%s] %s", q.Query, t)
-				}
-			}
-		}
-		if log.IsLevelEnabled(log.DebugLevel) {
-			for _, q := range res.Queries {
-				for _, o := range q.Outputs {
-					log.Debugf("[%s] %s", q.Query, o)
-				}
-			}
-		}
+	engine, err := conftest.LoadWithData(r.Policy, r.Data, r.Capabilities, r.Strict)
+	if err != nil {
+		return nil, fmt.Errorf("load: %w", err)
+	}
 
-		result = append(result, Outcome{
-			FileName:  res.FileName,
-			Namespace: res.Namespace,
-			// Conftest doesn't give us a list of successes, just a count. Here we turn that count
-			// into a placeholder slice of that size to make processing easier later on.
-			Successes:  make([]Result, res.Successes),
-			Skipped:    toRules(res.Skipped),
-			Warnings:   toRules(res.Warnings),
-			Failures:   toRules(res.Failures),
-			Exceptions: toRules(res.Exceptions),
-		})
+	namespaces := r.Namespace
+	if r.AllNamespaces {
+		namespaces = engine.Namespaces()
 	}
 
-	// we can't reference the engine from the test runner or from the results so
-	// we need to recreate it, this needs to remain the same as in
-	// runner.TestRunner's Run function
-	var engine *conftest.Engine
-	engine, err = conftest.LoadWithData(r.Policy, r.Data, r.Capabilities, r.Strict)
+	q, err := prepareQuery(ctx, engine.Compiler(), engine.Store(), namespaces, rego.Runtime(engine.Runtime()))
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	store := engine.Store()
+	r.prepared = q
 
-	var txn storage.Transaction
-	txn, err = store.NewTransaction(ctx)
+	return r.prepared, nil
+}
+
+func (r *conftestRunner) policies(ctx context.Context) (*ast.Compiler, storage.Store, error) {
+	q, err := r.load(ctx)
 	if err != nil {
-		return
+		return nil, nil, err
 	}
 
-	ids := []string{} // everything
+	return q.compiler, q.store, nil
+}
 
-	var d any
-	d, err = store.Read(ctx, txn, ids)
+func (r *conftestRunner) Run(ctx context.Context, fileList []string) ([]Outcome, error) {
+	q, err := r.load(ctx)
 	if err != nil {
-		return
-	}
-
-	var ok bool
-	if _, ok = d.(map[string]any); !ok {
-		err = fmt.Errorf("could not retrieve data from the policy engine: Data is: %v", d)
+		return nil, err
 	}
 
-	return
+	return q.run(ctx, fileList)
 }
 
 // NewConftestEvaluator returns initialized conftestEvaluator implementing
@@ -295,6 +402,18 @@ func NewConftestEvaluatorWithNamespace(ctx context.Context, policySources []sour
 		defer r.End()
 	}
 
+	c, err := newConftestEvaluator(ctx, policySources, p, source, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug("Conftest test runner created")
+	return c, nil
+}
+
+// newConftestEvaluator prepares the working directory, the data directory and
+// the capabilities file shared by all evaluator implementations.
+func newConftestEvaluator(ctx context.Context, policySources []source.PolicySource, p ConfigProvider, source ecc.Source, namespace []string) (conftestEvaluator, error) {
 	fs := utils.FS(ctx)
 	c := conftestEvaluator{
 		policySources: policySources,
@@ -302,35 +421,37 @@ func NewConftestEvaluatorWithNamespace(ctx context.Context, policySources []sour
 		policy:        p,
 		fs:            fs,
 		namespace:     namespace,
+		sourceGroup:   source.Name,
+		source:        source,
+		prepared:      &preparedPolicy{},
 	}
 
 	c.include, c.exclude = computeIncludeExclude(source, p)
 	dir, err := utils.CreateWorkDir(fs)
 	if err != nil {
 		log.Debug("Failed to create work dir!")
-		return nil, err
+		return conftestEvaluator{}, err
 	}
 	c.workDir = dir
 	c.policyDir = filepath.Join(c.workDir, "policy")
 	c.dataDir = filepath.Join(c.workDir, "data")
 
 	if err := c.createDataDirectory(ctx); err != nil {
-		return nil, err
+		return conftestEvaluator{}, err
 	}
 
 	log.Debugf("Created work dir %s", dir)
 
 	if err := c.createCapabilitiesFile(ctx); err != nil {
-		return nil, err
+		return conftestEvaluator{}, err
 	}
 
-	log.Debug("Conftest test runner created")
 	return c, nil
 }
 
 // Destroy removes the working directory
 func (c conftestEvaluator) Destroy() {
-	if os.Getenv("EC_DEBUG") == "" {
+	if c.workDir != "" && os.Getenv("EC_DEBUG") == "" {
 		_ = c.fs.RemoveAll(c.workDir)
 	}
 }
@@ -339,9 +460,25 @@ func (c conftestEvaluator) CapabilitiesPath() string {
 	return path.Join(c.workDir, "capabilities.json")
 }
 
-type policyRules map[string]rule.Info
+// ruleKey is the key of the rule information within policyRules, results are
+// reported per package so the package and the code of the rule identify the
+// rule the result was produced by
+type ruleKey struct {
+	pkg  string
+	code string
+}
 
-func (r *policyRules) collect(a *ast.AnnotationsRef) error {
+// policyRules holds the information about the rules, by package and code,
+// collected from all policy sources
+type policyRules map[ruleKey]rule.Info
+
+// collect adds the information about the rule from the annotations, recording
+// the URL of the policy source it was loaded from. Rules from different
+// packages or policy sources can share the same code. Rules with the same code
+// defined in the same package are considered a configuration error, as the
+// packages from different policy sources are merged when evaluated and the
+// results of the rules can't be told apart.
+func (r *policyRules) collect(a *ast.AnnotationsRef, source string) error {
 	if a.Annotations == nil {
 		return nil
 	}
@@ -354,115 +491,102 @@ func (r *policyRules) collect(a *ast.AnnotationsRef) error {
 		return nil
 	}
 
-	code := info.Code
+	key := ruleKey{pkg: info.Package, code: info.Code}
+	info.Source = source
+
+	if existing, ok := (*r)[key]; ok {
+		if existing.Source == info.Source {
+			return fmt.Errorf("found a second rule with the same code: `%s`", info.Code)
+		}
 
-	if _, ok := (*r)[code]; ok {
-		return fmt.Errorf("found a second rule with the same code: `%s`", code)
+		return fmt.Errorf("found a second rule with the same code: `%s`, defined in package `%s` from both source %q and source %q", info.Code, info.Package, existing.Source, info.Source)
 	}
 
-	(*r)[code] = info
+	(*r)[key] = info
 	return nil
 }
 
 func (c conftestEvaluator) Evaluate(ctx context.Context, target EvaluationTarget) ([]Outcome, error) {
-	var results []Outcome
-
 	if trace.IsEnabled() {
 		region := trace.StartRegion(ctx, "ec:conftest-evaluate")
 		defer region.End()
 	}
 
-	// hold all rule annotations from all policy sources
-	// NOTE: emphasis on _all rules from all sources_; meaning that if two rules
-	// exist with the same code in two separate sources the collected rule
-	// information is not deterministic
-	rules := policyRules{}
-	// Download all sources
-	for _, s := range c.policySources {
-		dir, err := s.GetPolicy(ctx, c.workDir, false)
-		if err != nil {
-			log.Debugf("Unable to download source from %s!", s.PolicyUrl())
-			// TODO do we want to download other policies instead of erroring out?
-			return nil, err
-		}
-		annotations := []*ast.AnnotationsRef{}
-		fs := utils.FS(ctx)
-		// We only want to inspect the directory of policy subdirs, not config or data subdirs.
-		if s.Subdir() == "policy" {
-			annotations, err = opa.InspectDir(fs, dir)
-			if err != nil {
-				errMsg := err
-				if err.Error() == "no rego files found in policy subdirectory" {
-					// Let's try to give some more robust messaging to the user.
-					policyURL, err := url.Parse(s.PolicyUrl())
-					if err != nil {
-						return nil, errMsg
-					}
-					// Do we have a prefix at the end of the URL path?
-					// If not, this means we aren't trying to access a specific file.
-					// TODO: Determine if we want to check for a .git suffix as well?
-					pos := strings.LastIndex(policyURL.Path, ".")
-					if pos == -1 {
-						// Are we accessing a GitHub or GitLab URL? If so, are we beginning with 'https' or 'http'?
-						if (policyURL.Host == "github.com" || policyURL.Host == "gitlab.com") && (policyURL.Scheme == "https" || policyURL.Scheme == "http") {
-							log.Debug("Git Hub or GitLab, http transport, and no file extension, this could be a problem.")
-							errMsg = fmt.Errorf("%s.\nYou've specified a %s URL with an %s:// scheme.\nDid you mean: %s instead?", errMsg, policyURL.Hostname(), policyURL.Scheme, fmt.Sprint(policyURL.Host+policyURL.RequestURI()))
-						}
-					}
-				}
-				return nil, errMsg
-			}
-		}
+	return c.evaluate(ctx, target, c.conftestRunner)
+}
 
-		for _, a := range annotations {
-			if a.Annotations == nil {
-				continue
-			}
-			if err := rules.collect(a); err != nil {
-				return nil, err
-			}
-		}
+// conftestRunner returns the testRunner that uses the Conftest test runner to
+// evaluate the policies
+func (c conftestEvaluator) conftestRunner() testRunner {
+	// should there be a namespace defined or not
+	allNamespaces := true
+	if len(c.namespace) > 0 {
+		allNamespaces = false
+	}
+
+	return &conftestRunner{
+		TestRunner: runner.TestRunner{
+			Data:          []string{c.dataDir},
+			Policy:        []string{c.policyDir},
+			Namespace:     c.namespace,
+			AllNamespaces: allNamespaces,
+			NoFail:        true,
+			Output:        c.outputFormat,
+			Capabilities:  c.CapabilitiesPath(),
+		},
 	}
+}
 
-	var r testRunner
-	var ok bool
-	if r, ok = ctx.Value(runnerKey).(testRunner); r == nil || !ok {
+// evaluate downloads the policy sources, runs the testRunner created by
+// newRunner and post-processes the results so that they include the rule
+// metadata and honor the include/exclude policy configuration. Any evaluator
+// implementation producing the same raw results from the testRunner will
+// produce the same outcomes.
+func (c conftestEvaluator) evaluate(ctx context.Context, target EvaluationTarget, newRunner func() testRunner) ([]Outcome, error) {
+	var results []Outcome
 
-		// should there be a namespace defined or not
-		allNamespaces := true
-		if len(c.namespace) > 0 {
-			allNamespaces = false
-		}
+	// The effective time, e.g. the current time, might have changed since the
+	// evaluator was created, the volatile configuration is applied as of the
+	// effective time of this evaluation
+	if c.source.VolatileConfig != nil {
+		c.include, c.exclude = computeIncludeExclude(c.source, c.policy)
+	}
 
-		r = &conftestRunner{
-			runner.TestRunner{
-				Data:          []string{c.dataDir},
-				Policy:        []string{c.policyDir},
-				Namespace:     c.namespace,
-				AllNamespaces: allNamespaces,
-				NoFail:        true,
-				Output:        c.outputFormat,
-				Capabilities:  c.CapabilitiesPath(),
-			},
+	rules, r, err := c.prepare(ctx, newRunner)
+	if err != nil {
+		var srcErr sourceError
+		if source.FetchErrorsReported(ctx) && errors.As(err, &srcErr) {
+			return []Outcome{c.sourceErrorOutcome(srcErr)}, nil
 		}
+		return nil, err
+	}
+
+	if override, ok := ctx.Value(runnerKey).(testRunner); override != nil && ok {
+		r = override
 	}
 
 	log.Debugf("runner: %#v", r)
 	log.Debugf("inputs: %#v", target.Inputs)
 
+	// The effective time is taken once per evaluation, and is provided to the
+	// policies in data.config.policy.when_ns
+	effectiveTime := c.policy.EffectiveTime()
+	ctx = context.WithValue(ctx, effectiveTimeKey, effectiveTime)
+
 	runResults, err := r.Run(ctx, target.Inputs)
 	if err != nil {
 		// TODO do we want to evaluate further policies instead of erroring out?
 		return nil, err
 	}
 
-	effectiveTime := c.policy.EffectiveTime()
-	ctx = context.WithValue(ctx, effectiveTimeKey, effectiveTime)
-
 	// Track how many rules have been processed. This is used later on to determine if anything
 	// at all was processed.
 	totalRules := 0
 
+	// rules excluded by the policy configuration, used to skip the rules
+	// depending on them
+	excluded := map[ruleID]bool{}
+
 	// loop over each policy (namespace) evaluation
 	// effectively replacing the results returned from conftest
 	for i, result := range runResults {
@@ -474,10 +598,13 @@ func (c conftestEvaluator) Evaluate(ctx context.Context, target EvaluationTarget
 
 		for i := range result.Warnings {
 			warning := result.Warnings[i]
-			addRuleMetadata(ctx, &warning, rules)
+			addRuleMetadata(ctx, &warning, rules, result.Namespace)
 
 			if !c.isResultIncluded(warning, target.Target) {
 				log.Debugf("Skipping result warning: %#v", warning)
+				if id, ok := resultRuleID(warning); ok {
+					excluded[id] = true
+				}
 				continue
 			}
 
@@ -490,10 +617,13 @@ func (c conftestEvaluator) Evaluate(ctx context.Context, target EvaluationTarget
 
 		for i := range result.Failures {
 			failure := result.Failures[i]
-			addRuleMetadata(ctx, &failure, rules)
+			addRuleMetadata(ctx, &failure, rules, result.Namespace)
 
 			if !c.isResultIncluded(failure, target.Target) {
 				log.Debugf("Skipping result failure: %#v", failure)
+				if id, ok := resultRuleID(failure); ok {
+					excluded[id] = true
+				}
 				continue
 			}
 
@@ -506,13 +636,13 @@ func (c conftestEvaluator) Evaluate(ctx context.Context, target EvaluationTarget
 
 		for i := range result.Exceptions {
 			exception := result.Exceptions[i]
-			addRuleMetadata(ctx, &exception, rules)
+			addRuleMetadata(ctx, &exception, rules, result.Namespace)
 			exceptions = append(exceptions, exception)
 		}
 
 		for i := range result.Skipped {
 			skip := result.Skipped[i]
-			addRuleMetadata(ctx, &skip, rules)
+			addRuleMetadata(ctx, &skip, rules, result.Namespace)
 			skipped = append(skipped, skip)
 		}
 
@@ -522,14 +652,20 @@ func (c conftestEvaluator) Evaluate(ctx context.Context, target EvaluationTarget
 		result.Skipped = skipped
 
 		// Replace the placeholder successes slice with the actual successes.
-		result.Successes = c.computeSuccesses(result, rules, target.Target)
+		result.Successes = c.computeSuccesses(result, rules, target.Target, excluded)
 
 		totalRules += len(result.Warnings) + len(result.Failures) + len(result.Successes)
 
 		results = append(results, result)
 	}
 
-	trim(&results)
+	trim(&results, excluded)
+
+	if explainEnabled(ctx) {
+		if err := explain(ctx, r, results); err != nil {
+			log.Warnf("Unable to explain the results: %v", err)
+		}
+	}
 
 	// If no rules were checked, then we have effectively failed, because no tests were actually
 	// ran due to input error, etc.
@@ -538,26 +674,189 @@ func (c conftestEvaluator) Evaluate(ctx context.Context, target EvaluationTarget
 		return nil, fmt.Errorf("no successes, warnings, or failures, check input")
 	}
 
+	if verified := c.signatureSuccesses(); len(verified) > 0 {
+		results = append(results, Outcome{Successes: verified})
+	}
+
 	return results, nil
 }
 
-func toRules(results []output.Result) []Result {
-	var eResults []Result
-	for _, r := range results {
-		eResults = append(eResults, Result{
-			Message:  r.Message,
-			Metadata: r.Metadata,
-			Outputs:  r.Outputs,
+// prepare downloads and inspects the policy sources, and creates the
+// testRunner using newRunner. This is done only once, on the first successful
+// evaluation, and the outcome is reused by all subsequent evaluations.
+func (c conftestEvaluator) prepare(ctx context.Context, newRunner func() testRunner) (policyRules, testRunner, error) {
+	c.prepared.mu.Lock()
+	defer c.prepared.mu.Unlock()
+
+	if c.prepared.runner != nil {
+		return c.prepared.rules, c.prepared.runner, nil
+	}
+
+	if trace.IsEnabled() {
+		region := trace.StartRegion(ctx, "ec:prepare-policy")
+		defer region.End()
+	}
+
+	rules, err := c.collectRules(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.prepared.rules = rules
+	c.prepared.runner = newRunner()
+
+	return c.prepared.rules, c.prepared.runner, nil
+}
+
+// sourceError is returned when a policy source can't be fetched, it is reported
+// as a failure of the source group when the context was created using
+// source.WithFetchErrorsReported
+type sourceError struct {
+	url string
+	err error
+}
+
+func (e sourceError) Error() string {
+	return e.err.Error()
+}
+
+func (e sourceError) Unwrap() error {
+	return e.err
+}
+
+const (
+	signatureCode        = "builtin.policy.source_signature"
+	signatureTitle       = "Policy source signature is verified"
+	signatureDescription = "The OCI bundle of the policy source is signed as required by its source group, so the policy rules and data within it can be trusted."
+)
+
+// sourceErrorOutcome returns the outcome reporting the policy source that
+// couldn't be fetched in place of the results of the source group
+func (c conftestEvaluator) sourceErrorOutcome(err sourceError) Outcome {
+	var sigErr source.SignatureError
+	if errors.As(err.err, &sigErr) {
+		return Outcome{
+			Failures: []Result{
+				{
+					Message: fmt.Sprintf("Unable to verify the signature of the policy source %q of the source group %q: %s", err.url, c.sourceGroup, sigErr.Err),
+					Metadata: map[string]interface{}{
+						metadataCode:        signatureCode,
+						metadataTitle:       signatureTitle,
+						metadataDescription: signatureDescription,
+						"source":            err.url,
+						"source_group":      c.sourceGroup,
+					},
+				},
+			},
+		}
+	}
+
+	return Outcome{
+		Failures: []Result{
+			{
+				Message: fmt.Sprintf("Unable to fetch the policy source %q of the source group %q: %s", err.url, c.sourceGroup, err.err),
+				Metadata: map[string]interface{}{
+					metadataCode:        "builtin.policy.source_accessible",
+					metadataTitle:       "Policy source is accessible",
+					metadataDescription: "The policy source can be fetched so the policy rules and data within it are evaluated.",
+					"source":            err.url,
+					"source_group":      c.sourceGroup,
+				},
+			},
+		},
+	}
+}
+
+// signatureSuccesses returns the successes recording the policy sources
+// whose signature was verified when they were fetched
+func (c conftestEvaluator) signatureSuccesses() []Result {
+	var successes []Result
+	for _, s := range c.policySources {
+		u, ok := s.(*source.PolicyUrl)
+		if !ok || !u.SignatureVerified() {
+			continue
+		}
+
+		successes = append(successes, Result{
+			Message: fmt.Sprintf("The signature of the policy source %q of the source group %q is verified", u.PolicyUrl(), c.sourceGroup),
+			Metadata: map[string]interface{}{
+				metadataCode:        signatureCode,
+				metadataTitle:       signatureTitle,
+				metadataDescription: signatureDescription,
+				"source":            u.PolicyUrl(),
+				"source_group":      c.sourceGroup,
+			},
 		})
 	}
 
-	return eResults
+	return successes
+}
+
+// collectRules downloads all policy sources and collects the rule annotations
+// from the policies within them
+func (c conftestEvaluator) collectRules(ctx context.Context) (policyRules, error) {
+	// hold all rule annotations from all policy sources, rules with the same
+	// code in the same package of two separate sources are reported as an
+	// error by collect
+	rules := policyRules{}
+	// Download all sources
+	for _, s := range c.policySources {
+		sourceUrl := s.PolicyUrl()
+		dir, err := s.GetPolicy(ctx, c.workDir, false)
+		if err != nil {
+			log.Debugf("Unable to download source from %s!", sourceUrl)
+			return nil, sourceError{url: sourceUrl, err: err}
+		}
+		annotations := []*ast.AnnotationsRef{}
+		fs := utils.FS(ctx)
+		// We only want to inspect the directory of policy subdirs, not config or data subdirs.
+		if s.Subdir() == "policy" {
+			annotations, err = opa.InspectDir(fs, dir)
+			if err != nil {
+				errMsg := err
+				if err.Error() == "no rego files found in policy subdirectory" {
+					// Let's try to give some more robust messaging to the user.
+					policyURL, err := url.Parse(s.PolicyUrl())
+					if err != nil {
+						return nil, errMsg
+					}
+					// Do we have a prefix at the end of the URL path?
+					// If not, this means we aren't trying to access a specific file.
+					// TODO: Determine if we want to check for a .git suffix as well?
+					pos := strings.LastIndex(policyURL.Path, ".")
+					if pos == -1 {
+						// Are we accessing a GitHub or GitLab URL? If so, are we beginning with 'https' or 'http'?
+						if (policyURL.Host == "github.com" || policyURL.Host == "gitlab.com") && (policyURL.Scheme == "https" || policyURL.Scheme == "http") {
+							log.Debug("Git Hub or GitLab, http transport, and no file extension, this could be a problem.")
+							errMsg = fmt.Errorf("%s.\nYou've specified a %s URL with an %s:// scheme.\nDid you mean: %s instead?", errMsg, policyURL.Hostname(), policyURL.Scheme, fmt.Sprint(policyURL.Host+policyURL.RequestURI()))
+						}
+					}
+				}
+				return nil, errMsg
+			}
+		}
+
+		for _, a := range annotations {
+			if a.Annotations == nil {
+				continue
+			}
+			// the URL as configured in the policy, not the pinned one, so
+			// the rules can be referred to by the source in the policy
+			// configuration
+			if err := rules.collect(a, sourceUrl); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return rules, nil
 }
 
 // computeSuccesses generates success results, these are not provided in the
 // Conftest results, so we reconstruct these from the parsed rules, any rule
-// that hasn't been touched by adding metadata must have succeeded
-func (c conftestEvaluator) computeSuccesses(result Outcome, rules policyRules, target string) []Result {
+// that hasn't been touched by adding metadata must have succeeded. The rules
+// excluded by the policy configuration are recorded in excluded.
+func (c conftestEvaluator) computeSuccesses(result Outcome, rules policyRules, target string, excluded map[ruleID]bool) []Result {
 	// what rules, by code, have we seen in the Conftest results, use map to
 	// take advantage of hashing for quicker lookup
 	seenRules := map[string]bool{}
@@ -576,13 +875,14 @@ func (c conftestEvaluator) computeSuccesses(result Outcome, rules policyRules, t
 
 	// any rule left DID NOT get metadata added so it's a success
 	// this depends on the delete in addMetadata
-	for code, rule := range rules {
-		if _, ok := seenRules[code]; ok {
+	for key, rule := range rules {
+		// Ignore any successes that are not meant for the package this CheckResult represents
+		if key.pkg != result.Namespace {
 			continue
 		}
 
-		// Ignore any successes that are not meant for the package this CheckResult represents
-		if rule.Package != result.Namespace {
+		code := key.code
+		if _, ok := seenRules[code]; ok {
 			continue
 		}
 
@@ -609,8 +909,13 @@ func (c conftestEvaluator) computeSuccesses(result Outcome, rules policyRules, t
 			success.Metadata[metadataDependsOn] = rule.DependsOn
 		}
 
+		if rule.Source != "" {
+			success.Metadata[metadataSource] = rule.Source
+		}
+
 		if !c.isResultIncluded(success, target) {
 			log.Debugf("Skipping result success: %#v", success)
+			excluded[ruleID{source: rule.Source, code: code}] = true
 			continue
 		}
 
@@ -627,10 +932,12 @@ func (c conftestEvaluator) computeSuccesses(result Outcome, rules policyRules, t
 	return successes
 }
 
-func addRuleMetadata(ctx context.Context, result *Result, rules policyRules) {
+// addRuleMetadata adds the information about the rule, defined in the given
+// package, that produced the result
+func addRuleMetadata(ctx context.Context, result *Result, rules policyRules, pkg string) {
 	code, ok := (*result).Metadata[metadataCode].(string)
 	if ok {
-		addMetadataToResults(ctx, result, rules[code])
+		addMetadataToResults(ctx, result, rules[ruleKey{pkg: pkg, code: code}])
 	}
 }
 
@@ -676,12 +983,18 @@ func addMetadataToResults(ctx context.Context, r *Result, rule rule.Info) {
 	if rule.Solution != "" {
 		r.Metadata[metadataSolution] = rule.Solution
 	}
+	if rule.DocumentationUrl != "" {
+		r.DocumentationUrl = rule.DocumentationUrl
+	}
 	if len(rule.Collections) > 0 {
 		r.Metadata[metadataCollections] = rule.Collections
 	}
 	if len(rule.DependsOn) > 0 {
 		r.Metadata[metadataDependsOn] = rule.DependsOn
 	}
+	if rule.Source != "" {
+		r.Metadata[metadataSource] = rule.Source
+	}
 
 	// If the rule has been effective for a long time, we'll consider
 	// the effective_on date not relevant and not bother including it
@@ -720,7 +1033,8 @@ func createConfigJSON(ctx context.Context, dataDir string, p ConfigProvider) err
 	// Now that the future deny logic is handled in the ec-cli and not in rego,
 	// this field is used only for the checking the effective times in the
 	// acceptable bundles list. Always set it, even when we are using the current
-	// time, so that a consistent current time is used everywhere.
+	// time, so that a consistent current time is used everywhere. Evaluations
+	// replace it with their own effective time.
 	pc.WhenNs = p.EffectiveTime().UnixNano()
 
 	opts, err := p.SigstoreOpts()
@@ -871,14 +1185,19 @@ func scoreMatches(needles, haystack []string) int {
 //  3. Add 10 if the name specifies a package name, e.g. "pkg", "pkg.", "pkg.*", or "pkg.rule"
 //  4. Add 100 if a term is used, e.g. "*:term", "pkg:term" or "pkg.rule:term"
 //  5. Add 100 if a rule is used, e.g. "pkg.rule", "pkg.rule:term"
+//  6. Add 1 if the name is qualified by a policy source, e.g. "source#pkg.rule"
 //
 // The score is cumulative. If a name is covered by multiple items in the guidelines, they
 // are added together. For example, "pkg.rule:term" scores at 210.
 func score(name string) int {
+	var value int
+	if _, qualified, ok := strings.Cut(name, sourceSeparator); ok {
+		value += 1
+		name = qualified
+	}
 	if strings.HasPrefix(name, "@") {
-		return 10
+		return value + 10
 	}
-	var value int
 	shortName, term, _ := strings.Cut(name, ":")
 	if term != "" {
 		value += 100
@@ -929,6 +1248,17 @@ func makeMatchers(result Result) []string {
 
 	matchers = append(matchers, extractCollections(result)...)
 
+	// Any of the matchers above can be qualified by the policy source the
+	// rule was loaded from, to tell apart the rules with the same code from
+	// different policy sources.
+	if source := ExtractStringFromMetadata(result, metadataSource); source != "" {
+		qualified := make([]string, 0, len(matchers))
+		for _, matcher := range matchers {
+			qualified = append(qualified, source+sourceSeparator+matcher)
+		}
+		matchers = append(matchers, qualified...)
+	}
+
 	return matchers
 }
 
@@ -23,20 +23,29 @@ import (
 	"context"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	"github.com/MakeNowJust/heredoc"
+	ociMetadata "github.com/conforma/go-gather/gather/oci"
+	"github.com/conforma/go-gather/metadata"
 	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
 	"github.com/gkampitakis/go-snaps/snaps"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/open-policy-agent/conftest/runner"
 	"github.com/open-policy-agent/opa/ast"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
@@ -291,7 +300,7 @@ func TestConftestEvaluatorEvaluateSeverity(t *testing.T) {
 
 	ctx := setupTestContext(&r, &dl)
 
-	r.On("Run", ctx, inputs.Inputs).Return(results, expectedData, nil)
+	r.On("Run", mock.Anything, inputs.Inputs).Return(results, expectedData, nil)
 
 	pol, err := policy.NewOfflinePolicy(ctx, policy.Now)
 	assert.NoError(t, err)
@@ -402,7 +411,7 @@ func TestConftestEvaluatorEvaluateNoSuccessWarningsOrFailures(t *testing.T) {
 			inputs := EvaluationTarget{Inputs: []string{"inputs"}}
 			ctx := setupTestContext(&r, &dl)
 
-			r.On("Run", ctx, inputs.Inputs).Return(tt.results, Data(nil), nil)
+			r.On("Run", mock.Anything, inputs.Inputs).Return(tt.results, Data(nil), nil)
 
 			p, err := policy.NewOfflinePolicy(ctx, policy.Now)
 			assert.NoError(t, err)
@@ -419,6 +428,341 @@ func TestConftestEvaluatorEvaluateNoSuccessWarningsOrFailures(t *testing.T) {
 	}
 }
 
+type countingPolicySource struct {
+	testPolicySource
+	count *atomic.Int32
+}
+
+func (c countingPolicySource) GetPolicy(ctx context.Context, dest string, showMsg bool) (string, error) {
+	c.count.Add(1)
+	return c.testPolicySource.GetPolicy(ctx, dest, showMsg)
+}
+
+func TestConftestEvaluatorPreparesOnce(t *testing.T) {
+	r := mockTestRunner{}
+	dl := mockDownloader{}
+	inputs := EvaluationTarget{Inputs: []string{"inputs"}}
+	ctx := setupTestContext(&r, &dl)
+
+	r.On("Run", mock.Anything, inputs.Inputs).Return([]Outcome{
+		{
+			Failures: []Result{{Message: "failure", Metadata: map[string]any{"code": "main.failure"}}},
+		},
+	}, Data(nil), nil)
+
+	p, err := policy.NewOfflinePolicy(ctx, policy.Now)
+	require.NoError(t, err)
+
+	src := countingPolicySource{count: &atomic.Int32{}}
+	evaluator, err := NewConftestEvaluator(ctx, []source.PolicySource{src}, p, ecc.Source{})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results, err := evaluator.Evaluate(ctx, inputs)
+			assert.NoError(t, err)
+			assert.Len(t, results, 1)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), src.count.Load())
+	r.AssertNumberOfCalls(t, "Run", 10)
+}
+
+type failingPolicySource struct {
+	testPolicySource
+}
+
+func (failingPolicySource) GetPolicy(ctx context.Context, dest string, showMsg bool) (string, error) {
+	return "", errors.New("connection refused")
+}
+
+func TestConftestEvaluatorSourceError(t *testing.T) {
+	r := mockTestRunner{}
+	dl := mockDownloader{}
+	inputs := EvaluationTarget{Inputs: []string{"inputs"}}
+	ctx := setupTestContext(&r, &dl)
+
+	p, err := policy.NewOfflinePolicy(ctx, policy.Now)
+	require.NoError(t, err)
+
+	evaluator, err := NewConftestEvaluator(ctx, []source.PolicySource{failingPolicySource{}}, p, ecc.Source{Name: "flaky"})
+	require.NoError(t, err)
+
+	_, err = evaluator.Evaluate(ctx, inputs)
+	assert.EqualError(t, err, "connection refused")
+
+	results, err := evaluator.Evaluate(source.WithFetchErrorsReported(ctx), inputs)
+	require.NoError(t, err)
+	assert.Equal(t, []Outcome{
+		{
+			Failures: []Result{
+				{
+					Message: `Unable to fetch the policy source "test-url" of the source group "flaky": connection refused`,
+					Metadata: map[string]any{
+						"code":         "builtin.policy.source_accessible",
+						"title":        "Policy source is accessible",
+						"description":  "The policy source can be fetched so the policy rules and data within it are evaluated.",
+						"source":       "test-url",
+						"source_group": "flaky",
+					},
+				},
+			},
+		},
+	}, results)
+
+	r.AssertNotCalled(t, "Run", mock.Anything, mock.Anything)
+}
+
+type unsignedPolicySource struct {
+	testPolicySource
+}
+
+func (unsignedPolicySource) GetPolicy(ctx context.Context, dest string, showMsg bool) (string, error) {
+	return "", source.SignatureError{Url: "test-url", Err: errors.New("no matching signatures")}
+}
+
+func TestConftestEvaluatorSourceSignatureError(t *testing.T) {
+	r := mockTestRunner{}
+	dl := mockDownloader{}
+	inputs := EvaluationTarget{Inputs: []string{"inputs"}}
+	ctx := setupTestContext(&r, &dl)
+
+	p, err := policy.NewOfflinePolicy(ctx, policy.Now)
+	require.NoError(t, err)
+
+	evaluator, err := NewConftestEvaluator(ctx, []source.PolicySource{unsignedPolicySource{}}, p, ecc.Source{Name: "signed"})
+	require.NoError(t, err)
+
+	_, err = evaluator.Evaluate(ctx, inputs)
+	assert.EqualError(t, err, `unable to verify the signature of the policy source "test-url": no matching signatures`)
+
+	results, err := evaluator.Evaluate(source.WithFetchErrorsReported(ctx), inputs)
+	require.NoError(t, err)
+	assert.Equal(t, []Outcome{
+		{
+			Failures: []Result{
+				{
+					Message: `Unable to verify the signature of the policy source "test-url" of the source group "signed": no matching signatures`,
+					Metadata: map[string]any{
+						"code":         "builtin.policy.source_signature",
+						"title":        "Policy source signature is verified",
+						"description":  "The OCI bundle of the policy source is signed as required by its source group, so the policy rules and data within it can be trusted.",
+						"source":       "test-url",
+						"source_group": "signed",
+					},
+				},
+			},
+		},
+	}, results)
+
+	r.AssertNotCalled(t, "Run", mock.Anything, mock.Anything)
+}
+
+type ociDownloader struct{}
+
+func (ociDownloader) Download(_ context.Context, _ string, _ string, _ bool) (metadata.Metadata, error) {
+	return &ociMetadata.OCIMetadata{Digest: "sha256:4e388ab32b10dc8dbc7e28144f552830adc74787c1e2c0824032078a79f227fb"}, nil
+}
+
+type acceptingVerifier struct{}
+
+func (acceptingVerifier) VerifyBundle(context.Context, name.Digest) error {
+	return nil
+}
+
+func TestConftestEvaluatorSignatureSuccesses(t *testing.T) {
+	ctx := context.WithValue(utils.WithFS(context.Background(), afero.NewMemMapFs()), source.DownloaderFuncKey, ociDownloader{})
+
+	signed := &source.PolicyUrl{Url: "oci::registry.io/signed-policy:latest", Kind: source.PolicyKind, Verifier: acceptingVerifier{}}
+	unsigned := &source.PolicyUrl{Url: "oci::registry.io/unsigned-policy:latest", Kind: source.PolicyKind}
+	for _, s := range []source.PolicySource{signed, unsigned} {
+		_, err := s.GetPolicy(ctx, "/tmp/ec-work-signature", false)
+		require.NoError(t, err)
+	}
+
+	c := conftestEvaluator{policySources: []source.PolicySource{signed, unsigned}, sourceGroup: "signed"}
+	assert.Equal(t, []Result{
+		{
+			Message: `The signature of the policy source "oci::registry.io/signed-policy:latest@sha256:4e388ab32b10dc8dbc7e28144f552830adc74787c1e2c0824032078a79f227fb" of the source group "signed" is verified`,
+			Metadata: map[string]any{
+				"code":         "builtin.policy.source_signature",
+				"title":        "Policy source signature is verified",
+				"description":  "The OCI bundle of the policy source is signed as required by its source group, so the policy rules and data within it can be trusted.",
+				"source":       "oci::registry.io/signed-policy:latest@sha256:4e388ab32b10dc8dbc7e28144f552830adc74787c1e2c0824032078a79f227fb",
+				"source_group": "signed",
+			},
+		},
+	}, c.signatureSuccesses())
+}
+
+// failingOncePolicySource fails to fetch the policy on the first attempt
+type failingOncePolicySource struct {
+	testPolicySource
+	count *atomic.Int32
+}
+
+func (f failingOncePolicySource) GetPolicy(ctx context.Context, dest string, showMsg bool) (string, error) {
+	if f.count.Add(1) == 1 {
+		return "", errors.New("temporarily unavailable")
+	}
+	return f.testPolicySource.GetPolicy(ctx, dest, showMsg)
+}
+
+func TestConftestEvaluatorPrepareRetriesAfterFailure(t *testing.T) {
+	r := mockTestRunner{}
+	dl := mockDownloader{}
+	inputs := EvaluationTarget{Inputs: []string{"inputs"}}
+	ctx := setupTestContext(&r, &dl)
+
+	r.On("Run", mock.Anything, inputs.Inputs).Return([]Outcome{
+		{
+			Failures: []Result{{Message: "failure", Metadata: map[string]any{"code": "main.failure"}}},
+		},
+	}, Data(nil), nil)
+
+	p, err := policy.NewOfflinePolicy(ctx, policy.Now)
+	require.NoError(t, err)
+
+	src := failingOncePolicySource{count: &atomic.Int32{}}
+	evaluator, err := NewConftestEvaluator(ctx, []source.PolicySource{src}, p, ecc.Source{})
+	require.NoError(t, err)
+
+	_, err = evaluator.Evaluate(ctx, inputs)
+	assert.ErrorContains(t, err, "temporarily unavailable")
+
+	results, err := evaluator.Evaluate(ctx, inputs)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, int32(2), src.count.Load())
+}
+
+func TestConftestRunnerFileInfo(t *testing.T) {
+	policyDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(policyDir, "file.rego"), []byte(heredoc.Doc(`
+		package main
+
+		import rego.v1
+
+		deny contains msg if {
+			msg := sprintf("%s in %s, %s", [data.conftest.file.name, data.conftest.file.dir, data.config.value])
+		}`)), 0600))
+
+	dataDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "data.json"), []byte(`{"config": {"value": "data"}}`), 0600))
+
+	inputDir := t.TempDir()
+	var inputs []string
+	for i := 0; i < 20; i++ {
+		input := filepath.Join(inputDir, fmt.Sprintf("input-%d.json", i))
+		require.NoError(t, os.WriteFile(input, []byte("{}"), 0600))
+		inputs = append(inputs, input)
+	}
+
+	r := conftestRunner{
+		TestRunner: runner.TestRunner{
+			Policy:        []string{policyDir},
+			Data:          []string{dataDir},
+			AllNamespaces: true,
+		},
+	}
+
+	// the file information must be the one of the input checked even when the
+	// runs are concurrent
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for _, input := range inputs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			outcomes, err := r.Run(ctx, []string{input})
+			require.NoError(t, err)
+			require.Len(t, outcomes, 1)
+			assert.Equal(t, []Result{
+				{Message: fmt.Sprintf("%s in %s, data", filepath.Base(input), inputDir)},
+			}, outcomes[0].Failures)
+		}()
+	}
+	wg.Wait()
+
+	// the queries of the deny rule and its exceptions are prepared once for all
+	// runs
+	require.NotNil(t, r.prepared)
+	assert.Len(t, r.prepared.prepared, 2)
+}
+
+// movingTimeConfigProvider is a ConfigProvider with the effective time set by
+// the test, as is the case with the current time
+type movingTimeConfigProvider struct {
+	*mockConfigProvider
+	now time.Time
+}
+
+func (p *movingTimeConfigProvider) EffectiveTime() time.Time {
+	return p.now
+}
+
+func TestConftestEvaluatorWhenNs(t *testing.T) {
+	rules, err := rulesArchive(t, fstest.MapFS{
+		"when.rego": &fstest.MapFile{Data: []byte(heredoc.Doc(`
+			package when
+
+			import rego.v1
+
+			# METADATA
+			# title: When
+			# custom:
+			#   short_name: when
+			deny contains result if {
+				result := {"code": "when.when", "msg": sprintf("%d", [data.config.policy.when_ns])}
+			}`))},
+	})
+	require.NoError(t, err)
+
+	input := path.Join(t.TempDir(), "input.json")
+	require.NoError(t, os.WriteFile(input, []byte("{}"), 0600))
+
+	ctx := withCapabilities(context.Background(), testCapabilities)
+	mockConfig := &mockConfigProvider{}
+	mockConfig.On("SigstoreOpts").Return(policy.SigstoreOpts{}, nil)
+	mockConfig.On("Spec").Return(ecc.EnterpriseContractPolicySpec{})
+
+	evaluators := map[string]func(context.Context, []source.PolicySource, ConfigProvider, ecc.Source) (Evaluator, error){
+		"conftest": NewConftestEvaluator,
+		"opa":      NewOPAEvaluator,
+	}
+
+	for name, newEvaluator := range evaluators {
+		config := &movingTimeConfigProvider{mockConfig, time.Unix(0, 1)}
+
+		evaluator, err := newEvaluator(ctx, []source.PolicySource{
+			&source.PolicyUrl{Url: rules, Kind: source.PolicyKind},
+		}, config, ecc.Source{})
+		require.NoError(t, err)
+		// the downloaded sources are shared between evaluators, so all are
+		// destroyed only at the end
+		t.Cleanup(evaluator.Destroy)
+
+		t.Run(name, func(t *testing.T) {
+
+			// the same evaluator, with its prepared queries, provides the
+			// effective time of each evaluation to the policies
+			for _, now := range []time.Time{time.Unix(0, 2), time.Unix(0, 3)} {
+				config.now = now
+				results, err := evaluator.Evaluate(ctx, EvaluationTarget{Inputs: []string{input}})
+				require.NoError(t, err)
+				require.Len(t, results, 1)
+				require.Len(t, results[0].Failures, 1)
+				assert.Equal(t, fmt.Sprint(now.UnixNano()), results[0].Failures[0].Message)
+			}
+		})
+	}
+}
+
 func TestConftestEvaluatorIncludeExclude(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -1245,6 +1589,36 @@ func TestConftestEvaluatorIncludeExclude(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "skip rules depending on excluded rules",
+			results: []Outcome{
+				{
+					Failures: []Result{
+						{Metadata: map[string]any{"code": "breakfast.spam"}},
+						{Metadata: map[string]any{"code": "lunch.spam", "depends_on": []string{"breakfast.spam"}}},
+					},
+					Warnings: []Result{
+						{Metadata: map[string]any{"code": "lunch.ham"}},
+					},
+				},
+			},
+			config: &ecc.EnterpriseContractPolicyConfiguration{Exclude: []string{"breakfast.spam"}},
+			want: []Outcome{
+				{
+					Failures: []Result{},
+					Warnings: []Result{
+						{Metadata: map[string]any{"code": "lunch.ham"}},
+					},
+					Skipped: []Result{
+						{
+							Message:  "Skipped because it depends on breakfast.spam which was excluded",
+							Metadata: map[string]any{"code": "lunch.spam", "depends_on": []string{"breakfast.spam"}},
+						},
+					},
+					Exceptions: []Result{},
+				},
+			},
+		},
 		{
 			name: "ignore unexpected code type",
 			results: []Outcome{
@@ -1289,7 +1663,7 @@ func TestConftestEvaluatorIncludeExclude(t *testing.T) {
 			dl := mockDownloader{}
 			inputs := EvaluationTarget{Inputs: []string{"inputs"}}
 			ctx := setupTestContext(&r, &dl)
-			r.On("Run", ctx, inputs.Inputs).Return(tt.results, Data(nil), nil)
+			r.On("Run", mock.Anything, inputs.Inputs).Return(tt.results, Data(nil), nil)
 
 			p, err := policy.NewOfflinePolicy(ctx, policy.Now)
 			assert.NoError(t, err)
@@ -1312,10 +1686,11 @@ func TestConftestEvaluatorIncludeExclude(t *testing.T) {
 
 func TestMakeMatchers(t *testing.T) {
 	cases := []struct {
-		name string
-		code string
-		term any
-		want []string
+		name   string
+		code   string
+		term   any
+		source string
+		want   []string
 	}{
 		{
 			name: "valid", code: "breakfast.spam", term: "eggs",
@@ -1345,6 +1720,14 @@ func TestMakeMatchers(t *testing.T) {
 		},
 		{name: "empty code", code: "", want: []string{"*"}},
 		{name: "empty code with term", code: "", term: "eggs", want: []string{"*"}},
+		{
+			name: "qualified by source", code: "breakfast.spam", source: "git::example.com/policy",
+			want: []string{
+				"breakfast", "breakfast.*", "breakfast.spam", "*",
+				"git::example.com/policy#breakfast", "git::example.com/policy#breakfast.*",
+				"git::example.com/policy#breakfast.spam", "git::example.com/policy#*",
+			},
+		},
 	}
 
 	for _, tt := range cases {
@@ -1356,6 +1739,9 @@ func TestMakeMatchers(t *testing.T) {
 			if tt.term != "" {
 				result.Metadata["term"] = tt.term
 			}
+			if tt.source != "" {
+				result.Metadata["source"] = tt.source
+			}
 			assert.Equal(t, tt.want, makeMatchers(result))
 		})
 	}
@@ -1379,10 +1765,10 @@ func TestCollectAnnotationData(t *testing.T) {
 	})
 
 	rules := policyRules{}
-	require.NoError(t, rules.collect(ast.NewAnnotationsRef(module.Annotations[0])))
+	require.NoError(t, rules.collect(ast.NewAnnotationsRef(module.Annotations[0]), "git::example.com/policy"))
 
 	assert.Equal(t, policyRules{
-		"a.b.c.short": {
+		{pkg: "a.b.c", code: "a.b.c.short"}: {
 			Code:        "a.b.c.short",
 			CodePackage: "a.b.c",
 			Collections: []string{"A", "B", "C"},
@@ -1392,11 +1778,77 @@ func TestCollectAnnotationData(t *testing.T) {
 			Kind:        rule.Deny,
 			Package:     "a.b.c",
 			ShortName:   "short",
+			Source:      "git::example.com/policy",
 			Title:       "Title",
 		},
 	}, rules)
 }
 
+func TestCollectAnnotationDataConflicts(t *testing.T) {
+	annotations := func(pkg string) *ast.AnnotationsRef {
+		module := ast.MustParseModuleWithOpts(heredoc.Docf(`
+			package %s
+			import rego.v1
+
+			# METADATA
+			# custom:
+			#   short_name: short
+			deny contains "hi" if {
+				true
+			}`, pkg), ast.ParserOptions{
+			ProcessAnnotation: true,
+		})
+
+		return ast.NewAnnotationsRef(module.Annotations[0])
+	}
+
+	cases := []struct {
+		name     string
+		pkg      string
+		source   string
+		expected string
+	}{
+		{
+			name:     "same package and source",
+			pkg:      "policy.release.a",
+			source:   "source-1",
+			expected: "found a second rule with the same code: `a.short`",
+		},
+		{
+			name:     "same package from different source",
+			pkg:      "policy.release.a",
+			source:   "source-2",
+			expected: "found a second rule with the same code: `a.short`, defined in package `policy.release.a` from both source \"source-1\" and source \"source-2\"",
+		},
+		{
+			name:   "different package",
+			pkg:    "policy.pipeline.a",
+			source: "source-1",
+		},
+		{
+			name:   "different package from different source",
+			pkg:    "policy.pipeline.a",
+			source: "source-2",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rules := policyRules{}
+			require.NoError(t, rules.collect(annotations("policy.release.a"), "source-1"))
+
+			err := rules.collect(annotations(c.pkg), c.source)
+			if c.expected != "" {
+				assert.EqualError(t, err, c.expected)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, rules, 2)
+			assert.Equal(t, c.source, rules[ruleKey{pkg: c.pkg, code: "a.short"}].Source)
+		})
+	}
+}
+
 func TestRuleMetadata(t *testing.T) {
 	effectiveOnTest := time.Now().Format(effectiveOnFormat)
 
@@ -1405,23 +1857,31 @@ func TestRuleMetadata(t *testing.T) {
 	ctx = context.WithValue(ctx, effectiveTimeKey, effectiveTimeTest)
 
 	rules := policyRules{
-		"warning1": rule.Info{
+		{code: "warning1"}: {
 			Title: "Warning1",
 		},
-		"failure2": rule.Info{
+		{code: "failure2"}: {
 			Title:       "Failure2",
 			Description: "Failure 2 description",
 		},
-		"warning2": rule.Info{
+		{code: "warning2"}: {
 			Title:       "Warning2",
 			Description: "Warning 2 description",
 			EffectiveOn: "2022-01-01T00:00:00Z",
 		},
-		"warning3": rule.Info{
+		{code: "warning3"}: {
 			Title:       "Warning3",
 			Description: "Warning 3 description",
 			EffectiveOn: effectiveOnTest,
 		},
+		{code: "sourced"}: {
+			Title:  "Sourced",
+			Source: "git::example.com/policy",
+		},
+		{code: "documented"}: {
+			Title:            "Documented",
+			DocumentationUrl: "https://example.com/docs#documented",
+		},
 	}
 	cases := []struct {
 		name   string
@@ -1429,6 +1889,38 @@ func TestRuleMetadata(t *testing.T) {
 		rules  policyRules
 		want   Result
 	}{
+		{
+			name: "record source",
+			result: Result{
+				Metadata: map[string]any{
+					"code": "sourced",
+				},
+			},
+			rules: rules,
+			want: Result{
+				Metadata: map[string]any{
+					"code":   "sourced",
+					"source": "git::example.com/policy",
+					"title":  "Sourced",
+				},
+			},
+		},
+		{
+			name: "record documentation url",
+			result: Result{
+				Metadata: map[string]any{
+					"code": "documented",
+				},
+			},
+			rules: rules,
+			want: Result{
+				Metadata: map[string]any{
+					"code":  "documented",
+					"title": "Documented",
+				},
+				DocumentationUrl: "https://example.com/docs#documented",
+			},
+		},
 		{
 			name: "update title",
 			result: Result{
@@ -1518,8 +2010,8 @@ func TestRuleMetadata(t *testing.T) {
 	}
 	for i, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
-			addRuleMetadata(ctx, &cases[i].result, tt.rules)
-			assert.Equal(t, tt.result, tt.want)
+			addRuleMetadata(ctx, &cases[i].result, tt.rules, "")
+			assert.Equal(t, tt.want, cases[i].result)
 		})
 	}
 }
@@ -1573,6 +2065,14 @@ func TestNameScoring(t *testing.T) {
 			name:  "pkg.rule:term",
 			score: 210,
 		},
+		{
+			name:  "git::example.com/policy#pkg.rule",
+			score: 111,
+		},
+		{
+			name:  "git::example.com/policy#@collection",
+			score: 11,
+		},
 	}
 
 	for _, c := range cases {
@@ -1586,6 +2086,7 @@ func TestCheckResultsTrim(t *testing.T) {
 	cases := []struct {
 		name     string
 		given    []Outcome
+		excluded map[ruleID]bool
 		expected []Outcome
 	}{
 		{
@@ -1622,6 +2123,15 @@ func TestCheckResultsTrim(t *testing.T) {
 						},
 					},
 					Successes: []Result{},
+					Skipped: []Result{
+						{
+							Message: "Skipped because it depends on a.failure1 which failed",
+							Metadata: map[string]interface{}{
+								metadataCode:      "a.success1",
+								metadataDependsOn: []string{"a.failure1"},
+							},
+						},
+					},
 				},
 			},
 		},
@@ -1725,6 +2235,193 @@ func TestCheckResultsTrim(t *testing.T) {
 					},
 					Warnings:  []Result{},
 					Successes: []Result{},
+					Skipped: []Result{
+						{
+							Message: "Skipped because it depends on a.failure which failed",
+							Metadata: map[string]interface{}{
+								metadataCode:      "a.failure",
+								metadataDependsOn: []string{"a.failure"},
+							},
+						},
+						{
+							Message: "Skipped because it depends on a.failure which failed",
+							Metadata: map[string]interface{}{
+								metadataCode:      "a.warning",
+								metadataDependsOn: []string{"a.failure"},
+							},
+						},
+						{
+							Message: "Skipped because it depends on a.failure which failed",
+							Metadata: map[string]interface{}{
+								metadataCode:      "a.success",
+								metadataDependsOn: []string{"a.failure"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "multiple results of a rule are skipped once",
+			given: []Outcome{
+				{
+					Failures: []Result{
+						{
+							Message: "Fails",
+							Metadata: map[string]interface{}{
+								metadataCode: "a.failure",
+							},
+						},
+						{
+							Message: "Fails and depends 1",
+							Metadata: map[string]interface{}{
+								metadataCode:      "a.dependant",
+								metadataTerm:      "1",
+								metadataDependsOn: []string{"a.failure"},
+							},
+						},
+						{
+							Message: "Fails and depends 2",
+							Metadata: map[string]interface{}{
+								metadataCode:      "a.dependant",
+								metadataTerm:      "2",
+								metadataDependsOn: []string{"a.failure"},
+							},
+						},
+					},
+				},
+			},
+			expected: []Outcome{
+				{
+					Failures: []Result{
+						{
+							Message: "Fails",
+							Metadata: map[string]interface{}{
+								metadataCode: "a.failure",
+							},
+						},
+					},
+					Skipped: []Result{
+						{
+							Message: "Skipped because it depends on a.failure which failed",
+							Metadata: map[string]interface{}{
+								metadataCode:      "a.dependant",
+								metadataTerm:      "1",
+								metadataDependsOn: []string{"a.failure"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "transitive dependencies",
+			given: []Outcome{
+				{
+					Failures: []Result{
+						{
+							Message: "Fails",
+							Metadata: map[string]interface{}{
+								metadataCode: "a.failure",
+							},
+						},
+					},
+				},
+				{
+					Successes: []Result{
+						{
+							Message: "pass",
+							Metadata: map[string]interface{}{
+								metadataCode:      "b.indirect",
+								metadataDependsOn: []string{"b.direct"},
+							},
+						},
+						{
+							Message: "pass",
+							Metadata: map[string]interface{}{
+								metadataCode:      "b.direct",
+								metadataDependsOn: []string{"a.failure"},
+							},
+						},
+					},
+				},
+			},
+			expected: []Outcome{
+				{
+					Failures: []Result{
+						{
+							Message: "Fails",
+							Metadata: map[string]interface{}{
+								metadataCode: "a.failure",
+							},
+						},
+					},
+				},
+				{
+					Successes: []Result{},
+					Skipped: []Result{
+						{
+							Message: "Skipped because it depends on a.failure which failed",
+							Metadata: map[string]interface{}{
+								metadataCode:      "b.direct",
+								metadataDependsOn: []string{"a.failure"},
+							},
+						},
+						{
+							Message: "Skipped because it depends on b.direct which was skipped",
+							Metadata: map[string]interface{}{
+								metadataCode:      "b.indirect",
+								metadataDependsOn: []string{"b.direct"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "excluded dependency",
+			given: []Outcome{
+				{
+					Failures: []Result{
+						{
+							Message: "Fails",
+							Metadata: map[string]interface{}{
+								metadataCode:      "a.failure",
+								metadataDependsOn: []string{"a.excluded", "a.partially_excluded"},
+							},
+						},
+					},
+					Successes: []Result{
+						{
+							Message: "pass",
+							Metadata: map[string]interface{}{
+								metadataCode: "a.partially_excluded",
+							},
+						},
+					},
+				},
+			},
+			excluded: map[ruleID]bool{{code: "a.excluded"}: true, {code: "a.partially_excluded"}: true},
+			expected: []Outcome{
+				{
+					Failures: []Result{},
+					Successes: []Result{
+						{
+							Message: "pass",
+							Metadata: map[string]interface{}{
+								metadataCode: "a.partially_excluded",
+							},
+						},
+					},
+					Skipped: []Result{
+						{
+							Message: "Skipped because it depends on a.excluded which was excluded",
+							Metadata: map[string]interface{}{
+								metadataCode:      "a.failure",
+								metadataDependsOn: []string{"a.excluded", "a.partially_excluded"},
+							},
+						},
+					},
 				},
 			},
 		},
@@ -1777,7 +2474,7 @@ func TestCheckResultsTrim(t *testing.T) {
 
 	for i, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			trim(&cases[i].given)
+			trim(&cases[i].given, c.excluded)
 			assert.Equal(t, c.expected, c.given)
 		})
 	}
@@ -1837,11 +2534,136 @@ func TestConftestEvaluatorEvaluate(t *testing.T) {
 		sort.Slice(results[i].Successes, func(l, r int) bool {
 			return strings.Compare(results[i].Successes[l].Metadata[metadataCode].(string), results[i].Successes[r].Metadata[metadataCode].(string)) < 0
 		})
+		// nor on different locations of the rules archive
+		for _, rs := range [][]Result{results[i].Successes, results[i].Skipped, results[i].Warnings, results[i].Failures, results[i].Exceptions} {
+			for _, r := range rs {
+				if source, ok := r.Metadata[metadataSource].(string); ok {
+					r.Metadata[metadataSource] = strings.Replace(source, rules, "$RULES", 1)
+				}
+			}
+		}
 	}
 
 	snaps.MatchSnapshot(t, results)
 }
 
+func TestConftestEvaluatorSameCodeFromDifferentSources(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(path.Join(dir, "inputs"), 0755))
+	require.NoError(t, os.WriteFile(path.Join(dir, "inputs", "data.json"), []byte("{}"), 0600))
+
+	// both sources define the a.check and a.dependent rules, a.check fails
+	// only in the release source
+	archive := func(pkg string, fails bool) string {
+		rules, err := rulesArchive(t, fstest.MapFS{
+			"a.rego": &fstest.MapFile{Data: []byte(heredoc.Docf(`
+				package %s
+
+				import rego.v1
+
+				# METADATA
+				# custom:
+				#   short_name: check
+				deny contains result if {
+					%t
+					result := {"code": "a.check", "msg": "Check failed"}
+				}
+
+				# METADATA
+				# custom:
+				#   short_name: dependent
+				#   depends_on: a.check
+				deny contains result if {
+					result := {"code": "a.dependent", "msg": "Dependent failed"}
+				}`, pkg, fails))},
+		})
+		require.NoError(t, err)
+		return rules
+	}
+	release := archive("policy.release.a", true)
+	pipeline := archive("policy.pipeline.a", false)
+
+	// summarize returns the results, by namespace, as code and message
+	summarize := func(outcomes []Outcome) map[string][]string {
+		summary := map[string][]string{}
+		for _, o := range outcomes {
+			for _, r := range slices.Concat(o.Failures, o.Warnings, o.Skipped, o.Successes) {
+				summary[o.Namespace] = append(summary[o.Namespace], fmt.Sprintf("%s: %s", r.Metadata[metadataCode], r.Message))
+			}
+			slices.Sort(summary[o.Namespace])
+		}
+		return summary
+	}
+
+	cases := []struct {
+		name     string
+		exclude  []string
+		expected map[string][]string
+	}{
+		{
+			name: "dependencies within the same source",
+			expected: map[string][]string{
+				"policy.release.a": {
+					"a.check: Check failed",
+					"a.dependent: Skipped because it depends on a.check which failed",
+				},
+				"policy.pipeline.a": {
+					"a.check: Pass",
+					"a.dependent: Dependent failed",
+				},
+			},
+		},
+		{
+			name:    "exclude by source",
+			exclude: []string{pipeline + "#a.check"},
+			expected: map[string][]string{
+				"policy.release.a": {
+					"a.check: Check failed",
+					"a.dependent: Skipped because it depends on a.check which failed",
+				},
+				"policy.pipeline.a": {
+					"a.dependent: Skipped because it depends on a.check which was excluded",
+				},
+			},
+		},
+		{
+			name:    "exclude by code",
+			exclude: []string{"a.dependent"},
+			expected: map[string][]string{
+				"policy.release.a": {
+					"a.check: Check failed",
+				},
+				"policy.pipeline.a": {
+					"a.check: Pass",
+				},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := withCapabilities(context.Background(), testCapabilities)
+
+			config := &mockConfigProvider{}
+			config.On("EffectiveTime").Return(time.Now())
+			config.On("SigstoreOpts").Return(policy.SigstoreOpts{}, nil)
+			config.On("Spec").Return(ecc.EnterpriseContractPolicySpec{})
+
+			evaluator, err := NewConftestEvaluator(ctx, []source.PolicySource{
+				&source.PolicyUrl{Url: release, Kind: source.PolicyKind},
+				&source.PolicyUrl{Url: pipeline, Kind: source.PolicyKind},
+			}, config, ecc.Source{
+				Config: &ecc.SourceConfig{Exclude: c.exclude},
+			})
+			require.NoError(t, err)
+
+			results, err := evaluator.Evaluate(ctx, EvaluationTarget{Inputs: []string{path.Join(dir, "inputs")}})
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, summarize(results))
+		})
+	}
+}
+
 type mockConfigProvider struct {
 	mock.Mock
 }
@@ -2172,7 +2994,7 @@ func init() {
 	testCapabilities = data
 }
 
-func rulesArchive(t *testing.T, files fs.FS) (string, error) {
+func rulesArchive(t testing.TB, files fs.FS) (string, error) {
 	t.Helper()
 
 	dir := t.TempDir()
@@ -71,6 +71,28 @@ func (c *Criteria) get(key string) []string {
 	return c.defaultItems
 }
 
+// matches returns the items applicable to the key that are equal to any of the
+// matchers, along with their score
+func (c *Criteria) matches(key string, matchers []string) []CriteriaMatch {
+	var matches []CriteriaMatch
+	add := func(digest string, items []string) {
+		for _, item := range items {
+			for _, matcher := range matchers {
+				if item == matcher {
+					matches = append(matches, CriteriaMatch{Value: item, Score: score(item), ImageDigest: digest})
+				}
+			}
+		}
+	}
+
+	if key != "" {
+		add(key, c.digestItems[key])
+	}
+	add("", c.defaultItems)
+
+	return matches
+}
+
 func computeIncludeExclude(src ecc.Source, p ConfigProvider) (*Criteria, *Criteria) {
 	include := &Criteria{}
 	exclude := &Criteria{}
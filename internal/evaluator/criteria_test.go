@@ -223,3 +223,25 @@ func TestGet(t *testing.T) {
 	assert.ElementsMatch(t, expectedDefaultItems, c.get("key2"))
 
 }
+
+func TestMatches(t *testing.T) {
+	c := &Criteria{
+		digestItems: map[string][]string{
+			"key1": {"pkg.rule", "other"},
+		},
+		defaultItems: []string{"*", "pkg", "@collection"},
+	}
+
+	matchers := []string{"pkg", "pkg.*", "pkg.rule", "*"}
+
+	assert.Equal(t, []CriteriaMatch{
+		{Value: "pkg.rule", Score: 110, ImageDigest: "key1"},
+		{Value: "*", Score: 1},
+		{Value: "pkg", Score: 10},
+	}, c.matches("key1", matchers))
+
+	assert.Equal(t, []CriteriaMatch{
+		{Value: "*", Score: 1},
+		{Value: "pkg", Score: 10},
+	}, c.matches("key2", matchers))
+}
@@ -48,7 +48,12 @@ type Outcome struct {
 }
 
 type Result struct {
-	Message  string                 `json:"msg"`
-	Metadata map[string]interface{} `json:"metadata,omitempty"`
-	Outputs  []string               `json:"outputs,omitempty"`
+	Message     string                 `json:"msg"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	Outputs     []string               `json:"outputs,omitempty"`
+	Explanation *Explanation           `json:"explanation,omitempty"`
+	// DocumentationUrl is the URL of the documentation of the rule that
+	// produced the result, it is not part of the serialized result and is only
+	// used by the report formats that link to the rule documentation
+	DocumentationUrl string `json:"-"`
 }
@@ -0,0 +1,483 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package evaluator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/conftest/parser"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/topdown"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/enterprise-contract/ec-cli/internal/opa/rule"
+)
+
+const explainKey contextKey = "ec.evaluator.explain"
+
+// Explanation describes how the rule that produced a result was evaluated
+type Explanation struct {
+	Expressions []ExpressionExplanation `json:"expressions"`
+}
+
+// ExpressionExplanation holds the outcome of evaluating a single expression of
+// a rule along with the values from the input referenced by the expression
+type ExpressionExplanation struct {
+	Location   string         `json:"location"`
+	Expression string         `json:"expression"`
+	Result     bool           `json:"result"`
+	Input      map[string]any `json:"input,omitempty"`
+}
+
+// String returns a compact, single line representation of the expression
+func (e ExpressionExplanation) String() string {
+	indicator := "✕"
+	if e.Result {
+		indicator = "✓"
+	}
+
+	s := fmt.Sprintf("%s %s: %s", indicator, e.Location, e.Expression)
+
+	if len(e.Input) == 0 {
+		return s
+	}
+
+	refs := make([]string, 0, len(e.Input))
+	for ref := range e.Input {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+
+	values := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		value, err := json.Marshal(e.Input[ref])
+		if err != nil {
+			value = []byte(fmt.Sprint(e.Input[ref]))
+		}
+		values = append(values, fmt.Sprintf("%s = %s", ref, value))
+	}
+
+	return fmt.Sprintf("%s (%s)", s, strings.Join(values, ", "))
+}
+
+// WithExplain returns a context in which the evaluators attach an explanation
+// to every failure and warning
+func WithExplain(ctx context.Context) context.Context {
+	return context.WithValue(ctx, explainKey, true)
+}
+
+func explainEnabled(ctx context.Context) bool {
+	enabled, ok := ctx.Value(explainKey).(bool)
+	return ok && enabled
+}
+
+// policyProvider is implemented by testRunners that can provide the compiled
+// policies and the data they evaluate with, which is needed to explain results
+type policyProvider interface {
+	policies(ctx context.Context) (*ast.Compiler, storage.Store, error)
+}
+
+// explain attaches an explanation to each failure and warning within the
+// outcomes. The rule producing the results is evaluated again, once for each
+// input, with tracing enabled. A result is explained by the expressions of the
+// evaluation of the rule's body that produced the result's message. If that
+// can't be determined, all the expressions of the rule are included, or if the
+// rule can't be determined either, all the expressions within the rule's
+// package.
+func explain(ctx context.Context, r testRunner, outcomes []Outcome) error {
+	p, ok := r.(policyProvider)
+	if !ok {
+		log.Debugf("Results from %T runner can't be explained", r)
+		return nil
+	}
+
+	compiler, store, err := p.policies(ctx)
+	if err != nil {
+		return err
+	}
+
+	inputs := map[string][]any{}
+	for _, o := range outcomes {
+		if len(o.Failures) == 0 && len(o.Warnings) == 0 {
+			continue
+		}
+
+		configs, ok := inputs[o.FileName]
+		if !ok {
+			if configs, err = parseInput(o.FileName); err != nil {
+				return err
+			}
+			inputs[o.FileName] = configs
+		}
+
+		// the outcome is of a single input and namespace, so the rules are
+		// identified by their code
+		traces := map[string]*ruleTrace{}
+		for _, results := range [][]Result{o.Failures, o.Warnings} {
+			for i := range results {
+				code := ExtractStringFromMetadata(results[i], metadataCode)
+				t, ok := traces[code]
+				if !ok {
+					if t, err = traceRule(ctx, compiler, store, configs, o.Namespace, code); err != nil {
+						return err
+					}
+					traces[code] = t
+				}
+
+				results[i].Explanation = t.explanation(results[i].Message)
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseInput parses the input file into one or more documents
+func parseInput(fileName string) ([]any, error) {
+	configurations, err := parser.ParseConfigurations([]string{fileName})
+	if err != nil {
+		return nil, fmt.Errorf("parse configurations: %w", err)
+	}
+
+	var configs []any
+	for _, config := range configurations {
+		if subconfigs, ok := config.([]any); ok {
+			configs = append(configs, subconfigs...)
+		} else {
+			configs = append(configs, config)
+		}
+	}
+
+	return configs, nil
+}
+
+// ruleTrace holds the expressions evaluated when evaluating a rule, for all
+// evaluations of the rule and for the evaluations producing each message
+type ruleTrace struct {
+	rule     *Explanation
+	messages map[string]*Explanation
+}
+
+// explanation returns the explanation of the result with the given message
+func (t ruleTrace) explanation(message string) *Explanation {
+	if e, ok := t.messages[message]; ok {
+		return e
+	}
+
+	return t.rule
+}
+
+// step is an expression evaluated within an evaluation of a rule's body
+type step struct {
+	location *ast.Location
+	expr     *ast.Expr
+}
+
+// traceRule evaluates the rule with the given code against each of the input
+// documents and collects the expressions evaluated
+func traceRule(ctx context.Context, compiler *ast.Compiler, store storage.Store, configs []any, namespace string, code string) (*ruleTrace, error) {
+	queries, matches := traceFilter(compiler, namespace, code)
+
+	explained := map[string]*explainedExpression{}
+	produced := map[string]map[string]*explainedExpression{}
+	for _, config := range configs {
+		input, err := ast.InterfaceToValue(config)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, query := range queries {
+			tracer := topdown.NewBufferTracer()
+			if _, err := rego.New(
+				rego.Query(query),
+				rego.Compiler(compiler),
+				rego.Store(store),
+				rego.ParsedInput(input),
+				rego.QueryTracer(tracer),
+			).Eval(ctx); err != nil {
+				return nil, fmt.Errorf("explaining %q: %w", query, err)
+			}
+
+			// the expressions evaluated so far by each evaluation of a rule's
+			// body, on backtracking the expressions following the expression
+			// evaluated again are dropped
+			steps := map[uint64][]step{}
+			for _, event := range *tracer {
+				switch node := event.Node.(type) {
+				case *ast.Expr:
+					if event.Location == nil || !matches(event.Location) {
+						continue
+					}
+
+					e := explainedExpressionAt(explained, event.Location)
+					switch event.Op {
+					case topdown.EvalOp:
+						e.evaluated++
+						addInputValues(e.input, node, event.Locals, input)
+
+						s := steps[event.QueryID]
+						for len(s) > 0 && s[len(s)-1].expr.Index >= node.Index {
+							s = s[:len(s)-1]
+						}
+						steps[event.QueryID] = append(s, step{location: event.Location, expr: node})
+					case topdown.FailOp:
+						e.failed++
+					}
+				case *ast.Rule:
+					if event.Op != topdown.ExitOp || len(steps[event.QueryID]) == 0 {
+						continue
+					}
+
+					message, ok := producedMessage(node, event.Locals)
+					if !ok {
+						continue
+					}
+
+					if produced[message] == nil {
+						produced[message] = map[string]*explainedExpression{}
+					}
+					// the input values are recorded using the bindings of the
+					// complete evaluation, as variables can be bound by the
+					// expression referencing them
+					for _, s := range steps[event.QueryID] {
+						e := explainedExpressionAt(produced[message], s.location)
+						e.evaluated++
+						addInputValues(e.input, s.expr, event.Locals, input)
+					}
+				}
+			}
+		}
+	}
+
+	sources := map[string][]string{}
+	t := ruleTrace{
+		rule:     toExplanation(sources, explained),
+		messages: make(map[string]*Explanation, len(produced)),
+	}
+	for message, e := range produced {
+		t.messages[message] = toExplanation(sources, e)
+	}
+
+	return &t, nil
+}
+
+// producedMessage returns the message of the result produced by an evaluation
+// of the rule's body with the given bindings, i.e. the value of the rule's key
+// or, for results holding metadata, the value of its msg attribute
+func producedMessage(r *ast.Rule, locals *ast.ValueMap) (string, bool) {
+	if locals == nil || r.Head == nil {
+		return "", false
+	}
+
+	key := r.Head.Key
+	if key == nil {
+		return "", false
+	}
+
+	plugged, err := ast.TransformVars(key.Copy().Value, func(v ast.Var) (ast.Value, error) {
+		if value := locals.Get(v); value != nil {
+			return value, nil
+		}
+		return v, nil
+	})
+	if err != nil {
+		return "", false
+	}
+
+	value, ok := plugged.(ast.Value)
+	if !ok || !ast.IsConstant(value) {
+		return "", false
+	}
+
+	switch v := value.(type) {
+	case ast.String:
+		return string(v), true
+	case ast.Object:
+		if msg := v.Get(ast.StringTerm("msg")); msg != nil {
+			if m, ok := msg.Value.(ast.String); ok {
+				return string(m), true
+			}
+		}
+	}
+
+	return "", false
+}
+
+func explainedExpressionAt(explained map[string]*explainedExpression, location *ast.Location) *explainedExpression {
+	key := location.String()
+	e, ok := explained[key]
+	if !ok {
+		e = &explainedExpression{
+			location: location,
+			input:    map[string]any{},
+		}
+		explained[key] = e
+	}
+
+	return e
+}
+
+// toExplanation returns the explanation holding the evaluated expressions in
+// the order of their location
+func toExplanation(sources map[string][]string, explained map[string]*explainedExpression) *Explanation {
+	sorted := make([]*explainedExpression, 0, len(explained))
+	for _, e := range explained {
+		if e.evaluated > 0 {
+			sorted = append(sorted, e)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].location.Compare(sorted[j].location) < 0
+	})
+
+	explanation := Explanation{
+		Expressions: make([]ExpressionExplanation, 0, len(sorted)),
+	}
+	for _, e := range sorted {
+		ee := ExpressionExplanation{
+			Location:   e.location.String(),
+			Expression: expressionText(sources, e.location),
+			// an expression that didn't fail at least once evaluated to true
+			Result: e.evaluated > e.failed,
+		}
+		if len(e.input) > 0 {
+			ee.Input = e.input
+		}
+		explanation.Expressions = append(explanation.Expressions, ee)
+	}
+
+	return &explanation
+}
+
+// expressionText returns the source of the expression at the location. The
+// compiler rewrites expressions and the location of the rewritten expression
+// holds only the text of its first term, so the line from the policy file is
+// used when it can be read.
+func expressionText(sources map[string][]string, location *ast.Location) string {
+	lines, ok := sources[location.File]
+	if !ok {
+		if b, err := os.ReadFile(location.File); err == nil {
+			lines = strings.Split(string(b), "\n")
+		}
+		sources[location.File] = lines
+	}
+
+	if location.Row > 0 && location.Row <= len(lines) {
+		if line := strings.TrimSpace(lines[location.Row-1]); line != "" {
+			return line
+		}
+	}
+
+	return string(location.Text)
+}
+
+type explainedExpression struct {
+	location  *ast.Location
+	evaluated int
+	failed    int
+	input     map[string]any
+}
+
+// traceFilter returns the queries to evaluate and the function matching the
+// trace events to include in the explanation. If the rule with the given code
+// is found, only the rule is queried and only the expressions from the body of
+// the rule are included, otherwise all rules within the namespace are queried
+// and all expressions within the namespace are included.
+func traceFilter(compiler *ast.Compiler, namespace string, code string) ([]string, func(*ast.Location) bool) {
+	if code != "" {
+		for _, a := range compiler.GetAnnotationSet().Flatten() {
+			r := a.GetRule()
+			if r == nil || a.Annotations == nil || strings.TrimPrefix(r.Module.Package.Path.String(), "data.") != namespace {
+				continue
+			}
+
+			if rule.RuleInfo(a).Code != code {
+				continue
+			}
+
+			body := map[string]bool{}
+			for _, expr := range r.Body {
+				if expr.Location != nil {
+					body[expr.Location.String()] = true
+				}
+			}
+
+			return []string{ruleQuery(namespace, r.Head.Name.String())}, func(l *ast.Location) bool {
+				return body[l.String()]
+			}
+		}
+	}
+
+	files := map[string]bool{}
+	for _, module := range compiler.Modules {
+		if strings.TrimPrefix(module.Package.Path.String(), "data.") == namespace && module.Package.Location != nil {
+			files[module.Package.Location.File] = true
+		}
+	}
+
+	rules, _ := checkRules(compiler, namespace)
+	queries := make([]string, 0, len(rules))
+	for _, r := range rules {
+		queries = append(queries, ruleQuery(namespace, r))
+	}
+
+	return queries, func(l *ast.Location) bool {
+		return files[l.File]
+	}
+}
+
+// addInputValues records the values of all references to the input within the
+// expression. Local variables within the references are replaced by their
+// values at the time of evaluation.
+func addInputValues(values map[string]any, expr *ast.Expr, locals *ast.ValueMap, input ast.Value) {
+	ast.WalkRefs(expr, func(ref ast.Ref) bool {
+		if !ref.HasPrefix(ast.InputRootRef) {
+			return false
+		}
+
+		plugged := ref.Copy()
+		for i, t := range plugged {
+			if v, ok := t.Value.(ast.Var); ok && locals != nil {
+				if value := locals.Get(v); value != nil {
+					plugged[i] = ast.NewTerm(value)
+				}
+			}
+		}
+
+		if !plugged.IsGround() {
+			return false
+		}
+
+		value, err := input.Find(plugged[1:])
+		if err != nil {
+			return false
+		}
+
+		if v, err := ast.JSON(value); err == nil {
+			values[plugged.String()] = v
+		}
+
+		return false
+	})
+}
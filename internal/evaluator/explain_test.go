@@ -0,0 +1,218 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package evaluator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplain(t *testing.T) {
+	dir := t.TempDir()
+
+	policyDir := path.Join(dir, "policy")
+	require.NoError(t, os.MkdirAll(policyDir, 0755))
+	policyFile := path.Join(policyDir, "main.rego")
+	require.NoError(t, os.WriteFile(policyFile, []byte(`package main
+
+import rego.v1
+
+# METADATA
+# custom:
+#   short_name: kind
+deny contains result if {
+	input.kind != "Allowed"
+	input.spec.replicas > 1
+	result := {"code": "main.kind", "msg": "not allowed"}
+}
+
+deny contains "unannotated" if {
+	input.spec.replicas > 2
+}
+`), 0600))
+
+	input := path.Join(dir, "input.json")
+	require.NoError(t, os.WriteFile(input, []byte(`{"kind": "Denied", "spec": {"replicas": 3}}`), 0600))
+
+	capabilities := path.Join(dir, "capabilities.json")
+	require.NoError(t, os.WriteFile(capabilities, []byte(testCapabilities), 0600))
+
+	r := &opaRunner{
+		policy:       []string{policyDir},
+		capabilities: capabilities,
+	}
+
+	ctx := context.Background()
+	outcomes, err := r.Run(ctx, []string{input})
+	require.NoError(t, err)
+	require.Len(t, outcomes, 1)
+	require.Len(t, outcomes[0].Failures, 2)
+
+	require.NoError(t, explain(ctx, r, outcomes))
+
+	explanations := map[string]*Explanation{}
+	for _, f := range outcomes[0].Failures {
+		explanations[f.Message] = f.Explanation
+	}
+
+	assert.Equal(t, &Explanation{
+		Expressions: []ExpressionExplanation{
+			{
+				Location:   policyFile + ":9",
+				Expression: `input.kind != "Allowed"`,
+				Result:     true,
+				Input:      map[string]any{"input.kind": "Denied"},
+			},
+			{
+				Location:   policyFile + ":10",
+				Expression: "input.spec.replicas > 1",
+				Result:     true,
+				Input:      map[string]any{"input.spec.replicas": json.Number("3")},
+			},
+			{
+				Location:   policyFile + ":11",
+				Expression: `result := {"code": "main.kind", "msg": "not allowed"}`,
+				Result:     true,
+			},
+		},
+	}, explanations["not allowed"])
+
+	// without the rule code the rule is found by the message it produced
+	assert.Equal(t, &Explanation{
+		Expressions: []ExpressionExplanation{
+			{
+				Location:   policyFile + ":15",
+				Expression: "input.spec.replicas > 2",
+				Result:     true,
+				Input:      map[string]any{"input.spec.replicas": json.Number("3")},
+			},
+		},
+	}, explanations["unannotated"])
+}
+
+func TestExplainPerResult(t *testing.T) {
+	dir := t.TempDir()
+
+	policyDir := path.Join(dir, "policy")
+	require.NoError(t, os.MkdirAll(policyDir, 0755))
+	policyFile := path.Join(policyDir, "main.rego")
+	require.NoError(t, os.WriteFile(policyFile, []byte(`package main
+
+import rego.v1
+
+# METADATA
+# custom:
+#   short_name: privileged
+deny contains result if {
+	some i
+	input.containers[i].privileged == true
+	result := {"code": "main.privileged", "msg": sprintf("%s is privileged", [input.containers[i].name])}
+}
+`), 0600))
+
+	input := path.Join(dir, "input.json")
+	require.NoError(t, os.WriteFile(input, []byte(`{"containers": [
+		{"name": "a", "privileged": true},
+		{"name": "b", "privileged": false},
+		{"name": "c", "privileged": true}
+	]}`), 0600))
+
+	capabilities := path.Join(dir, "capabilities.json")
+	require.NoError(t, os.WriteFile(capabilities, []byte(testCapabilities), 0600))
+
+	r := &opaRunner{
+		policy:       []string{policyDir},
+		capabilities: capabilities,
+	}
+
+	ctx := context.Background()
+	outcomes, err := r.Run(ctx, []string{input})
+	require.NoError(t, err)
+	require.Len(t, outcomes, 1)
+	require.Len(t, outcomes[0].Failures, 2)
+
+	require.NoError(t, explain(ctx, r, outcomes))
+
+	explanations := map[string]*Explanation{}
+	for _, f := range outcomes[0].Failures {
+		explanations[f.Message] = f.Explanation
+	}
+
+	for i, name := range map[int]string{0: "a", 2: "c"} {
+		assert.Equal(t, &Explanation{
+			Expressions: []ExpressionExplanation{
+				{
+					Location:   policyFile + ":10",
+					Expression: "input.containers[i].privileged == true",
+					Result:     true,
+					Input:      map[string]any{fmt.Sprintf("input.containers[%d].privileged", i): true},
+				},
+				{
+					Location:   policyFile + ":11",
+					Expression: `result := {"code": "main.privileged", "msg": sprintf("%s is privileged", [input.containers[i].name])}`,
+					Result:     true,
+					Input:      map[string]any{fmt.Sprintf("input.containers[%d].name", i): name},
+				},
+			},
+		}, explanations[name+" is privileged"], name)
+	}
+}
+
+func TestExplainNotSupported(t *testing.T) {
+	outcomes := []Outcome{{Failures: []Result{{Message: "failure"}}}}
+
+	require.NoError(t, explain(context.Background(), &mockTestRunner{}, outcomes))
+	assert.Nil(t, outcomes[0].Failures[0].Explanation)
+}
+
+func TestExpressionExplanationString(t *testing.T) {
+	cases := []struct {
+		name     string
+		given    ExpressionExplanation
+		expected string
+	}{
+		{
+			name:     "passing",
+			given:    ExpressionExplanation{Location: "main.rego:1", Expression: "true", Result: true},
+			expected: "✓ main.rego:1: true",
+		},
+		{
+			name: "failing with input",
+			given: ExpressionExplanation{
+				Location:   "main.rego:2",
+				Expression: "input.a == input.b",
+				Input:      map[string]any{"input.b": 2, "input.a": "x"},
+			},
+			expected: `✕ main.rego:2: input.a == input.b (input.a = "x", input.b = 2)`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.expected, c.given.String())
+		})
+	}
+}
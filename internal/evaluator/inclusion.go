@@ -0,0 +1,136 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package evaluator
+
+import (
+	"sort"
+
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+
+	"github.com/enterprise-contract/ec-cli/internal/opa/rule"
+)
+
+// CriteriaMatch is an include or exclude entry of the policy configuration that
+// matches a rule, along with the specificity score of the entry
+type CriteriaMatch struct {
+	Value string `json:"value"`
+	Score int    `json:"score"`
+	// ImageDigest is set when the entry applies only to the image with the
+	// digest
+	ImageDigest string `json:"imageDigest,omitempty"`
+}
+
+// InclusionVerdict holds the include and exclude entries matching a rule and
+// the resulting decision. The rule is included when the combined score of the
+// matching include entries is greater than the combined score of the matching
+// exclude entries.
+type InclusionVerdict struct {
+	// ImageDigest is set when the verdict applies only to the image with the
+	// digest, otherwise the verdict applies to any image
+	ImageDigest  string          `json:"imageDigest,omitempty"`
+	Include      []CriteriaMatch `json:"include,omitempty"`
+	Exclude      []CriteriaMatch `json:"exclude,omitempty"`
+	IncludeScore int             `json:"includeScore"`
+	ExcludeScore int             `json:"excludeScore"`
+	Included     bool            `json:"included"`
+}
+
+// RuleInclusion explains if a rule is included in the evaluation
+type RuleInclusion struct {
+	Code     string             `json:"code"`
+	Source   string             `json:"source,omitempty"`
+	Verdicts []InclusionVerdict `json:"verdicts"`
+}
+
+// ExplainInclusion explains for each of the rules if the include and exclude
+// configuration of the policy source includes the rule in the evaluation. A
+// verdict is given for any image, and in addition for each image digest that
+// has image specific criteria matching the rule. Entries with terms are not
+// considered as terms are known only from the results of the evaluation.
+func ExplainInclusion(src ecc.Source, p ConfigProvider, rules []rule.Info) []RuleInclusion {
+	include, exclude := computeIncludeExclude(src, p)
+
+	seen := map[string]bool{}
+	digests := []string{}
+	for _, c := range []*Criteria{include, exclude} {
+		for digest := range c.digestItems {
+			if !seen[digest] {
+				seen[digest] = true
+				digests = append(digests, digest)
+			}
+		}
+	}
+	sort.Strings(digests)
+
+	inclusions := make([]RuleInclusion, 0, len(rules))
+	for _, r := range rules {
+		result := Result{
+			Metadata: map[string]any{
+				metadataCode: r.Code,
+			},
+		}
+		if len(r.Collections) > 0 {
+			result.Metadata[metadataCollections] = r.Collections
+		}
+		matchers := makeMatchers(result)
+
+		inclusion := RuleInclusion{
+			Code:     r.Code,
+			Source:   r.Source,
+			Verdicts: []InclusionVerdict{verdict(include, exclude, "", matchers)},
+		}
+
+		for _, digest := range digests {
+			v := verdict(include, exclude, digest, matchers)
+			if hasDigestMatch(v.Include) || hasDigestMatch(v.Exclude) {
+				inclusion.Verdicts = append(inclusion.Verdicts, v)
+			}
+		}
+
+		inclusions = append(inclusions, inclusion)
+	}
+
+	return inclusions
+}
+
+// verdict computes the inclusion verdict for the rule represented by the
+// matchers the same way isResultIncluded does
+func verdict(include, exclude *Criteria, digest string, matchers []string) InclusionVerdict {
+	includeItems := include.get(digest)
+	excludeItems := exclude.get(digest)
+
+	v := InclusionVerdict{
+		ImageDigest:  digest,
+		Include:      include.matches(digest, matchers),
+		Exclude:      exclude.matches(digest, matchers),
+		IncludeScore: scoreMatches(matchers, includeItems),
+		ExcludeScore: scoreMatches(matchers, excludeItems),
+	}
+	v.Included = v.IncludeScore > v.ExcludeScore
+
+	return v
+}
+
+func hasDigestMatch(matches []CriteriaMatch) bool {
+	for _, m := range matches {
+		if m.ImageDigest != "" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,102 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package evaluator
+
+import (
+	"testing"
+	"time"
+
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/enterprise-contract/ec-cli/internal/opa/rule"
+)
+
+func TestExplainInclusion(t *testing.T) {
+	digest := "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+
+	src := ecc.Source{
+		Config: &ecc.SourceConfig{
+			Include: []string{"*", "@minimal"},
+			Exclude: []string{"pkg"},
+		},
+		VolatileConfig: &ecc.VolatileSourceConfig{
+			Exclude: []ecc.VolatileCriteria{
+				{Value: "other.rule", ImageRef: digest},
+				{Value: "other", EffectiveUntil: "2000-01-01T00:00:00Z"},
+			},
+		},
+	}
+
+	config := &mockConfigProvider{}
+	config.On("EffectiveTime").Return(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	config.On("Spec").Return(ecc.EnterpriseContractPolicySpec{})
+
+	rules := []rule.Info{
+		{Code: "pkg.rule", Collections: []string{"minimal"}, Source: "source"},
+		{Code: "pkg.excluded", Source: "source"},
+		{Code: "other.rule", Source: "source"},
+	}
+
+	assert.Equal(t, []RuleInclusion{
+		{
+			Code:   "pkg.rule",
+			Source: "source",
+			Verdicts: []InclusionVerdict{
+				{
+					Include:      []CriteriaMatch{{Value: "*", Score: 1}, {Value: "@minimal", Score: 10}},
+					Exclude:      []CriteriaMatch{{Value: "pkg", Score: 10}},
+					IncludeScore: 11,
+					ExcludeScore: 10,
+					Included:     true,
+				},
+			},
+		},
+		{
+			Code:   "pkg.excluded",
+			Source: "source",
+			Verdicts: []InclusionVerdict{
+				{
+					Include:      []CriteriaMatch{{Value: "*", Score: 1}},
+					Exclude:      []CriteriaMatch{{Value: "pkg", Score: 10}},
+					IncludeScore: 1,
+					ExcludeScore: 10,
+				},
+			},
+		},
+		{
+			Code:   "other.rule",
+			Source: "source",
+			Verdicts: []InclusionVerdict{
+				{
+					Include:      []CriteriaMatch{{Value: "*", Score: 1}},
+					IncludeScore: 1,
+					Included:     true,
+				},
+				{
+					ImageDigest:  digest,
+					Include:      []CriteriaMatch{{Value: "*", Score: 1}},
+					Exclude:      []CriteriaMatch{{Value: "other.rule", Score: 110, ImageDigest: digest}},
+					IncludeScore: 1,
+					ExcludeScore: 110,
+				},
+			},
+		},
+	}, ExplainInclusion(src, config, rules))
+}
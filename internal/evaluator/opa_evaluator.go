@@ -17,33 +17,588 @@
 package evaluator
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"os"
-	"path"
+	"path/filepath"
+	"regexp"
+	"runtime/trace"
+	"slices"
+	"strings"
+	"sync"
 
-	"github.com/spf13/afero"
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	"github.com/open-policy-agent/conftest/parser"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/loader"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/topdown"
+	"github.com/open-policy-agent/opa/topdown/print"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
+	"github.com/enterprise-contract/ec-cli/internal/tracing"
+)
+
+var (
+	// rules named as these are evaluated, same as Conftest does
+	warningRuleRegex = regexp.MustCompile("^warn(_[a-zA-Z0-9]+)*$")
+	failureRuleRegex = regexp.MustCompile("^(deny|violation)(_[a-zA-Z0-9]+)*$")
 )
 
-// not sure what the properties will be yet, so setting the minimum.
+// opaEvaluator evaluates the policies using the OPA Go SDK directly, without
+// the Conftest test runner. The raw results are processed in the same way as
+// in the conftestEvaluator, so both produce the same outcomes. The ec.*
+// builtins are registered globally by importing the internal/rego package, as
+// done by the validate commands, and are subject to the capabilities file.
 type opaEvaluator struct {
-	workDir string
-	fs      afero.Fs
+	conftestEvaluator
+}
+
+// NewOPAEvaluator returns initialized opaEvaluator implementing Evaluator
+// interface
+func NewOPAEvaluator(ctx context.Context, policySources []source.PolicySource, p ConfigProvider, source ecc.Source) (Evaluator, error) {
+	return NewOPAEvaluatorWithNamespace(ctx, policySources, p, source, nil)
 }
 
-func NewOPAEvaluator() (Evaluator, error) {
-	return opaEvaluator{}, nil
+// NewOPAEvaluatorWithNamespace returns initialized opaEvaluator limited to
+// evaluating the given policy namespaces
+func NewOPAEvaluatorWithNamespace(ctx context.Context, policySources []source.PolicySource, p ConfigProvider, source ecc.Source, namespace []string) (Evaluator, error) {
+	if trace.IsEnabled() {
+		r := trace.StartRegion(ctx, "ec:opa-create-evaluator")
+		defer r.End()
+	}
+
+	c, err := newConftestEvaluator(ctx, policySources, p, source, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug("OPA evaluator created")
+	return opaEvaluator{c}, nil
 }
 
 func (o opaEvaluator) Evaluate(ctx context.Context, target EvaluationTarget) ([]Outcome, error) {
-	return []Outcome{}, nil
+	if trace.IsEnabled() {
+		region := trace.StartRegion(ctx, "ec:opa-evaluate")
+		defer region.End()
+	}
+
+	return o.evaluate(ctx, target, o.opaRunner)
+}
+
+// opaRunner returns the testRunner that evaluates policies natively
+func (o opaEvaluator) opaRunner() testRunner {
+	return &opaRunner{
+		policy:       []string{o.policyDir},
+		data:         []string{o.dataDir},
+		namespace:    o.namespace,
+		capabilities: o.CapabilitiesPath(),
+	}
+}
+
+// opaRunner compiles the policies from the policy directories, loads the data
+// from the data directories and evaluates the deny, violation and warn rules
+// against each input, the same way as the conftestRunner does, including the
+// information of the input file in data.conftest.file. Policies are compiled and queries prepared on the first
+// successful run, all subsequent runs, which can happen concurrently, reuse
+// them.
+type opaRunner struct {
+	policy       []string
+	data         []string
+	namespace    []string
+	capabilities string
+	mu           sync.Mutex
+	prepared     *query
+}
+
+func (r *opaRunner) Run(ctx context.Context, fileList []string) ([]Outcome, error) {
+	q, err := r.prepare(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.run(ctx, fileList)
+}
+
+func (r *opaRunner) policies(ctx context.Context) (*ast.Compiler, storage.Store, error) {
+	q, err := r.prepare(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return q.compiler, q.store, nil
+}
+
+// prepare compiles the policies, loads the data and prepares the queries for
+// all rules within the evaluated namespaces
+func (r *opaRunner) prepare(ctx context.Context) (*query, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.prepared != nil {
+		return r.prepared, nil
+	}
+
+	if trace.IsEnabled() {
+		region := trace.StartRegion(ctx, "ec:opa-prepare")
+		defer region.End()
+	}
+
+	compiler, err := r.compile()
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := r.store()
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces := r.namespace
+	if len(namespaces) == 0 {
+		namespaces = packages(compiler)
+	}
+
+	q, err := prepareQuery(ctx, compiler, store, namespaces)
+	if err != nil {
+		return nil, err
+	}
+
+	r.prepared = q
+
+	return r.prepared, nil
+}
+
+// prepareQuery prepares the queries for all rules, and the exceptions to them,
+// within the namespaces. The queries read the data from the store with the
+// overlay of each evaluation placed over it.
+func prepareQuery(ctx context.Context, compiler *ast.Compiler, store storage.Store, namespaces []string, options ...func(*rego.Rego)) (*query, error) {
+	q := query{
+		compiler:   compiler,
+		store:      overlayStore{store},
+		namespaces: namespaces,
+		rules:      make(map[string]namespaceRules, len(namespaces)),
+		prepared:   map[string]rego.PreparedEvalQuery{},
+	}
+
+	for _, namespace := range namespaces {
+		rules, count := checkRules(compiler, namespace)
+		q.rules[namespace] = namespaceRules{names: rules, count: count}
+
+		for _, rule := range rules {
+			for _, qry := range []string{exceptionQuery(namespace, rule), ruleQuery(namespace, rule)} {
+				pq, err := rego.New(append([]func(*rego.Rego){
+					rego.Query(qry),
+					rego.Compiler(compiler),
+					rego.Store(q.store),
+					rego.EnablePrintStatements(true),
+				}, options...)...).PrepareForEval(ctx)
+				if err != nil {
+					return nil, fmt.Errorf("prepare query %q: %w", qry, err)
+				}
+				q.prepared[qry] = pq
+			}
+		}
+	}
+
+	return &q, nil
+}
+
+// compile parses and compiles all Rego files in the policy directories honoring
+// the capabilities file
+func (r *opaRunner) compile() (*ast.Compiler, error) {
+	policies, err := loader.NewFileLoader().WithProcessAnnotation(true).Filtered(r.policy, func(_ string, info os.FileInfo, _ int) bool {
+		return !info.IsDir() && !strings.HasSuffix(info.Name(), bundle.RegoExt)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("load: %w", err)
+	}
+
+	if len(policies.Modules) == 0 {
+		return nil, fmt.Errorf("no policies found in %v", r.policy)
+	}
+
+	capabilities := ast.CapabilitiesForThisVersion()
+	if r.capabilities != "" {
+		f, err := os.Open(r.capabilities)
+		if err != nil {
+			return nil, fmt.Errorf("capabilities not opened: %w", err)
+		}
+		defer f.Close()
+
+		if capabilities, err = ast.LoadCapabilitiesJSON(f); err != nil {
+			return nil, fmt.Errorf("capabilities not loaded: %w", err)
+		}
+	}
+
+	compiler := ast.NewCompiler().WithEnablePrintStatements(true).WithCapabilities(capabilities)
+	compiler.Compile(policies.ParsedModules())
+	if compiler.Failed() {
+		return nil, fmt.Errorf("compile policies: %w", compiler.Errors)
+	}
+
+	return compiler, nil
+}
+
+// store loads all JSON and YAML documents from the data directories
+func (r *opaRunner) store() (storage.Store, error) {
+	paths, err := loader.FilteredPaths(r.data, func(_ string, info os.FileInfo, _ int) bool {
+		if info.IsDir() {
+			return false
+		}
+		return !slices.Contains([]string{".yaml", ".yml", ".json"}, filepath.Ext(info.Name()))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("filter data paths: %w", err)
+	}
+
+	documents, err := loader.NewFileLoader().All(paths)
+	if err != nil {
+		return nil, fmt.Errorf("load documents: %w", err)
+	}
+
+	store, err := documents.Store()
+	if err != nil {
+		return nil, fmt.Errorf("get documents store: %w", err)
+	}
+
+	return store, nil
 }
 
-func (o opaEvaluator) Destroy() {
-	if o.workDir != "" && os.Getenv("EC_DEBUG") == "" {
-		_ = o.fs.RemoveAll(o.workDir)
+// inputFiles expands any directories in the provided list into the supported
+// files found within them
+func inputFiles(fileList []string) ([]string, error) {
+	var files []string
+	for _, file := range fileList {
+		if file == "" {
+			continue
+		}
+
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, fmt.Errorf("get file info: %w", err)
+		}
+
+		if !info.IsDir() {
+			files = append(files, file)
+			continue
+		}
+
+		if err := filepath.Walk(file, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return fmt.Errorf("walk path: %w", err)
+			}
+
+			if !info.IsDir() && parser.FileSupported(path) {
+				files = append(files, path)
+			}
+
+			return nil
+		}); err != nil {
+			return nil, err
+		}
 	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files found")
+	}
+
+	return files, nil
+}
+
+// packages returns the unique package names, without the "data." prefix, of
+// all compiled modules
+func packages(compiler *ast.Compiler) []string {
+	var namespaces []string
+	for _, module := range compiler.Modules {
+		namespace := strings.TrimPrefix(module.Package.Path.String(), "data.")
+		if !slices.Contains(namespaces, namespace) {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+
+	return namespaces
+}
+
+// checkRules returns the unique names of the rules that are evaluated within
+// the namespace, along with the total number of such rule definitions
+func checkRules(compiler *ast.Compiler, namespace string) (rules []string, count int) {
+	for _, module := range compiler.Modules {
+		if strings.TrimPrefix(module.Package.Path.String(), "data.") != namespace {
+			continue
+		}
+
+		for _, r := range module.Rules {
+			name := r.Head.Name.String()
+			if !failureRuleRegex.MatchString(name) && !warningRuleRegex.MatchString(name) {
+				continue
+			}
+
+			// Multiple definitions of the same rule are evaluated by a single
+			// query, but each is accounted for when computing successes
+			count++
+
+			if !slices.Contains(rules, name) {
+				rules = append(rules, name)
+			}
+		}
+	}
+
+	return
+}
+
+// namespaceRules holds the unique names of the rules evaluated within a
+// namespace and the total number of definitions of those rules
+type namespaceRules struct {
+	names []string
+	count int
+}
+
+// query holds the prepared queries for every rule evaluated, prepared queries
+// are safe for concurrent use
+type query struct {
+	compiler   *ast.Compiler
+	store      storage.Store
+	namespaces []string
+	rules      map[string]namespaceRules
+	prepared   map[string]rego.PreparedEvalQuery
+}
+
+// run checks each input file against the rules of each namespace, the same
+// way as Conftest checks them, with the information of the input file in
+// data.conftest.file and the effective time of the evaluation in
+// data.config.policy.when_ns
+func (q query) run(ctx context.Context, fileList []string) ([]Outcome, error) {
+	files, err := inputFiles(fileList)
+	if err != nil {
+		return nil, fmt.Errorf("parse files: %w", err)
+	}
+
+	configurations, err := parser.ParseConfigurations(files)
+	if err != nil {
+		return nil, fmt.Errorf("parse configurations: %w", err)
+	}
+
+	var results []Outcome
+	for file, config := range configurations {
+		overlay, err := evaluationOverlay(ctx, file)
+		if err != nil {
+			return nil, fmt.Errorf("check: %w", err)
+		}
+		fileCtx := withOverlay(ctx, overlay)
+
+		// It is possible for a configuration to have multiple configurations,
+		// e.g. multi-document YAML files, each one is evaluated separately and
+		// the results are aggregated under the same file name
+		configs := []any{config}
+		if subconfigs, ok := config.([]any); ok {
+			configs = subconfigs
+		}
+
+		for _, namespace := range q.namespaces {
+			outcome := Outcome{
+				FileName:  file,
+				Namespace: namespace,
+			}
+			for _, c := range configs {
+				if err := q.check(fileCtx, &outcome, c); err != nil {
+					return nil, fmt.Errorf("check: %w", err)
+				}
+			}
+
+			results = append(results, outcome)
+		}
+	}
+
+	return results, nil
+}
+
+// exceptionQuery returns the query for exceptions to the rule. Exceptions are
+// matched by the name of the rule without the deny_, violation_ or warn_
+// prefix.
+func exceptionQuery(namespace, rule string) string {
+	return fmt.Sprintf("data.%s.exception[_][_] == %q", namespace, exceptionName(rule))
+}
+
+// ruleQuery returns the query for the results of the rule
+func ruleQuery(namespace, rule string) string {
+	return fmt.Sprintf("data.%s.%s", namespace, rule)
+}
+
+// check evaluates all deny, violation and warn rules, and any exceptions to
+// them, from the namespace of the outcome against the given input, adding the
+// results to the outcome. As with Conftest, the results of a rule with an
+// exception are not reported, and the rules that produced no results are
+// counted as successes.
+func (q query) check(ctx context.Context, outcome *Outcome, input any) error {
+	namespace := outcome.Namespace
+	rules := q.rules[namespace]
+
+	var failures, warnings, exceptions []Result
+	successes := 0
+	for _, rule := range rules.names {
+		exceptionQuery := exceptionQuery(namespace, rule)
+		rs, err := q.eval(ctx, exceptionQuery, input)
+		if err != nil {
+			return fmt.Errorf("query exception: %w", err)
+		}
+
+		if len(rs) > 0 {
+			for range rs {
+				exceptions = append(exceptions, Result{Message: exceptionQuery})
+			}
+			continue
+		}
+
+		rs, err = q.eval(ctx, ruleQuery(namespace, rule), input)
+		if err != nil {
+			return fmt.Errorf("query rule: %w", err)
+		}
+
+		results, passed, err := toResults(rs)
+		if err != nil {
+			return err
+		}
+		successes += passed
+
+		if failureRuleRegex.MatchString(rule) {
+			failures = append(failures, results...)
+		} else {
+			warnings = append(warnings, results...)
+		}
+	}
+
+	// Only a single success is counted when a rule succeeds, even if the rule
+	// has multiple definitions, so any difference between the number of rule
+	// definitions and the number of results is counted as successes
+	resultCount := len(failures) + len(warnings) + len(exceptions) + successes
+	if resultCount < rules.count {
+		successes += rules.count - resultCount
+	}
+
+	outcome.Failures = append(outcome.Failures, failures...)
+	outcome.Warnings = append(outcome.Warnings, warnings...)
+	outcome.Exceptions = append(outcome.Exceptions, exceptions...)
+	// Successes are placeholders here, they're replaced by the actual successes
+	// computed from the rule annotations
+	outcome.Successes = append(outcome.Successes, make([]Result, successes)...)
+
+	return nil
+}
+
+// eval runs the prepared query against the given input
+func (q query) eval(ctx context.Context, query string, input any) (rego.ResultSet, error) {
+	pq, ok := q.prepared[query]
+	if !ok {
+		return nil, fmt.Errorf("no prepared query found for %q", query)
+	}
+
+	options := []rego.EvalOption{
+		rego.EvalInput(input),
+		rego.EvalPrintHook(printHook{query: query}),
+	}
+
+	var tracer *topdown.BufferTracer
+	if tracing.FromContext(ctx).Enabled(tracing.Opa) {
+		tracer = topdown.NewBufferTracer()
+		options = append(options, rego.EvalQueryTracer(tracer))
+	}
+
+	rs, err := pq.Eval(ctx, options...)
+
+	if tracer != nil && log.IsLevelEnabled(log.TraceLevel) {
+		buf := bytes.Buffer{}
+		topdown.PrettyTrace(&buf, *tracer)
+		for _, line := range strings.Split(buf.String(), "\n") {
+			if len(line) > 0 {
+				log.Tracef("[%s] %s", query, line)
+			}
+		}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("evaluating policy: %w", err)
+	}
+
+	return rs, nil
+}
+
+// toResults converts the values produced by a rule into results. Rules produce
+// either plain string messages or objects with the "msg" attribute holding the
+// message and any other attributes being the metadata. The number of passing
+// evaluations, i.e. the ones without any value, is returned as well.
+func toResults(rs rego.ResultSet) (results []Result, passed int, err error) {
+	for _, r := range rs {
+		for _, expression := range r.Expressions {
+			values, _ := expression.Value.([]any)
+			if len(values) == 0 {
+				passed++
+				continue
+			}
+
+			for _, v := range values {
+				switch val := v.(type) {
+				case string:
+					if val == "" {
+						passed++
+						continue
+					}
+					results = append(results, Result{Message: val})
+				case map[string]any:
+					msg, ok := val["msg"]
+					if !ok {
+						return nil, 0, fmt.Errorf("rule missing msg field: %v", val)
+					}
+					message, ok := msg.(string)
+					if !ok {
+						return nil, 0, fmt.Errorf("msg field must be string: %v", val)
+					}
+					if message == "" {
+						passed++
+						continue
+					}
+
+					result := Result{
+						Message:  message,
+						Metadata: make(map[string]any, len(val)-1),
+					}
+					for k, v := range val {
+						if k != "msg" {
+							result.Metadata[k] = v
+						}
+					}
+					results = append(results, result)
+				}
+			}
+		}
+	}
+
+	return
+}
+
+// exceptionName returns the name of the rule as used in exceptions
+func exceptionName(rule string) string {
+	if rule == "violation" || rule == "deny" || rule == "warn" {
+		return ""
+	}
+
+	for _, prefix := range []string{"violation_", "deny_", "warn_"} {
+		rule = strings.TrimPrefix(rule, prefix)
+	}
+
+	return rule
+}
+
+// printHook logs the output of print statements in the policies
+type printHook struct {
+	query string
 }
 
-func (o opaEvaluator) CapabilitiesPath() string {
-	return path.Join(o.workDir, "capabilities.json")
+func (p printHook) Print(ctx print.Context, msg string) error {
+	log.Debugf("[%s] %v: %s", p.query, ctx.Location, msg)
+	return nil
 }
@@ -14,30 +14,318 @@
 //
 // SPDX-License-Identifier: Apache-2.0
 
+//go:build unit
+
 package evaluator
 
 import (
 	"context"
+	"io/fs"
 	"os"
+	"path"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	"github.com/open-policy-agent/conftest/runner"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
 )
 
 // TestNewOPAEvaluator tests the constructor NewOPAEvaluator.
 func TestNewOPAEvaluator(t *testing.T) {
-	evaluator, err := NewOPAEvaluator()
+	ctx := withCapabilities(context.Background(), testCapabilities)
+	p, err := policy.NewInertPolicy(ctx, "")
+	require.NoError(t, err)
+
+	evaluator, err := NewOPAEvaluator(ctx, []source.PolicySource{}, p, ecc.Source{})
 	assert.NoError(t, err, "Expected no error from NewOPAEvaluator")
-	assert.Equal(t, evaluator, opaEvaluator{})
+	t.Cleanup(evaluator.Destroy)
+
+	o, ok := evaluator.(opaEvaluator)
+	require.True(t, ok)
+	assert.NotEmpty(t, o.workDir)
+	assert.Equal(t, path.Join(o.workDir, "capabilities.json"), o.CapabilitiesPath())
+
+	capabilities, err := afero.ReadFile(utils.FS(ctx), o.CapabilitiesPath())
+	require.NoError(t, err)
+	assert.Equal(t, testCapabilities, string(capabilities))
+}
+
+func TestOPAEvaluatorSameAsConftest(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(path.Join(dir, "inputs"), 0755))
+	require.NoError(t, os.WriteFile(path.Join(dir, "inputs", "data.json"), []byte("{}"), 0600))
+
+	rego, err := fs.Sub(policies, "__testdir__/simple")
+	require.NoError(t, err)
+
+	rules, err := rulesArchive(t, rego)
+	require.NoError(t, err)
+
+	ctx := withCapabilities(context.Background(), testCapabilities)
+
+	eTime, err := time.Parse(policy.DateFormat, "2014-05-31")
+	require.NoError(t, err)
+	config := &mockConfigProvider{}
+	config.On("EffectiveTime").Return(eTime)
+	config.On("SigstoreOpts").Return(policy.SigstoreOpts{}, nil)
+	config.On("Spec").Return(ecc.EnterpriseContractPolicySpec{})
+
+	evaluate := func(newEvaluator func(context.Context, []source.PolicySource, ConfigProvider, ecc.Source) (Evaluator, error)) []Outcome {
+		// the policy URL is pinned when fetched, so each evaluator needs its
+		// own sources
+		sources := []source.PolicySource{
+			&source.PolicyUrl{
+				Url:  rules,
+				Kind: source.PolicyKind,
+			},
+		}
+		evaluator, err := newEvaluator(ctx, sources, config, ecc.Source{})
+		require.NoError(t, err)
+		t.Cleanup(evaluator.Destroy)
+
+		results, err := evaluator.Evaluate(ctx, EvaluationTarget{Inputs: []string{path.Join(dir, "inputs")}})
+		require.NoError(t, err)
+
+		sort.Slice(results, func(l, r int) bool {
+			return strings.Compare(results[l].Namespace, results[r].Namespace) < 0
+		})
+		for i := range results {
+			sort.Slice(results[i].Successes, func(l, r int) bool {
+				return strings.Compare(results[i].Successes[l].Metadata[metadataCode].(string), results[i].Successes[r].Metadata[metadataCode].(string)) < 0
+			})
+		}
+
+		return results
+	}
+
+	expected := evaluate(NewConftestEvaluator)
+	actual := evaluate(NewOPAEvaluator)
+
+	assert.Equal(t, expected, actual)
+}
+
+func BenchmarkEvaluate(b *testing.B) {
+	dir := b.TempDir()
+	require.NoError(b, os.MkdirAll(path.Join(dir, "inputs"), 0755))
+	require.NoError(b, os.WriteFile(path.Join(dir, "inputs", "data.json"), []byte("{}"), 0600))
+
+	rego, err := fs.Sub(policies, "__testdir__/simple")
+	require.NoError(b, err)
+
+	rules, err := rulesArchive(b, rego)
+	require.NoError(b, err)
+
+	ctx := withCapabilities(context.Background(), testCapabilities)
+
+	p, err := policy.NewInertPolicy(ctx, "")
+	require.NoError(b, err)
+
+	sources := []source.PolicySource{
+		&source.PolicyUrl{
+			Url:  rules,
+			Kind: source.PolicyKind,
+		},
+	}
+
+	evaluators := map[string]func(context.Context, []source.PolicySource, ConfigProvider, ecc.Source) (Evaluator, error){
+		"conftest": NewConftestEvaluator,
+		"opa":      NewOPAEvaluator,
+	}
+
+	for name, newEvaluator := range evaluators {
+		// the downloaded sources are shared between evaluators, so all are
+		// destroyed only at the end
+		evaluator, err := newEvaluator(ctx, sources, p, ecc.Source{})
+		require.NoError(b, err)
+		b.Cleanup(evaluator.Destroy)
+
+		b.Run(name, func(b *testing.B) {
+			target := EvaluationTarget{Inputs: []string{path.Join(dir, "inputs")}}
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := evaluator.Evaluate(ctx, target); err != nil {
+						b.Error(err)
+					}
+				}
+			})
+		})
+	}
+}
+
+// runnerFixture writes the policy, the data, the multi-document input and the
+// capabilities evaluated by the runners, returning their paths
+func runnerFixture(t *testing.T) (policyDir, dataDir, inputDir, capabilities string) {
+	dir := t.TempDir()
+
+	policyDir = path.Join(dir, "policy")
+	require.NoError(t, os.MkdirAll(policyDir, 0755))
+	require.NoError(t, os.WriteFile(path.Join(policyDir, "main.rego"), []byte(`package main
+
+import rego.v1
+
+deny contains "plain message" if {
+	input.kind == "Plain"
+}
+
+deny_metadata contains {"msg": "with metadata", "code": "main.metadata"} if {
+	input.kind == "Metadata"
+}
+
+warn contains sprintf("from data: %s", [data.rule_data.value]) if {
+	input.kind == "Data"
+}
+
+warn_file contains sprintf("in file: %s", [data.conftest.file.name]) if {
+	input.kind == "Data"
+}
+
+deny_excepted contains "excepted" if {
+	true
+}
+
+exception contains rules if {
+	rules := ["excepted"]
+}
+`), 0600))
+
+	dataDir = path.Join(dir, "data")
+	require.NoError(t, os.MkdirAll(dataDir, 0755))
+	require.NoError(t, os.WriteFile(path.Join(dataDir, "data.json"), []byte(`{"rule_data": {"value": "hello"}}`), 0600))
+
+	inputDir = path.Join(dir, "inputs")
+	require.NoError(t, os.MkdirAll(inputDir, 0755))
+	require.NoError(t, os.WriteFile(path.Join(inputDir, "input.yaml"), []byte(`kind: Plain
+---
+kind: Metadata
+---
+kind: Data
+`), 0600))
+
+	capabilities = path.Join(dir, "capabilities.json")
+	require.NoError(t, os.WriteFile(capabilities, []byte(testCapabilities), 0600))
+
+	return
 }
 
-func TestEvaluate(t *testing.T) {
-	opaEval := opaEvaluator{}
+func TestOPARunner(t *testing.T) {
+	policyDir, dataDir, inputDir, capabilities := runnerFixture(t)
+
+	r := opaRunner{
+		policy:       []string{policyDir},
+		data:         []string{dataDir},
+		capabilities: capabilities,
+	}
+
+	results, err := r.Run(context.Background(), []string{inputDir})
+	require.NoError(t, err)
 
-	outcomes, err := opaEval.Evaluate(context.Background(), EvaluationTarget{})
-	assert.NoError(t, err, "Expected no error from Evaluate")
-	assert.Equal(t, []Outcome{}, outcomes)
+	assert.Equal(t, []Outcome{
+		{
+			FileName:  path.Join(inputDir, "input.yaml"),
+			Namespace: "main",
+			Successes: make([]Result, 8),
+			Failures: []Result{
+				{Message: "plain message"},
+				{Message: "with metadata", Metadata: map[string]any{"code": "main.metadata"}},
+			},
+			Warnings: []Result{
+				{Message: "from data: hello"},
+				{Message: "in file: input.yaml"},
+			},
+			Exceptions: []Result{
+				{Message: `data.main.exception[_][_] == "excepted"`},
+				{Message: `data.main.exception[_][_] == "excepted"`},
+				{Message: `data.main.exception[_][_] == "excepted"`},
+			},
+		},
+	}, results)
+}
+
+func TestOPARunnerSameAsConftestRunner(t *testing.T) {
+	policyDir, dataDir, inputDir, capabilities := runnerFixture(t)
+
+	runners := map[string]testRunner{
+		"conftest": &conftestRunner{
+			TestRunner: runner.TestRunner{
+				Policy:        []string{policyDir},
+				Data:          []string{dataDir},
+				AllNamespaces: true,
+				Capabilities:  capabilities,
+			},
+		},
+		"opa": &opaRunner{
+			policy:       []string{policyDir},
+			data:         []string{dataDir},
+			capabilities: capabilities,
+		},
+	}
+
+	results := map[string][]Outcome{}
+	for name, r := range runners {
+		outcomes, err := r.Run(context.Background(), []string{inputDir})
+		require.NoError(t, err, name)
+		results[name] = outcomes
+	}
+
+	assert.NotEmpty(t, results["conftest"])
+	assert.Equal(t, results["conftest"], results["opa"])
+}
+
+func TestOPARunnerCapabilities(t *testing.T) {
+	dir := t.TempDir()
+
+	policyDir := path.Join(dir, "policy")
+	require.NoError(t, os.MkdirAll(policyDir, 0755))
+	require.NoError(t, os.WriteFile(path.Join(policyDir, "main.rego"), []byte(`package main
+
+import rego.v1
+
+deny contains "env" if {
+	opa.runtime().env.HOME
+}
+`), 0600))
+
+	input := path.Join(dir, "input.json")
+	require.NoError(t, os.WriteFile(input, []byte("{}"), 0600))
+
+	capabilities := path.Join(dir, "capabilities.json")
+	require.NoError(t, os.WriteFile(capabilities, []byte(testCapabilities), 0600))
+
+	r := opaRunner{
+		policy:       []string{policyDir},
+		capabilities: capabilities,
+	}
+
+	_, err := r.Run(context.Background(), []string{input})
+	assert.ErrorContains(t, err, "undefined function opa.runtime")
+}
+
+func TestExceptionName(t *testing.T) {
+	cases := map[string]string{
+		"deny":           "",
+		"violation":      "",
+		"warn":           "",
+		"deny_rule":      "rule",
+		"violation_rule": "rule",
+		"warn_rule":      "rule",
+	}
+
+	for rule, expected := range cases {
+		t.Run(rule, func(t *testing.T) {
+			assert.Equal(t, expected, exceptionName(rule))
+		})
+	}
 }
 
 // Test Destroy method of opaEvaluator.
@@ -89,8 +377,10 @@ func TestDestroy(t *testing.T) {
 
 			// Initialize the evaluator
 			opaEval := opaEvaluator{
-				workDir: tc.workDir,
-				fs:      fs,
+				conftestEvaluator{
+					workDir: tc.workDir,
+					fs:      fs,
+				},
 			}
 
 			// Call Destroy
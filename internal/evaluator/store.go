@@ -0,0 +1,138 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package evaluator
+
+import (
+	"context"
+	"maps"
+	"path/filepath"
+	"time"
+
+	"github.com/open-policy-agent/opa/storage"
+)
+
+const overlayKey contextKey = "ec.evaluator.overlay"
+
+// overlayStore is a store that reads the documents of the underlying store
+// with the documents of the overlay, carried by the context of the evaluation,
+// placed over them. This allows the same prepared queries to be evaluated
+// concurrently with data that differs per evaluation, e.g. data.conftest.file,
+// without writing to the underlying store.
+type overlayStore struct {
+	storage.Store
+}
+
+// withOverlay returns the context carrying the overlay, a document the values
+// of which replace the values at the same paths within the data document
+func withOverlay(ctx context.Context, overlay map[string]any) context.Context {
+	return context.WithValue(ctx, overlayKey, overlay)
+}
+
+// evaluationOverlay returns the overlay holding, as Conftest does, the name
+// and the directory of the input file in data.conftest.file, and the effective
+// time of the evaluation, if any, in data.config.policy.when_ns
+func evaluationOverlay(ctx context.Context, file string) (map[string]any, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+
+	overlay := map[string]any{
+		"conftest": map[string]any{
+			"file": map[string]any{
+				"name": filepath.Base(abs),
+				"dir":  filepath.Dir(abs),
+			},
+		},
+	}
+
+	if effectiveTime, ok := ctx.Value(effectiveTimeKey).(time.Time); ok {
+		overlay["config"] = map[string]any{
+			"policy": map[string]any{
+				"when_ns": effectiveTime.UnixNano(),
+			},
+		}
+	}
+
+	return overlay, nil
+}
+
+func (s overlayStore) Read(ctx context.Context, txn storage.Transaction, path storage.Path) (any, error) {
+	value, err := s.Store.Read(ctx, txn, path)
+
+	overlay, ok := ctx.Value(overlayKey).(map[string]any)
+	if !ok {
+		return value, err
+	}
+
+	var over any = overlay
+	for i, key := range path {
+		m, ok := over.(map[string]any)
+		if !ok {
+			// the overlay holds a value other than an object on the path, which
+			// replaces the document of the store
+			return readPath(over, path[i:])
+		}
+
+		if over, ok = m[key]; !ok {
+			return value, err
+		}
+	}
+
+	if err != nil && !storage.IsNotFound(err) {
+		return nil, err
+	}
+
+	return merge(value, over), nil
+}
+
+// readPath returns the value at the path within the document
+func readPath(document any, path storage.Path) (any, error) {
+	for _, key := range path {
+		m, ok := document.(map[string]any)
+		if ok {
+			document, ok = m[key]
+		}
+		if !ok {
+			return nil, &storage.Error{Code: storage.NotFoundErr, Message: path.String() + ": document not found"}
+		}
+	}
+
+	return document, nil
+}
+
+// merge returns the document with the values of the overlay placed over it,
+// the document isn't modified, objects within it are copied as needed
+func merge(document any, overlay any) any {
+	o, ok := overlay.(map[string]any)
+	if !ok {
+		return overlay
+	}
+
+	d, ok := document.(map[string]any)
+	if !ok {
+		d = map[string]any{}
+	} else {
+		d = maps.Clone(d)
+	}
+
+	for k, v := range o {
+		d[k] = merge(d[k], v)
+	}
+
+	return d
+}
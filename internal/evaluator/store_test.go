@@ -0,0 +1,100 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package evaluator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverlayStoreRead(t *testing.T) {
+	data := map[string]any{
+		"config": map[string]any{"value": "data", "policy": map[string]any{"when_ns": "one"}},
+		"rules":  []any{"a"},
+	}
+	store := overlayStore{inmem.NewFromObject(data)}
+	overlay := map[string]any{
+		"conftest": map[string]any{"file": map[string]any{"name": "input.json"}},
+		"config":   map[string]any{"policy": map[string]any{"when_ns": "two"}},
+	}
+
+	cases := []struct {
+		name     string
+		path     string
+		overlay  bool
+		expected any
+		notFound bool
+	}{
+		{name: "without overlay", path: "/config/policy/when_ns", expected: "one"},
+		{name: "replaced value", path: "/config/policy/when_ns", overlay: true, expected: "two"},
+		{name: "value not in overlay", path: "/config/value", overlay: true, expected: "data"},
+		{name: "value only in overlay", path: "/conftest/file/name", overlay: true, expected: "input.json"},
+		{name: "path within a value of the overlay", path: "/conftest/file/name/missing", overlay: true, notFound: true},
+		{name: "missing", path: "/missing", overlay: true, notFound: true},
+		{
+			name:    "merged document",
+			path:    "/config",
+			overlay: true,
+			expected: map[string]any{
+				"value":  "data",
+				"policy": map[string]any{"when_ns": "two"},
+			},
+		},
+		{
+			name:    "root document",
+			path:    "/",
+			overlay: true,
+			expected: map[string]any{
+				"config":   map[string]any{"value": "data", "policy": map[string]any{"when_ns": "two"}},
+				"rules":    []any{"a"},
+				"conftest": map[string]any{"file": map[string]any{"name": "input.json"}},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := context.Background()
+			if c.overlay {
+				ctx = withOverlay(ctx, overlay)
+			}
+
+			path, ok := storage.ParsePath(c.path)
+			require.True(t, ok)
+
+			value, err := storage.ReadOne(ctx, store, path)
+			if c.notFound {
+				assert.True(t, storage.IsNotFound(err), "expected not found, got: %v", err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, value)
+		})
+	}
+
+	// the data of the store is left as is
+	value, err := storage.ReadOne(context.Background(), store, storage.Path{})
+	require.NoError(t, err)
+	assert.Equal(t, data, value)
+}
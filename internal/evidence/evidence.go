@@ -0,0 +1,553 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package evidence exports the inputs of a validation so that the validation
+// can be re-run offline, e.g. by an auditor, producing the same result.
+package evidence
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	"github.com/google/go-containerregistry/pkg/name"
+	app "github.com/konflux-ci/application-api/api/v1alpha1"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+
+	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/policy/cache"
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
+	"github.com/enterprise-contract/ec-cli/internal/version"
+)
+
+const (
+	// ManifestVersion is the version of the format of the Manifest
+	ManifestVersion = 1
+	// ManifestFile is the name of the file holding the Manifest
+	ManifestFile = "manifest.json"
+	PolicyFile   = "policy.json"
+	SnapshotFile = "snapshot.json"
+	sourcesDir   = "sources"
+	imagesDir    = "images"
+	inputsDir    = "inputs"
+)
+
+// Evidence holds the inputs of a validation
+type Evidence struct {
+	// Policy with its sources pinned to the revisions that were evaluated
+	Policy policy.Policy
+	// Sources holds the directories the pinned sources were evaluated from
+	Sources    *cache.PolicyCache
+	Snapshot   *app.SnapshotSpec
+	Components []Component
+	// Flags affecting the result of the validation, included in the command
+	// re-running the validation, e.g. --strict=false
+	Flags []string
+}
+
+// Component is a validated component, with the image resolved to the digest
+// that was validated, along with the input the policy was evaluated against
+type Component struct {
+	app.SnapshotComponent
+	PolicyInput []byte
+}
+
+// Manifest describes the content of the exported evidence
+type Manifest struct {
+	Version       int                 `json:"version"`
+	Created       time.Time           `json:"created"`
+	ECVersion     string              `json:"ecVersion"`
+	EffectiveTime time.Time           `json:"effectiveTime"`
+	Policy        string              `json:"policy"`
+	Snapshot      string              `json:"snapshot"`
+	Sources       []Source            `json:"sources"`
+	Components    []ManifestComponent `json:"components"`
+	// Command re-runs the validation from within the evidence directory
+	Command string `json:"command"`
+	Files   []File `json:"files"`
+}
+
+// Source is an evaluated policy or data source
+type Source struct {
+	// Group is the name, or the index if unnamed, of the source group
+	Group string `json:"group"`
+	Kind  string `json:"kind"`
+	// URL is the source URL pinned to the evaluated revision
+	URL  string `json:"url"`
+	Path string `json:"path"`
+}
+
+// ManifestComponent points to the evidence of a single component
+type ManifestComponent struct {
+	Name           string `json:"name"`
+	ContainerImage string `json:"containerImage"`
+	// Image is the OCI image layout holding the image, its signatures and
+	// attestations
+	Image string `json:"image"`
+	Input string `json:"input"`
+}
+
+// File is a file within the evidence and its digest
+type File struct {
+	Path   string `json:"path"`
+	Digest string `json:"digest"`
+}
+
+// Export writes the evidence to the dest directory, or to a gzip compressed
+// tar archive if dest ends with .tar.gz or .tgz. The directory must not exist
+// or be empty.
+func Export(ctx context.Context, dest string, e Evidence) error {
+	fs := utils.FS(ctx)
+
+	archive := strings.HasSuffix(dest, ".tar.gz") || strings.HasSuffix(dest, ".tgz")
+	dir := dest
+	if archive {
+		tmp, err := afero.TempDir(fs, "", "ec-evidence-")
+		if err != nil {
+			return err
+		}
+		defer utils.CleanupWorkDir(fs, tmp)
+		dir = tmp
+	} else if empty, err := isEmptyDir(fs, dir); err != nil {
+		return err
+	} else if !empty {
+		return fmt.Errorf("export directory %q is not empty", dir)
+	}
+
+	if err := fs.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	m := Manifest{
+		Version:       ManifestVersion,
+		Created:       time.Now().UTC(),
+		ECVersion:     version.Version,
+		EffectiveTime: e.Policy.EffectiveTime(),
+		Policy:        PolicyFile,
+		Snapshot:      SnapshotFile,
+	}
+
+	spec, sources, err := exportSources(fs, dir, e.Policy, e.Sources)
+	if err != nil {
+		return err
+	}
+	m.Sources = sources
+
+	if err := writeJSON(fs, filepath.Join(dir, PolicyFile), spec); err != nil {
+		return err
+	}
+
+	snapshot := app.SnapshotSpec{}
+	if e.Snapshot != nil {
+		snapshot = *e.Snapshot
+	}
+	snapshot.Components = nil
+	for i, c := range e.Components {
+		mc, err := exportComponent(ctx, fs, dir, i, c)
+		if err != nil {
+			return err
+		}
+		m.Components = append(m.Components, mc)
+
+		// Pin the image to the exported digest so it can be found in the
+		// image layouts
+		c.SnapshotComponent.ContainerImage = mc.ContainerImage
+		snapshot.Components = append(snapshot.Components, c.SnapshotComponent)
+	}
+
+	if err := writeJSON(fs, filepath.Join(dir, SnapshotFile), snapshot); err != nil {
+		return err
+	}
+
+	command := []string{
+		"ec validate image",
+		"--images", SnapshotFile,
+		"--policy", PolicyFile,
+		"--oci-layout", imagesDir,
+		"--effective-time", m.EffectiveTime.Format(time.RFC3339),
+	}
+	m.Command = strings.Join(append(command, e.Flags...), " ")
+
+	if m.Files, err = digests(fs, dir); err != nil {
+		return err
+	}
+
+	if err := writeJSON(fs, filepath.Join(dir, ManifestFile), m); err != nil {
+		return err
+	}
+
+	if archive {
+		return writeArchive(fs, dir, dest)
+	}
+
+	return nil
+}
+
+// exportSources copies the evaluated directories of the policy and data
+// sources of the policy to the sources directory and returns the policy spec
+// referencing the copies along with the public key, or the identity, used for
+// verification. The sources of the policy are expected to be pinned, as they
+// are once fetched for the validation.
+func exportSources(fs afero.Fs, dir string, p policy.Policy, dirs *cache.PolicyCache) (ecc.EnterpriseContractPolicySpec, []Source, error) {
+	spec := p.Spec()
+
+	if p.Keyless() {
+		identity := p.Identity()
+		if identity.Subject != "" || identity.SubjectRegExp != "" || identity.Issuer != "" || identity.IssuerRegExp != "" {
+			spec.Identity = &ecc.Identity{
+				Subject:       identity.Subject,
+				SubjectRegExp: identity.SubjectRegExp,
+				Issuer:        identity.Issuer,
+				IssuerRegExp:  identity.IssuerRegExp,
+			}
+		}
+	} else {
+		// The public key might reference a file or a Kubernetes secret, include
+		// the key itself instead
+		pem, err := p.PublicKeyPEM()
+		if err != nil {
+			return spec, nil, err
+		}
+		spec.PublicKey = string(pem)
+	}
+
+	var sources []Source
+	groups := make([]ecc.Source, 0, len(spec.Sources))
+	for i, group := range spec.Sources {
+		groupName := group.Name
+		if groupName == "" {
+			groupName = fmt.Sprint(i)
+		}
+
+		export := func(urls []string, kind source.PolicyType) ([]string, error) {
+			exported := make([]string, 0, len(urls))
+			for j, url := range urls {
+				// Inline data is exported as part of the policy
+				if strings.HasPrefix(url, "data:") {
+					exported = append(exported, url)
+					continue
+				}
+
+				var evaluated string
+				var ok bool
+				if dirs != nil {
+					evaluated, ok = dirs.Get(url)
+				}
+				if !ok {
+					return nil, fmt.Errorf("%s source %q was not fetched for the validation", kind, url)
+				}
+
+				path := filepath.Join(sourcesDir, fmt.Sprint(i), string(kind), fmt.Sprint(j))
+				if err := copyDir(fs, evaluated, filepath.Join(dir, path)); err != nil {
+					return nil, err
+				}
+
+				sources = append(sources, Source{
+					Group: groupName,
+					Kind:  string(kind),
+					URL:   url,
+					Path:  path,
+				})
+				// The relative path needs to be explicit to be treated as a
+				// local source
+				exported = append(exported, "./"+filepath.ToSlash(path))
+			}
+			return exported, nil
+		}
+
+		var err error
+		if group.Policy, err = export(group.Policy, source.PolicyKind); err != nil {
+			return spec, nil, err
+		}
+		if group.Data, err = export(group.Data, source.DataKind); err != nil {
+			return spec, nil, err
+		}
+		groups = append(groups, group)
+	}
+	spec.Sources = groups
+
+	return spec, sources, nil
+}
+
+// exportComponent writes the validated image of the component, with its
+// signatures, attestations and Sigstore bundles, to an OCI image layout and
+// the policy input of the component
+func exportComponent(ctx context.Context, fs afero.Fs, dir string, i int, c Component) (ManifestComponent, error) {
+	ref, err := name.NewDigest(c.ContainerImage)
+	if err != nil {
+		return ManifestComponent{}, fmt.Errorf("image %s of component %s wasn't validated by digest: %w", c.ContainerImage, c.Name, err)
+	}
+
+	client := oci.NewClient(ctx)
+	se, err := client.SignedEntity(ref)
+	if err != nil {
+		return ManifestComponent{}, err
+	}
+
+	bundles, err := client.SigstoreBundles(ref)
+	if err != nil {
+		return ManifestComponent{}, err
+	}
+
+	mc := ManifestComponent{
+		Name:           c.Name,
+		ContainerImage: ref.Name(),
+		Image:          filepath.Join(imagesDir, fmt.Sprint(i)),
+		Input:          filepath.Join(inputsDir, fmt.Sprintf("%d.json", i)),
+	}
+
+	if err := writeLayout(fs, filepath.Join(dir, mc.Image), se, bundles); err != nil {
+		return ManifestComponent{}, fmt.Errorf("writing image %s: %w", mc.ContainerImage, err)
+	}
+
+	if err := fs.MkdirAll(filepath.Join(dir, inputsDir), 0o755); err != nil {
+		return ManifestComponent{}, err
+	}
+	if err := afero.WriteFile(fs, filepath.Join(dir, mc.Input), c.PolicyInput, 0o644); err != nil {
+		return ManifestComponent{}, err
+	}
+
+	return mc, nil
+}
+
+func isEmptyDir(fs afero.Fs, dir string) (bool, error) {
+	entries, err := afero.ReadDir(fs, dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return len(entries) == 0, nil
+}
+
+func writeJSON(fs afero.Fs, path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return afero.WriteFile(fs, path, append(data, '\n'), 0o644)
+}
+
+// copyDir copies the content of the src directory to dst following any
+// symbolic links, the downloaded sources can be symbolic links to previously
+// downloaded sources
+func copyDir(fs afero.Fs, src, dst string) error {
+	src, err := resolveLink(fs, src)
+	if err != nil {
+		return err
+	}
+
+	info, err := fs.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return copyFile(fs, src, dst)
+	}
+
+	if err := fs.MkdirAll(dst, 0o755); err != nil {
+		return err
+	}
+
+	entries, err := afero.ReadDir(fs, src)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := copyDir(fs, filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func resolveLink(fs afero.Fs, path string) (string, error) {
+	lstater, ok := fs.(afero.Lstater)
+	if !ok {
+		return path, nil
+	}
+
+	info, _, err := lstater.LstatIfPossible(path)
+	if err != nil {
+		return "", err
+	}
+
+	if info.Mode()&os.ModeSymlink == 0 {
+		return path, nil
+	}
+
+	reader, ok := fs.(afero.LinkReader)
+	if !ok {
+		return path, nil
+	}
+
+	target, err := reader.ReadlinkIfPossible(path)
+	if err != nil {
+		return "", err
+	}
+
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(path), target)
+	}
+
+	return resolveLink(fs, target)
+}
+
+func copyFile(fs afero.Fs, src, dst string) error {
+	in, err := fs.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := fs.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// digests computes the digest of each file within dir
+func digests(fs afero.Fs, dir string) ([]File, error) {
+	var files []File
+	err := afero.Walk(fs, dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		f, err := fs.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, File{
+			Path:   filepath.ToSlash(rel),
+			Digest: "sha256:" + hex.EncodeToString(h.Sum(nil)),
+		})
+
+		return nil
+	})
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+
+	return files, err
+}
+
+// writeArchive writes the content of the dir directory to a gzip compressed
+// tar archive at dest
+func writeArchive(fs afero.Fs, dir, dest string) (err error) {
+	f, err := fs.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, f.Close())
+	}()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	err = afero.Walk(fs, dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		in, err := fs.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+
+		_, err = io.Copy(tw, in)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	log.Debugf("Wrote evidence archive to %s", dest)
+	return gz.Close()
+}
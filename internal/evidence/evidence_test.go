@@ -0,0 +1,284 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package evidence
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	ggcrmutate "github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	app "github.com/konflux-ci/application-api/api/v1alpha1"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/cosign/v2/pkg/oci/mutate"
+	"github.com/sigstore/cosign/v2/pkg/oci/signed"
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	sigPayload "github.com/sigstore/sigstore/pkg/signature/payload"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/policy/cache"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci/fake"
+)
+
+type fixture struct {
+	ctx      context.Context
+	signer   signature.SignerVerifier
+	evidence Evidence
+	image    name.Digest
+	bundle   v1.Image
+	sources  []string
+}
+
+func setUp(t *testing.T) fixture {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	signer, err := signature.LoadECDSASignerVerifier(key, crypto.SHA256)
+	require.NoError(t, err)
+	pem, err := cryptoutils.MarshalPublicKeyToPEM(key.Public())
+	require.NoError(t, err)
+
+	img, err := random.Image(512, 1)
+	require.NoError(t, err)
+	h, err := img.Digest()
+	require.NoError(t, err)
+	digest, err := name.NewDigest("registry.local/image@" + h.String())
+	require.NoError(t, err)
+
+	payload, err := sigPayload.Cosign{Image: digest}.MarshalJSON()
+	require.NoError(t, err)
+	rawSig, err := signer.SignMessage(bytes.NewReader(payload))
+	require.NoError(t, err)
+	sig, err := static.NewSignature(payload, base64.StdEncoding.EncodeToString(rawSig))
+	require.NoError(t, err)
+	se, err := mutate.AttachSignatureToImage(signed.Image(img), sig)
+	require.NoError(t, err)
+
+	bundle, err := random.Image(128, 1)
+	require.NoError(t, err)
+	bundle = ggcrmutate.ConfigMediaType(bundle, types.MediaType(oci.SigstoreBundleMediaType+".v0.3+json"))
+
+	client := fake.FakeClient{}
+	client.On("SignedEntity", digest).Return(se, nil)
+	client.On("SigstoreBundles", digest).Return([]v1.Image{bundle}, nil)
+
+	// the sources as pinned when fetched for the validation, along with the
+	// directories they were fetched to
+	sources := []string{
+		"git::https://git.local/policy.git?ref=0123456789abcdef0123456789abcdef01234567",
+		"oci::registry.local/data@sha256:" + strings.Repeat("a", 64),
+	}
+	dirs, err := cache.CreatePolicyCache()
+	require.NoError(t, err)
+	for _, s := range sources {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "main.rego"), []byte("package main\n"), 0o600))
+		dirs.Set(s, dir, nil)
+	}
+
+	ctx := utils.WithFS(context.Background(), afero.NewOsFs())
+	ctx = oci.WithClient(ctx, &client)
+
+	spec := ecc.EnterpriseContractPolicySpec{
+		PublicKey: "k8s://tekton/signing-secret",
+		Sources: []ecc.Source{
+			{Name: "default", Policy: sources[:1], Data: sources[1:]},
+		},
+	}
+	specJSON, err := json.Marshal(spec)
+	require.NoError(t, err)
+
+	p, err := policy.NewPolicy(ctx, policy.Options{
+		PolicyRef:     string(specJSON),
+		PublicKey:     string(pem),
+		EffectiveTime: "2024-01-01T00:00:00Z",
+		IgnoreRekor:   true,
+	})
+	require.NoError(t, err)
+
+	return fixture{
+		ctx:    ctx,
+		signer: signer,
+		image:  digest,
+		bundle: bundle,
+		evidence: Evidence{
+			Policy:   p,
+			Sources:  dirs,
+			Snapshot: &app.SnapshotSpec{Application: "app"},
+			Components: []Component{{
+				SnapshotComponent: app.SnapshotComponent{Name: "image", ContainerImage: digest.String()},
+				PolicyInput:       []byte(`{"image":{"ref":"registry.local/image:latest"}}`),
+			}},
+			Flags: []string{"--strict=false"},
+		},
+		sources: sources,
+	}
+}
+
+func readJSON(t *testing.T, path string, v any) {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, v))
+}
+
+func TestExport(t *testing.T) {
+	f := setUp(t)
+	dir := filepath.Join(t.TempDir(), "evidence")
+
+	require.NoError(t, Export(f.ctx, dir, f.evidence))
+
+	var m Manifest
+	readJSON(t, filepath.Join(dir, ManifestFile), &m)
+
+	assert.Equal(t, ManifestVersion, m.Version)
+	assert.Equal(t, "2024-01-01T00:00:00Z", m.EffectiveTime.Format("2006-01-02T15:04:05Z07:00"))
+	assert.Equal(t, "ec validate image --images snapshot.json --policy policy.json --oci-layout images --effective-time 2024-01-01T00:00:00Z --strict=false", m.Command)
+	assert.Equal(t, []Source{
+		{Group: "default", Kind: "policy", URL: f.sources[0], Path: "sources/0/policy/0"},
+		{Group: "default", Kind: "data", URL: f.sources[1], Path: "sources/0/data/0"},
+	}, m.Sources)
+	assert.Equal(t, []ManifestComponent{
+		{Name: "image", ContainerImage: f.image.String(), Image: "images/0", Input: "inputs/0.json"},
+	}, m.Components)
+
+	// every exported file is listed with its digest
+	paths := []string{}
+	for _, file := range m.Files {
+		paths = append(paths, file.Path)
+		assert.Regexp(t, `^sha256:[0-9a-f]{64}$`, file.Digest)
+	}
+	assert.Contains(t, paths, "policy.json")
+	assert.Contains(t, paths, "snapshot.json")
+	assert.Contains(t, paths, "inputs/0.json")
+	assert.Contains(t, paths, "images/0/index.json")
+	assert.Contains(t, paths, "sources/0/policy/0/main.rego")
+	assert.Contains(t, paths, "sources/0/data/0/main.rego")
+	assert.NotContains(t, paths, "manifest.json")
+
+	var spec ecc.EnterpriseContractPolicySpec
+	readJSON(t, filepath.Join(dir, PolicyFile), &spec)
+	assert.Contains(t, spec.PublicKey, "-----BEGIN PUBLIC KEY-----")
+	assert.Equal(t, []string{"./sources/0/policy/0"}, spec.Sources[0].Policy)
+	assert.Equal(t, []string{"./sources/0/data/0"}, spec.Sources[0].Data)
+
+	var snapshot app.SnapshotSpec
+	readJSON(t, filepath.Join(dir, SnapshotFile), &snapshot)
+	assert.Equal(t, "app", snapshot.Application)
+	require.Len(t, snapshot.Components, 1)
+	assert.Equal(t, f.image.String(), snapshot.Components[0].ContainerImage)
+
+	input, err := os.ReadFile(filepath.Join(dir, "inputs", "0.json"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"image":{"ref":"registry.local/image:latest"}}`, string(input))
+
+	// the exported image can be verified offline
+	client, err := oci.NewLayoutClient(context.Background(), filepath.Join(dir, "images"))
+	require.NoError(t, err)
+	t.Cleanup(client.Close)
+	signatures, _, err := client.VerifyImageSignatures(f.image, &cosign.CheckOpts{SigVerifier: f.signer, IgnoreTlog: true})
+	require.NoError(t, err)
+	assert.Len(t, signatures, 1)
+
+	// the Sigstore bundles are exported along with the image
+	bundles, err := client.SigstoreBundles(f.image)
+	require.NoError(t, err)
+	require.Len(t, bundles, 1)
+	expected, err := f.bundle.Digest()
+	require.NoError(t, err)
+	got, err := bundles[0].Digest()
+	require.NoError(t, err)
+	assert.Equal(t, expected, got)
+}
+
+func TestExportUnfetchedSource(t *testing.T) {
+	f := setUp(t)
+	f.evidence.Sources, _ = cache.CreatePolicyCache()
+
+	assert.EqualError(t, Export(f.ctx, filepath.Join(t.TempDir(), "evidence"), f.evidence),
+		fmt.Sprintf("policy source %q was not fetched for the validation", f.sources[0]))
+}
+
+func TestExportImageNotResolved(t *testing.T) {
+	f := setUp(t)
+	f.evidence.Components[0].ContainerImage = "registry.local/image:latest"
+
+	assert.ErrorContains(t, Export(f.ctx, filepath.Join(t.TempDir(), "evidence"), f.evidence),
+		"image registry.local/image:latest of component image wasn't validated by digest")
+}
+
+func TestExportArchive(t *testing.T) {
+	f := setUp(t)
+	archive := filepath.Join(t.TempDir(), "evidence.tar.gz")
+
+	require.NoError(t, Export(f.ctx, archive, f.evidence))
+
+	file, err := os.Open(archive)
+	require.NoError(t, err)
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+
+	names := []string{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, hdr.Name)
+	}
+
+	assert.Contains(t, names, "manifest.json")
+	assert.Contains(t, names, "policy.json")
+	assert.Contains(t, names, "images/0/index.json")
+	assert.Contains(t, names, "sources/0/policy/0/main.rego")
+}
+
+func TestExportNonEmptyDirectory(t *testing.T) {
+	f := setUp(t)
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file"), []byte{}, 0o600))
+
+	assert.EqualError(t, Export(f.ctx, dir, f.evidence), fmt.Sprintf("export directory %q is not empty", dir))
+}
@@ -0,0 +1,261 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package evidence
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	cosignoci "github.com/sigstore/cosign/v2/pkg/oci"
+	"github.com/spf13/afero"
+)
+
+const (
+	// annotations used by cosign to mark the content of the OCI layout it
+	// writes with `cosign save`
+	kindAnnotation       = "kind"
+	imageAnnotation      = "dev.cosignproject.cosign/image"
+	imageIndexAnnotation = "dev.cosignproject.cosign/imageIndex"
+	sigsAnnotation       = "dev.cosignproject.cosign/sigs"
+	attsAnnotation       = "dev.cosignproject.cosign/atts"
+)
+
+// layoutWriter writes an OCI image layout to a directory of the file system
+type layoutWriter struct {
+	fs  afero.Fs
+	dir string
+}
+
+// writeLayout writes the image or image index, along with its signatures,
+// attestations and Sigstore bundles, to an OCI image layout in dir in the
+// same form as `cosign save` does. The Sigstore bundles are recorded in the
+// index with their artifact type.
+func writeLayout(fs afero.Fs, dir string, se cosignoci.SignedEntity, bundles []v1.Image) error {
+	w := layoutWriter{fs: fs, dir: dir}
+
+	if err := fs.MkdirAll(filepath.Join(dir, "blobs"), 0o755); err != nil {
+		return err
+	}
+
+	if err := afero.WriteFile(fs, filepath.Join(dir, "oci-layout"), []byte(`{"imageLayoutVersion":"1.0.0"}`), 0o644); err != nil {
+		return err
+	}
+
+	var manifests []v1.Descriptor
+	add := func(desc *v1.Descriptor, kind string) {
+		if kind != "" {
+			desc.Annotations = map[string]string{kindAnnotation: kind}
+		}
+		manifests = append(manifests, *desc)
+	}
+
+	switch e := se.(type) {
+	case cosignoci.SignedImageIndex:
+		desc, err := w.writeIndex(e)
+		if err != nil {
+			return err
+		}
+		add(desc, imageIndexAnnotation)
+	case cosignoci.SignedImage:
+		desc, err := w.writeImage(e)
+		if err != nil {
+			return err
+		}
+		add(desc, imageAnnotation)
+	default:
+		return fmt.Errorf("unsupported signed entity %T", se)
+	}
+
+	sigs, err := se.Signatures()
+	if err != nil {
+		return fmt.Errorf("getting signatures: %w", err)
+	}
+	atts, err := se.Attestations()
+	if err != nil {
+		return fmt.Errorf("getting attestations: %w", err)
+	}
+	for _, s := range []struct {
+		signatures cosignoci.Signatures
+		kind       string
+	}{{sigs, sigsAnnotation}, {atts, attsAnnotation}} {
+		if got, err := s.signatures.Get(); err != nil {
+			return err
+		} else if len(got) == 0 {
+			continue
+		}
+
+		desc, err := w.writeImage(s.signatures)
+		if err != nil {
+			return err
+		}
+		add(desc, s.kind)
+	}
+
+	for _, b := range bundles {
+		desc, err := w.writeImage(b)
+		if err != nil {
+			return fmt.Errorf("writing Sigstore bundle: %w", err)
+		}
+		add(desc, "")
+	}
+
+	index, err := json.MarshalIndent(v1.IndexManifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIImageIndex,
+		Manifests:     manifests,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return afero.WriteFile(fs, filepath.Join(dir, "index.json"), index, 0o644)
+}
+
+// writeImage writes the manifest, config and layers of the image as blobs
+func (w layoutWriter) writeImage(img v1.Image) (*v1.Descriptor, error) {
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, layer := range layers {
+		h, err := layer.Digest()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := w.writeBlobFrom(h, layer.Compressed); err != nil {
+			return nil, err
+		}
+	}
+
+	config, err := img.RawConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	configName, err := img.ConfigName()
+	if err != nil {
+		return nil, err
+	}
+	if err := w.writeBlob(configName, config); err != nil {
+		return nil, err
+	}
+
+	return w.writeManifest(img)
+}
+
+// writeIndex writes the manifest of the image index and the images and image
+// indexes it references as blobs
+func (w layoutWriter) writeIndex(idx v1.ImageIndex) (*v1.Descriptor, error) {
+	manifest, err := idx.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, desc := range manifest.Manifests {
+		switch {
+		case desc.MediaType.IsIndex():
+			child, err := idx.ImageIndex(desc.Digest)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := w.writeIndex(child); err != nil {
+				return nil, err
+			}
+		case desc.MediaType.IsImage():
+			child, err := idx.Image(desc.Digest)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := w.writeImage(child); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return w.writeManifest(idx)
+}
+
+type manifest interface {
+	partial.Describable
+	partial.WithRawManifest
+}
+
+func (w layoutWriter) writeManifest(m manifest) (*v1.Descriptor, error) {
+	raw, err := m.RawManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	desc, err := partial.Descriptor(m)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.writeBlob(desc.Digest, raw); err != nil {
+		return nil, err
+	}
+
+	return desc, nil
+}
+
+func (w layoutWriter) writeBlob(h v1.Hash, data []byte) error {
+	return w.writeBlobFrom(h, func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	})
+}
+
+// writeBlobFrom writes the content read from the reader as the blob with the
+// digest, blobs already present are not written again
+func (w layoutWriter) writeBlobFrom(h v1.Hash, open func() (io.ReadCloser, error)) (err error) {
+	dir := filepath.Join(w.dir, "blobs", h.Algorithm)
+	path := filepath.Join(dir, h.Hex)
+	if _, err := w.fs.Stat(path); err == nil {
+		return nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if err := w.fs.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	rc, err := open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	f, err := w.fs.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, f.Close())
+	}()
+
+	_, err = io.Copy(f, rc)
+	return err
+}
@@ -0,0 +1,105 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package input
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+// Change is a rule that started, or stopped, failing for an input between two
+// evaluations
+type Change struct {
+	FilePath string
+	Document int
+	Code     string
+	Failing  bool
+}
+
+// Name returns the file path of the input, including the number of the
+// document for a document of a multi-document YAML file
+func (c Change) Name() string {
+	return name(c.FilePath, c.Document)
+}
+
+// Changes returns the rules that started or stopped failing for each input of
+// the current report compared to the previous report, sorted by the input and
+// the rule. Inputs missing from the previous report are considered to have
+// had no failing rules, and inputs missing from the current report are not
+// compared.
+func Changes(previous, current Report) []Change {
+	type key struct {
+		filePath string
+		document int
+	}
+
+	before := map[key]map[string]bool{}
+	for _, i := range previous.FilePaths {
+		before[key{i.FilePath, i.Document}] = failingRules(i.Violations)
+	}
+
+	var changes []Change
+	for _, i := range current.FilePaths {
+		k := key{i.FilePath, i.Document}
+		now := failingRules(i.Violations)
+		for code := range now {
+			if !before[k][code] {
+				changes = append(changes, Change{FilePath: i.FilePath, Document: i.Document, Code: code, Failing: true})
+			}
+		}
+		for code := range before[k] {
+			if !now[code] {
+				changes = append(changes, Change{FilePath: i.FilePath, Document: i.Document, Code: code, Failing: false})
+			}
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].FilePath != changes[j].FilePath {
+			return changes[i].FilePath < changes[j].FilePath
+		}
+		if changes[i].Document != changes[j].Document {
+			return changes[i].Document < changes[j].Document
+		}
+		return changes[i].Code < changes[j].Code
+	})
+
+	return changes
+}
+
+// RenderChanges returns the changes in text form
+func RenderChanges(changes []Change) ([]byte, error) {
+	return utils.RenderFromTemplatesWithMain(changes, "changes.tmpl", efs)
+}
+
+// failingRules returns the rules of the violations, identified by their code
+// or, lacking one, by the message
+func failingRules(violations []evaluator.Result) map[string]bool {
+	rules := make(map[string]bool, len(violations))
+	for _, v := range violations {
+		if code, ok := v.Metadata["code"]; ok {
+			rules[fmt.Sprint(code)] = true
+		} else {
+			rules[v.Message] = true
+		}
+	}
+
+	return rules
+}
@@ -0,0 +1,282 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package input
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/gobwas/glob"
+	"github.com/open-policy-agent/conftest/parser"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+type contextKey string
+
+const filtersKey contextKey = "ec.input.filters"
+
+// Filters select the files to validate within a directory input by their
+// path relative to the directory. A pattern without a slash matches the name
+// of the file at any depth, e.g. "*.yaml", otherwise it matches the whole
+// relative path, where "**" matches any number of directories, e.g.
+// "overlays/**/*.yaml".
+type Filters struct {
+	include []glob.Glob
+	exclude []glob.Glob
+}
+
+// NewFilters returns the Filters selecting the files matching any of the
+// include patterns, or all files if there are none, and not matching any of
+// the exclude patterns. Directories matching an exclude pattern are skipped
+// altogether.
+func NewFilters(include, exclude []string) (Filters, error) {
+	var f Filters
+	var err error
+	if f.include, err = compilePatterns(include); err != nil {
+		return Filters{}, err
+	}
+	if f.exclude, err = compilePatterns(exclude); err != nil {
+		return Filters{}, err
+	}
+
+	return f, nil
+}
+
+// WithFilters returns a context in which the files within directory inputs
+// are selected using the given Filters
+func WithFilters(ctx context.Context, f Filters) context.Context {
+	return context.WithValue(ctx, filtersKey, f)
+}
+
+func filtersFrom(ctx context.Context) Filters {
+	if f, ok := ctx.Value(filtersKey).(Filters); ok {
+		return f
+	}
+
+	return Filters{}
+}
+
+func compilePatterns(patterns []string) ([]glob.Glob, error) {
+	globs := make([]glob.Glob, 0, len(patterns))
+	for _, p := range patterns {
+		g, err := glob.Compile(p, '/')
+		if err != nil {
+			return nil, fmt.Errorf("invalid file pattern %q: %w", p, err)
+		}
+		globs = append(globs, g)
+	}
+
+	return globs, nil
+}
+
+// included returns true if the file at the relative path is selected
+func (f Filters) included(rel string) bool {
+	if f.excluded(rel) {
+		return false
+	}
+
+	return len(f.include) == 0 || matchAny(f.include, rel)
+}
+
+// excluded returns true if the file, or directory, at the relative path
+// matches an exclude pattern
+func (f Filters) excluded(rel string) bool {
+	return matchAny(f.exclude, rel)
+}
+
+func matchAny(globs []glob.Glob, rel string) bool {
+	name := path.Base(rel)
+	for _, g := range globs {
+		if g.Match(rel) || g.Match(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// supported returns true if there is a parser for the file at the path. Files
+// without an extension, other than Dockerfiles, are not considered supported
+// even though conftest parses them as YAML, as those are more often READMEs,
+// licenses and such in directories of inputs.
+func supported(fpath string) bool {
+	if filepath.Ext(fpath) == "" && !strings.EqualFold(filepath.Base(fpath), "dockerfile") {
+		return false
+	}
+
+	return parser.FileSupported(fpath)
+}
+
+// if a single file is provided, return it
+// if the file is a directory, return the supported files within the directory
+// and its subdirectories selected by the filters from the context
+func fileLookup(ctx context.Context, root string) ([]string, error) {
+	fs := utils.FS(ctx)
+
+	dir, err := afero.IsDir(fs, root)
+	if err != nil {
+		return nil, err
+	}
+
+	if !dir {
+		return []string{root}, nil
+	}
+
+	filters := filtersFrom(ctx)
+	var defFiles []string
+	empty := true
+	err = afero.Walk(fs, root, func(fpath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if fpath == root {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, fpath)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if info.IsDir() {
+			if filters.excluded(rel) {
+				log.Debugf("Skipping excluded directory %q", fpath)
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		empty = false
+		if !supported(fpath) {
+			log.Debugf("Skipping file %q of unsupported type", fpath)
+			return nil
+		}
+		if !filters.included(rel) {
+			log.Debugf("Skipping file %q not selected by the filters", fpath)
+			return nil
+		}
+		defFiles = append(defFiles, fpath)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// a directory was provided, but contained no files
+	if empty {
+		return nil, fmt.Errorf("the directory %v contained no files", root)
+	}
+
+	if len(defFiles) == 0 {
+		return nil, fmt.Errorf("the directory %v contained no supported files selected by the include and exclude patterns", root)
+	}
+
+	return defFiles, nil
+}
+
+// document is a single input file, or a single document of a multi-document
+// YAML file, numbered from 1
+type document struct {
+	path   string
+	number int
+}
+
+// splitDocuments writes each document of the multi-document YAML files to a
+// temporary file so that each document is validated as a separate input. It
+// returns the files to validate, the files or documents the temporary files
+// hold and a function to remove the temporary files.
+func splitDocuments(ctx context.Context, files []string) ([]string, map[string]document, func(), error) {
+	fs := utils.FS(ctx)
+
+	var split []string
+	documents := map[string]document{}
+	cleanup := func() {
+		for tmp := range documents {
+			if err := fs.Remove(tmp); err != nil {
+				log.Debugf("Unable to remove temporary file %q: %v", tmp, err)
+			}
+		}
+	}
+
+	for _, f := range files {
+		ext := strings.ToLower(filepath.Ext(f))
+		if ext != ".yaml" && ext != ".yml" {
+			split = append(split, f)
+			continue
+		}
+
+		content, err := afero.ReadFile(fs, f)
+		if err != nil {
+			cleanup()
+			return nil, nil, nil, err
+		}
+
+		docs := separateDocuments(content)
+		if len(docs) < 2 {
+			split = append(split, f)
+			continue
+		}
+
+		for i, doc := range docs {
+			tmp, err := afero.TempFile(fs, "", "input-document-*"+ext)
+			if err != nil {
+				cleanup()
+				return nil, nil, nil, err
+			}
+			documents[tmp.Name()] = document{path: f, number: i + 1}
+			_, err = tmp.Write(doc)
+			tmp.Close()
+			if err != nil {
+				cleanup()
+				return nil, nil, nil, err
+			}
+			split = append(split, tmp.Name())
+		}
+	}
+
+	return split, documents, cleanup, nil
+}
+
+// separateDocuments returns the non-empty documents of the YAML content,
+// separated the same way the conftest YAML parser separates them
+func separateDocuments(content []byte) [][]byte {
+	linebreak := "\n"
+	if bytes.Contains(content, []byte("\r\n---\r\n")) {
+		linebreak = "\r\n"
+	}
+
+	var docs [][]byte
+	for _, doc := range bytes.Split(content, []byte(linebreak+"---"+linebreak)) {
+		if len(bytes.TrimSpace(bytes.TrimPrefix(doc, []byte("---")))) == 0 {
+			continue
+		}
+		docs = append(docs, doc)
+	}
+
+	return docs
+}
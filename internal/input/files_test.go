@@ -0,0 +1,194 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package input
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+func TestFileLookup(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	for _, f := range []string{
+		"/root/deployment.yaml",
+		"/root/README",
+		"/root/notes.txt",
+		"/root/Dockerfile",
+		"/root/config/settings.json",
+		"/root/config/settings.toml",
+		"/root/config/main.tf",
+		"/root/overlays/prod/patch.yml",
+		"/root/overlays/prod/chart.tmpl.yaml",
+		"/root/vendor/dependency.yaml",
+		"/unsupported/LICENSE",
+		"/single.txt",
+	} {
+		require.NoError(t, afero.WriteFile(fs, f, []byte("data"), 0644))
+	}
+	require.NoError(t, fs.MkdirAll("/empty", 0755))
+
+	cases := []struct {
+		name    string
+		path    string
+		include []string
+		exclude []string
+		files   []string
+		err     string
+	}{
+		{
+			name: "single file",
+			path: "/single.txt",
+			files: []string{
+				"/single.txt",
+			},
+		},
+		{
+			name: "recursive",
+			path: "/root",
+			files: []string{
+				"/root/Dockerfile",
+				"/root/config/main.tf",
+				"/root/config/settings.json",
+				"/root/config/settings.toml",
+				"/root/deployment.yaml",
+				"/root/overlays/prod/chart.tmpl.yaml",
+				"/root/overlays/prod/patch.yml",
+				"/root/vendor/dependency.yaml",
+			},
+		},
+		{
+			name:    "include by name",
+			path:    "/root",
+			include: []string{"*.{yaml,yml}"},
+			files: []string{
+				"/root/deployment.yaml",
+				"/root/overlays/prod/chart.tmpl.yaml",
+				"/root/overlays/prod/patch.yml",
+				"/root/vendor/dependency.yaml",
+			},
+		},
+		{
+			name:    "include by path",
+			path:    "/root",
+			include: []string{"overlays/**/*.yml", "config/*"},
+			files: []string{
+				"/root/config/main.tf",
+				"/root/config/settings.json",
+				"/root/config/settings.toml",
+				"/root/overlays/prod/patch.yml",
+			},
+		},
+		{
+			name:    "exclude",
+			path:    "/root",
+			include: []string{"*.yaml"},
+			exclude: []string{"vendor", "*.tmpl.yaml"},
+			files: []string{
+				"/root/deployment.yaml",
+			},
+		},
+		{
+			name: "empty directory",
+			path: "/empty",
+			err:  "the directory /empty contained no files",
+		},
+		{
+			name: "no supported files",
+			path: "/unsupported",
+			err:  "the directory /unsupported contained no supported files selected by the include and exclude patterns",
+		},
+		{
+			name:    "no files selected",
+			path:    "/root",
+			exclude: []string{"*"},
+			err:     "the directory /root contained no supported files selected by the include and exclude patterns",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			filters, err := NewFilters(c.include, c.exclude)
+			require.NoError(t, err)
+			ctx := WithFilters(utils.WithFS(context.Background(), fs), filters)
+
+			files, err := fileLookup(ctx, c.path)
+			if c.err != "" {
+				assert.EqualError(t, err, c.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, c.files, files)
+		})
+	}
+}
+
+func TestNewFilters(t *testing.T) {
+	_, err := NewFilters([]string{"*.yaml"}, []string{"[unterminated"})
+	assert.ErrorContains(t, err, `invalid file pattern "[unterminated"`)
+}
+
+func TestSplitDocuments(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/single.yaml", []byte("---\nkind: A\n"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/multi.yaml", []byte("kind: A\n---\n# empty\n---\n\n---\nkind: B\n---\n"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/crlf.yml", []byte("kind: A\r\n---\r\nkind: B\r\n"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/data.json", []byte(`{"a": "\n---\n"}`), 0644))
+	ctx := utils.WithFS(context.Background(), fs)
+
+	files, documents, cleanup, err := splitDocuments(ctx, []string{"/single.yaml", "/multi.yaml", "/crlf.yml", "/data.json"})
+	require.NoError(t, err)
+	require.Len(t, files, 7)
+
+	assert.Equal(t, "/single.yaml", files[0])
+	assert.Equal(t, "/data.json", files[6])
+
+	expected := []struct {
+		document document
+		content  string
+		ext      string
+	}{
+		{document{"/multi.yaml", 1}, "kind: A", ".yaml"},
+		{document{"/multi.yaml", 2}, "# empty", ".yaml"},
+		{document{"/multi.yaml", 3}, "kind: B", ".yaml"},
+		{document{"/crlf.yml", 1}, "kind: A", ".yml"},
+		{document{"/crlf.yml", 2}, "kind: B\r\n", ".yml"},
+	}
+	for i, e := range expected {
+		tmp := files[i+1]
+		assert.Equal(t, e.document, documents[tmp])
+		assert.Regexp(t, `input-document-\d+\`+e.ext+`$`, tmp)
+		content, err := afero.ReadFile(fs, tmp)
+		require.NoError(t, err)
+		assert.Equal(t, e.content, string(content))
+	}
+	assert.Len(t, documents, 5)
+
+	cleanup()
+	for tmp := range documents {
+		exists, err := afero.Exists(fs, tmp)
+		require.NoError(t, err)
+		assert.False(t, exists)
+	}
+}
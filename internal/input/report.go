@@ -18,9 +18,12 @@ package input
 
 import (
 	"bytes"
+	"embed"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
+	"sort"
 	"time"
 
 	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
@@ -29,11 +32,16 @@ import (
 	"github.com/enterprise-contract/ec-cli/internal/evaluator"
 	"github.com/enterprise-contract/ec-cli/internal/format"
 	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/report/sarif"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
 	"github.com/enterprise-contract/ec-cli/internal/version"
 )
 
 type Input struct {
-	FilePath     string             `json:"filepath"`
+	FilePath string `json:"filepath"`
+	// Document is the number of the document within a multi-document YAML
+	// file, starting from 1, or 0 if the input is the whole file
+	Document     int                `json:"document,omitempty"`
 	Violations   []evaluator.Result `json:"violations"`
 	Warnings     []evaluator.Result `json:"warnings"`
 	Successes    []evaluator.Result `json:"successes"`
@@ -60,6 +68,7 @@ type summary struct {
 
 type inputSummary struct {
 	FilePath        string              `json:"name"`
+	Document        int                 `json:"document,omitempty"`
 	Success         bool                `json:"success"`
 	Violations      map[string][]string `json:"violations"`
 	Warnings        map[string][]string `json:"warnings"`
@@ -87,9 +96,96 @@ type TestReport struct {
 const (
 	JSON    = "json"
 	YAML    = "yaml"
+	Text    = "text"
 	Summary = "summary"
+	SARIF   = "sarif"
 )
 
+// OutputFormats are the formats the report can be written as.
+var OutputFormats = []string{
+	JSON,
+	YAML,
+	Text,
+	Summary,
+	SARIF,
+}
+
+// Name returns the file path of the input, including the number of the
+// document for a document of a multi-document YAML file
+func (i Input) Name() string {
+	return name(i.FilePath, i.Document)
+}
+
+func name(filePath string, document int) string {
+	if document == 0 {
+		return filePath
+	}
+
+	return fmt.Sprintf("%s (document %d)", filePath, document)
+}
+
+// NewInputs returns the inputs validated for the file path, one for each
+// file, and each document of a multi-document YAML file, the results of the
+// output are of. The results not of any file, e.g. of failing to fetch a
+// policy source, are included in every input.
+func NewInputs(fpath string, out *Output, showSuccesses bool) []Input {
+	type key struct {
+		filePath string
+		document int
+	}
+
+	var keys []key
+	var common []evaluator.Outcome
+	outcomes := map[key][]evaluator.Outcome{}
+	for _, o := range out.Outcomes {
+		if o.FileName == "" {
+			common = append(common, o.Outcome)
+			continue
+		}
+
+		k := key{o.FileName, o.Document}
+		if _, ok := outcomes[k]; !ok {
+			keys = append(keys, k)
+		}
+		outcomes[k] = append(outcomes[k], o.Outcome)
+	}
+
+	if len(keys) == 0 {
+		keys = append(keys, key{filePath: fpath})
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].filePath != keys[j].filePath {
+			return keys[i].filePath < keys[j].filePath
+		}
+		return keys[i].document < keys[j].document
+	})
+
+	inputs := make([]Input, 0, len(keys))
+	for _, k := range keys {
+		o := out.Output
+		o.PolicyCheck = append(slices.Clone(common), outcomes[k]...)
+
+		i := Input{
+			FilePath:   k.filePath,
+			Document:   k.document,
+			Violations: o.Violations(),
+			Warnings:   o.Warnings(),
+		}
+
+		successes := o.Successes()
+		i.SuccessCount = len(successes)
+		if showSuccesses {
+			i.Successes = successes
+		}
+		i.Success = len(i.Violations) == 0
+
+		inputs = append(inputs, i)
+	}
+
+	return inputs
+}
+
 // WriteReport returns a new instance of Report representing the state of
 // the filepaths provided.
 func NewReport(inputs []Input, policy policy.Policy, policyInput [][]byte) (Report, error) {
@@ -152,20 +248,60 @@ func (r *Report) toFormat(format string) (data []byte, err error) {
 		data, err = json.Marshal(r)
 	case YAML:
 		data, err = yaml.Marshal(r)
+	case Text:
+		data, err = generateTextReport(r)
 	case Summary:
 		data, err = json.Marshal(r.toSummary())
+	case SARIF:
+		data, err = json.Marshal(r.toSARIF())
 	default:
 		return nil, fmt.Errorf("%q is not a valid report format", format)
 	}
 	return
 }
 
+//go:embed templates/*.tmpl
+var efs embed.FS
+
+func generateTextReport(r *Report) ([]byte, error) {
+	input := struct {
+		Report         *Report
+		Violations     int
+		Warnings       int
+		Successes      int
+		ShownSuccesses int
+	}{
+		Report: r,
+	}
+	for _, i := range r.FilePaths {
+		input.Violations += len(i.Violations)
+		input.Warnings += len(i.Warnings)
+		input.Successes += i.SuccessCount
+		input.ShownSuccesses += len(i.Successes)
+	}
+
+	return utils.RenderFromTemplatesWithMain(input, "text_report.tmpl", efs)
+}
+
+// toSARIF returns the violations and warnings of all inputs in the SARIF
+// format, located at the file path of the input
+func (r *Report) toSARIF() sarif.Log {
+	b := sarif.NewBuilder(r.EcVersion)
+	for _, i := range r.FilePaths {
+		b.AddFile(i.FilePath, sarif.Error, i.Violations)
+		b.AddFile(i.FilePath, sarif.Warning, i.Warnings)
+	}
+
+	return b.Log()
+}
+
 // toSummary returns a condensed version of the report.
 func (r *Report) toSummary() summary {
 	pr := summary{}
 	for _, cmp := range r.FilePaths {
 		c := inputSummary{
 			FilePath:        cmp.FilePath,
+			Document:        cmp.Document,
 			TotalViolations: len(cmp.Violations),
 			TotalWarnings:   len(cmp.Warnings),
 
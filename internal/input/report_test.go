@@ -27,6 +27,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/output"
 	"github.com/enterprise-contract/ec-cli/internal/policy"
 	"github.com/enterprise-contract/ec-cli/internal/utils"
 )
@@ -121,6 +122,62 @@ func Test_ReportJson(t *testing.T) {
 	assert.False(t, report.Success)
 }
 
+func Test_ReportSARIF(t *testing.T) {
+	filePaths := []string{"/path/to/file1.yaml", "/path/to/file2.yaml", "/path/to/file3.yaml"}
+	inputs := testInputsFor(filePaths)
+	inputs[1].Violations[0].Metadata = map[string]any{
+		"code":  "pkg.rule",
+		"title": "Rule title",
+	}
+	ctx := context.Background()
+	report, err := NewReport(inputs, createTestPolicy(t, ctx), nil)
+	assert.NoError(t, err)
+
+	reportSARIF, err := report.toFormat(SARIF)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"version": "2.1.0",
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"runs": [
+			{
+				"tool": {
+					"driver": {
+						"name": "ec",
+						"version": "development",
+						"informationUri": "https://enterprisecontract.dev",
+						"rules": [
+							{
+								"id": "pkg.rule",
+								"name": "Rule title",
+								"shortDescription": {"text": "Rule title"}
+							}
+						]
+					}
+				},
+				"results": [
+					{
+						"level": "error",
+						"message": {"text": "violation1"},
+						"locations": [{"physicalLocation": {"artifactLocation": {"uri": "file:///path/to/file1.yaml"}}}]
+					},
+					{
+						"level": "warning",
+						"message": {"text": "warning1"},
+						"locations": [{"physicalLocation": {"artifactLocation": {"uri": "file:///path/to/file1.yaml"}}}]
+					},
+					{
+						"ruleId": "pkg.rule",
+						"ruleIndex": 0,
+						"level": "error",
+						"message": {"text": "violation2"},
+						"locations": [{"physicalLocation": {"artifactLocation": {"uri": "file:///path/to/file2.yaml"}}}]
+					}
+				]
+			}
+		]
+	}`, string(reportSARIF))
+}
+
 func Test_ReportYaml(t *testing.T) {
 	filePaths := []string{"/path/to/file1.yaml", "/path/to/file2.yaml", "/path/to/file3.yaml"}
 	inputs := testInputsFor(filePaths)
@@ -241,6 +298,167 @@ func Test_ReportSummary(t *testing.T) {
 	}
 }
 
+func Test_ReportText(t *testing.T) {
+	inputs := []Input{
+		{
+			FilePath: "/path/to/file1.yaml",
+			Violations: []evaluator.Result{
+				{
+					Message: "violation1",
+					Metadata: map[string]any{
+						"code":     "main.rule1",
+						"title":    "Rule 1",
+						"solution": "Fix it",
+					},
+				},
+			},
+			Warnings:     []evaluator.Result{{Message: "warning1", Metadata: map[string]any{"code": "main.rule2"}}},
+			SuccessCount: 2,
+		},
+		{
+			FilePath:     "/path/to/file2.yaml",
+			Success:      true,
+			SuccessCount: 3,
+		},
+	}
+	report, err := NewReport(inputs, createTestPolicy(t, context.Background()), nil)
+	assert.NoError(t, err)
+
+	text, err := report.toFormat(Text)
+	assert.NoError(t, err)
+	assert.Equal(t, `Success: false
+Violations: 1, Warnings: 1, Successes: 5
+
+Files:
+- Path: /path/to/file1.yaml
+  Violations: 1, Warnings: 1, Successes: 2
+- Path: /path/to/file2.yaml
+  Violations: 0, Warnings: 0, Successes: 3
+
+Results:
+✕ [Violation] main.rule1
+  File: /path/to/file1.yaml
+  Reason: violation1
+  Title: Rule 1
+  Solution: Fix it
+
+› [Warning] main.rule2
+  File: /path/to/file1.yaml
+  Reason: warning1
+
+`, string(text))
+
+	report.FilePaths = inputs[1:]
+	text, err = report.toFormat(Text)
+	assert.NoError(t, err)
+	assert.Equal(t, `Success: false
+Violations: 0, Warnings: 0, Successes: 3
+
+File: /path/to/file2.yaml
+`, string(text))
+
+	report.FilePaths = []Input{{FilePath: "/path/to/file3.yaml", Document: 2, Success: true}}
+	text, err = report.toFormat(Text)
+	assert.NoError(t, err)
+	assert.Contains(t, string(text), "File: /path/to/file3.yaml (document 2)\n")
+}
+
+func TestChanges(t *testing.T) {
+	violation := func(code string) evaluator.Result {
+		return evaluator.Result{Message: "failed", Metadata: map[string]any{"code": code}}
+	}
+
+	previous := Report{FilePaths: []Input{
+		{FilePath: "a.yaml", Violations: []evaluator.Result{violation("main.one"), violation("main.two")}},
+		{FilePath: "b.yaml", Violations: []evaluator.Result{violation("main.one")}},
+		{FilePath: "removed.yaml", Violations: []evaluator.Result{violation("main.one")}},
+		{FilePath: "c.yaml", Document: 1, Violations: []evaluator.Result{violation("main.one")}},
+	}}
+	current := Report{FilePaths: []Input{
+		{FilePath: "b.yaml", Violations: []evaluator.Result{violation("main.one"), {Message: "no code"}}},
+		{FilePath: "a.yaml", Violations: []evaluator.Result{violation("main.two"), violation("main.three")}},
+		{FilePath: "added.yaml", Violations: []evaluator.Result{violation("main.one")}},
+		{FilePath: "c.yaml", Document: 2, Violations: []evaluator.Result{violation("main.one")}},
+		{FilePath: "c.yaml", Document: 1, Violations: []evaluator.Result{violation("main.one")}},
+	}}
+
+	changes := Changes(previous, current)
+	assert.Equal(t, []Change{
+		{FilePath: "a.yaml", Code: "main.one", Failing: false},
+		{FilePath: "a.yaml", Code: "main.three", Failing: true},
+		{FilePath: "added.yaml", Code: "main.one", Failing: true},
+		{FilePath: "b.yaml", Code: "no code", Failing: true},
+		{FilePath: "c.yaml", Document: 2, Code: "main.one", Failing: true},
+	}, changes)
+
+	text, err := RenderChanges(changes)
+	assert.NoError(t, err)
+	assert.Equal(t, `Changes since the previous evaluation:
+✓ Newly passing: [main.one] a.yaml
+✕ Newly failing: [main.three] a.yaml
+✕ Newly failing: [main.one] added.yaml
+✕ Newly failing: [no code] b.yaml
+✕ Newly failing: [main.one] c.yaml (document 2)
+`, string(text))
+
+	text, err = RenderChanges(Changes(current, current))
+	assert.NoError(t, err)
+	assert.Equal(t, "No rules started or stopped failing since the previous evaluation\n", string(text))
+}
+
+func TestNewInputs(t *testing.T) {
+	result := func(msg string) []evaluator.Result {
+		return []evaluator.Result{{Message: msg}}
+	}
+
+	out := &Output{Outcomes: []Outcome{
+		{Outcome: evaluator.Outcome{FileName: "b.yaml", Successes: result("b")}},
+		{Outcome: evaluator.Outcome{FileName: "a.yaml", Failures: result("a2")}, Document: 2},
+		{Outcome: evaluator.Outcome{Warnings: result("source")}},
+		{Outcome: evaluator.Outcome{FileName: "a.yaml", Successes: result("a1")}, Document: 1},
+		{Outcome: evaluator.Outcome{FileName: "a.yaml", Namespace: "other", Successes: result("a2")}, Document: 2},
+	}}
+
+	assert.Equal(t, []Input{
+		{
+			FilePath:     "a.yaml",
+			Document:     1,
+			Violations:   []evaluator.Result{},
+			Warnings:     result("source"),
+			SuccessCount: 1,
+			Success:      true,
+		},
+		{
+			FilePath:     "a.yaml",
+			Document:     2,
+			Violations:   result("a2"),
+			Warnings:     result("source"),
+			SuccessCount: 1,
+			Success:      false,
+		},
+		{
+			FilePath:     "b.yaml",
+			Violations:   []evaluator.Result{},
+			Warnings:     result("source"),
+			SuccessCount: 1,
+			Success:      true,
+		},
+	}, NewInputs("dir", out, false))
+
+	inputs := NewInputs("dir", out, true)
+	assert.Equal(t, result("b"), inputs[2].Successes)
+
+	// without any results of files, the results are of the input itself
+	out = NewOutput(output.Output{PolicyCheck: []evaluator.Outcome{{Failures: result("fail")}}})
+	assert.Equal(t, []Input{
+		{
+			FilePath:   "dir",
+			Violations: result("fail"),
+			Warnings:   []evaluator.Result{},
+		},
+	}, NewInputs("dir", out, false))
+}
+
 func testInputsFor(filePaths []string) []Input {
 	inputs := []Input{
 		{
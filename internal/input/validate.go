@@ -19,11 +19,9 @@ package input
 import (
 	"context"
 	"fmt"
-	"path/filepath"
 	"runtime/trace"
 
 	log "github.com/sirupsen/logrus"
-	"github.com/spf13/afero"
 
 	"github.com/enterprise-contract/ec-cli/internal/evaluation_target/input"
 	"github.com/enterprise-contract/ec-cli/internal/evaluator"
@@ -34,7 +32,35 @@ import (
 
 var inputFile = input.NewInput
 
-func ValidateInput(ctx context.Context, fpath string, policy policy.Policy, detailed bool) (*output.Output, error) {
+// Output is the output of validating an input, along with the outcomes of the
+// policy check attributed to the documents of the input files
+type Output struct {
+	output.Output
+	// Outcomes are the outcomes of the PolicyCheck, in the same order
+	Outcomes []Outcome
+}
+
+// Outcome is the outcome of the evaluation of an input file, or of a document
+// within a multi-document YAML input file
+type Outcome struct {
+	evaluator.Outcome
+	// Document is the number of the document within a multi-document YAML
+	// file, starting from 1, or 0 if the outcome is of the whole file
+	Document int
+}
+
+// NewOutput returns the Output with the outcomes of the policy check of the
+// given output, each of the whole file
+func NewOutput(out output.Output) *Output {
+	o := Output{Output: out}
+	for _, r := range out.PolicyCheck {
+		o.Outcomes = append(o.Outcomes, Outcome{Outcome: r})
+	}
+
+	return &o
+}
+
+func ValidateInput(ctx context.Context, fpath string, policy policy.Policy, detailed bool) (*Output, error) {
 	if trace.IsEnabled() {
 		region := trace.StartRegion(ctx, "ec:validate-input")
 		defer region.End()
@@ -42,10 +68,20 @@ func ValidateInput(ctx context.Context, fpath string, policy policy.Policy, deta
 	}
 
 	log.Debugf("Current input filePath: %q", fpath)
-	inputFiles, err := detectInput(ctx, fpath)
+	inputFiles, inline, err := detectInput(ctx, fpath)
+	if err != nil {
+		return nil, err
+	}
+
+	inputFiles, documents, cleanup, err := splitDocuments(ctx, inputFiles)
 	if err != nil {
 		return nil, err
 	}
+	defer cleanup()
+
+	if inline {
+		documents[inputFiles[0]] = document{path: fpath}
+	}
 
 	p, err := inputFile(ctx, inputFiles, policy)
 	if err != nil {
@@ -62,20 +98,37 @@ func ValidateInput(ctx context.Context, fpath string, policy policy.Policy, deta
 		allResults = append(allResults, results...)
 	}
 
+	// attribute the results of the temporary files to the input, or the
+	// document of the input, they were written from
+	numbers := make([]int, len(allResults))
+	for i, o := range allResults {
+		if d, ok := documents[o.FileName]; ok {
+			allResults[i].FileName = d.path
+			numbers[i] = d.number
+		}
+	}
+
 	log.Debug("Conftest policy check complete")
 
 	out := output.Output{Detailed: detailed}
 	out.SetPolicyCheck(allResults)
 
-	return &out, nil
+	o := NewOutput(out)
+	for i := range o.Outcomes {
+		o.Outcomes[i].Document = numbers[i]
+	}
+
+	return o, nil
 }
 
-// detect if a file or directory was passed. if a directory, gather all files in it
-// the order is file lookup, json lookup then yaml
-func detectInput(ctx context.Context, fpath string) ([]string, error) {
+// detect if a file or directory was passed. if a directory, gather the supported files in it
+// the order is json lookup, yaml lookup then file lookup, inline json and yaml
+// are written to a temporary file and reported as inline
+func detectInput(ctx context.Context, fpath string) ([]string, bool, error) {
 	if utils.IsJson(fpath) {
 		log.Debug("valid JSON found for definition file")
-		return inputFromString(ctx, fpath)
+		files, err := inputFromString(ctx, fpath)
+		return files, true, err
 	}
 	log.Debug("unable to detect input as JSON")
 
@@ -83,59 +136,23 @@ func detectInput(ctx context.Context, fpath string) ([]string, error) {
 	// since a provided filename that does not exist could be considered valid yaml
 	if utils.IsYamlMap(fpath) {
 		log.Debug("valid YAML map found for definition file")
-		return inputFromString(ctx, fpath)
+		files, err := inputFromString(ctx, fpath)
+		return files, true, err
 	}
 	log.Debug("unable to detect input as YAML")
 
 	fileExists, err := utils.IsFile(ctx, fpath)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	if fileExists {
-		return fileLookup(ctx, fpath)
+		files, err := fileLookup(ctx, fpath)
+		return files, false, err
 	}
 	log.Debugf("unable to detect a file at path %v", fpath)
 
-	return nil, fmt.Errorf("unable to parse the provided input file: %v", fpath)
-}
-
-// if a single file is provided, return it
-// if the file is a directory, return the files inside the directory
-func fileLookup(ctx context.Context, path string) ([]string, error) {
-	fs := utils.FS(ctx)
-	var defFiles []string
-
-	file, err := fs.Open(path)
-	if err != nil {
-		return nil, err
-	}
-
-	defer file.Close()
-
-	dir, err := afero.IsDir(fs, path)
-	if err != nil {
-		return nil, err
-	}
-
-	if dir {
-		files, err := afero.ReadDir(fs, path)
-		if err != nil {
-			return nil, err
-		}
-		// a directory was provided, but contained no files
-		if len(files) == 0 {
-			return nil, fmt.Errorf("the directory %v contained no files", path)
-		}
-
-		for _, f := range files {
-			defFiles = append(defFiles, filepath.Join(path, f.Name()))
-		}
-	} else {
-		defFiles = append(defFiles, path)
-	}
-
-	return defFiles, nil
+	return nil, false, fmt.Errorf("unable to parse the provided input file: %v", fpath)
 }
 
 // write the input file if a json or yaml string is provided
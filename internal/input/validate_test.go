@@ -30,7 +30,6 @@ import (
 
 	"github.com/enterprise-contract/ec-cli/internal/evaluation_target/input"
 	"github.com/enterprise-contract/ec-cli/internal/evaluator"
-	"github.com/enterprise-contract/ec-cli/internal/output"
 	"github.com/enterprise-contract/ec-cli/internal/policy"
 	"github.com/enterprise-contract/ec-cli/internal/utils"
 )
@@ -84,14 +83,14 @@ func Test_ValidatePipeline(t *testing.T) {
 		name    string
 		fpath   string
 		err     error
-		output  *output.Output
+		output  *Output
 		defFunc func(ctx context.Context, fpath []string, policy policy.Policy) (*input.Input, error)
 	}{
 		{
 			name:    "validation succeeds",
 			fpath:   validFile,
 			err:     nil,
-			output:  &output.Output{},
+			output:  &Output{},
 			defFunc: mockNewPipelineDefinitionFile,
 		},
 		{
@@ -119,14 +118,14 @@ func Test_ValidatePipeline(t *testing.T) {
 			name:    "validation succeeds with json input",
 			fpath:   "{\"json\": 1}",
 			err:     nil,
-			output:  &output.Output{},
+			output:  &Output{},
 			defFunc: mockNewPipelineDefinitionFile,
 		},
 		{
 			name:    "validation succeeds with yaml input",
 			fpath:   "kind: task",
 			err:     nil,
-			output:  &output.Output{},
+			output:  &Output{},
 			defFunc: mockNewPipelineDefinitionFile,
 		},
 		{
@@ -158,3 +157,70 @@ func Test_ValidatePipeline(t *testing.T) {
 		})
 	}
 }
+
+// inputsMockEvaluator fails each input file, and succeeds once regardless of
+// the input files
+type inputsMockEvaluator struct{}
+
+func (e inputsMockEvaluator) Evaluate(ctx context.Context, target evaluator.EvaluationTarget) ([]evaluator.Outcome, error) {
+	outcomes := []evaluator.Outcome{{Successes: []evaluator.Result{{Message: "Pass"}}}}
+	for _, f := range target.Inputs {
+		outcomes = append(outcomes, evaluator.Outcome{
+			FileName: f,
+			Failures: []evaluator.Result{{Message: "Fail"}},
+		})
+	}
+
+	return outcomes, nil
+}
+
+func (e inputsMockEvaluator) Destroy() {
+}
+
+func (e inputsMockEvaluator) CapabilitiesPath() string {
+	return ""
+}
+
+func Test_ValidateInputDocuments(t *testing.T) {
+	appFS := afero.NewMemMapFs()
+	assert.NoError(t, afero.WriteFile(appFS, "/dir/multi.yaml", []byte("kind: A\n---\nkind: B\n"), 0644))
+	assert.NoError(t, afero.WriteFile(appFS, "/dir/single.json", []byte(`{"kind": "C"}`), 0644))
+	ctx := utils.WithFS(context.Background(), appFS)
+	p, err := policy.NewInputPolicy(ctx, "", "2023-01-01T00:00:00.00Z")
+	assert.NoError(t, err)
+
+	inputFile = func(ctx context.Context, fpath []string, policy policy.Policy) (*input.Input, error) {
+		return &input.Input{
+			Evaluators: []evaluator.Evaluator{inputsMockEvaluator{}},
+		}, nil
+	}
+
+	type result struct {
+		FileName string
+		Document int
+	}
+	results := func(out *Output) []result {
+		var r []result
+		for _, o := range out.Outcomes {
+			r = append(r, result{o.FileName, o.Document})
+		}
+		return r
+	}
+
+	out, err := ValidateInput(ctx, "/dir", p, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []result{
+		{"", 0},
+		{"/dir/multi.yaml", 1},
+		{"/dir/multi.yaml", 2},
+		{"/dir/single.json", 0},
+	}, results(out))
+
+	inline := `{"kind": "D"}`
+	out, err = ValidateInput(ctx, inline, p, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []result{
+		{"", 0},
+		{inline, 0},
+	}, results(out))
+}
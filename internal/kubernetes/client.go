@@ -18,15 +18,20 @@ package kubernetes
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 
 	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
 	app "github.com/konflux-ci/application-api/api/v1alpha1"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -38,7 +43,11 @@ const clientContextKey contextKey = "ec.kubernetes.client"
 
 type Client interface {
 	FetchEnterpriseContractPolicy(ctx context.Context, ref string) (*ecc.EnterpriseContractPolicy, error)
+	FetchEnterpriseContractPolicySpec(ctx context.Context, ref string) (string, string, error)
 	FetchSnapshot(ctx context.Context, ref string) (*app.Snapshot, error)
+	AnnotateSnapshot(ctx context.Context, ref string, annotations map[string]string) (*app.Snapshot, error)
+	CreateEvent(ctx context.Context, event corev1.Event) error
+	ApplyConfigMap(ctx context.Context, configMap corev1.ConfigMap) error
 }
 
 type kubernetesClient struct {
@@ -98,6 +107,45 @@ func createK8SClient() (client dynamic.Interface, err error) {
 // The reference is expected to be in the format [<namespace>/]<name>. If it does not contain
 // a namespace, the current namespace is used.
 func (k *kubernetesClient) FetchEnterpriseContractPolicy(ctx context.Context, ref string) (*ecc.EnterpriseContractPolicy, error) {
+	unstructuredPolicy, err := k.fetchEnterpriseContractPolicy(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := ecc.EnterpriseContractPolicy{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(unstructuredPolicy.UnstructuredContent(), &policy); err != nil {
+		log.Debugf("Failed to convert unstructured content to concrete policy structure: %s", err)
+		return nil, err
+	}
+
+	log.Debugf("Policy successfully fetched from cluster: %#v", policy)
+
+	return &policy, nil
+}
+
+// FetchEnterpriseContractPolicySpec gets the spec, as JSON, and the resource
+// version of the Enterprise Contract Policy from the given reference in a
+// Kubernetes cluster. Unlike FetchEnterpriseContractPolicy, the spec retains
+// the fields not part of the EnterpriseContractPolicySpec, e.g. the signatures
+// of the policy sources.
+//
+// The reference is expected to be in the format [<namespace>/]<name>. If it does not contain
+// a namespace, the current namespace is used.
+func (k *kubernetesClient) FetchEnterpriseContractPolicySpec(ctx context.Context, ref string) (string, string, error) {
+	unstructuredPolicy, err := k.fetchEnterpriseContractPolicy(ctx, ref)
+	if err != nil {
+		return "", "", err
+	}
+
+	spec, err := json.Marshal(unstructuredPolicy.Object["spec"])
+	if err != nil {
+		return "", "", err
+	}
+
+	return string(spec), unstructuredPolicy.GetResourceVersion(), nil
+}
+
+func (k *kubernetesClient) fetchEnterpriseContractPolicy(ctx context.Context, ref string) (*unstructured.Unstructured, error) {
 	if len(ref) == 0 {
 		return nil, errors.New("policy reference cannot be empty")
 	}
@@ -112,21 +160,13 @@ func (k *kubernetesClient) FetchEnterpriseContractPolicy(ctx context.Context, re
 		return nil, errors.New("unable to determine namespace for policy")
 	}
 
-	var unstructuredPolicy *unstructured.Unstructured
-	if unstructuredPolicy, err = k.client.Resource(ecc.GroupVersion.WithResource("enterprisecontractpolicies")).Namespace(name.Namespace).Get(ctx, name.Name, v1.GetOptions{}); err != nil {
+	unstructuredPolicy, err := k.client.Resource(ecc.GroupVersion.WithResource("enterprisecontractpolicies")).Namespace(name.Namespace).Get(ctx, name.Name, v1.GetOptions{})
+	if err != nil {
 		log.Debugf("Failed to fetch the policy from cluster: %s", err)
 		return nil, err
 	}
 
-	policy := ecc.EnterpriseContractPolicy{}
-	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(unstructuredPolicy.UnstructuredContent(), &policy); err != nil {
-		log.Debugf("Failed to convert unstructured content to concrete policy structure: %s", err)
-		return nil, err
-	}
-
-	log.Debugf("Policy successfully fetched from cluster: %#v", policy)
-
-	return &policy, nil
+	return unstructuredPolicy, nil
 }
 
 // FetchSnapshot gets the AppStudio Snapshot from the given
@@ -165,3 +205,129 @@ func (k *kubernetesClient) FetchSnapshot(ctx context.Context, ref string) (*app.
 
 	return &snapshot, nil
 }
+
+// AnnotateSnapshot sets the annotations on the AppStudio Snapshot from the
+// given reference in a Kubernetes cluster, keeping any other annotations of
+// the Snapshot, and returns the updated Snapshot.
+//
+// The reference is expected to be in the format [<namespace>/]<name>. If it does not contain
+// a namespace, the current namespace is used.
+func (k *kubernetesClient) AnnotateSnapshot(ctx context.Context, ref string, annotations map[string]string) (*app.Snapshot, error) {
+	if len(ref) == 0 {
+		return nil, errors.New("snapshot reference cannot be empty")
+	}
+
+	name, err := NamespacedName(ref)
+	if err != nil {
+		return nil, err
+	}
+	if name.Namespace == "" {
+		return nil, errors.New("unable to determine namespace for snapshot")
+	}
+
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var unstructuredSnapshot *unstructured.Unstructured
+	if unstructuredSnapshot, err = k.client.Resource(app.GroupVersion.WithResource("snapshots")).Namespace(name.Namespace).Patch(ctx, name.Name, types.MergePatchType, patch, v1.PatchOptions{}); err != nil {
+		log.Debugf("Failed to annotate the snapshot in cluster: %s", err)
+		return nil, err
+	}
+
+	snapshot := app.Snapshot{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(unstructuredSnapshot.UnstructuredContent(), &snapshot); err != nil {
+		log.Debugf("Failed to convert unstructured content to concrete snapshot structure: %s", err)
+		return nil, err
+	}
+
+	log.Debugf("Snapshot successfully annotated in cluster: %v", annotations)
+
+	return &snapshot, nil
+}
+
+// CreateEvent creates the core Event in its namespace in a Kubernetes cluster.
+func (k *kubernetesClient) CreateEvent(ctx context.Context, event corev1.Event) error {
+	event.TypeMeta = v1.TypeMeta{APIVersion: "v1", Kind: "Event"}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&event)
+	if err != nil {
+		return err
+	}
+
+	if _, err := k.client.Resource(corev1.SchemeGroupVersion.WithResource("events")).Namespace(event.Namespace).Create(ctx, &unstructured.Unstructured{Object: content}, v1.CreateOptions{}); err != nil {
+		log.Debugf("Failed to create the event in cluster: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// ApplyConfigMap creates the ConfigMap in its namespace in a Kubernetes
+// cluster, or replaces the existing ConfigMap of the same name. To avoid
+// overwriting unrelated data, an existing ConfigMap is only replaced if it has
+// an owner in common with the ConfigMap, or carries all of its labels.
+func (k *kubernetesClient) ApplyConfigMap(ctx context.Context, configMap corev1.ConfigMap) error {
+	configMap.TypeMeta = v1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"}
+	resource := k.client.Resource(corev1.SchemeGroupVersion.WithResource("configmaps")).Namespace(configMap.Namespace)
+
+	existing, err := resource.Get(ctx, configMap.Name, v1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		log.Debugf("Failed to fetch the config map from cluster: %s", err)
+		return err
+	}
+	exists := err == nil
+	if exists {
+		if !sameOwnership(configMap.ObjectMeta, existing) {
+			return fmt.Errorf("the ConfigMap %s/%s already exists and is neither labeled nor owned as the one to store, refusing to replace it", configMap.Namespace, configMap.Name)
+		}
+		configMap.ResourceVersion = existing.GetResourceVersion()
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&configMap)
+	if err != nil {
+		return err
+	}
+	obj := &unstructured.Unstructured{Object: content}
+
+	if exists {
+		_, err = resource.Update(ctx, obj, v1.UpdateOptions{})
+	} else {
+		_, err = resource.Create(ctx, obj, v1.CreateOptions{})
+	}
+	if err != nil {
+		log.Debugf("Failed to apply the config map in cluster: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// sameOwnership returns true if the existing object has an owner in common
+// with the object's metadata, or all of its labels with the same values
+func sameOwnership(meta v1.ObjectMeta, existing *unstructured.Unstructured) bool {
+	for _, o := range meta.OwnerReferences {
+		for _, e := range existing.GetOwnerReferences() {
+			if o.UID != "" && o.UID == e.UID {
+				return true
+			}
+		}
+	}
+
+	if len(meta.Labels) == 0 {
+		return false
+	}
+	labels := existing.GetLabels()
+	for k, v := range meta.Labels {
+		if l, ok := labels[k]; !ok || l != v {
+			return false
+		}
+	}
+
+	return true
+}
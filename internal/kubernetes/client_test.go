@@ -27,7 +27,10 @@ import (
 	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
 	app "github.com/konflux-ci/application-api/api/v1alpha1"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/dynamic/fake"
@@ -155,6 +158,38 @@ func Test_FetchEnterpriseContractPolicy(t *testing.T) {
 	}
 }
 
+func Test_FetchEnterpriseContractPolicySpec(t *testing.T) {
+	policy := unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": ecc.GroupVersion.String(),
+		"kind":       "EnterpriseContractPolicy",
+		"metadata": map[string]any{
+			"name":            "signed",
+			"namespace":       "test",
+			"resourceVersion": "42",
+		},
+		"spec": map[string]any{
+			"sources": []any{
+				map[string]any{
+					"policy":    []any{"oci::registry.io/policy:latest"},
+					"signature": map[string]any{"publicKey": "key"},
+				},
+			},
+		},
+	}}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, ecc.AddToScheme(scheme))
+	k := kubernetesClient{client: fake.NewSimpleDynamicClient(scheme, &policy)}
+
+	spec, resourceVersion, err := k.FetchEnterpriseContractPolicySpec(context.TODO(), "test/signed")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"sources": [{"policy": ["oci::registry.io/policy:latest"], "signature": {"publicKey": "key"}}]}`, spec)
+	assert.Equal(t, "42", resourceVersion)
+
+	_, _, err = k.FetchEnterpriseContractPolicySpec(context.TODO(), "test/missing")
+	assert.ErrorContains(t, err, `enterprisecontractpolicies.appstudio.redhat.com "missing" not found`)
+}
+
 func Test_FailureToCreateClient(t *testing.T) {
 	t.Setenv("KUBECONFIG", "/nonexistant")
 	_, err := createK8SClient()
@@ -213,3 +248,111 @@ func Test_FetchSnapshot(t *testing.T) {
 		})
 	}
 }
+
+func Test_AnnotateSnapshot(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, app.AddToScheme(scheme))
+
+	snapshot := testSnapshot.DeepCopy()
+	snapshot.Annotations = map[string]string{"existing": "annotation"}
+	k := kubernetesClient{
+		client: fake.NewSimpleDynamicClient(scheme, snapshot),
+	}
+
+	got, err := k.AnnotateSnapshot(context.TODO(), "test/snapshot", map[string]string{"enterprisecontract.dev/result": "success"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"existing":                      "annotation",
+		"enterprisecontract.dev/result": "success",
+	}, got.Annotations)
+	assert.Equal(t, testSnapshot.Spec, got.Spec)
+
+	_, err = k.AnnotateSnapshot(context.TODO(), "missing/snapshot", map[string]string{"enterprisecontract.dev/result": "success"})
+	assert.ErrorContains(t, err, `snapshots.appstudio.redhat.com "snapshot" not found`)
+}
+
+func Test_CreateEvent(t *testing.T) {
+	client := fake.NewSimpleDynamicClient(runtime.NewScheme())
+	k := kubernetesClient{
+		client: client,
+	}
+
+	err := k.CreateEvent(context.TODO(), corev1.Event{
+		ObjectMeta: v1.ObjectMeta{Namespace: "test", Name: "snapshot.1"},
+		Reason:     "PolicyViolation",
+		Message:    "Component A violates the policy",
+		Type:       corev1.EventTypeWarning,
+	})
+	assert.NoError(t, err)
+
+	got, err := client.Resource(corev1.SchemeGroupVersion.WithResource("events")).Namespace("test").Get(context.TODO(), "snapshot.1", v1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "Event", got.GetKind())
+	assert.Equal(t, "Component A violates the policy", got.Object["message"])
+}
+
+func Test_ApplyConfigMap(t *testing.T) {
+	unrelated := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]any{"namespace": "test", "name": "unrelated"},
+	}}
+	owned := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]any{
+			"namespace":       "test",
+			"name":            "owned",
+			"ownerReferences": []any{map[string]any{"apiVersion": "v1", "kind": "Snapshot", "name": "snapshot", "uid": "snapshot-uid"}},
+		},
+	}}
+	client := fake.NewSimpleDynamicClient(runtime.NewScheme(), unrelated, owned)
+	k := kubernetesClient{
+		client: client,
+	}
+	resource := client.Resource(corev1.SchemeGroupVersion.WithResource("configmaps")).Namespace("test")
+
+	for _, report := range []string{"first", "second"} {
+		err := k.ApplyConfigMap(context.TODO(), corev1.ConfigMap{
+			ObjectMeta: v1.ObjectMeta{Namespace: "test", Name: "results", Labels: map[string]string{"snapshot": "snapshot"}},
+			Data:       map[string]string{"report.json": report},
+		})
+		assert.NoError(t, err)
+
+		got, err := resource.Get(context.TODO(), "results", v1.GetOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]any{"report.json": report}, got.Object["data"])
+	}
+
+	// the label of a different snapshot isn't the same ownership
+	err := k.ApplyConfigMap(context.TODO(), corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{Namespace: "test", Name: "results", Labels: map[string]string{"snapshot": "other"}},
+		Data:       map[string]string{"report.json": "other"},
+	})
+	assert.EqualError(t, err, "the ConfigMap test/results already exists and is neither labeled nor owned as the one to store, refusing to replace it")
+
+	err = k.ApplyConfigMap(context.TODO(), corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{
+			Namespace:       "test",
+			Name:            "owned",
+			OwnerReferences: []v1.OwnerReference{{APIVersion: "v1", Kind: "Snapshot", Name: "snapshot", UID: "snapshot-uid"}},
+		},
+		Data: map[string]string{"report.json": "owned"},
+	})
+	assert.NoError(t, err)
+
+	err = k.ApplyConfigMap(context.TODO(), corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{
+			Namespace:       "test",
+			Name:            "unrelated",
+			Labels:          map[string]string{"snapshot": "snapshot"},
+			OwnerReferences: []v1.OwnerReference{{APIVersion: "v1", Kind: "Snapshot", Name: "snapshot", UID: "snapshot-uid"}},
+		},
+		Data: map[string]string{"report.json": "unrelated"},
+	})
+	assert.EqualError(t, err, "the ConfigMap test/unrelated already exists and is neither labeled nor owned as the one to store, refusing to replace it")
+
+	got, err := resource.Get(context.TODO(), "unrelated", v1.GetOptions{})
+	assert.NoError(t, err)
+	assert.NotContains(t, got.Object, "data")
+}
@@ -304,7 +304,10 @@ type Info struct {
 	Package          string
 	ShortName        string
 	Solution         string
-	Title            string
+	// Source is the URL of the policy source the rule was loaded from, it is
+	// not set by RuleInfo as it is not known from the annotations alone
+	Source string
+	Title  string
 }
 
 func RuleInfo(a *ast.AnnotationsRef) Info {
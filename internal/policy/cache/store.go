@@ -0,0 +1,689 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	gitMetadata "github.com/conforma/go-gather/gather/git"
+	ociMetadata "github.com/conforma/go-gather/gather/oci"
+	"github.com/conforma/go-gather/metadata"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// DefaultTTL is how long a cached download of a mutable reference, e.g. a
+	// git branch or an image tag, is used before it is fetched again.
+	DefaultTTL = time.Hour
+
+	// DefaultMaxSize is the size, in bytes, the cache is trimmed to once it
+	// grows past it.
+	DefaultMaxSize int64 = 1 << 30
+
+	gitKind = "git"
+	ociKind = "oci"
+
+	entriesDir = "entries"
+	contentDir = "content"
+	tmpDir     = "tmp"
+	lockFile   = ".lock"
+)
+
+// pinnedRef matches source URLs that already point to an immutable revision,
+// i.e. a full git commit SHA or an image digest.
+var pinnedRef = regexp.MustCompile(`(?:[?&]ref=[0-9a-f]{40}(?:&|$))|(?:@sha256:[0-9a-f]{64})`)
+
+// Pinned returns true if the source URL points to an immutable revision.
+func Pinned(url string) bool {
+	return pinnedRef.MatchString(url)
+}
+
+// storeKey is the key for Store values in Context.
+var storeKey = storeKeyType{}
+
+type storeKeyType struct{}
+
+// Store is an on-disk cache of downloaded policy sources shared between ec
+// invocations. Downloads are keyed by the source they resolved to, i.e. the
+// URL pinned to the git commit SHA or image digest, and the source URLs
+// referring to them are tracked in separate entries. Entries for mutable
+// references expire after TTL, and the least recently used content is evicted
+// when the cache grows past MaxSize. Access from multiple processes is
+// coordinated through a file lock within Dir.
+type Store struct {
+	Dir     string
+	TTL     time.Duration
+	MaxSize int64
+}
+
+// Entry describes a cached download of a policy source URL.
+type Entry struct {
+	URL       string    `json:"url"`
+	PinnedURL string    `json:"pinnedUrl"`
+	Kind      string    `json:"kind"`
+	Revision  string    `json:"revision"`
+	Content   string    `json:"content"`
+	Size      int64     `json:"size"`
+	Immutable bool      `json:"immutable"`
+	Fetched   time.Time `json:"fetched"`
+	Used      time.Time `json:"used"`
+}
+
+// Expired returns true if the entry refers to a mutable reference that was
+// fetched longer than ttl before now.
+func (e Entry) Expired(now time.Time, ttl time.Duration) bool {
+	return !e.Immutable && now.Sub(e.Fetched) >= ttl
+}
+
+// DefaultDir returns the directory used for the persistent policy cache: the
+// value of EC_POLICY_CACHE if it holds a path, otherwise the ec/policies
+// directory within the user's cache directory, e.g. $XDG_CACHE_HOME.
+func DefaultDir() (string, error) {
+	if v := os.Getenv("EC_POLICY_CACHE"); v != "" {
+		if _, err := strconv.ParseBool(v); err != nil {
+			return v, nil
+		}
+	}
+
+	userCache, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(userCache, "ec", "policies"), nil
+}
+
+// StoreFromEnvironment returns the persistent Store configured via the
+// environment, or nil if it is not enabled. The cache is opt-in, it is enabled
+// by setting EC_POLICY_CACHE to true or to the directory to use.
+func StoreFromEnvironment() (*Store, error) {
+	v := os.Getenv("EC_POLICY_CACHE")
+	if v == "" {
+		return nil, nil
+	}
+	if enabled, err := strconv.ParseBool(v); err == nil && !enabled {
+		return nil, nil
+	}
+
+	return ConfiguredStore()
+}
+
+// ConfiguredStore returns the Store in DefaultDir regardless of whether the
+// persistent cache is enabled. EC_POLICY_CACHE_TTL and EC_POLICY_CACHE_MAX_SIZE
+// override the DefaultTTL and DefaultMaxSize.
+func ConfiguredStore() (*Store, error) {
+	dir, err := DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store{Dir: dir, TTL: DefaultTTL, MaxSize: DefaultMaxSize}
+
+	if v := os.Getenv("EC_POLICY_CACHE_TTL"); v != "" {
+		if s.TTL, err = time.ParseDuration(v); err != nil {
+			return nil, fmt.Errorf("invalid EC_POLICY_CACHE_TTL value %q: %w", v, err)
+		}
+	}
+
+	if v := os.Getenv("EC_POLICY_CACHE_MAX_SIZE"); v != "" {
+		if s.MaxSize, err = ParseSize(v); err != nil {
+			return nil, fmt.Errorf("invalid EC_POLICY_CACHE_MAX_SIZE value %q: %w", v, err)
+		}
+	}
+
+	return s, nil
+}
+
+// ParseSize parses a size given in bytes or as a quantity with a suffix, e.g.
+// 512Mi or 2G.
+func ParseSize(v string) (int64, error) {
+	q, err := resource.ParseQuantity(v)
+	if err != nil {
+		return 0, err
+	}
+
+	return q.Value(), nil
+}
+
+var environmentStore = sync.OnceValue(func() *Store {
+	s, err := StoreFromEnvironment()
+	if err != nil {
+		log.Warnf("Persistent policy cache disabled: %v", err)
+		return nil
+	}
+
+	if s != nil {
+		log.Debugf("Using %q directory to store policy cache", s.Dir)
+	}
+
+	return s
+})
+
+// StoreFromContext returns the Store from the context, or the one configured
+// via the environment if the context holds none.
+func StoreFromContext(ctx context.Context) (*Store, bool) {
+	if s, ok := ctx.Value(storeKey).(*Store); ok {
+		return s, s != nil
+	}
+
+	s := environmentStore()
+	return s, s != nil
+}
+
+// WithStore returns a new context with the provided Store added. A nil Store
+// disables the persistent cache.
+func WithStore(ctx context.Context, s *Store) context.Context {
+	return context.WithValue(ctx, storeKey, s)
+}
+
+// Fetch places the content of the source URL in dest, within the filesystem
+// fs. Cached content is copied from the store when present and not expired,
+// otherwise download is invoked and the git or OCI sources it downloaded are
+// added to the store. Failures of the store itself are logged and fall back to
+// downloading.
+func (s *Store) Fetch(fs afero.Fs, url, dest string, download func(string) (metadata.Metadata, error)) (metadata.Metadata, error) {
+	m, err := s.lookup(fs, url, dest)
+	if err != nil {
+		log.Debugf("Policy cache lookup for %s failed: %v", url, err)
+		if err := fs.RemoveAll(dest); err != nil {
+			return nil, err
+		}
+	} else if m != nil {
+		log.Debugf("Policy cache hit: %s", url)
+		return m, nil
+	}
+
+	log.Debugf("Policy cache miss: %s", url)
+	m, err = download(dest)
+	if err != nil {
+		return m, err
+	}
+
+	if err := s.store(fs, url, dest, m); err != nil {
+		log.Debugf("Unable to add %s to the policy cache: %v", url, err)
+	}
+
+	return m, nil
+}
+
+func (s *Store) lookup(fs afero.Fs, url, dest string) (metadata.Metadata, error) {
+	unlock, err := s.lock(false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	e, err := s.readEntry(entryName(url))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if e.Expired(now, s.TTL) {
+		log.Debugf("Policy cache entry for %s expired", url)
+		return nil, nil
+	}
+
+	content := filepath.Join(s.Dir, contentDir, e.Content)
+	if _, err := os.Stat(content); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if _, err := copyTree(afero.NewOsFs(), content, fs, dest); err != nil {
+		return nil, err
+	}
+
+	// Recording the use is best-effort and done under the shared lock, so
+	// concurrent lookups of the same entry race to replace it. The race is
+	// tolerated: the entry file is replaced atomically, the exclusive lock keeps
+	// the entry from changing otherwise, and whichever lookup wins only differs
+	// in Used by the time between the lookups.
+	e.Used = now
+	if err := s.writeEntry(e); err != nil {
+		log.Debugf("Unable to update policy cache entry for %s: %v", url, err)
+	}
+
+	var m metadata.Metadata
+	switch e.Kind {
+	case gitKind:
+		m = &gitMetadata.GitMetadata{Path: dest, LatestCommit: e.Revision}
+	case ociKind:
+		m = &ociMetadata.OCIMetadata{Path: dest, Digest: e.Revision}
+	default:
+		return nil, fmt.Errorf("unknown policy cache entry kind %q", e.Kind)
+	}
+
+	return m, nil
+}
+
+func (s *Store) store(fs afero.Fs, url, src string, m metadata.Metadata) error {
+	var kind, revision string
+	switch v := m.(type) {
+	case *gitMetadata.GitMetadata:
+		kind, revision = gitKind, v.LatestCommit
+	case *ociMetadata.OCIMetadata:
+		kind, revision = ociKind, v.Digest
+	default:
+		// Only remote sources resolving to an immutable revision are cached
+		return nil
+	}
+	if revision == "" {
+		return nil
+	}
+
+	pinned, err := m.GetPinnedURL(url)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := s.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	content := entryName(pinned)
+	dst := filepath.Join(s.Dir, contentDir, content)
+	size, err := dirSize(dst)
+	if errors.Is(err, os.ErrNotExist) {
+		if size, err = s.addContent(fs, src, dst); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := s.writeEntry(Entry{
+		URL:       url,
+		PinnedURL: pinned,
+		Kind:      kind,
+		Revision:  revision,
+		Content:   content,
+		Size:      size,
+		Immutable: Pinned(url),
+		Fetched:   now,
+		Used:      now,
+	}); err != nil {
+		return err
+	}
+
+	_, err = s.evict(s.MaxSize, content)
+	return err
+}
+
+// addContent copies the downloaded source into the store, going through a
+// temporary directory so a partially copied source is never visible.
+func (s *Store) addContent(fs afero.Fs, src, dst string) (int64, error) {
+	tmp := filepath.Join(s.Dir, tmpDir)
+	if err := os.MkdirAll(tmp, 0700); err != nil {
+		return 0, err
+	}
+
+	work, err := os.MkdirTemp(tmp, "content-")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(work)
+
+	size, err := copyTree(fs, src, afero.NewOsFs(), filepath.Join(work, "c"))
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return 0, err
+	}
+
+	return size, os.Rename(filepath.Join(work, "c"), dst)
+}
+
+// List returns all entries in the store ordered by URL.
+func (s *Store) List() ([]Entry, error) {
+	unlock, err := s.lock(false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return s.entries()
+}
+
+// Prune removes expired entries, content no longer referenced by any entry
+// and, least recently used first, content exceeding maxSize. The removed
+// entries are returned.
+func (s *Store) Prune(ttl time.Duration, maxSize int64) ([]Entry, error) {
+	unlock, err := s.lock(true)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	entries, err := s.entries()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	removed := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		if !e.Expired(now, ttl) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.Dir, entriesDir, entryName(e.URL)+".json")); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return removed, err
+		}
+		removed = append(removed, e)
+	}
+
+	evicted, err := s.evict(maxSize, "")
+	removed = append(removed, evicted...)
+	if err != nil {
+		return removed, err
+	}
+
+	return removed, os.RemoveAll(filepath.Join(s.Dir, tmpDir))
+}
+
+// Clear removes everything from the store.
+func (s *Store) Clear() error {
+	unlock, err := s.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for _, d := range []string{entriesDir, contentDir, tmpDir} {
+		if err := os.RemoveAll(filepath.Join(s.Dir, d)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// evict removes content not referenced by any entry and, if the remaining
+// content is larger than maxSize, removes the least recently used content
+// together with the entries referring to it. The content named keep is never
+// evicted. Must be called with the exclusive lock held.
+func (s *Store) evict(maxSize int64, keep string) ([]Entry, error) {
+	entries, err := s.entries()
+	if err != nil {
+		return nil, err
+	}
+
+	type content struct {
+		name    string
+		size    int64
+		used    time.Time
+		entries []Entry
+	}
+
+	contents := map[string]*content{}
+	for _, e := range entries {
+		c, ok := contents[e.Content]
+		if !ok {
+			c = &content{name: e.Content, size: e.Size}
+			contents[e.Content] = c
+		}
+		if e.Used.After(c.used) {
+			c.used = e.Used
+		}
+		c.entries = append(c.entries, e)
+	}
+
+	dirs, err := os.ReadDir(filepath.Join(s.Dir, contentDir))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for _, d := range dirs {
+		if _, ok := contents[d.Name()]; !ok && d.Name() != keep {
+			log.Debugf("Removing unreferenced policy cache content %s", d.Name())
+			if err := os.RemoveAll(filepath.Join(s.Dir, contentDir, d.Name())); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	lru := make([]*content, 0, len(contents))
+	var total int64
+	for _, c := range contents {
+		total += c.size
+		lru = append(lru, c)
+	}
+	sort.Slice(lru, func(i, j int) bool {
+		return lru[i].used.Before(lru[j].used)
+	})
+
+	var removed []Entry
+	for _, c := range lru {
+		if total <= maxSize {
+			break
+		}
+		if c.name == keep {
+			continue
+		}
+		log.Debugf("Evicting policy cache content %s (%d bytes)", c.name, c.size)
+		for _, e := range c.entries {
+			if err := os.Remove(filepath.Join(s.Dir, entriesDir, entryName(e.URL)+".json")); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return removed, err
+			}
+			removed = append(removed, e)
+		}
+		if err := os.RemoveAll(filepath.Join(s.Dir, contentDir, c.name)); err != nil {
+			return removed, err
+		}
+		total -= c.size
+	}
+
+	return removed, nil
+}
+
+func (s *Store) entries() ([]Entry, error) {
+	files, err := os.ReadDir(filepath.Join(s.Dir, entriesDir))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(files))
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != ".json" {
+			continue
+		}
+		e, err := s.readEntry(f.Name()[:len(f.Name())-len(".json")])
+		if err != nil {
+			log.Debugf("Ignoring unreadable policy cache entry %s: %v", f.Name(), err)
+			continue
+		}
+		entries = append(entries, e)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].URL < entries[j].URL
+	})
+
+	return entries, nil
+}
+
+func (s *Store) readEntry(name string) (Entry, error) {
+	var e Entry
+	b, err := os.ReadFile(filepath.Join(s.Dir, entriesDir, name+".json"))
+	if err != nil {
+		return e, err
+	}
+
+	return e, json.Unmarshal(b, &e)
+}
+
+// writeEntry atomically replaces the entry file for the entry's URL.
+func (s *Store) writeEntry(e Entry) error {
+	dir := filepath.Join(s.Dir, entriesDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(dir, ".entry-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), filepath.Join(dir, entryName(e.URL)+".json"))
+}
+
+// lock acquires the store lock, shared for reading or exclusive for
+// modifying the store, and returns the function releasing it.
+func (s *Store) lock(exclusive bool) (func(), error) {
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(filepath.Join(s.Dir, lockFile), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockFD(f, exclusive); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("locking policy cache %q: %w", s.Dir, err)
+	}
+
+	return func() {
+		if err := unlockFD(f); err != nil {
+			log.Debugf("Unable to unlock policy cache %q: %v", s.Dir, err)
+		}
+		f.Close()
+	}, nil
+}
+
+func entryName(key string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(key)))
+}
+
+// copyTree copies the src directory from the srcFs filesystem to dst in the
+// dstFs filesystem and returns the number of bytes copied. Symbolic links are
+// recreated when both filesystems support them.
+func copyTree(srcFs afero.Fs, src string, dstFs afero.Fs, dst string) (int64, error) {
+	var size int64
+	err := afero.Walk(srcFs, src, func(p string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir():
+			return dstFs.MkdirAll(target, 0755)
+		case info.Mode()&fs.ModeSymlink != 0:
+			reader, rok := srcFs.(afero.LinkReader)
+			linker, lok := dstFs.(afero.Linker)
+			if !rok || !lok {
+				return fmt.Errorf("unable to copy symbolic link %q", p)
+			}
+			link, err := reader.ReadlinkIfPossible(p)
+			if err != nil {
+				return err
+			}
+			return linker.SymlinkIfPossible(link, target)
+		case !info.Mode().IsRegular():
+			return nil
+		}
+
+		in, err := srcFs.Open(p)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+
+		out, err := dstFs.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode().Perm()|0600)
+		if err != nil {
+			return err
+		}
+
+		n, err := io.Copy(out, in)
+		size += n
+		if err != nil {
+			out.Close()
+			return err
+		}
+
+		return out.Close()
+	})
+
+	return size, err
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+
+	return size, err
+}
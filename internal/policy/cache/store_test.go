@@ -0,0 +1,358 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	fileMetadata "github.com/conforma/go-gather/gather/file"
+	gitMetadata "github.com/conforma/go-gather/gather/git"
+	ociMetadata "github.com/conforma/go-gather/gather/oci"
+	"github.com/conforma/go-gather/metadata"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	commit = "6b0ae8c8f2d1d1f5a2c9d8c4b6fcd2a4a6b8e0f1"
+	digest = "sha256:4e1b7d3c0a2f5e6d9b8c7a6f5e4d3c2b1a0f9e8d7c6b5a4f3e2d1c0b9a8f7e6d"
+)
+
+// fakeDownload returns a download function writing a policy file and counting
+// its invocations.
+func fakeDownload(fs afero.Fs, m metadata.Metadata, count *int) func(string) (metadata.Metadata, error) {
+	return func(dest string) (metadata.Metadata, error) {
+		*count++
+		if err := fs.MkdirAll(dest, 0755); err != nil {
+			return nil, err
+		}
+		return m, afero.WriteFile(fs, filepath.Join(dest, "policy.rego"), []byte("package main"), 0644)
+	}
+}
+
+func TestStoreFetch(t *testing.T) {
+	cases := []struct {
+		name     string
+		url      string
+		metadata metadata.Metadata
+		ttl      time.Duration
+		cached   bool
+		pinned   string
+	}{
+		{
+			name:     "git branch",
+			url:      "git::https://github.com/org/repo//policy?ref=main",
+			metadata: &gitMetadata.GitMetadata{LatestCommit: commit},
+			ttl:      time.Hour,
+			cached:   true,
+			pinned:   "git::github.com/org/repo//policy?ref=" + commit,
+		},
+		{
+			name:     "oci tag",
+			url:      "oci::registry.io/org/policy:latest",
+			metadata: &ociMetadata.OCIMetadata{Digest: digest},
+			ttl:      time.Hour,
+			cached:   true,
+			pinned:   "oci::registry.io/org/policy:latest@" + digest,
+		},
+		{
+			name:     "expired git branch",
+			url:      "git::https://github.com/org/repo//policy?ref=main",
+			metadata: &gitMetadata.GitMetadata{LatestCommit: commit},
+			ttl:      0,
+			cached:   false,
+		},
+		{
+			name:     "pinned git commit",
+			url:      "git::https://github.com/org/repo//policy?ref=" + commit,
+			metadata: &gitMetadata.GitMetadata{LatestCommit: commit},
+			ttl:      0,
+			cached:   true,
+			pinned:   "git::github.com/org/repo//policy?ref=" + commit,
+		},
+		{
+			name:     "local files",
+			url:      "/policy",
+			metadata: &fileMetadata.FSMetadata{Path: "/policy"},
+			ttl:      time.Hour,
+			cached:   false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := &Store{Dir: t.TempDir(), TTL: c.ttl, MaxSize: DefaultMaxSize}
+			fs := afero.NewMemMapFs()
+			count := 0
+			dl := fakeDownload(fs, c.metadata, &count)
+
+			_, err := s.Fetch(fs, c.url, "/work/1", dl)
+			require.NoError(t, err)
+			assert.Equal(t, 1, count)
+
+			m, err := s.Fetch(fs, c.url, "/work/2", dl)
+			require.NoError(t, err)
+
+			b, err := afero.ReadFile(fs, "/work/2/policy.rego")
+			require.NoError(t, err)
+			assert.Equal(t, "package main", string(b))
+
+			if !c.cached {
+				assert.Equal(t, 2, count)
+				return
+			}
+
+			assert.Equal(t, 1, count)
+			pinned, err := m.GetPinnedURL(c.url)
+			require.NoError(t, err)
+			assert.Equal(t, c.pinned, pinned)
+		})
+	}
+}
+
+func TestStoreFetchError(t *testing.T) {
+	s := &Store{Dir: t.TempDir(), TTL: time.Hour, MaxSize: DefaultMaxSize}
+	fs := afero.NewMemMapFs()
+	expected := errors.New("expected")
+
+	_, err := s.Fetch(fs, "git::https://github.com/org/repo", "/work", func(string) (metadata.Metadata, error) {
+		return nil, expected
+	})
+	assert.ErrorIs(t, err, expected)
+
+	entries, err := s.List()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestStoreSharedContent(t *testing.T) {
+	s := &Store{Dir: t.TempDir(), TTL: time.Hour, MaxSize: DefaultMaxSize}
+	fs := afero.NewMemMapFs()
+	count := 0
+	dl := fakeDownload(fs, &gitMetadata.GitMetadata{LatestCommit: commit}, &count)
+
+	_, err := s.Fetch(fs, "git::https://github.com/org/repo?ref=main", "/work/1", dl)
+	require.NoError(t, err)
+	_, err = s.Fetch(fs, "git::github.com/org/repo?ref="+commit, "/work/2", dl)
+	require.NoError(t, err)
+
+	entries, err := s.List()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, entries[0].Content, entries[1].Content)
+	// entries are ordered by URL
+	assert.True(t, entries[0].Immutable)
+	assert.False(t, entries[1].Immutable)
+
+	content, err := os.ReadDir(filepath.Join(s.Dir, contentDir))
+	require.NoError(t, err)
+	assert.Len(t, content, 1)
+}
+
+func TestStorePrune(t *testing.T) {
+	s := &Store{Dir: t.TempDir(), TTL: time.Hour, MaxSize: DefaultMaxSize}
+	fs := afero.NewMemMapFs()
+	count := 0
+
+	urls := []string{
+		"git::https://github.com/org/a?ref=main",
+		"git::https://github.com/org/b?ref=" + commit,
+		"oci::registry.io/org/c:latest",
+	}
+	revisions := []metadata.Metadata{
+		&gitMetadata.GitMetadata{LatestCommit: "a" + commit[1:]},
+		&gitMetadata.GitMetadata{LatestCommit: commit},
+		&ociMetadata.OCIMetadata{Digest: digest},
+	}
+	for i, u := range urls {
+		_, err := s.Fetch(fs, u, filepath.Join("/work", u), fakeDownload(fs, revisions[i], &count))
+		require.NoError(t, err)
+	}
+
+	// nothing has expired and everything fits
+	removed, err := s.Prune(time.Hour, DefaultMaxSize)
+	require.NoError(t, err)
+	assert.Empty(t, removed)
+
+	// mutable references expire, the pinned one is kept
+	removed, err = s.Prune(0, DefaultMaxSize)
+	require.NoError(t, err)
+	require.Len(t, removed, 2)
+	assert.Equal(t, urls[0], removed[0].URL)
+	assert.Equal(t, urls[2], removed[1].URL)
+
+	content, err := os.ReadDir(filepath.Join(s.Dir, contentDir))
+	require.NoError(t, err)
+	assert.Len(t, content, 1)
+
+	// the remaining content does not fit
+	removed, err = s.Prune(time.Hour, 0)
+	require.NoError(t, err)
+	require.Len(t, removed, 1)
+	assert.Equal(t, urls[1], removed[0].URL)
+
+	entries, err := s.List()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestStoreEviction(t *testing.T) {
+	// room for a single policy file
+	s := &Store{Dir: t.TempDir(), TTL: time.Hour, MaxSize: int64(len("package main"))}
+	fs := afero.NewMemMapFs()
+	count := 0
+
+	_, err := s.Fetch(fs, "oci::registry.io/org/a:1", "/work/a", fakeDownload(fs, &ociMetadata.OCIMetadata{Digest: digest}, &count))
+	require.NoError(t, err)
+	_, err = s.Fetch(fs, "git::https://github.com/org/b", "/work/b", fakeDownload(fs, &gitMetadata.GitMetadata{LatestCommit: commit}, &count))
+	require.NoError(t, err)
+
+	entries, err := s.List()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "git::https://github.com/org/b", entries[0].URL)
+}
+
+func TestStoreClear(t *testing.T) {
+	s := &Store{Dir: t.TempDir(), TTL: time.Hour, MaxSize: DefaultMaxSize}
+	fs := afero.NewMemMapFs()
+	count := 0
+
+	_, err := s.Fetch(fs, "git::https://github.com/org/repo", "/work", fakeDownload(fs, &gitMetadata.GitMetadata{LatestCommit: commit}, &count))
+	require.NoError(t, err)
+
+	require.NoError(t, s.Clear())
+
+	entries, err := s.List()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	assert.NoDirExists(t, filepath.Join(s.Dir, contentDir))
+}
+
+func TestStoreConcurrentFetch(t *testing.T) {
+	dir := t.TempDir()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// separate Store instances simulate separate processes
+			s := &Store{Dir: dir, TTL: time.Hour, MaxSize: DefaultMaxSize}
+			fs := afero.NewMemMapFs()
+			count := 0
+			_, err := s.Fetch(fs, "git::https://github.com/org/repo", "/work", fakeDownload(fs, &gitMetadata.GitMetadata{LatestCommit: commit}, &count))
+			assert.NoError(t, err)
+			b, err := afero.ReadFile(fs, "/work/policy.rego")
+			assert.NoError(t, err)
+			assert.Equal(t, "package main", string(b))
+		}()
+	}
+	wg.Wait()
+
+	s := &Store{Dir: dir}
+	entries, err := s.List()
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestStoreFromEnvironment(t *testing.T) {
+	cacheHome := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheHome)
+	t.Setenv("HOME", cacheHome)
+
+	cases := []struct {
+		name     string
+		env      map[string]string
+		expected *Store
+		err      string
+	}{
+		{
+			name: "not enabled",
+		},
+		{
+			name: "disabled",
+			env:  map[string]string{"EC_POLICY_CACHE": "false"},
+		},
+		{
+			name:     "enabled",
+			env:      map[string]string{"EC_POLICY_CACHE": "true"},
+			expected: &Store{Dir: filepath.Join(cacheHome, "ec", "policies"), TTL: DefaultTTL, MaxSize: DefaultMaxSize},
+		},
+		{
+			name: "directory",
+			env: map[string]string{
+				"EC_POLICY_CACHE":          "/some/dir",
+				"EC_POLICY_CACHE_TTL":      "24h",
+				"EC_POLICY_CACHE_MAX_SIZE": "100Mi",
+			},
+			expected: &Store{Dir: "/some/dir", TTL: 24 * time.Hour, MaxSize: 100 * 1024 * 1024},
+		},
+		{
+			name: "invalid ttl",
+			env: map[string]string{
+				"EC_POLICY_CACHE":     "1",
+				"EC_POLICY_CACHE_TTL": "tomorrow",
+			},
+			err: `invalid EC_POLICY_CACHE_TTL value "tomorrow"`,
+		},
+		{
+			name: "invalid size",
+			env: map[string]string{
+				"EC_POLICY_CACHE":          "1",
+				"EC_POLICY_CACHE_MAX_SIZE": "large",
+			},
+			err: `invalid EC_POLICY_CACHE_MAX_SIZE value "large"`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			for _, k := range []string{"EC_POLICY_CACHE", "EC_POLICY_CACHE_TTL", "EC_POLICY_CACHE_MAX_SIZE"} {
+				t.Setenv(k, c.env[k])
+			}
+
+			s, err := StoreFromEnvironment()
+			if c.err != "" {
+				assert.ErrorContains(t, err, c.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, s)
+		})
+	}
+}
+
+func TestStoreFromContext(t *testing.T) {
+	s := &Store{Dir: t.TempDir()}
+	ctx := WithStore(context.Background(), s)
+
+	got, ok := StoreFromContext(ctx)
+	assert.True(t, ok)
+	assert.Same(t, s, got)
+
+	_, ok = StoreFromContext(WithStore(ctx, nil))
+	assert.False(t, ok)
+}
@@ -18,16 +18,28 @@ package policy
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 
 	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
 	app "github.com/konflux-ci/application-api/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type FakeKubernetesClient struct {
-	Policy     ecc.EnterpriseContractPolicySpec
+	Policy ecc.EnterpriseContractPolicySpec
+	// PolicySpec, if set, is the policy spec as stored in the cluster, used
+	// instead of Policy by FetchEnterpriseContractPolicySpec
+	PolicySpec string
 	Snapshot   app.SnapshotSpec
 	FetchError bool
+	// Annotations, Events and ConfigMaps record what was written to the
+	// cluster
+	Annotations map[string]string
+	Events      []corev1.Event
+	ConfigMaps  []corev1.ConfigMap
+	WriteError  bool
 }
 
 func (c *FakeKubernetesClient) FetchEnterpriseContractPolicy(ctx context.Context, ref string) (*ecc.EnterpriseContractPolicy, error) {
@@ -37,9 +49,53 @@ func (c *FakeKubernetesClient) FetchEnterpriseContractPolicy(ctx context.Context
 	return &ecc.EnterpriseContractPolicy{Spec: c.Policy}, nil
 }
 
+func (c *FakeKubernetesClient) FetchEnterpriseContractPolicySpec(ctx context.Context, ref string) (string, string, error) {
+	if c.FetchError {
+		return "", "", errors.New("no fetching for you")
+	}
+	if c.PolicySpec != "" {
+		return c.PolicySpec, "", nil
+	}
+	spec, err := json.Marshal(c.Policy)
+	return string(spec), "", err
+}
+
 func (c *FakeKubernetesClient) FetchSnapshot(ctx context.Context, ref string) (*app.Snapshot, error) {
 	if c.FetchError {
 		return nil, errors.New("no fetching for you")
 	}
 	return &app.Snapshot{Spec: c.Snapshot}, nil
 }
+
+func (c *FakeKubernetesClient) AnnotateSnapshot(ctx context.Context, ref string, annotations map[string]string) (*app.Snapshot, error) {
+	if c.WriteError {
+		return nil, errors.New("no writing for you")
+	}
+	if c.Annotations == nil {
+		c.Annotations = map[string]string{}
+	}
+	for k, v := range annotations {
+		c.Annotations[k] = v
+	}
+	return &app.Snapshot{
+		TypeMeta:   metav1.TypeMeta{APIVersion: app.GroupVersion.String(), Kind: "Snapshot"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: ref, UID: "snapshot-uid", Annotations: c.Annotations},
+		Spec:       c.Snapshot,
+	}, nil
+}
+
+func (c *FakeKubernetesClient) CreateEvent(ctx context.Context, event corev1.Event) error {
+	if c.WriteError {
+		return errors.New("no writing for you")
+	}
+	c.Events = append(c.Events, event)
+	return nil
+}
+
+func (c *FakeKubernetesClient) ApplyConfigMap(ctx context.Context, configMap corev1.ConfigMap) error {
+	if c.WriteError {
+		return errors.New("no writing for you")
+	}
+	c.ConfigMaps = append(c.ConfigMaps, configMap)
+	return nil
+}
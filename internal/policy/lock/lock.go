@@ -0,0 +1,220 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package lock resolves the policy and data sources of a policy to immutable
+// references, i.e. git commit SHAs or image digests, and records them in a
+// lock file so later evaluations use exactly the same policy.
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"sigs.k8s.io/yaml"
+
+	"github.com/enterprise-contract/ec-cli/internal/policy/cache"
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+// Version is the version of the lock file format.
+const Version = 1
+
+// Lock holds the pinned references of every source group of a policy, in the
+// order the source groups appear in the policy.
+type Lock struct {
+	Version int      `json:"version"`
+	Sources []Source `json:"sources"`
+}
+
+// Source holds the pinned references of the policy and data URLs of a source
+// group.
+type Source struct {
+	Name   string `json:"name,omitempty"`
+	Policy []Pin  `json:"policy,omitempty"`
+	Data   []Pin  `json:"data,omitempty"`
+}
+
+// Pin records the immutable reference a source URL resolved to.
+type Pin struct {
+	URL    string `json:"url"`
+	Pinned string `json:"pinned"`
+}
+
+// Resolve fetches every policy and data source of the policy spec and returns
+// the Lock recording the immutable references they resolved to.
+func Resolve(ctx context.Context, spec ecc.EnterpriseContractPolicySpec) (*Lock, error) {
+	fs := utils.FS(ctx)
+	workDir, err := utils.CreateWorkDir(fs)
+	if err != nil {
+		return nil, err
+	}
+	defer utils.CleanupWorkDir(fs, workDir)
+
+	l := Lock{Version: Version, Sources: make([]Source, 0, len(spec.Sources))}
+	for _, s := range spec.Sources {
+		log.Debugf("Resolving policy source group %q", s.Name)
+		policy, err := resolve(ctx, workDir, s.Policy, source.PolicyKind)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := resolve(ctx, workDir, s.Data, source.DataKind)
+		if err != nil {
+			return nil, err
+		}
+
+		l.Sources = append(l.Sources, Source{Name: s.Name, Policy: policy, Data: data})
+	}
+
+	return &l, nil
+}
+
+func resolve(ctx context.Context, workDir string, urls []string, kind source.PolicyType) ([]Pin, error) {
+	if len(urls) == 0 {
+		return nil, nil
+	}
+
+	pins := make([]Pin, 0, len(urls))
+	for _, u := range urls {
+		pinned, err := fetch(ctx, workDir, u, kind)
+		if err != nil {
+			return nil, err
+		}
+		log.Debugf("Resolved %s to %s", u, pinned)
+		pins = append(pins, Pin{URL: u, Pinned: pinned})
+	}
+
+	return pins, nil
+}
+
+// fetch downloads the source URL and returns the URL pinned to the revision
+// that was downloaded.
+func fetch(ctx context.Context, workDir string, u string, kind source.PolicyType) (string, error) {
+	if strings.HasPrefix(u, "data:") {
+		return u, nil
+	}
+
+	s := source.PolicyUrl{Url: u, Kind: kind}
+	if _, err := s.GetPolicy(ctx, workDir, false); err != nil {
+		return "", fmt.Errorf("unable to fetch policy source %q: %w", u, err)
+	}
+
+	return s.PolicyUrl(), nil
+}
+
+// Read reads the lock file at the given path.
+func Read(ctx context.Context, path string) (*Lock, error) {
+	b, err := afero.ReadFile(utils.FS(ctx), path)
+	if err != nil {
+		return nil, err
+	}
+
+	var l Lock
+	if err := yaml.UnmarshalStrict(b, &l); err != nil {
+		return nil, fmt.Errorf("unable to parse policy lock file %q: %w", path, err)
+	}
+
+	if l.Version != Version {
+		return nil, fmt.Errorf("unsupported policy lock file version %d in %q, expected %d", l.Version, path, Version)
+	}
+
+	return &l, nil
+}
+
+// Write writes the lock file to the given path.
+func (l *Lock) Write(ctx context.Context, path string) error {
+	b, err := yaml.Marshal(l)
+	if err != nil {
+		return err
+	}
+
+	return afero.WriteFile(utils.FS(ctx), path, b, 0644)
+}
+
+// Apply returns the policy spec with the policy and data URLs of each source
+// group replaced by their pinned references. Every source must be present in
+// the lock and be pinned to a commit SHA or an image digest, so the sources
+// can't drift from the revisions recorded in the lock.
+func (l *Lock) Apply(_ context.Context, spec ecc.EnterpriseContractPolicySpec) (ecc.EnterpriseContractPolicySpec, error) {
+	if len(spec.Sources) != len(l.Sources) {
+		return spec, fmt.Errorf("the policy has %d source groups but the policy lock has %d", len(spec.Sources), len(l.Sources))
+	}
+
+	sources := make([]ecc.Source, 0, len(spec.Sources))
+	var errs error
+	for i, s := range spec.Sources {
+		locked := l.Sources[i]
+		if s.Name != locked.Name {
+			return spec, fmt.Errorf("policy source group %d is named %q but %q in the policy lock", i, s.Name, locked.Name)
+		}
+
+		policy, err := pin(s.Name, s.Policy, locked.Policy, source.PolicyKind)
+		errs = errors.Join(errs, err)
+
+		data, err := pin(s.Name, s.Data, locked.Data, source.DataKind)
+		errs = errors.Join(errs, err)
+
+		s.Policy = policy
+		s.Data = data
+		sources = append(sources, s)
+	}
+
+	if errs != nil {
+		return spec, errs
+	}
+
+	spec.Sources = sources
+	return spec, nil
+}
+
+// pin returns the pinned references of the source URLs. The pinned references
+// must be immutable, the content fetched for them is then exactly the content
+// the lock was created for.
+func pin(group string, urls []string, pins []Pin, kind source.PolicyType) ([]string, error) {
+	if urls == nil {
+		return nil, nil
+	}
+
+	pinned := make(map[string]string, len(pins))
+	for _, p := range pins {
+		pinned[p.URL] = p.Pinned
+	}
+
+	ret := make([]string, 0, len(urls))
+	var errs error
+	for _, u := range urls {
+		p, ok := pinned[u]
+		if !ok {
+			errs = errors.Join(errs, fmt.Errorf("%s source %q of the policy source group %q is not in the policy lock", kind, u, group))
+			continue
+		}
+
+		if !strings.HasPrefix(p, "data:") && !cache.Pinned(p) {
+			errs = errors.Join(errs, fmt.Errorf("%s source %q of the policy source group %q is locked to %q, which isn't a commit SHA or an image digest", kind, u, group, p))
+			continue
+		}
+
+		ret = append(ret, p)
+	}
+
+	return ret, errs
+}
@@ -0,0 +1,186 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package lock
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	gitMetadata "github.com/conforma/go-gather/gather/git"
+	ociMetadata "github.com/conforma/go-gather/gather/oci"
+	"github.com/conforma/go-gather/metadata"
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+const (
+	commit      = "6b0ae8c8f2d1d1f5a2c9d8c4b6fcd2a4a6b8e0f1"
+	otherCommit = "0f1e2d3c4b5a69788796a5b4c3d2e1f00f1e2d3c"
+	digest      = "sha256:4e1b7d3c0a2f5e6d9b8c7a6f5e4d3c2b1a0f9e8d7c6b5a4f3e2d1c0b9a8f7e6d"
+)
+
+type mockDownloader struct {
+	mock.Mock
+}
+
+func (m *mockDownloader) Download(_ context.Context, dest string, sourceUrl string, showMsg bool) (metadata.Metadata, error) {
+	args := m.Called(dest, sourceUrl, showMsg)
+
+	return args.Get(0).(metadata.Metadata), args.Error(1)
+}
+
+// urls returns source URLs unique to the test, the download cache is shared
+// within the process.
+func urls(t *testing.T) (string, string) {
+	name := strings.ReplaceAll(strings.ToLower(t.Name()), "/", "-")
+	return fmt.Sprintf("git::https://example.com/org/%s//policy?ref=main", name),
+		fmt.Sprintf("oci::registry.io/org/%s-data:latest", name)
+}
+
+func setUp(t *testing.T, revision string) (context.Context, ecc.EnterpriseContractPolicySpec) {
+	policyURL, dataURL := urls(t)
+	ctx := utils.WithFS(context.Background(), afero.NewMemMapFs())
+
+	dl := mockDownloader{}
+	dl.On("Download", mock.Anything, mock.MatchedBy(func(u string) bool {
+		return strings.HasPrefix(u, "git::")
+	}), false).Return(&gitMetadata.GitMetadata{LatestCommit: revision}, nil)
+	dl.On("Download", mock.Anything, mock.MatchedBy(func(u string) bool {
+		return strings.HasPrefix(u, "oci::")
+	}), false).Return(&ociMetadata.OCIMetadata{Digest: digest}, nil)
+	ctx = context.WithValue(ctx, source.DownloaderFuncKey, &dl)
+
+	return ctx, ecc.EnterpriseContractPolicySpec{
+		Sources: []ecc.Source{
+			{
+				Name:   "default",
+				Policy: []string{policyURL},
+				Data:   []string{dataURL, "data:application/json;base64,e30="},
+			},
+		},
+	}
+}
+
+func TestResolve(t *testing.T) {
+	ctx, spec := setUp(t, commit)
+	policyURL, dataURL := urls(t)
+
+	l, err := Resolve(ctx, spec)
+	require.NoError(t, err)
+
+	assert.Equal(t, &Lock{
+		Version: Version,
+		Sources: []Source{
+			{
+				Name: "default",
+				Policy: []Pin{
+					{URL: policyURL, Pinned: "git::" + strings.TrimPrefix(strings.Replace(policyURL, "main", commit, 1), "git::https://")},
+				},
+				Data: []Pin{
+					{URL: dataURL, Pinned: strings.Replace(dataURL, ":latest", ":latest@"+digest, 1)},
+					{URL: "data:application/json;base64,e30=", Pinned: "data:application/json;base64,e30="},
+				},
+			},
+		},
+	}, l)
+}
+
+func TestReadWrite(t *testing.T) {
+	ctx, spec := setUp(t, commit)
+
+	l, err := Resolve(ctx, spec)
+	require.NoError(t, err)
+
+	require.NoError(t, l.Write(ctx, "/policy.lock.yaml"))
+
+	read, err := Read(ctx, "/policy.lock.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, l, read)
+}
+
+func TestReadErrors(t *testing.T) {
+	ctx := utils.WithFS(context.Background(), afero.NewMemMapFs())
+	fs := utils.FS(ctx)
+
+	_, err := Read(ctx, "/missing.yaml")
+	assert.Error(t, err)
+
+	require.NoError(t, afero.WriteFile(fs, "/version.yaml", []byte("version: 2\n"), 0644))
+	_, err = Read(ctx, "/version.yaml")
+	assert.EqualError(t, err, `unsupported policy lock file version 2 in "/version.yaml", expected 1`)
+
+	require.NoError(t, afero.WriteFile(fs, "/unknown.yaml", []byte("version: 1\nextra: true\n"), 0644))
+	_, err = Read(ctx, "/unknown.yaml")
+	assert.ErrorContains(t, err, `unable to parse policy lock file "/unknown.yaml"`)
+}
+
+func TestApply(t *testing.T) {
+	ctx, spec := setUp(t, commit)
+
+	l, err := Resolve(ctx, spec)
+	require.NoError(t, err)
+
+	pinned, err := l.Apply(ctx, spec)
+	require.NoError(t, err)
+
+	require.Len(t, pinned.Sources, 1)
+	assert.Equal(t, []string{l.Sources[0].Policy[0].Pinned}, pinned.Sources[0].Policy)
+	assert.Equal(t, []string{l.Sources[0].Data[0].Pinned, l.Sources[0].Data[1].Pinned}, pinned.Sources[0].Data)
+}
+
+func TestApplyErrors(t *testing.T) {
+	t.Run("mutable", func(t *testing.T) {
+		ctx, spec := setUp(t, commit)
+		policyURL, dataURL := urls(t)
+
+		l := &Lock{
+			Version: Version,
+			Sources: []Source{
+				{
+					Name:   "default",
+					Policy: []Pin{{URL: policyURL, Pinned: "git::example.com/org/pinned//policy?ref=" + commit}},
+					Data:   []Pin{{URL: dataURL, Pinned: dataURL}},
+				},
+			},
+		}
+
+		_, err := l.Apply(ctx, spec)
+		assert.NotContains(t, err.Error(), fmt.Sprintf("policy source %q", policyURL))
+		assert.ErrorContains(t, err, fmt.Sprintf(`data source %q of the policy source group "default" is locked to %q, which isn't a commit SHA or an image digest`, dataURL, dataURL))
+		assert.ErrorContains(t, err, `data source "data:application/json;base64,e30=" of the policy source group "default" is not in the policy lock`)
+	})
+
+	t.Run("source groups", func(t *testing.T) {
+		ctx, spec := setUp(t, commit)
+
+		_, err := (&Lock{Version: Version}).Apply(ctx, spec)
+		assert.EqualError(t, err, "the policy has 1 source groups but the policy lock has 0")
+
+		_, err = (&Lock{Version: Version, Sources: []Source{{Name: "other"}}}).Apply(ctx, spec)
+		assert.EqualError(t, err, `policy source group 0 is named "default" but "other" in the policy lock`)
+	})
+}
@@ -39,6 +39,7 @@ import (
 
 	"github.com/enterprise-contract/ec-cli/internal/kubernetes"
 	"github.com/enterprise-contract/ec-cli/internal/policy/cache"
+	"github.com/enterprise-contract/ec-cli/internal/policy/lock"
 	"github.com/enterprise-contract/ec-cli/internal/policy/source"
 	"github.com/enterprise-contract/ec-cli/internal/utils"
 )
@@ -78,6 +79,7 @@ type Policy interface {
 	Identity() cosign.Identity
 	Keyless() bool
 	SigstoreOpts() (SigstoreOpts, error)
+	PolicySources(sourceGroup int) []source.PolicySource
 }
 
 type policy struct {
@@ -88,6 +90,7 @@ type policy struct {
 	attestationTime *time.Time
 	identity        cosign.Identity
 	ignoreRekor     bool
+	sourceVerifiers []*bundleVerifier
 }
 
 // PublicKeyPEM returns the PublicKey in PEM format.
@@ -149,6 +152,7 @@ type Options struct {
 	EffectiveTime string
 	Identity      cosign.Identity
 	IgnoreRekor   bool
+	PolicyLock    *lock.Lock
 	PolicyRef     string
 	PublicKey     string
 	RekorURL      string
@@ -319,6 +323,10 @@ func (p *policy) loadPolicy(ctx context.Context, policyRef string) error {
 				return fmt.Errorf("policy does not conform to the schema")
 			}
 		}
+
+		if err := p.loadSourceVerifiers(policyRef); err != nil {
+			return err
+		}
 	} else {
 		log.Debug("Read EnterpriseContractPolicy as k8s resource")
 		k8s, err := kubernetes.NewClient(ctx)
@@ -328,16 +336,44 @@ func (p *policy) loadPolicy(ctx context.Context, policyRef string) error {
 		}
 		log.Debug("Initialized Kubernetes client")
 
-		ecp, err := k8s.FetchEnterpriseContractPolicy(ctx, policyRef)
+		// The spec is fetched as stored in the cluster, as the signatures of the
+		// policy sources aren't part of the EnterpriseContractPolicySpec
+		spec, _, err := k8s.FetchEnterpriseContractPolicySpec(ctx, policyRef)
 		if err != nil {
 			log.Debug("Failed to fetch the enterprise contract policy from the cluster!")
 			return fmt.Errorf("unable to fetch EnterpriseContractPolicy: %w", err)
 		}
-		p.EnterpriseContractPolicySpec = ecp.Spec
+		if err := yaml.Unmarshal([]byte(spec), &p.EnterpriseContractPolicySpec); err != nil {
+			return fmt.Errorf("unable to parse EnterpriseContractPolicySpec: %w", err)
+		}
+
+		if err := p.loadSourceVerifiers(spec); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// loadSourceVerifiers creates the verifiers of the policy source groups that
+// have a signature in the policy configuration
+func (p *policy) loadSourceVerifiers(policyConfig string) error {
+	signatures, err := sourceSignatures(policyConfig)
+	if err != nil {
+		return err
+	}
+	for i, signature := range signatures {
+		if signature == nil {
+			continue
+		}
+		if p.sourceVerifiers == nil {
+			p.sourceVerifiers = make([]*bundleVerifier, len(signatures))
+		}
+		p.sourceVerifiers[i] = newBundleVerifier(p, *signature)
+	}
+
+	return nil
+}
+
 // isConformant checks if the given policy conforms to the Enterprise Contract
 // Policy schema. It returns a boolean indicating conformance and an error if any
 // occurred during the validation process.
@@ -534,7 +570,12 @@ func validateIdentity(identity cosign.Identity) error {
 }
 
 func validatePolicyConfig(policyConfig string) error {
-	policySchema, err := jsonschema.CompileString("schema.json", ecc.Schema)
+	schema, err := policySchema()
+	if err != nil {
+		return err
+	}
+
+	policySchema, err := jsonschema.CompileString("schema.json", schema)
 	if err != nil {
 		log.Errorf("Failed to compile schema: %s", err)
 		return err
@@ -579,53 +620,82 @@ func PreProcessPolicy(ctx context.Context, policyOptions Options) (Policy, *cach
 		return nil, nil, err
 	}
 
-	sources := p.Spec().Sources
-	for i, sourceGroup := range sources {
-		log.Debugf("Fetching policy source group '%+v'\n", sourceGroup.Name)
-		policySources := PolicySourcesFrom(sourceGroup)
-
-		fs := utils.FS(ctx)
-		dir, err := utils.CreateWorkDir(fs)
+	if policyOptions.PolicyLock != nil {
+		spec, err := policyOptions.PolicyLock.Apply(ctx, p.Spec())
 		if err != nil {
-			log.Debug("Failed to create work dir!")
 			return nil, nil, err
 		}
+		p = p.WithSpec(spec)
+	}
 
+	fs := utils.FS(ctx)
+	dir, err := utils.CreateWorkDir(fs)
+	if err != nil {
+		log.Debug("Failed to create work dir!")
+		return nil, nil, err
+	}
+
+	// Fetch the sources of all source groups up front and concurrently
+	sources := p.Spec().Sources
+	groups := make([][]source.PolicySource, 0, len(sources))
+	var fetch []source.PolicySource
+	for i := range sources {
+		policySources := p.PolicySources(i)
+		groups = append(groups, policySources)
 		for _, policySource := range policySources {
 			if strings.HasPrefix(policySource.PolicyUrl(), "data:") {
 				continue
 			}
+			fetch = append(fetch, policySource)
+		}
+	}
 
-			destDir, err := policySource.GetPolicy(ctx, dir, false)
-			if err != nil {
-				log.Debugf("Unable to download source from %s!", policySource.PolicyUrl())
-				return nil, nil, err
-			}
-			log.Debugf("Downloaded policy source from %s to %s\n", policySource.PolicyUrl(), destDir)
+	// URLs of the sources before they're pinned by fetching them
+	fetchUrls := make([]string, 0, len(fetch))
+	for _, policySource := range fetch {
+		fetchUrls = append(fetchUrls, policySource.PolicyUrl())
+	}
+
+	destDirs, err := source.FetchAll(ctx, dir, fetch)
+	if err != nil {
+		if !source.FetchErrorsReported(ctx) {
+			return nil, nil, err
+		}
+		// The evaluators of the affected source groups report the failures
+		log.Warnf("Unable to fetch all policy sources: %v", err)
+	}
 
-			url := policySource.PolicyUrl()
+	for i, policySource := range fetch {
+		if destDirs[i] == "" {
+			continue
+		}
 
-			if _, found := policyCache.Get(policySource.PolicyUrl()); !found {
-				log.Debugf("Cache miss for: %s, adding to cache", url)
-				policyCache.Set(url, destDir, nil)
-				pinnedPolicyUrls[policySource.Subdir()] = append(pinnedPolicyUrls[policySource.Subdir()], url)
-				log.Debugf("Added %s to the pinnedPolicyUrls in \"%s\"", url, policySource.Subdir())
-			} else {
-				log.Debugf("Cache hit for: %s", url)
-			}
+		log.Debugf("Downloaded policy source from %s to %s\n", fetchUrls[i], destDirs[i])
+
+		url := policySource.PolicyUrl()
+
+		if _, found := policyCache.Get(url); !found {
+			log.Debugf("Cache miss for: %s, adding to cache", url)
+			policyCache.Set(url, destDirs[i], nil)
+			pinnedPolicyUrls[policySource.Subdir()] = append(pinnedPolicyUrls[policySource.Subdir()], url)
+			log.Debugf("Added %s to the pinnedPolicyUrls in \"%s\"", url, policySource.Subdir())
+		} else {
+			log.Debugf("Cache hit for: %s", url)
 		}
+	}
 
+	for i, sourceGroup := range sources {
 		sources[i] = ecc.Source{
 			Name:           sourceGroup.Name,
-			Policy:         urls(policySources, source.PolicyKind),
-			Data:           urls(policySources, source.DataKind),
+			Policy:         urls(groups[i], source.PolicyKind),
+			Data:           urls(groups[i], source.DataKind),
 			RuleData:       sourceGroup.RuleData,
 			Config:         sourceGroup.Config,
 			VolatileConfig: sourceGroup.VolatileConfig,
 		}
 	}
 
-	return p, policyCache, err
+	return p, policyCache, nil
 }
 
 func urls(s []source.PolicySource, kind source.PolicyType) []string {
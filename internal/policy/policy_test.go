@@ -689,6 +689,18 @@ func TestIsConformant(t *testing.T) {
 			expectPass: false,
 			expectErr:  true,
 		},
+		{
+			name:       "valid policy with source signature",
+			policyRef:  `{"sources": [{"policy": ["oci::registry.io/policy:latest"], "signature": {"publicKey": "test-key"}}]}`,
+			expectPass: true,
+			expectErr:  false,
+		},
+		{
+			name:       "invalid source signature",
+			policyRef:  `{"sources": [{"policy": ["oci::registry.io/policy:latest"], "signature": {"key": "test-key"}}]}`,
+			expectPass: false,
+			expectErr:  true,
+		},
 		{
 			name:       "invalid YAML",
 			policyRef:  `invalid-yaml`,
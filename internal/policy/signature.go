@@ -0,0 +1,216 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"sigs.k8s.io/yaml"
+
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
+)
+
+// SourceSignature is the signature required on the OCI policy and data bundles
+// of a source group. The bundles are either signed with the private key
+// matching the public key, or signed using the keyless workflow by the given
+// identity.
+type SourceSignature struct {
+	PublicKey string        `json:"publicKey,omitempty"`
+	Identity  *ecc.Identity `json:"identity,omitempty"`
+}
+
+// sourceSignatureSchema describes the signature property added to the source
+// groups in the policy configuration
+const sourceSignatureSchema = `{
+	"type": "object",
+	"additionalProperties": false,
+	"description": "Signature required on the OCI policy and data bundles of the source group",
+	"properties": {
+		"publicKey": {
+			"type": "string",
+			"description": "Public key used to verify the signature of the bundles"
+		},
+		"identity": {
+			"$ref": "#/$defs/Identity",
+			"description": "Identity of the keyless signature of the bundles"
+		}
+	}
+}`
+
+// policySchema returns the Enterprise Contract Policy schema extended with the
+// signature property of the source groups
+var policySchema = sync.OnceValues(func() (string, error) {
+	var schema map[string]any
+	if err := json.Unmarshal([]byte(ecc.Schema), &schema); err != nil {
+		return "", err
+	}
+
+	var signature map[string]any
+	if err := json.Unmarshal([]byte(sourceSignatureSchema), &signature); err != nil {
+		return "", err
+	}
+
+	defs, _ := schema["$defs"].(map[string]any)
+	sourceDef, _ := defs["Source"].(map[string]any)
+	properties, ok := sourceDef["properties"].(map[string]any)
+	if !ok {
+		return "", fmt.Errorf("no source properties found in the policy schema")
+	}
+	properties["signature"] = signature
+
+	extended, err := json.Marshal(schema)
+	if err != nil {
+		return "", err
+	}
+
+	return string(extended), nil
+})
+
+// sourceSignatures parses the signatures required by the source groups of the
+// given YAML or JSON policy configuration. The returned slice has an entry for
+// each source group, nil if the source group doesn't require a signature.
+func sourceSignatures(policyConfig string) ([]*SourceSignature, error) {
+	type sourceGroup struct {
+		Signature *SourceSignature `json:"signature,omitempty"`
+	}
+	type spec struct {
+		Sources []sourceGroup `json:"sources,omitempty"`
+	}
+	var config struct {
+		spec
+		Spec *spec `json:"spec,omitempty"`
+	}
+
+	if err := yaml.Unmarshal([]byte(policyConfig), &config); err != nil {
+		return nil, err
+	}
+
+	sources := config.Sources
+	if config.Spec != nil {
+		sources = config.Spec.Sources
+	}
+
+	signatures := make([]*SourceSignature, 0, len(sources))
+	for i, s := range sources {
+		if s.Signature != nil {
+			if err := s.Signature.validate(); err != nil {
+				return nil, fmt.Errorf("invalid signature of the source group at index %d: %w", i, err)
+			}
+		}
+		signatures = append(signatures, s.Signature)
+	}
+
+	return signatures, nil
+}
+
+func (s SourceSignature) validate() error {
+	if s.PublicKey != "" {
+		return nil
+	}
+
+	if s.Identity == nil {
+		return fmt.Errorf("either a public key or an identity must be provided")
+	}
+
+	return validateIdentity(s.cosignIdentity())
+}
+
+func (s SourceSignature) cosignIdentity() cosign.Identity {
+	if s.Identity == nil {
+		return cosign.Identity{}
+	}
+
+	return cosign.Identity{
+		Issuer:        s.Identity.Issuer,
+		Subject:       s.Identity.Subject,
+		IssuerRegExp:  s.Identity.IssuerRegExp,
+		SubjectRegExp: s.Identity.SubjectRegExp,
+	}
+}
+
+// bundleVerifier verifies the signatures of the OCI bundles of a source group,
+// the Rekor configuration is taken from the policy. The outcome of each
+// verification is remembered so that a bundle fetched by several evaluators is
+// verified only once.
+type bundleVerifier struct {
+	policy    *policy
+	signature SourceSignature
+	once      sync.Once
+	checkOpts *cosign.CheckOpts
+	err       error
+	verified  sync.Map
+}
+
+func newBundleVerifier(p *policy, signature SourceSignature) *bundleVerifier {
+	return &bundleVerifier{policy: p, signature: signature}
+}
+
+func (v *bundleVerifier) VerifyBundle(ctx context.Context, ref name.Digest) error {
+	verify, _ := v.verified.LoadOrStore(ref.String(), sync.OnceValue(func() error {
+		opts, err := v.opts(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, _, err = oci.NewClient(ctx).VerifyImageSignatures(ref, opts)
+		return err
+	}))
+
+	return verify.(func() error)()
+}
+
+func (v *bundleVerifier) opts(ctx context.Context) (*cosign.CheckOpts, error) {
+	v.once.Do(func() {
+		p := policy{
+			EnterpriseContractPolicySpec: ecc.EnterpriseContractPolicySpec{
+				PublicKey: v.signature.PublicKey,
+				RekorUrl:  v.policy.RekorUrl,
+			},
+			identity:    v.signature.cosignIdentity(),
+			ignoreRekor: v.policy.ignoreRekor,
+		}
+		v.checkOpts, v.err = checkOpts(ctx, &p)
+	})
+
+	return v.checkOpts, v.err
+}
+
+// PolicySources returns the policy sources of the source group at the given
+// index. If the source group requires a signature, the signatures of the OCI
+// bundles of the sources are verified when the sources are fetched.
+func (p *policy) PolicySources(sourceGroup int) []source.PolicySource {
+	sources := PolicySourcesFrom(p.Sources[sourceGroup])
+
+	if sourceGroup >= len(p.sourceVerifiers) || p.sourceVerifiers[sourceGroup] == nil {
+		return sources
+	}
+
+	for _, s := range sources {
+		if u, ok := s.(*source.PolicyUrl); ok {
+			u.Verifier = p.sourceVerifiers[sourceGroup]
+		}
+	}
+
+	return sources
+}
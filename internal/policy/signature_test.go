@@ -0,0 +1,179 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package policy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/kubernetes"
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci/fake"
+)
+
+func TestSourceSignatures(t *testing.T) {
+	cases := []struct {
+		name     string
+		config   string
+		expected []*SourceSignature
+		err      string
+	}{
+		{
+			name:     "no sources",
+			config:   `{"publicKey": "test-key"}`,
+			expected: []*SourceSignature{},
+		},
+		{
+			name: "spec",
+			config: `
+sources:
+  - policy: ["oci::registry.io/policy:latest"]
+    signature:
+      publicKey: test-key
+  - policy: ["oci::registry.io/other:latest"]
+`,
+			expected: []*SourceSignature{{PublicKey: "test-key"}, nil},
+		},
+		{
+			name: "resource",
+			config: `
+apiVersion: appstudio.redhat.com/v1alpha1
+kind: EnterpriseContractPolicy
+spec:
+  sources:
+    - policy: ["oci::registry.io/policy:latest"]
+      signature:
+        identity:
+          subject: https://github.com/org/policy/.github/workflows/release.yaml@refs/heads/main
+          issuer: https://token.actions.githubusercontent.com
+`,
+			expected: []*SourceSignature{{Identity: &ecc.Identity{
+				Subject: "https://github.com/org/policy/.github/workflows/release.yaml@refs/heads/main",
+				Issuer:  "https://token.actions.githubusercontent.com",
+			}}},
+		},
+		{
+			name:   "no key or identity",
+			config: `{"sources": [{"policy": ["oci::registry.io/policy:latest"], "signature": {}}]}`,
+			err:    "invalid signature of the source group at index 0: either a public key or an identity must be provided",
+		},
+		{
+			name:   "incomplete identity",
+			config: `{"sources": [{"policy": ["oci::registry.io/policy:latest"], "signature": {"identity": {"subject": "me"}}}]}`,
+			err:    "invalid signature of the source group at index 0: certificate OIDC issuer must be provided for keyless workflow",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			signatures, err := sourceSignatures(c.config)
+			if c.err != "" {
+				assert.EqualError(t, err, c.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, signatures)
+		})
+	}
+}
+
+func TestPolicySourcesVerifySignature(t *testing.T) {
+	config := `{"sources": [
+		{"name": "signed", "policy": ["oci::registry.io/policy:latest"], "data": ["oci::registry.io/data:latest"], "signature": {"publicKey": ` + toJson(utils.TestPublicKey) + `}},
+		{"name": "unsigned", "policy": ["oci::registry.io/other:latest"]}
+	]}`
+
+	p, err := NewPolicy(context.Background(), Options{
+		PolicyRef:     config,
+		PublicKey:     utils.TestPublicKey,
+		IgnoreRekor:   true,
+		EffectiveTime: Now,
+	})
+	require.NoError(t, err)
+
+	signed := p.PolicySources(0)
+	require.Len(t, signed, 2)
+	var verifier source.BundleVerifier
+	for _, s := range signed {
+		u, ok := s.(*source.PolicyUrl)
+		require.True(t, ok)
+		require.NotNil(t, u.Verifier)
+		verifier = u.Verifier
+	}
+
+	unsigned := p.PolicySources(1)
+	require.Len(t, unsigned, 1)
+	assert.Nil(t, unsigned[0].(*source.PolicyUrl).Verifier)
+
+	valid := name.MustParseReference("registry.io/policy@sha256:4e388ab32b10dc8dbc7e28144f552830adc74787c1e2c0824032078a79f227fb").(name.Digest)
+	invalid := name.MustParseReference("registry.io/data@sha256:4e388ab32b10dc8dbc7e28144f552830adc74787c1e2c0824032078a79f227fb").(name.Digest)
+
+	client := fake.FakeClient{}
+	client.On("VerifyImageSignatures", valid, mock.MatchedBy(func(opts *cosign.CheckOpts) bool {
+		return opts.SigVerifier != nil && opts.IgnoreTlog
+	})).Return(nil, true, nil).Once()
+	client.On("VerifyImageSignatures", invalid, mock.Anything).Return(nil, false, errors.New("no matching signatures")).Once()
+	ctx := oci.WithClient(context.Background(), &client)
+
+	assert.NoError(t, verifier.VerifyBundle(ctx, valid))
+	assert.EqualError(t, verifier.VerifyBundle(ctx, invalid), "no matching signatures")
+
+	// the outcome of the verification is reused
+	assert.NoError(t, verifier.VerifyBundle(ctx, valid))
+	assert.EqualError(t, verifier.VerifyBundle(ctx, invalid), "no matching signatures")
+
+	client.AssertExpectations(t)
+}
+
+func TestPolicySourcesSignatureFromCluster(t *testing.T) {
+	spec := `{"sources": [
+		{"name": "signed", "policy": ["oci::registry.io/policy:latest"], "signature": {"publicKey": ` + toJson(utils.TestPublicKey) + `}},
+		{"name": "unsigned", "policy": ["oci::registry.io/other:latest"]}
+	]}`
+
+	ctx := kubernetes.WithClient(context.Background(), &FakeKubernetesClient{PolicySpec: spec})
+	p, err := NewPolicy(ctx, Options{
+		PolicyRef:     "ec-policy",
+		PublicKey:     utils.TestPublicKey,
+		IgnoreRekor:   true,
+		EffectiveTime: Now,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "signed", p.Spec().Sources[0].Name)
+
+	signed := p.PolicySources(0)
+	require.Len(t, signed, 1)
+	assert.NotNil(t, signed[0].(*source.PolicyUrl).Verifier)
+
+	unsigned := p.PolicySources(1)
+	require.Len(t, unsigned, 1)
+	assert.Nil(t, unsigned[0].(*source.PolicyUrl).Verifier)
+}
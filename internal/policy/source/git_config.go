@@ -24,6 +24,7 @@ package source
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/conforma/go-gather/detector"
 	log "github.com/sirupsen/logrus"
@@ -36,6 +37,20 @@ func SourceIsFile(src string) bool {
 	return detector.FileDetector(src)
 }
 
+// LocalPath returns the path on the local file system of the src if it looks
+// like a file path, e.g. "./policy" or "file::/path/to/policy".
+func LocalPath(src string) (string, bool) {
+	if !SourceIsFile(src) {
+		return "", false
+	}
+
+	for _, prefix := range []string{"file://", "file::"} {
+		src = strings.TrimPrefix(src, prefix)
+	}
+
+	return src, true
+}
+
 // SourceIsGit returns true if go-getter thinks the src looks like a git url
 func SourceIsGit(src string) bool {
 	return detector.GitDetector(src)
@@ -47,6 +47,28 @@ func TestSourceIsFile(t *testing.T) {
 	}
 }
 
+func TestLocalPath(t *testing.T) {
+	tests := []struct {
+		src   string
+		path  string
+		local bool
+	}{
+		{src: "", local: false},
+		{src: "github.com/foo/bar", local: false},
+		{src: "oci::registry.io/policy:latest", local: false},
+		{src: "./policy", path: "./policy", local: true},
+		{src: "/path/to/policy", path: "/path/to/policy", local: true},
+		{src: "file::/path/to/policy", path: "/path/to/policy", local: true},
+		{src: "file:///path/to/policy", path: "/path/to/policy", local: true},
+	}
+
+	for _, tt := range tests {
+		path, local := LocalPath(tt.src)
+		assert.Equal(t, tt.local, local, "LocalPath(%s)", tt.src)
+		assert.Equal(t, tt.path, path, "LocalPath(%s)", tt.src)
+	}
+}
+
 func TestSourceIsGit(t *testing.T) {
 	tests := []struct {
 		src  string
@@ -25,10 +25,14 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"os"
 	"path"
 	"path/filepath"
 	"runtime/trace"
+	"strconv"
+	"strings"
 	"sync"
 
 	fileMetadata "github.com/conforma/go-gather/gather/file"
@@ -36,10 +40,13 @@ import (
 	ociMetadata "github.com/conforma/go-gather/gather/oci"
 	"github.com/conforma/go-gather/metadata"
 	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	"github.com/google/go-containerregistry/pkg/name"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/enterprise-contract/ec-cli/internal/downloader"
+	"github.com/enterprise-contract/ec-cli/internal/policy/cache"
 	"github.com/enterprise-contract/ec-cli/internal/utils"
 )
 
@@ -50,10 +57,15 @@ type (
 
 const (
 	DownloaderFuncKey key        = 0
+	fetchErrorsKey    key        = 1
+	downloadsKey      key        = 2
 	PolicyKind        PolicyType = "policy"
 	DataKind          PolicyType = "data"
 	ConfigKind        PolicyType = "config"
 	InlineDataKind    PolicyType = "inline-data"
+
+	workersEnvVar  = "POLICY_SOURCE_WORKERS"
+	defaultWorkers = 5
 )
 
 type downloaderFunc interface {
@@ -73,6 +85,31 @@ type PolicyUrl struct {
 	// A string containing a go-getter style source url compatible with conftest pull
 	Url  string
 	Kind PolicyType
+	// Verifier, if set, verifies the signature of the OCI bundle of the source
+	// before it is used
+	Verifier BundleVerifier
+	verified bool
+}
+
+// BundleVerifier verifies the signature of an OCI policy or data bundle given
+// its digest reference.
+type BundleVerifier interface {
+	VerifyBundle(ctx context.Context, ref name.Digest) error
+}
+
+// SignatureError is returned when the signature of a policy source can't be
+// verified.
+type SignatureError struct {
+	Url string
+	Err error
+}
+
+func (e SignatureError) Error() string {
+	return fmt.Sprintf("unable to verify the signature of the policy source %q: %v", e.Url, e.Err)
+}
+
+func (e SignatureError) Unwrap() error {
+	return e.Err
 }
 
 // downloadCache is a concurrent map used to cache downloaded files.
@@ -84,6 +121,16 @@ type cacheContent struct {
 	err       error
 }
 
+// downloadCacheKey returns the key of the source in the download cache. Sources
+// with a signature to verify are downloaded separately from the same sources
+// without one, as those might be restored from the persistent policy cache.
+func downloadCacheKey(s PolicySource) string {
+	if p, ok := s.(*PolicyUrl); ok && p.Verifier != nil {
+		return "signed:" + s.PolicyUrl()
+	}
+	return s.PolicyUrl()
+}
+
 func getPolicyThroughCache(ctx context.Context, s PolicySource, workDir string, dl func(string, string) (metadata.Metadata, error)) (string, metadata.Metadata, error) {
 	sourceUrl := s.PolicyUrl()
 	dest := uniqueDestination(workDir, s.Subdir(), sourceUrl)
@@ -91,7 +138,11 @@ func getPolicyThroughCache(ctx context.Context, s PolicySource, workDir string,
 	// Load or store the downloaded policy file from the given source URL.
 	// If the file is already in the download cache, it is loaded from there.
 	// Otherwise, it is downloaded from the source URL and stored in the cache.
-	dfn, _ := downloadCache.LoadOrStore(sourceUrl, sync.OnceValues(func() (string, cacheContent) {
+	key := downloadCacheKey(s)
+	if d, ok := ctx.Value(downloadsKey).(*Downloads); ok {
+		d.add(key)
+	}
+	dfn, _ := downloadCache.LoadOrStore(key, sync.OnceValues(func() (string, cacheContent) {
 		log.Debugf("Download cache miss: %s", sourceUrl)
 		// Checkout policy repo into work directory.
 		log.Debugf("Downloading policy files from source url %s to destination %s", sourceUrl, dest)
@@ -139,6 +190,51 @@ func getPolicyThroughCache(ctx context.Context, s PolicySource, workDir string,
 	return d, c.metadata, c.err
 }
 
+// ClearDownloadCache forgets the downloaded policy sources, so that they're
+// downloaded again the next time they're fetched. Directories the sources were
+// already downloaded to are left as they are.
+func ClearDownloadCache() {
+	downloadCache.Range(func(key, _ any) bool {
+		downloadCache.Delete(key)
+		return true
+	})
+}
+
+// Downloads records the policy sources fetched using a context created by
+// WithDownloads.
+type Downloads struct {
+	mu   sync.Mutex
+	keys map[string]bool
+}
+
+// WithDownloads returns a context recording the policy sources fetched using it
+// in downloads.
+func WithDownloads(ctx context.Context, downloads *Downloads) context.Context {
+	return context.WithValue(ctx, downloadsKey, downloads)
+}
+
+func (d *Downloads) add(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.keys == nil {
+		d.keys = map[string]bool{}
+	}
+	d.keys[key] = true
+}
+
+// Forget forgets the recorded policy sources, as ClearDownloadCache does for
+// all policy sources, leaving the other policy sources in the download cache.
+func (d *Downloads) Forget() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for key := range d.keys {
+		downloadCache.Delete(key)
+	}
+	d.keys = nil
+}
+
 // GetPolicies clones the repository for a given PolicyUrl
 func (p *PolicyUrl) GetPolicy(ctx context.Context, workDir string, showMsg bool) (string, error) {
 	if trace.IsEnabled() {
@@ -148,27 +244,89 @@ func (p *PolicyUrl) GetPolicy(ctx context.Context, workDir string, showMsg bool)
 	}
 
 	dl := func(source string, dest string) (metadata.Metadata, error) {
-		x := ctx.Value(DownloaderFuncKey)
-		if dl, ok := x.(downloaderFunc); ok {
-			return dl.Download(ctx, dest, source, showMsg)
+		download := func(dest string) (metadata.Metadata, error) {
+			x := ctx.Value(DownloaderFuncKey)
+			if dl, ok := x.(downloaderFunc); ok {
+				return dl.Download(ctx, dest, source, showMsg)
+			}
+			return downloader.Download(ctx, dest, source, showMsg)
+		}
+
+		// Sources with a signature to verify bypass the persistent cache because
+		// cached content is not re-verified
+		if store, ok := cache.StoreFromContext(ctx); ok && p.Verifier == nil {
+			return store.Fetch(utils.FS(ctx), source, dest, download)
 		}
-		return downloader.Download(ctx, dest, source, showMsg)
+		return download(dest)
 	}
 
+	key := downloadCacheKey(p)
 	dest, metadata, err := getPolicyThroughCache(ctx, p, workDir, dl)
 	if err != nil {
 		return "", err
 	}
 
+	// The signature is verified on every fetch, the content might have been
+	// downloaded for a source that doesn't require a signature
+	if p.Verifier != nil {
+		if err := verifyBundle(ctx, p.Verifier, p.Url, metadata); err != nil {
+			return "", err
+		}
+		p.verified = true
+	}
+
+	sourceUrl := p.Url
 	p.Url, err = metadata.GetPinnedURL(p.Url)
 	log.Debug("Pinned URL: ", p.Url)
 	if err != nil {
 		return "", err
 	}
 
+	// The pinned URL refers to the same content, make sure fetching it later
+	// on, e.g. from the policy with its sources pinned, is a cache hit
+	if p.Url != sourceUrl {
+		if dfn, ok := downloadCache.Load(key); ok {
+			downloadCache.LoadOrStore(downloadCacheKey(p), dfn)
+		}
+	}
+
 	return dest, err
 }
 
+// verifyBundle verifies the signature of the OCI bundle described by the
+// metadata, downloaded from sourceUrl, using the verifier. Sources other than
+// OCI bundles can't be signed and fail the verification.
+func verifyBundle(ctx context.Context, verifier BundleVerifier, sourceUrl string, m metadata.Metadata) error {
+	oci, ok := m.(*ociMetadata.OCIMetadata)
+	if !ok {
+		return SignatureError{Url: sourceUrl, Err: errors.New("only OCI bundles can be signed")}
+	}
+
+	u := sourceUrl
+	for _, scheme := range []string{"oci::", "oci://", "https://"} {
+		u = strings.TrimPrefix(u, scheme)
+	}
+	u, _, _ = strings.Cut(u, "@")
+
+	ref, err := name.ParseReference(u)
+	if err != nil {
+		return SignatureError{Url: sourceUrl, Err: err}
+	}
+
+	if err := verifier.VerifyBundle(ctx, ref.Context().Digest(oci.Digest)); err != nil {
+		return SignatureError{Url: sourceUrl, Err: err}
+	}
+
+	log.Debugf("Verified the signature of the policy source %s", sourceUrl)
+	return nil
+}
+
+// SignatureVerified returns true if the source was fetched and the signature
+// of its OCI bundle was verified.
+func (p *PolicyUrl) SignatureVerified() bool {
+	return p.verified
+}
+
 func (p *PolicyUrl) PolicyUrl() string {
 	return p.Url
 }
@@ -247,6 +405,79 @@ func (inlineData) Type() PolicyType {
 	return InlineDataKind
 }
 
+// WithFetchErrorsReported returns a context in which a policy source that
+// can't be fetched doesn't abort the validation, instead the failure is
+// reported as a violation of its source group and the remaining source groups
+// are still evaluated.
+func WithFetchErrorsReported(ctx context.Context) context.Context {
+	return context.WithValue(ctx, fetchErrorsKey, true)
+}
+
+// FetchErrorsReported returns true if the context was created using
+// WithFetchErrorsReported.
+func FetchErrorsReported(ctx context.Context) bool {
+	reported, ok := ctx.Value(fetchErrorsKey).(bool)
+	return ok && reported
+}
+
+// FetchAll downloads the given policy sources into workDir concurrently and
+// returns the directory of each source in the same order. Sources with the
+// same URL are downloaded only once, and at most POLICY_SOURCE_WORKERS, by
+// default 5, downloads are performed at the same time. If any of the sources
+// fails to download, the errors of all failed sources are returned along with
+// the directories of the sources that were downloaded, the directory of a
+// failed source is left empty.
+func FetchAll(ctx context.Context, workDir string, sources []PolicySource) ([]string, error) {
+	if trace.IsEnabled() {
+		region := trace.StartRegion(ctx, "ec:fetch-policy-sources")
+		defer region.End()
+	}
+
+	byUrl := map[string][]int{}
+	urls := make([]string, 0, len(sources))
+	for i, s := range sources {
+		u := s.PolicyUrl()
+		if _, ok := byUrl[u]; !ok {
+			urls = append(urls, u)
+		}
+		byUrl[u] = append(byUrl[u], i)
+	}
+
+	dirs := make([]string, len(sources))
+	errs := make([]error, len(urls))
+	g, _ := errgroup.WithContext(ctx)
+	g.SetLimit(downloadWorkers())
+	for i, u := range urls {
+		g.Go(func() error {
+			// Sources sharing the URL are fetched by the same worker, all but
+			// the first are served from the download cache
+			for _, j := range byUrl[u] {
+				dir, err := sources[j].GetPolicy(ctx, workDir, false)
+				if err != nil {
+					log.Debugf("Unable to download source from %s!", u)
+					errs[i] = fmt.Errorf("unable to fetch policy source %q: %w", u, err)
+					return nil
+				}
+				dirs[j] = dir
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	return dirs, errors.Join(errs...)
+}
+
+func downloadWorkers() int {
+	workers := defaultWorkers
+	if value, exists := os.LookupEnv(workersEnvVar); exists {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+			workers = parsed
+		}
+	}
+	return workers
+}
+
 // PolicySourcesFrom returns an array of policy sources
 func PolicySourcesFrom(s ecc.Source) []PolicySource {
 	policySources := make([]PolicySource, 0, len(s.Policy)+len(s.Data))
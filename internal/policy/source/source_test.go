@@ -26,17 +26,23 @@ import (
 	"path/filepath"
 	"regexp"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	fileMetadata "github.com/conforma/go-gather/gather/file"
+	gitMetadata "github.com/conforma/go-gather/gather/git"
+	ociMetadata "github.com/conforma/go-gather/gather/oci"
 	"github.com/conforma/go-gather/metadata"
 	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 
+	"github.com/enterprise-contract/ec-cli/internal/policy/cache"
 	"github.com/enterprise-contract/ec-cli/internal/utils"
 )
 
@@ -303,3 +309,268 @@ func TestDownloadCacheWorkdirMismatch(t *testing.T) {
 
 	assert.Equal(t, destination1, destination2)
 }
+
+func TestGetPolicyThroughPersistentCache(t *testing.T) {
+	t.Cleanup(func() {
+		downloadCache = sync.Map{}
+	})
+
+	fs := afero.NewMemMapFs()
+	store := &cache.Store{Dir: t.TempDir(), TTL: time.Hour, MaxSize: cache.DefaultMaxSize}
+	ctx := cache.WithStore(utils.WithFS(context.Background(), fs), store)
+
+	sourceUrl := "git::https://example.com/user/persistent.git?ref=main"
+	commit := "6b0ae8c8f2d1d1f5a2c9d8c4b6fcd2a4a6b8e0f1"
+
+	dl := mockDownloader{}
+	dl.On("Download", mock.Anything, sourceUrl, false).Return(&gitMetadata.GitMetadata{LatestCommit: commit}, nil).Run(func(args mock.Arguments) {
+		require.NoError(t, fs.MkdirAll(args.String(0), 0755))
+		require.NoError(t, afero.WriteFile(fs, path.Join(args.String(0), "policy.rego"), []byte("package main"), 0644))
+	}).Once()
+	ctx = usingDownloader(ctx, &dl)
+
+	for _, workDir := range []string{"/tmp/ec-work-1", "/tmp/ec-work-2"} {
+		// start afresh as a new ec invocation would
+		downloadCache = sync.Map{}
+
+		p := PolicyUrl{Url: sourceUrl, Kind: PolicyKind}
+		dest, err := p.GetPolicy(ctx, workDir, false)
+		require.NoError(t, err)
+		assert.Equal(t, "git::example.com/user/persistent.git?ref="+commit, p.Url)
+
+		b, err := afero.ReadFile(fs, path.Join(dest, "policy.rego"))
+		require.NoError(t, err)
+		assert.Equal(t, "package main", string(b))
+	}
+
+	mock.AssertExpectationsForObjects(t, &dl)
+}
+
+func TestFetchAll(t *testing.T) {
+	t.Cleanup(func() {
+		downloadCache = sync.Map{}
+	})
+	t.Setenv("POLICY_SOURCE_WORKERS", "2")
+
+	var running, maxRunning atomic.Int32
+	dl := mockDownloader{}
+	dl.On("Download", mock.Anything, mock.Anything, false).Return(&fileMetadata.FSMetadata{}, nil).Run(func(mock.Arguments) {
+		n := running.Add(1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		running.Add(-1)
+	})
+	ctx := usingDownloader(context.Background(), &dl)
+
+	urls := []string{"fetch-all-1", "fetch-all-2", "fetch-all-3", "fetch-all-1", "fetch-all-4"}
+	kinds := []PolicyType{PolicyKind, PolicyKind, DataKind, PolicyKind, DataKind}
+	sources := make([]PolicySource, 0, len(urls))
+	for i, u := range urls {
+		sources = append(sources, &PolicyUrl{Url: u, Kind: kinds[i]})
+	}
+
+	dirs, err := FetchAll(ctx, "/tmp/ec-work-fetch-all", sources)
+	require.NoError(t, err)
+	require.Len(t, dirs, len(sources))
+	for i, d := range dirs {
+		assert.Equal(t, uniqueDestination("/tmp/ec-work-fetch-all", string(kinds[i]), urls[i]), d)
+	}
+
+	// the duplicate URL is downloaded once
+	dl.AssertNumberOfCalls(t, "Download", 4)
+	assert.Equal(t, int32(2), maxRunning.Load())
+}
+
+func TestFetchAllErrors(t *testing.T) {
+	t.Cleanup(func() {
+		downloadCache = sync.Map{}
+	})
+
+	dl := mockDownloader{}
+	dl.On("Download", mock.Anything, "fetch-all-ok", false).Return(&fileMetadata.FSMetadata{}, nil)
+	dl.On("Download", mock.Anything, "fetch-all-fail-1", false).Return(&fileMetadata.FSMetadata{}, errors.New("failure 1"))
+	dl.On("Download", mock.Anything, "fetch-all-fail-2", false).Return(&fileMetadata.FSMetadata{}, errors.New("failure 2"))
+	ctx := usingDownloader(context.Background(), &dl)
+
+	dirs, err := FetchAll(ctx, "/tmp/ec-work-fetch-all", []PolicySource{
+		&PolicyUrl{Url: "fetch-all-fail-1", Kind: PolicyKind},
+		&PolicyUrl{Url: "fetch-all-ok", Kind: PolicyKind},
+		&PolicyUrl{Url: "fetch-all-fail-2", Kind: DataKind},
+	})
+	assert.EqualError(t, err, `unable to fetch policy source "fetch-all-fail-1": failure 1`+"\n"+
+		`unable to fetch policy source "fetch-all-fail-2": failure 2`)
+	assert.Equal(t, []string{"", uniqueDestination("/tmp/ec-work-fetch-all", "policy", "fetch-all-ok"), ""}, dirs)
+	mock.AssertExpectationsForObjects(t, &dl)
+}
+
+func TestGetPolicyPinnedUrlCached(t *testing.T) {
+	t.Cleanup(func() {
+		downloadCache = sync.Map{}
+	})
+
+	commit := "6b0ae8c8f2d1d1f5a2c9d8c4b6fcd2a4a6b8e0f1"
+	dl := mockDownloader{}
+	dl.On("Download", mock.Anything, "git::https://example.com/org/pinned.git?ref=main", false).Return(&gitMetadata.GitMetadata{LatestCommit: commit}, nil).Once()
+	ctx := usingDownloader(utils.WithFS(context.Background(), afero.NewOsFs()), &dl)
+	tmp := t.TempDir()
+
+	p := PolicyUrl{Url: "git::https://example.com/org/pinned.git?ref=main", Kind: PolicyKind}
+	_, err := p.GetPolicy(ctx, filepath.Join(tmp, "work1"), false)
+	require.NoError(t, err)
+
+	// fetching the pinned URL reuses the download
+	pinned := PolicyUrl{Url: p.Url, Kind: PolicyKind}
+	_, err = pinned.GetPolicy(ctx, filepath.Join(tmp, "work2"), false)
+	require.NoError(t, err)
+	assert.Equal(t, "git::example.com/org/pinned.git?ref="+commit, pinned.Url)
+
+	mock.AssertExpectationsForObjects(t, &dl)
+}
+
+type mockVerifier struct {
+	mock.Mock
+}
+
+func (m *mockVerifier) VerifyBundle(_ context.Context, ref name.Digest) error {
+	return m.Called(ref.String()).Error(0)
+}
+
+func TestGetPolicySignatureVerification(t *testing.T) {
+	digest := "sha256:4e388ab32b10dc8dbc7e28144f552830adc74787c1e2c0824032078a79f227fb"
+
+	cases := []struct {
+		name      string
+		url       string
+		metadata  metadata.Metadata
+		verifyErr error
+		ref       string
+		err       string
+	}{
+		{
+			name:     "verified",
+			url:      "oci::registry.io/org/signed:latest",
+			metadata: &ociMetadata.OCIMetadata{Digest: digest},
+			ref:      "registry.io/org/signed@" + digest,
+		},
+		{
+			name:      "not verified",
+			url:       "oci::registry.io/org/unsigned:latest",
+			metadata:  &ociMetadata.OCIMetadata{Digest: digest},
+			verifyErr: errors.New("no matching signatures"),
+			ref:       "registry.io/org/unsigned@" + digest,
+			err:       `unable to verify the signature of the policy source "oci::registry.io/org/unsigned:latest": no matching signatures`,
+		},
+		{
+			name:     "not an OCI bundle",
+			url:      "git::https://example.com/org/policy.git",
+			metadata: &gitMetadata.GitMetadata{LatestCommit: "6b0ae8c8f2d1d1f5a2c9d8c4b6fcd2a4a6b8e0f1"},
+			err:      `unable to verify the signature of the policy source "git::https://example.com/org/policy.git": only OCI bundles can be signed`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				downloadCache = sync.Map{}
+			})
+
+			dl := mockDownloader{}
+			dl.On("Download", mock.Anything, c.url, false).Return(c.metadata, nil)
+			ctx := usingDownloader(utils.WithFS(context.Background(), afero.NewMemMapFs()), &dl)
+
+			verifier := mockVerifier{}
+			if c.ref != "" {
+				verifier.On("VerifyBundle", c.ref).Return(c.verifyErr)
+			}
+
+			p := PolicyUrl{Url: c.url, Kind: PolicyKind, Verifier: &verifier}
+			_, err := p.GetPolicy(ctx, "/tmp/ec-work-signature", false)
+			if c.err == "" {
+				require.NoError(t, err)
+				assert.True(t, p.SignatureVerified())
+			} else {
+				require.EqualError(t, err, c.err)
+				assert.ErrorAs(t, err, &SignatureError{})
+				assert.False(t, p.SignatureVerified())
+				// the URL is not pinned to the unverified content
+				assert.Equal(t, c.url, p.Url)
+			}
+
+			mock.AssertExpectationsForObjects(t, &dl, &verifier)
+		})
+	}
+}
+
+func TestGetPolicySignedBypassesPersistentCache(t *testing.T) {
+	t.Cleanup(func() {
+		downloadCache = sync.Map{}
+	})
+
+	fs := afero.NewMemMapFs()
+	store := &cache.Store{Dir: t.TempDir(), TTL: time.Hour, MaxSize: cache.DefaultMaxSize}
+	ctx := cache.WithStore(utils.WithFS(context.Background(), fs), store)
+
+	sourceUrl := "oci::registry.io/org/signed-cached:latest"
+	digest := "sha256:4e388ab32b10dc8dbc7e28144f552830adc74787c1e2c0824032078a79f227fb"
+
+	dl := mockDownloader{}
+	dl.On("Download", mock.Anything, sourceUrl, false).Return(&ociMetadata.OCIMetadata{Digest: digest}, nil).Run(func(args mock.Arguments) {
+		require.NoError(t, fs.MkdirAll(args.String(0), 0755))
+		require.NoError(t, afero.WriteFile(fs, path.Join(args.String(0), "policy.rego"), []byte("package main"), 0644))
+	})
+	ctx = usingDownloader(ctx, &dl)
+
+	// populates the persistent and the in-process caches
+	unsigned := PolicyUrl{Url: sourceUrl, Kind: PolicyKind}
+	_, err := unsigned.GetPolicy(ctx, "/tmp/ec-work-unsigned", false)
+	require.NoError(t, err)
+
+	verifier := mockVerifier{}
+	verifier.On("VerifyBundle", "registry.io/org/signed-cached@"+digest).Return(nil)
+
+	for i, workDir := range []string{"/tmp/ec-work-signed-1", "/tmp/ec-work-signed-2"} {
+		if i > 0 {
+			// start afresh as a new ec invocation would
+			downloadCache = sync.Map{}
+		}
+
+		signed := PolicyUrl{Url: sourceUrl, Kind: PolicyKind, Verifier: &verifier}
+		_, err = signed.GetPolicy(ctx, workDir, false)
+		require.NoError(t, err)
+		assert.True(t, signed.SignatureVerified())
+	}
+
+	// the signed source is downloaded every time instead of being taken from
+	// the download of the unsigned source or restored from the persistent cache
+	dl.AssertNumberOfCalls(t, "Download", 3)
+}
+
+func TestDownloadsForget(t *testing.T) {
+	t.Cleanup(func() {
+		downloadCache = sync.Map{}
+	})
+
+	dl := func(string, string) (metadata.Metadata, error) { return nil, nil }
+	ctx := utils.WithFS(context.Background(), afero.NewMemMapFs())
+
+	downloads := Downloads{}
+	recorded := PolicyUrl{Url: "https://example.com/recorded.git", Kind: PolicyKind}
+	_, _, err := getPolicyThroughCache(WithDownloads(ctx, &downloads), &recorded, "/tmp/ec-work-downloads", dl)
+	require.NoError(t, err)
+
+	other := PolicyUrl{Url: "https://example.com/other.git", Kind: PolicyKind}
+	_, _, err = getPolicyThroughCache(ctx, &other, "/tmp/ec-work-downloads", dl)
+	require.NoError(t, err)
+
+	downloads.Forget()
+
+	_, ok := downloadCache.Load(recorded.Url)
+	assert.False(t, ok)
+	_, ok = downloadCache.Load(other.Url)
+	assert.True(t, ok)
+}
@@ -0,0 +1,287 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package sarif converts policy evaluation results into the Static Analysis
+// Results Interchange Format (SARIF) version 2.1.0, see
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+package sarif
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+)
+
+const (
+	Version = "2.1.0"
+	Schema  = "https://json.schemastore.org/sarif-2.1.0.json"
+
+	toolName           = "ec"
+	toolInformationURI = "https://enterprisecontract.dev"
+)
+
+// Level is the SARIF level of a result
+type Level string
+
+const (
+	Error   Level = "error"
+	Warning Level = "warning"
+)
+
+type Log struct {
+	Version string `json:"version"`
+	Schema  string `json:"$schema"`
+	Runs    []Run  `json:"runs"`
+}
+
+type Run struct {
+	Tool    Tool     `json:"tool"`
+	Results []Result `json:"results"`
+}
+
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+type Driver struct {
+	Name           string                `json:"name"`
+	Version        string                `json:"version,omitempty"`
+	InformationURI string                `json:"informationUri"`
+	Rules          []ReportingDescriptor `json:"rules"`
+}
+
+// ReportingDescriptor describes the rule producing results
+type ReportingDescriptor struct {
+	ID               string          `json:"id"`
+	Name             string          `json:"name,omitempty"`
+	ShortDescription *Message        `json:"shortDescription,omitempty"`
+	FullDescription  *Message        `json:"fullDescription,omitempty"`
+	Help             *Message        `json:"help,omitempty"`
+	HelpURI          string          `json:"helpUri,omitempty"`
+	Properties       *RuleProperties `json:"properties,omitempty"`
+}
+
+type RuleProperties struct {
+	Tags []string `json:"tags,omitempty"`
+}
+
+type Message struct {
+	Text string `json:"text"`
+}
+
+type Result struct {
+	RuleID     string            `json:"ruleId,omitempty"`
+	RuleIndex  *int              `json:"ruleIndex,omitempty"`
+	Level      Level             `json:"level"`
+	Message    Message           `json:"message"`
+	Locations  []Location        `json:"locations,omitempty"`
+	Properties *ResultProperties `json:"properties,omitempty"`
+}
+
+// ResultProperties holds the information about the result that has no
+// counterpart in SARIF
+type ResultProperties struct {
+	// Image is the reference of the image the result is for
+	Image string `json:"image,omitempty"`
+}
+
+type Location struct {
+	PhysicalLocation *PhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []LogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+// LogicalLocation locates a result within something other than a file, the
+// image the result is for
+type LogicalLocation struct {
+	Name string `json:"name"`
+	Kind string `json:"kind,omitempty"`
+}
+
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+}
+
+type ArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// Builder collects results into a single SARIF run. A rule descriptor is added
+// for each distinct rule code found in the results, built from the rule
+// metadata the evaluator attaches to the results.
+type Builder struct {
+	version string
+	rules   []ReportingDescriptor
+	indexes map[string]int
+	results []Result
+}
+
+// NewBuilder returns a Builder for the given version of ec
+func NewBuilder(version string) *Builder {
+	return &Builder{
+		version: version,
+		indexes: map[string]int{},
+	}
+}
+
+// AddFile adds the results at the given level, located at the input file with
+// the given path
+func (b *Builder) AddFile(path string, level Level, results []evaluator.Result) {
+	var locations []Location
+	if path != "" {
+		locations = []Location{
+			{PhysicalLocation: &PhysicalLocation{ArtifactLocation: ArtifactLocation{URI: fileURI(path)}}},
+		}
+	}
+
+	b.add(level, results, locations, nil)
+}
+
+// AddImage adds the results at the given level for the image with the given
+// reference. An image is not a file within the analyzed repository so the
+// image is recorded as a logical location and in the result properties
+// instead of as a physical location.
+func (b *Builder) AddImage(ref string, level Level, results []evaluator.Result) {
+	var locations []Location
+	var properties *ResultProperties
+	if ref != "" {
+		locations = []Location{
+			{LogicalLocations: []LogicalLocation{{Name: ref, Kind: "image"}}},
+		}
+		properties = &ResultProperties{Image: ref}
+	}
+
+	b.add(level, results, locations, properties)
+}
+
+func (b *Builder) add(level Level, results []evaluator.Result, locations []Location, properties *ResultProperties) {
+	for _, r := range results {
+		result := Result{
+			Level:      level,
+			Message:    Message{Text: r.Message},
+			Locations:  locations,
+			Properties: properties,
+		}
+
+		if code := evaluator.ExtractStringFromMetadata(r, "code"); code != "" {
+			i := b.rule(code, r)
+			result.RuleID = code
+			result.RuleIndex = &i
+		}
+
+		b.results = append(b.results, result)
+	}
+}
+
+// fileURI returns the URI of the file at the path, absolute paths are
+// converted to file URIs and relative paths to relative URI references
+func fileURI(path string) string {
+	path = filepath.ToSlash(path)
+	if !filepath.IsAbs(filepath.FromSlash(path)) {
+		return (&url.URL{Path: path}).String()
+	}
+
+	if !strings.HasPrefix(path, "/") {
+		// Windows paths, e.g. C:/path
+		path = "/" + path
+	}
+
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// Log returns the SARIF log with the results added
+func (b *Builder) Log() Log {
+	rules := b.rules
+	if rules == nil {
+		rules = []ReportingDescriptor{}
+	}
+
+	results := b.results
+	if results == nil {
+		results = []Result{}
+	}
+
+	return Log{
+		Version: Version,
+		Schema:  Schema,
+		Runs: []Run{
+			{
+				Tool: Tool{
+					Driver: Driver{
+						Name:           toolName,
+						Version:        b.version,
+						InformationURI: toolInformationURI,
+						Rules:          rules,
+					},
+				},
+				Results: results,
+			},
+		},
+	}
+}
+
+// rule returns the index of the rule descriptor for the code, adding the
+// descriptor built from the result's metadata if not added before
+func (b *Builder) rule(code string, r evaluator.Result) int {
+	if i, ok := b.indexes[code]; ok {
+		return i
+	}
+
+	descriptor := ReportingDescriptor{
+		ID:      code,
+		HelpURI: r.DocumentationUrl,
+	}
+
+	if title := evaluator.ExtractStringFromMetadata(r, "title"); title != "" {
+		descriptor.Name = title
+		descriptor.ShortDescription = &Message{Text: title}
+	}
+
+	if description := evaluator.ExtractStringFromMetadata(r, "description"); description != "" {
+		descriptor.FullDescription = &Message{Text: description}
+	}
+
+	if solution := evaluator.ExtractStringFromMetadata(r, "solution"); solution != "" {
+		descriptor.Help = &Message{Text: solution}
+	}
+
+	if collections := metadataStrings(r, "collections"); len(collections) > 0 {
+		descriptor.Properties = &RuleProperties{Tags: collections}
+	}
+
+	i := len(b.rules)
+	b.rules = append(b.rules, descriptor)
+	b.indexes[code] = i
+
+	return i
+}
+
+func metadataStrings(r evaluator.Result, key string) []string {
+	switch v := r.Metadata[key].(type) {
+	case []string:
+		return v
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, s := range v {
+			values = append(values, fmt.Sprint(s))
+		}
+		return values
+	}
+
+	return nil
+}
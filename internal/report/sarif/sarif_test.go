@@ -0,0 +1,183 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package sarif
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+)
+
+func TestEmptyLog(t *testing.T) {
+	data, err := json.Marshal(NewBuilder("v1.2.3").Log())
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"version": "2.1.0",
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"runs": [
+			{
+				"tool": {
+					"driver": {
+						"name": "ec",
+						"version": "v1.2.3",
+						"informationUri": "https://enterprisecontract.dev",
+						"rules": []
+					}
+				},
+				"results": []
+			}
+		]
+	}`, string(data))
+}
+
+func TestBuilder(t *testing.T) {
+	b := NewBuilder("v1.2.3")
+
+	b.AddImage("registry.io/repository/image@sha256:abc", Error, []evaluator.Result{
+		{
+			Message: "Violation 1",
+			Metadata: map[string]any{
+				"code":        "pkg.rule1",
+				"title":       "Rule 1",
+				"description": "Rule 1 description",
+				"solution":    "Rule 1 solution",
+				"collections": []string{"minimal", "redhat"},
+			},
+			DocumentationUrl: "https://docs.example.com/rule1",
+		},
+		{
+			Message: "No code",
+		},
+	})
+	b.AddImage("registry.io/repository/image@sha256:abc", Warning, []evaluator.Result{
+		{
+			Message: "Warning 2",
+			Metadata: map[string]any{
+				"code":        "pkg.rule2",
+				"collections": []any{"minimal"},
+			},
+		},
+	})
+	b.AddImage("registry.io/repository/other@sha256:def", Error, []evaluator.Result{
+		{
+			Message: "Violation 1 again",
+			Metadata: map[string]any{
+				"code":  "pkg.rule1",
+				"title": "Rule 1",
+			},
+		},
+	})
+
+	data, err := json.Marshal(b.Log())
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"version": "2.1.0",
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"runs": [
+			{
+				"tool": {
+					"driver": {
+						"name": "ec",
+						"version": "v1.2.3",
+						"informationUri": "https://enterprisecontract.dev",
+						"rules": [
+							{
+								"id": "pkg.rule1",
+								"name": "Rule 1",
+								"shortDescription": {"text": "Rule 1"},
+								"fullDescription": {"text": "Rule 1 description"},
+								"help": {"text": "Rule 1 solution"},
+								"helpUri": "https://docs.example.com/rule1",
+								"properties": {"tags": ["minimal", "redhat"]}
+							},
+							{
+								"id": "pkg.rule2",
+								"properties": {"tags": ["minimal"]}
+							}
+						]
+					}
+				},
+				"results": [
+					{
+						"ruleId": "pkg.rule1",
+						"ruleIndex": 0,
+						"level": "error",
+						"message": {"text": "Violation 1"},
+						"locations": [{"logicalLocations": [{"name": "registry.io/repository/image@sha256:abc", "kind": "image"}]}],
+						"properties": {"image": "registry.io/repository/image@sha256:abc"}
+					},
+					{
+						"level": "error",
+						"message": {"text": "No code"},
+						"locations": [{"logicalLocations": [{"name": "registry.io/repository/image@sha256:abc", "kind": "image"}]}],
+						"properties": {"image": "registry.io/repository/image@sha256:abc"}
+					},
+					{
+						"ruleId": "pkg.rule2",
+						"ruleIndex": 1,
+						"level": "warning",
+						"message": {"text": "Warning 2"},
+						"locations": [{"logicalLocations": [{"name": "registry.io/repository/image@sha256:abc", "kind": "image"}]}],
+						"properties": {"image": "registry.io/repository/image@sha256:abc"}
+					},
+					{
+						"ruleId": "pkg.rule1",
+						"ruleIndex": 0,
+						"level": "error",
+						"message": {"text": "Violation 1 again"},
+						"locations": [{"logicalLocations": [{"name": "registry.io/repository/other@sha256:def", "kind": "image"}]}],
+						"properties": {"image": "registry.io/repository/other@sha256:def"}
+					}
+				]
+			}
+		]
+	}`, string(data))
+}
+
+func TestAddFile(t *testing.T) {
+	cases := []struct {
+		name string
+		path string
+		uri  string
+	}{
+		{name: "absolute", path: "/path/to/file.yaml", uri: "file:///path/to/file.yaml"},
+		{name: "relative", path: "path/to/file.yaml", uri: "path/to/file.yaml"},
+		{name: "escaped", path: "/path/to/my file.yaml", uri: "file:///path/to/my%20file.yaml"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b := NewBuilder("v1.2.3")
+			b.AddFile(c.path, Error, []evaluator.Result{{Message: "Violation"}})
+
+			results := b.Log().Runs[0].Results
+			require.Len(t, results, 1)
+			assert.Equal(t, []Location{
+				{PhysicalLocation: &PhysicalLocation{ArtifactLocation: ArtifactLocation{URI: c.uri}}},
+			}, results[0].Locations)
+			assert.Nil(t, results[0].Properties)
+		})
+	}
+}
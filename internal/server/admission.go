@@ -0,0 +1,333 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/enterprise-contract/ec-cli/internal/applicationsnapshot"
+	"github.com/enterprise-contract/ec-cli/internal/image"
+	"github.com/enterprise-contract/ec-cli/internal/kubernetes"
+)
+
+// verdict is the outcome of the validation of an image digest, the summary of
+// violations is empty if the image conforms to the policy
+type verdict struct {
+	violations string
+	expires    time.Time
+}
+
+// podSpecs returns the pod spec within the objects of each supported kind
+var podSpecs = map[string]func([]byte) (*corev1.PodSpec, error){
+	"Pod": podSpecOf(func(o *corev1.Pod) *corev1.PodSpec {
+		return &o.Spec
+	}),
+	"Deployment": podSpecOf(func(o *appsv1.Deployment) *corev1.PodSpec {
+		return &o.Spec.Template.Spec
+	}),
+	"ReplicaSet": podSpecOf(func(o *appsv1.ReplicaSet) *corev1.PodSpec {
+		return &o.Spec.Template.Spec
+	}),
+	"StatefulSet": podSpecOf(func(o *appsv1.StatefulSet) *corev1.PodSpec {
+		return &o.Spec.Template.Spec
+	}),
+	"DaemonSet": podSpecOf(func(o *appsv1.DaemonSet) *corev1.PodSpec {
+		return &o.Spec.Template.Spec
+	}),
+	"Job": podSpecOf(func(o *batchv1.Job) *corev1.PodSpec {
+		return &o.Spec.Template.Spec
+	}),
+	"CronJob": podSpecOf(func(o *batchv1.CronJob) *corev1.PodSpec {
+		return &o.Spec.JobTemplate.Spec.Template.Spec
+	}),
+}
+
+func podSpecOf[T any](spec func(*T) *corev1.PodSpec) func([]byte) (*corev1.PodSpec, error) {
+	return func(raw []byte) (*corev1.PodSpec, error) {
+		var o T
+		if err := json.Unmarshal(raw, &o); err != nil {
+			return nil, err
+		}
+		return spec(&o), nil
+	}
+}
+
+// containerImages returns the sorted, unique, images of all containers of the
+// object in the admission request. Objects of kinds without a pod spec have no
+// images.
+func containerImages(req *admissionv1.AdmissionRequest) ([]string, error) {
+	podSpec, ok := podSpecs[req.Kind.Kind]
+	if !ok || len(req.Object.Raw) == 0 {
+		return nil, nil
+	}
+
+	spec, err := podSpec(req.Object.Raw)
+	if err != nil {
+		return nil, err
+	}
+
+	unique := map[string]bool{}
+	for _, c := range spec.InitContainers {
+		unique[c.Image] = true
+	}
+	for _, c := range spec.Containers {
+		unique[c.Image] = true
+	}
+	for _, c := range spec.EphemeralContainers {
+		unique[c.Image] = true
+	}
+
+	images := make([]string, 0, len(unique))
+	for i := range unique {
+		if i != "" {
+			images = append(images, i)
+		}
+	}
+	sort.Strings(images)
+
+	return images, nil
+}
+
+func (s *Server) handleAdmission(w http.ResponseWriter, r *http.Request) {
+	var review admissionv1.AdmissionReview
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&review); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid admission review: %w", err))
+		return
+	}
+
+	if review.Request == nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid admission review: no request"))
+		return
+	}
+
+	ctx, done, ok := s.limit(w, r)
+	if !ok {
+		return
+	}
+	defer done()
+
+	response := s.admit(ctx, review.Request)
+	response.UID = review.Request.UID
+
+	writeJSON(w, http.StatusOK, admissionv1.AdmissionReview{
+		TypeMeta: review.TypeMeta,
+		Response: response,
+	})
+}
+
+// admit validates the images of the object in the admission request against
+// the policy of its namespace
+func (s *Server) admit(ctx context.Context, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	images, err := containerImages(req)
+	if err != nil {
+		return s.decision([]string{fmt.Sprintf("unable to read the %s: %v", req.Kind.Kind, err)})
+	}
+
+	if len(images) == 0 {
+		return s.decision(nil)
+	}
+
+	policy, err := s.admissionPolicy(ctx, req.Namespace)
+	if err != nil {
+		return s.decision([]string{err.Error()})
+	}
+
+	reasons := make([]string, len(images))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(s.opts.Workers)
+	for i, img := range images {
+		g.Go(func() error {
+			reasons[i] = s.verdict(gctx, policy, img)
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	denied := make([]string, 0, len(reasons))
+	for _, r := range reasons {
+		if r != "" {
+			denied = append(denied, r)
+		}
+	}
+
+	return s.decision(denied)
+}
+
+// decision returns the admission response for the reasons to deny the object,
+// in audit mode the object is admitted with the reasons reported as warnings
+func (s *Server) decision(reasons []string) *admissionv1.AdmissionResponse {
+	if len(reasons) == 0 {
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+
+	reason := strings.Join(reasons, "; ")
+	if s.opts.AdmissionAudit {
+		log.Infof("Admitting in audit mode: %s", reason)
+		return &admissionv1.AdmissionResponse{
+			Allowed:          true,
+			Warnings:         reasons,
+			AuditAnnotations: map[string]string{"violations": reason},
+		}
+	}
+
+	log.Infof("Denying admission: %s", reason)
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    http.StatusForbidden,
+			Reason:  metav1.StatusReasonForbidden,
+			Message: reason,
+		},
+	}
+}
+
+// admissionPolicy returns the configuration of the AdmissionPolicy
+// EnterpriseContractPolicy in the namespace, along with the resource it was
+// read from, if there is no such policy the DefaultPolicy is used
+func (s *Server) admissionPolicy(ctx context.Context, namespace string) (Request, error) {
+	ref := namespace + "/" + s.opts.AdmissionPolicy
+
+	client, err := kubernetes.NewClient(ctx)
+	if err != nil {
+		return Request{}, fmt.Errorf("cannot initialize Kubernetes client: %w", err)
+	}
+
+	// The spec is fetched as stored in the cluster, retaining the signatures of
+	// the policy sources, and used inline, so that changes to the policy
+	// resource are picked up by the following admission requests
+	spec, resourceVersion, err := client.FetchEnterpriseContractPolicySpec(ctx, ref)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			if s.opts.DefaultPolicy != "" {
+				return Request{Policy: s.opts.DefaultPolicy}, nil
+			}
+			return Request{}, fmt.Errorf("no EnterpriseContractPolicy %q found in the namespace %q", s.opts.AdmissionPolicy, namespace)
+		}
+		return Request{}, fmt.Errorf("unable to fetch the EnterpriseContractPolicy %s: %w", ref, err)
+	}
+
+	return Request{
+		Policy: spec,
+		resource: &policyResource{
+			name:            ref,
+			resourceVersion: resourceVersion,
+		},
+	}, nil
+}
+
+// verdict resolves the image to its digest and validates it, returning the
+// reason to deny the admission, or empty if the image conforms to the policy.
+// The outcome of a completed validation is reused for AdmissionCacheTTL.
+func (s *Server) verdict(ctx context.Context, policy Request, img string) string {
+	ref, err := image.NewImageReference(img)
+	if err != nil {
+		return fmt.Sprintf("unable to parse image %s: %v", img, err)
+	}
+
+	if ref.Digest == "" {
+		// The tag is resolved again when the container is created, by then it
+		// might refer to a different image than the validated one
+		if s.opts.AdmissionRequireDigest {
+			return fmt.Sprintf("image %s is not referenced by its digest", img)
+		}
+
+		if ref, err = image.ParseAndResolve(ctx, img); err != nil {
+			return fmt.Sprintf("unable to resolve image %s: %v", img, err)
+		}
+	}
+	digestRef := ref.Repository + "@" + ref.Digest
+
+	key := fmt.Sprintf("%x", sha256.Sum256([]byte(policy.Policy+"\n"+digestRef)))
+	if v, ok := s.cachedVerdict(key); ok {
+		log.Debugf("Using the cached verdict for %s", digestRef)
+		return denialReason(img, v.violations)
+	}
+
+	req := policy
+	req.Image = digestRef
+	report, err := s.validateImage(ctx, req)
+	if err != nil {
+		return fmt.Sprintf("unable to validate image %s: %v", img, err)
+	}
+
+	violations := ""
+	if !report.Success {
+		violations = applicationsnapshot.ViolationsSummary(report.Components)
+	}
+	s.cacheVerdict(key, violations)
+
+	return denialReason(img, violations)
+}
+
+// denialReason returns the reason to deny the admission of the image, the image is
+// named as in the admitted object, as the same digest can be referenced by
+// different tags
+func denialReason(img string, violations string) string {
+	if violations == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("image %s %s", img, violations)
+}
+
+func (s *Server) cachedVerdict(key string) (verdict, bool) {
+	s.verdictsMu.Lock()
+	defer s.verdictsMu.Unlock()
+
+	v, ok := s.verdicts[key]
+	if !ok || time.Now().After(v.expires) {
+		return verdict{}, false
+	}
+
+	return v, true
+}
+
+func (s *Server) cacheVerdict(key string, violations string) {
+	if s.opts.AdmissionCacheTTL <= 0 {
+		return
+	}
+
+	s.verdictsMu.Lock()
+	defer s.verdictsMu.Unlock()
+
+	now := time.Now()
+	for k, v := range s.verdicts {
+		if now.After(v.expires) {
+			delete(s.verdicts, k)
+		}
+	}
+
+	s.verdicts[key] = verdict{violations: violations, expires: now.Add(s.opts.AdmissionCacheTTL)}
+}
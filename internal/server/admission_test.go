@@ -0,0 +1,321 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+// fakeAPIServer starts an in-process Kubernetes API server serving the given
+// EnterpriseContractPolicy resources, by namespace/name, and configures the
+// Kubernetes client to use it. The resource version of a policy changes with
+// its spec.
+func fakeAPIServer(t *testing.T, policies map[string]ecc.EnterpriseContractPolicySpec) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		// /apis/appstudio.redhat.com/v1alpha1/namespaces/<namespace>/enterprisecontractpolicies/<name>
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if r.Method == http.MethodGet && len(parts) == 7 && parts[5] == "enterprisecontractpolicies" {
+			if spec, ok := policies[parts[4]+"/"+parts[6]]; ok {
+				version := fmt.Sprintf("%x", sha256.Sum256([]byte(toJSON(t, spec))))[:8]
+				require.NoError(t, json.NewEncoder(w).Encode(ecc.EnterpriseContractPolicy{
+					TypeMeta: metav1.TypeMeta{APIVersion: ecc.GroupVersion.String(), Kind: "EnterpriseContractPolicy"},
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace:       parts[4],
+						Name:            parts[6],
+						ResourceVersion: version,
+					},
+					Spec: spec,
+				}))
+				return
+			}
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+		require.NoError(t, json.NewEncoder(w).Encode(metav1.Status{
+			TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Status"},
+			Status:   metav1.StatusFailure,
+			Reason:   metav1.StatusReasonNotFound,
+			Code:     http.StatusNotFound,
+		}))
+	}))
+	t.Cleanup(api.Close)
+
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	require.NoError(t, os.WriteFile(kubeconfig, []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: fake
+  cluster:
+    server: `+api.URL+`
+contexts:
+- name: fake
+  context:
+    cluster: fake
+    user: fake
+current-context: fake
+users:
+- name: fake
+  user: {}
+`), 0600))
+	t.Setenv("KUBECONFIG", kubeconfig)
+}
+
+func admissionReview(t *testing.T, obj runtime.Object, kind string, namespace string) string {
+	raw, err := json.Marshal(obj)
+	require.NoError(t, err)
+
+	return toJSON(t, admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request: &admissionv1.AdmissionRequest{
+			UID:       types.UID("b1d0c3a4-9e5f-4f6a-8c7d-2e1f0a9b8c7d"),
+			Kind:      metav1.GroupVersionKind{Kind: kind},
+			Namespace: namespace,
+			Operation: admissionv1.Create,
+			Object:    runtime.RawExtension{Raw: raw},
+		},
+	})
+}
+
+func pod(images ...string) *corev1.Pod {
+	p := &corev1.Pod{}
+	for _, i := range images {
+		p.Spec.Containers = append(p.Spec.Containers, corev1.Container{Image: i})
+	}
+	return p
+}
+
+func deployment(images ...string) *appsv1.Deployment {
+	d := &appsv1.Deployment{}
+	d.Spec.Template.Spec = pod(images...).Spec
+	d.Spec.Template.Spec.InitContainers = []corev1.Container{{Image: images[0]}}
+	return d
+}
+
+func admit(t *testing.T, s *Server, body string) *admissionv1.AdmissionResponse {
+	rec := request(t, s, http.MethodPost, "/v1/admission", body)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var review admissionv1.AdmissionReview
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &review))
+	assert.Equal(t, "AdmissionReview", review.Kind)
+	require.NotNil(t, review.Response)
+	assert.Equal(t, types.UID("b1d0c3a4-9e5f-4f6a-8c7d-2e1f0a9b8c7d"), review.Response.UID)
+
+	return review.Response
+}
+
+const (
+	goodImage = "registry.io/repository/good@sha256:" + testDigest
+	badImage  = "registry.io/repository/bad@sha256:" + testDigest
+)
+
+func TestAdmission(t *testing.T) {
+	utils.SetTestRekorPublicKey(t)
+	fakeAPIServer(t, map[string]ecc.EnterpriseContractPolicySpec{
+		"team/default": {PublicKey: utils.TestPublicKey},
+	})
+
+	violation := "image " + badImage + " violates the policy: [main.reject] Fails always"
+	notByDigest := "image registry.io/repository/good:tag is not referenced by its digest"
+
+	cases := []struct {
+		name     string
+		opts     Options
+		review   string
+		expected admissionv1.AdmissionResponse
+		calls    int32
+	}{
+		{
+			name:     "conforming pod",
+			review:   admissionReview(t, pod(goodImage), "Pod", "team"),
+			expected: admissionv1.AdmissionResponse{Allowed: true},
+			calls:    1,
+		},
+		{
+			name:   "violating deployment",
+			review: admissionReview(t, deployment(badImage, goodImage), "Deployment", "team"),
+			expected: admissionv1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Status:  metav1.StatusFailure,
+					Code:    http.StatusForbidden,
+					Reason:  metav1.StatusReasonForbidden,
+					Message: violation,
+				},
+			},
+			calls: 2,
+		},
+		{
+			name:   "audit mode",
+			opts:   Options{AdmissionAudit: true},
+			review: admissionReview(t, pod(badImage), "Pod", "team"),
+			expected: admissionv1.AdmissionResponse{
+				Allowed:          true,
+				Warnings:         []string{violation},
+				AuditAnnotations: map[string]string{"violations": violation},
+			},
+			calls: 1,
+		},
+		{
+			name:     "image referenced by tag",
+			review:   admissionReview(t, pod("registry.io/repository/good:tag"), "Pod", "team"),
+			expected: admissionv1.AdmissionResponse{Allowed: true},
+			calls:    1,
+		},
+		{
+			name:   "image not referenced by digest",
+			opts:   Options{AdmissionRequireDigest: true},
+			review: admissionReview(t, pod("registry.io/repository/good:tag"), "Pod", "team"),
+			expected: admissionv1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Status:  metav1.StatusFailure,
+					Code:    http.StatusForbidden,
+					Reason:  metav1.StatusReasonForbidden,
+					Message: notByDigest,
+				},
+			},
+		},
+		{
+			name:   "no policy in the namespace",
+			review: admissionReview(t, pod(goodImage), "Pod", "other"),
+			expected: admissionv1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Status:  metav1.StatusFailure,
+					Code:    http.StatusForbidden,
+					Reason:  metav1.StatusReasonForbidden,
+					Message: `no EnterpriseContractPolicy "default" found in the namespace "other"`,
+				},
+			},
+		},
+		{
+			name:     "default policy",
+			opts:     Options{DefaultPolicy: `{"publicKey": ` + toJSON(t, utils.TestPublicKey) + `}`},
+			review:   admissionReview(t, pod(goodImage), "Pod", "other"),
+			expected: admissionv1.AdmissionResponse{Allowed: true},
+			calls:    1,
+		},
+		{
+			name:     "kind without containers",
+			review:   admissionReview(t, &corev1.ConfigMap{}, "ConfigMap", "other"),
+			expected: admissionv1.AdmissionResponse{Allowed: true},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.opts.AdmissionPolicy = "default"
+			s, calls := testServer(t, c.opts)
+
+			response := admit(t, s, c.review)
+			response.UID = ""
+			assert.Equal(t, c.expected, *response)
+			assert.Equal(t, c.calls, calls.Load())
+		})
+	}
+}
+
+func TestAdmissionCache(t *testing.T) {
+	utils.SetTestRekorPublicKey(t)
+	fakeAPIServer(t, map[string]ecc.EnterpriseContractPolicySpec{
+		"team/default": {PublicKey: utils.TestPublicKey},
+	})
+
+	s, calls := testServer(t, Options{AdmissionPolicy: "default", AdmissionCacheTTL: time.Hour})
+
+	// all references have, or resolve to, the same digest
+	for _, i := range []string{"registry.io/repository/bad:one@sha256:" + testDigest, badImage, "registry.io/repository/bad:two"} {
+		response := admit(t, s, admissionReview(t, pod(i), "Pod", "team"))
+		assert.False(t, response.Allowed)
+		assert.Equal(t, "image "+i+" violates the policy: [main.reject] Fails always", response.Result.Message)
+	}
+	assert.Equal(t, int32(1), calls.Load())
+
+	// a different policy doesn't use the cached outcome
+	s.opts.DefaultPolicy = `{"publicKey": ` + toJSON(t, utils.TestPublicKey) + `}`
+	response := admit(t, s, admissionReview(t, pod(badImage), "Pod", "other"))
+	assert.False(t, response.Allowed)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestAdmissionPolicyResourceVersion(t *testing.T) {
+	utils.SetTestRekorPublicKey(t)
+	fakeAPIServer(t, map[string]ecc.EnterpriseContractPolicySpec{
+		"team/default": {PublicKey: utils.TestPublicKey},
+	})
+
+	s, calls := testServer(t, Options{AdmissionPolicy: "default", MaxPolicies: 10})
+
+	response := admit(t, s, admissionReview(t, pod(goodImage), "Pod", "team"))
+	assert.True(t, response.Allowed)
+	require.Len(t, s.policies, 1)
+	var key string
+	for k := range s.policies {
+		key = k
+	}
+	assert.True(t, strings.HasPrefix(key, "team/default@"))
+
+	// the same version of the policy resource uses the prepared policy
+	response = admit(t, s, admissionReview(t, pod(goodImage), "Pod", "team"))
+	assert.True(t, response.Allowed)
+	assert.Len(t, s.policies, 1)
+	assert.Contains(t, s.policies, key)
+
+	// a new version of the policy resource replaces the prepared policy
+	fakeAPIServer(t, map[string]ecc.EnterpriseContractPolicySpec{
+		"team/default": {PublicKey: utils.TestPublicKey, Description: "changed"},
+	})
+	response = admit(t, s, admissionReview(t, pod(goodImage), "Pod", "team"))
+	assert.True(t, response.Allowed)
+	assert.Len(t, s.policies, 1)
+	assert.NotContains(t, s.policies, key)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestAdmissionBadRequest(t *testing.T) {
+	s, _ := testServer(t, Options{})
+
+	rec := request(t, s, http.MethodPost, "/v1/admission", `{"apiVersion": "admission.k8s.io/v1", "kind": "AdmissionReview"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, `{"error": "invalid admission review: no request"}`, rec.Body.String())
+}
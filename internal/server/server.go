@@ -0,0 +1,642 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package server implements the HTTP API of the validation service started by
+// the `ec serve` command. The policies, along with their evaluators, are
+// prepared on the first request using them and reused by subsequent requests,
+// so the policy sources are downloaded and compiled only once.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	app "github.com/konflux-ci/application-api/api/v1alpha1"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/enterprise-contract/ec-cli/internal/applicationsnapshot"
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/output"
+	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+	validate_utils "github.com/enterprise-contract/ec-cli/internal/validate"
+)
+
+// maxRequestSize limits the size of the validation request body
+const maxRequestSize = 10 << 20
+
+type ImageValidationFunc func(context.Context, app.SnapshotComponent, *app.SnapshotSpec, policy.Policy, []evaluator.Evaluator, bool) (*output.Output, error)
+
+var newConftestEvaluator = evaluator.NewConftestEvaluator
+var newOPAEvaluator = evaluator.NewOPAEvaluator
+
+// Options configures the Server.
+type Options struct {
+	// DefaultPolicy is the policy configuration used by requests not providing one
+	DefaultPolicy string
+	// AllowRequestPolicy allows the validation requests to provide the policy
+	// configuration and the public key. These are read, and any policy sources
+	// fetched, with the permissions and the credentials of the server, so only
+	// the DefaultPolicy is used unless allowed.
+	AllowRequestPolicy bool
+	// MaxConcurrency is the maximum number of requests validated at the same
+	// time, other requests wait for their turn until they time out
+	MaxConcurrency int
+	// PolicyRefresh is how long a prepared policy is reused before it is
+	// prepared again, picking up any changes to the policy and its sources.
+	// Prepared policies not used for as long are removed.
+	PolicyRefresh time.Duration
+	// MaxPolicies is the maximum number of prepared policies kept, the least
+	// recently used one is removed to make room for a new one
+	MaxPolicies int
+	// Timeout is the maximum duration of a request
+	Timeout time.Duration
+	// Workers is the number of components of a request validated at the same time
+	Workers int
+	// AdmissionAudit makes the admission webhook admit all objects, reporting
+	// the policy violations as warnings and audit annotations instead
+	AdmissionAudit bool
+	// AdmissionCacheTTL is how long the outcome of the validation of an image
+	// digest is reused by the admission webhook
+	AdmissionCacheTTL time.Duration
+	// AdmissionRequireDigest makes the admission webhook deny the images not
+	// referenced by their digests, instead of validating the image the tag
+	// currently refers to
+	AdmissionRequireDigest bool
+	// AdmissionPolicy is the name of the EnterpriseContractPolicy, in the
+	// namespace of the admitted object, used by the admission webhook
+	AdmissionPolicy string
+}
+
+// Request is the body of a validation request. Either the Image or the
+// Snapshot, an ApplicationSnapshot spec, needs to be provided. The remaining
+// fields have the same meaning as the flags of the `ec validate image` command.
+type Request struct {
+	Image                       string          `json:"image,omitempty"`
+	Snapshot                    json.RawMessage `json:"snapshot,omitempty"`
+	Policy                      string          `json:"policy,omitempty"`
+	PublicKey                   string          `json:"publicKey,omitempty"`
+	RekorURL                    string          `json:"rekorUrl,omitempty"`
+	IgnoreRekor                 bool            `json:"ignoreRekor,omitempty"`
+	CertificateIdentity         string          `json:"certificateIdentity,omitempty"`
+	CertificateIdentityRegExp   string          `json:"certificateIdentityRegExp,omitempty"`
+	CertificateOIDCIssuer       string          `json:"certificateOIDCIssuer,omitempty"`
+	CertificateOIDCIssuerRegExp string          `json:"certificateOIDCIssuerRegExp,omitempty"`
+	EffectiveTime               string          `json:"effectiveTime,omitempty"`
+	Info                        bool            `json:"info,omitempty"`
+	ShowSuccesses               bool            `json:"showSuccesses,omitempty"`
+	NestImageManifests          bool            `json:"nestImageManifests,omitempty"`
+
+	// resource the policy configuration was read from, if any
+	resource *policyResource
+}
+
+// policyResource identifies the version of the EnterpriseContractPolicy
+// resource a policy configuration was read from
+type policyResource struct {
+	// name is the namespace/name of the resource
+	name            string
+	resourceVersion string
+}
+
+// policyOptions returns the options of the policy used to validate the request
+func (r Request) policyOptions() policy.Options {
+	effectiveTime := r.EffectiveTime
+	if effectiveTime == "" {
+		effectiveTime = policy.Now
+	}
+
+	return policy.Options{
+		EffectiveTime: effectiveTime,
+		Identity: cosign.Identity{
+			Issuer:        r.CertificateOIDCIssuer,
+			IssuerRegExp:  r.CertificateOIDCIssuerRegExp,
+			Subject:       r.CertificateIdentity,
+			SubjectRegExp: r.CertificateIdentityRegExp,
+		},
+		IgnoreRekor: r.IgnoreRekor,
+		PolicyRef:   r.Policy,
+		PublicKey:   r.PublicKey,
+		RekorURL:    r.RekorURL,
+	}
+}
+
+// badRequestError is returned when the request itself is invalid
+type badRequestError struct {
+	err error
+}
+
+func (e badRequestError) Error() string {
+	return e.err.Error()
+}
+
+func (e badRequestError) Unwrap() error {
+	return e.err
+}
+
+// preparedPolicy holds a policy and its evaluators shared by the requests
+// using the same policy options. The evaluators are destroyed once the policy
+// is replaced and no longer used by any request.
+type preparedPolicy struct {
+	key string
+	// resource is the namespace/name of the resource the policy was read from
+	resource   string
+	created    time.Time
+	done       chan struct{}
+	policy     policy.Policy
+	evaluators []evaluator.Evaluator
+	err        error
+	// policy sources fetched to prepare the policy
+	downloads source.Downloads
+	// guarded by Server.mu
+	refs    int
+	used    time.Time
+	retired bool
+}
+
+// currentTimePolicy is a policy with the current time as its effective time.
+// A prepared policy is reused, taking the current time when it's prepared
+// would have the following requests evaluated at a time in the past. The
+// evaluators take the effective time on each evaluation, and provide it to the
+// policies in data.config.policy.when_ns.
+type currentTimePolicy struct {
+	policy.Policy
+}
+
+func (p currentTimePolicy) EffectiveTime() time.Time {
+	return time.Now().UTC()
+}
+
+func (p *preparedPolicy) prepare(ctx context.Context, opts policy.Options) {
+	defer close(p.done)
+
+	policyConfiguration, err := validate_utils.GetPolicyConfig(ctx, opts.PolicyRef)
+	if err != nil {
+		p.err = err
+		return
+	}
+	opts.PolicyRef = policyConfiguration
+
+	pol, _, err := policy.PreProcessPolicy(ctx, opts)
+	if err != nil {
+		p.err = err
+		return
+	}
+
+	if strings.EqualFold(opts.EffectiveTime, policy.Now) {
+		pol = currentTimePolicy{pol}
+	}
+
+	for i, sourceGroup := range pol.Spec().Sources {
+		policySources := pol.PolicySources(i)
+
+		var c evaluator.Evaluator
+		if utils.IsOpaEnabled() {
+			c, err = newOPAEvaluator(ctx, policySources, pol, sourceGroup)
+		} else {
+			c, err = newConftestEvaluator(ctx, policySources, pol, sourceGroup)
+		}
+		if err != nil {
+			p.destroy()
+			p.err = err
+			return
+		}
+
+		p.evaluators = append(p.evaluators, c)
+	}
+
+	p.policy = pol
+	log.Debugf("Prepared policy with %d evaluators", len(p.evaluators))
+}
+
+func (p *preparedPolicy) destroy() {
+	for _, e := range p.evaluators {
+		e.Destroy()
+	}
+	p.evaluators = nil
+}
+
+// Server validates images with the policies provided in the requests.
+type Server struct {
+	ctx      context.Context
+	opts     Options
+	validate ImageValidationFunc
+	slots    chan struct{}
+	ready    atomic.Bool
+	mu       sync.Mutex
+	policies map[string]*preparedPolicy
+	closed   chan struct{}
+	// verdicts of the admission webhook by policy and image digest
+	verdictsMu sync.Mutex
+	verdicts   map[string]verdict
+}
+
+// New returns a Server that prepares the policies using ctx and validates the
+// components of each request using validate.
+func New(ctx context.Context, opts Options, validate ImageValidationFunc) *Server {
+	if opts.MaxConcurrency < 1 {
+		opts.MaxConcurrency = 1
+	}
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+	if opts.MaxPolicies < 1 {
+		opts.MaxPolicies = 1
+	}
+
+	s := &Server{
+		ctx:      ctx,
+		opts:     opts,
+		validate: validate,
+		slots:    make(chan struct{}, opts.MaxConcurrency),
+		policies: map[string]*preparedPolicy{},
+		closed:   make(chan struct{}),
+		verdicts: map[string]verdict{},
+	}
+
+	if opts.PolicyRefresh > 0 {
+		go s.removeIdle()
+	}
+
+	return s
+}
+
+// removeIdle removes, every PolicyRefresh, the prepared policies that weren't
+// used for as long, until the server is closed or its context is done.
+func (s *Server) removeIdle() {
+	ticker := time.NewTicker(s.opts.PolicyRefresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-s.closed:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			for _, p := range s.policies {
+				if p.refs == 0 && time.Since(p.used) > s.opts.PolicyRefresh {
+					log.Debug("Removing the idle prepared policy")
+					s.retire(p)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// SetReady sets whether the server reports being ready to receive requests.
+func (s *Server) SetReady(ready bool) {
+	s.ready.Store(ready)
+}
+
+// Close destroys the evaluators of all prepared policies once they are no
+// longer used.
+func (s *Server) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.closed:
+	default:
+		close(s.closed)
+	}
+
+	for _, p := range s.policies {
+		s.retire(p)
+	}
+}
+
+// Handler returns the HTTP handler serving the API:
+//
+//	POST /v1/validate/image  validates the image or snapshot in the request
+//	POST /v1/admission       admits or denies the object in the AdmissionReview
+//	GET  /healthz            reports the server is alive
+//	GET  /readyz             reports the server is ready to receive requests
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/validate/image", s.handleValidateImage)
+	mux.HandleFunc("POST /v1/admission", s.handleAdmission)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, _ *http.Request) {
+		if !s.ready.Load() {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "not ready"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+	})
+
+	return mux
+}
+
+func (s *Server) handleValidateImage(w http.ResponseWriter, r *http.Request) {
+	var req Request
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return
+	}
+
+	if !s.opts.AllowRequestPolicy && (req.Policy != "" || req.PublicKey != "") {
+		writeError(w, http.StatusBadRequest, errors.New("invalid request: the policy and the public key are not allowed to be provided by requests"))
+		return
+	}
+
+	ctx, done, ok := s.limit(w, r)
+	if !ok {
+		return
+	}
+	defer done()
+
+	report, err := s.validateImage(ctx, req)
+	if err != nil {
+		var badRequest badRequestError
+		switch {
+		case errors.As(err, &badRequest):
+			writeError(w, http.StatusBadRequest, err)
+		case errors.Is(err, context.DeadlineExceeded):
+			writeError(w, http.StatusGatewayTimeout, fmt.Errorf("validation timed out: %w", err))
+		default:
+			writeError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// limit applies the request timeout and waits for one of the MaxConcurrency
+// validation slots to become free. The returned function frees the slot and
+// needs to be called once the request is handled. If the request timed out
+// waiting for a slot, the error response is written and false is returned.
+func (s *Server) limit(w http.ResponseWriter, r *http.Request) (context.Context, func(), bool) {
+	ctx := r.Context()
+	cancel := func() {}
+	if s.opts.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, s.opts.Timeout)
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+		return ctx, func() {
+			<-s.slots
+			cancel()
+		}, true
+	case <-ctx.Done():
+		cancel()
+		writeError(w, http.StatusServiceUnavailable, errors.New("too many concurrent validations"))
+		return nil, nil, false
+	}
+}
+
+// validateImage validates the components of the request and returns the same
+// report the `ec validate image` command outputs in the JSON format.
+func (s *Server) validateImage(ctx context.Context, req Request) (*applicationsnapshot.Report, error) {
+	if req.Image == "" && len(req.Snapshot) == 0 {
+		return nil, badRequestError{errors.New("either image or snapshot must be provided")}
+	}
+
+	if req.Policy == "" {
+		req.Policy = s.opts.DefaultPolicy
+	}
+
+	spec, manifests, err := applicationsnapshot.DetermineInputSpec(ctx, applicationsnapshot.Input{
+		Image:  req.Image,
+		Images: string(req.Snapshot),
+	})
+	if err != nil {
+		return nil, badRequestError{err}
+	}
+
+	p, err := s.acquire(ctx, req.policyOptions(), req.resource)
+	if err != nil {
+		return nil, err
+	}
+	defer s.release(p)
+
+	components := make([]applicationsnapshot.Component, len(spec.Components))
+	policyInputs := make([][]byte, len(spec.Components))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(s.opts.Workers)
+	for i, comp := range spec.Components {
+		g.Go(func() error {
+			out, err := s.validate(gctx, comp, spec, p.policy, p.evaluators, req.Info)
+			if err != nil {
+				return fmt.Errorf("error validating image %s of component %s: %w", comp.ContainerImage, comp.Name, err)
+			}
+
+			components[i] = component(comp, out, req.ShowSuccesses)
+			policyInputs[i] = out.PolicyInput
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		// The evaluators might have failed to prepare, e.g. the policy sources
+		// couldn't be downloaded, use a new policy for the next request
+		s.invalidate(p)
+		return nil, err
+	}
+
+	if req.NestImageManifests {
+		components = applicationsnapshot.NestImageManifests(components, manifests)
+	}
+
+	// Ensure some consistency in output.
+	sort.Slice(components, func(i, j int) bool {
+		return components[i].ContainerImage > components[j].ContainerImage
+	})
+
+	report, err := applicationsnapshot.NewReport("", components, p.policy, policyInputs, req.ShowSuccesses)
+	if err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// component returns the report component with the outcome of the validation
+func component(comp app.SnapshotComponent, out *output.Output, showSuccesses bool) applicationsnapshot.Component {
+	c := applicationsnapshot.Component{
+		SnapshotComponent: comp,
+		Violations:        out.Violations(),
+		Warnings:          out.Warnings(),
+		Signatures:        out.Signatures,
+	}
+
+	successes := out.Successes()
+	c.SuccessCount = len(successes)
+	if showSuccesses {
+		c.Successes = successes
+	}
+
+	for _, att := range out.Attestations {
+		c.Attestations = append(c.Attestations, applicationsnapshot.NewAttestationResult(att))
+	}
+
+	if out.ImageURL != "" {
+		c.ContainerImage = out.ImageURL
+	}
+	c.Success = len(c.Violations) == 0
+
+	return c
+}
+
+// acquire returns the prepared policy for the given options, preparing it if
+// needed. The returned policy must be released once it is no longer used.
+// Policies read from a resource are identified by the version of the resource,
+// and only the policy of the latest version is kept.
+func (s *Server) acquire(ctx context.Context, opts policy.Options, resource *policyResource) (*preparedPolicy, error) {
+	keyBytes, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+	key := string(keyBytes)
+	if resource != nil {
+		key = resource.name + "@" + resource.resourceVersion
+	}
+
+	s.mu.Lock()
+	p, ok := s.policies[key]
+	if ok && s.opts.PolicyRefresh > 0 && time.Since(p.created) > s.opts.PolicyRefresh {
+		log.Debug("Refreshing the prepared policy")
+		s.retire(p)
+		ok = false
+	}
+	if !ok {
+		if resource != nil {
+			for _, other := range s.policies {
+				if other.resource == resource.name {
+					log.Debugf("Removing the prepared policy of a previous version of %s", resource.name)
+					s.retire(other)
+				}
+			}
+		}
+		if len(s.policies) >= s.opts.MaxPolicies {
+			s.retire(s.leastRecentlyUsed())
+		}
+		p = &preparedPolicy{key: key, created: time.Now(), done: make(chan struct{})}
+		if resource != nil {
+			p.resource = resource.name
+		}
+		s.policies[key] = p
+		go p.prepare(source.WithDownloads(s.ctx, &p.downloads), opts)
+	}
+	p.refs++
+	p.used = time.Now()
+	s.mu.Unlock()
+
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		s.release(p)
+		return nil, ctx.Err()
+	}
+
+	if p.err != nil {
+		s.invalidate(p)
+		s.release(p)
+		return nil, p.err
+	}
+
+	return p, nil
+}
+
+// release marks the prepared policy as no longer used by a request
+func (s *Server) release(p *preparedPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p.refs--
+	if p.retired && p.refs == 0 {
+		go func() {
+			<-p.done
+			p.destroy()
+		}()
+	}
+}
+
+// invalidate makes sure the prepared policy isn't used by subsequent requests
+func (s *Server) invalidate(p *preparedPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.retire(p)
+}
+
+// leastRecentlyUsed returns the prepared policy used the longest time ago.
+// Must be called with s.mu held.
+func (s *Server) leastRecentlyUsed() *preparedPolicy {
+	var lru *preparedPolicy
+	for _, p := range s.policies {
+		if lru == nil || p.used.Before(lru.used) {
+			lru = p
+		}
+	}
+
+	return lru
+}
+
+// retire removes the prepared policy from the server, destroying it if it's
+// not used by any request. Must be called with s.mu held.
+func (s *Server) retire(p *preparedPolicy) {
+	if p.retired {
+		return
+	}
+	p.retired = true
+
+	if s.policies[p.key] == p {
+		delete(s.policies, p.key)
+	}
+
+	// Otherwise the policy sources referring to a branch or a tag would be
+	// served from the download cache when the policy is prepared again
+	p.downloads.Forget()
+
+	if p.refs == 0 {
+		go func() {
+			<-p.done
+			p.destroy()
+		}()
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Warnf("Unable to write the response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	log.Debugf("Request failed with status %d: %v", status, err)
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,334 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	app "github.com/konflux-ci/application-api/api/v1alpha1"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/applicationsnapshot"
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/image"
+	"github.com/enterprise-contract/ec-cli/internal/output"
+	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci/fake"
+)
+
+const testDigest = "4e388ab32b10dc8dbc7e28144f552830adc74787c1e2c0824032078a79f227fb"
+
+// testServer returns a server validating with a fake validation function which
+// reports a violation for each component, except for the images of the "good"
+// repository, along with the number of times the validation function was
+// invoked. All images resolve to the testDigest.
+func testServer(t *testing.T, opts Options) (*Server, *atomic.Int32) {
+	client := fake.FakeClient{}
+	client.On("Head", mock.Anything).Return(&v1.Descriptor{MediaType: types.OCIManifestSchema1}, nil)
+	ctx := oci.WithClient(utils.WithFS(context.Background(), afero.NewMemMapFs()), &client)
+	ctx = context.WithValue(ctx, image.RemoteHead, func(name.Reference, ...remote.Option) (*v1.Descriptor, error) {
+		return &v1.Descriptor{Digest: v1.Hash{Algorithm: "sha256", Hex: testDigest}}, nil
+	})
+
+	calls := &atomic.Int32{}
+	validate := func(_ context.Context, comp app.SnapshotComponent, _ *app.SnapshotSpec, _ policy.Policy, _ []evaluator.Evaluator, _ bool) (*output.Output, error) {
+		calls.Add(1)
+		out := &output.Output{
+			ImageAccessibleCheck:      output.VerificationStatus{Passed: true},
+			ImageSignatureCheck:       output.VerificationStatus{Passed: true},
+			AttestationSignatureCheck: output.VerificationStatus{Passed: true},
+			AttestationSyntaxCheck:    output.VerificationStatus{Passed: true},
+			ImageURL:                  comp.ContainerImage,
+		}
+		if !strings.Contains(comp.ContainerImage, "/good") {
+			out.PolicyCheck = []evaluator.Outcome{
+				{
+					Failures: []evaluator.Result{
+						{Message: "Fails always", Metadata: map[string]any{"code": "main.reject"}},
+					},
+				},
+			}
+		}
+		return out, nil
+	}
+
+	s := New(ctx, opts, validate)
+	t.Cleanup(s.Close)
+
+	return s, calls
+}
+
+func request(t *testing.T, s *Server, method string, path string, body string) *httptest.ResponseRecorder {
+	// as with the http.Server BaseContext, requests carry the server context
+	req := httptest.NewRequest(method, path, strings.NewReader(body)).WithContext(s.ctx)
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+
+	return rec
+}
+
+func validationRequest(t *testing.T, r Request) string {
+	if r.Policy == "" {
+		r.Policy = `{"publicKey": ` + toJSON(t, utils.TestPublicKey) + `}`
+	}
+	r.IgnoreRekor = true
+
+	return toJSON(t, r)
+}
+
+func toJSON(t *testing.T, v any) string {
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestHealthAndReadiness(t *testing.T) {
+	s, _ := testServer(t, Options{AllowRequestPolicy: true})
+
+	rec := request(t, s, http.MethodGet, "/healthz", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status": "ok"}`, rec.Body.String())
+
+	rec = request(t, s, http.MethodGet, "/readyz", "")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.JSONEq(t, `{"status": "not ready"}`, rec.Body.String())
+
+	s.SetReady(true)
+	rec = request(t, s, http.MethodGet, "/readyz", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status": "ready"}`, rec.Body.String())
+}
+
+func TestValidateImageBadRequest(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+		err  string
+	}{
+		{
+			name: "not JSON",
+			body: "image: registry.io/repository/image:tag",
+			err:  "invalid request: invalid character 'i' looking for beginning of value",
+		},
+		{
+			name: "unknown field",
+			body: `{"images": "registry.io/repository/image:tag"}`,
+			err:  `invalid request: json: unknown field "images"`,
+		},
+		{
+			name: "nothing to validate",
+			body: `{"policy": "policy.yaml"}`,
+			err:  "either image or snapshot must be provided",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s, calls := testServer(t, Options{AllowRequestPolicy: true})
+
+			rec := request(t, s, http.MethodPost, "/v1/validate/image", c.body)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.JSONEq(t, toJSON(t, map[string]string{"error": c.err}), rec.Body.String())
+			assert.Zero(t, calls.Load())
+		})
+	}
+}
+
+func TestValidateImage(t *testing.T) {
+	s, calls := testServer(t, Options{AllowRequestPolicy: true, Workers: 2})
+
+	var prepared *preparedPolicy
+	for _, body := range []string{
+		validationRequest(t, Request{Image: "registry.io/repository/image:tag"}),
+		validationRequest(t, Request{Snapshot: json.RawMessage(`{"components": [
+			{"name": "one", "containerImage": "registry.io/repository/one:tag"},
+			{"name": "two", "containerImage": "registry.io/repository/two:tag"}
+		]}`)}),
+	} {
+		rec := request(t, s, http.MethodPost, "/v1/validate/image", body)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+		var report applicationsnapshot.Report
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		assert.False(t, report.Success)
+		assert.Equal(t, utils.TestPublicKey, report.Key)
+		require.NotEmpty(t, report.Components)
+		for _, c := range report.Components {
+			assert.False(t, c.Success)
+			require.Len(t, c.Violations, 1)
+			assert.Equal(t, "Fails always", c.Violations[0].Message)
+		}
+
+		// the policy is prepared once and reused
+		require.Len(t, s.policies, 1)
+		for _, p := range s.policies {
+			if prepared == nil {
+				prepared = p
+			}
+			assert.Same(t, prepared, p)
+		}
+	}
+
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestValidateImageRequestPolicy(t *testing.T) {
+	defaultPolicy := `{"publicKey": ` + toJSON(t, utils.TestPublicKey) + `}`
+	s, calls := testServer(t, Options{DefaultPolicy: defaultPolicy})
+
+	for _, r := range []Request{
+		{Image: "registry.io/repository/image:tag", Policy: "policy.yaml"},
+		{Image: "registry.io/repository/image:tag", PublicKey: "k8s://namespace/name"},
+	} {
+		rec := request(t, s, http.MethodPost, "/v1/validate/image", toJSON(t, r))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"error": "invalid request: the policy and the public key are not allowed to be provided by requests"}`, rec.Body.String())
+	}
+	assert.Zero(t, calls.Load())
+
+	rec := request(t, s, http.MethodPost, "/v1/validate/image", toJSON(t, Request{Image: "registry.io/repository/image:tag", IgnoreRekor: true}))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var report applicationsnapshot.Report
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, utils.TestPublicKey, report.Key)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestValidateImagePolicyRefresh(t *testing.T) {
+	s, _ := testServer(t, Options{AllowRequestPolicy: true, PolicyRefresh: time.Hour})
+
+	body := validationRequest(t, Request{Image: "registry.io/repository/image:tag"})
+
+	var previous *preparedPolicy
+	for i := 0; i < 2; i++ {
+		rec := request(t, s, http.MethodPost, "/v1/validate/image", body)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		s.mu.Lock()
+		require.Len(t, s.policies, 1)
+		for _, p := range s.policies {
+			assert.NotSame(t, previous, p)
+			previous = p
+			// due for a refresh
+			p.created = p.created.Add(-2 * time.Hour)
+		}
+		s.mu.Unlock()
+	}
+
+	assert.NotNil(t, previous)
+}
+
+func TestValidateImagePolicyIdle(t *testing.T) {
+	s, _ := testServer(t, Options{AllowRequestPolicy: true, PolicyRefresh: 10 * time.Millisecond})
+
+	body := validationRequest(t, Request{Image: "registry.io/repository/image:tag"})
+	rec := request(t, s, http.MethodPost, "/v1/validate/image", body)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	assert.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return len(s.policies) == 0
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestValidateImageMaxPolicies(t *testing.T) {
+	s, _ := testServer(t, Options{AllowRequestPolicy: true, MaxPolicies: 2})
+
+	var keys []string
+	for _, effectiveTime := range []string{"2024-01-01T00:00:00Z", "2024-01-02T00:00:00Z", "2024-01-03T00:00:00Z"} {
+		body := validationRequest(t, Request{Image: "registry.io/repository/image:tag", EffectiveTime: effectiveTime})
+		rec := request(t, s, http.MethodPost, "/v1/validate/image", body)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		for key := range s.policies {
+			if !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	// the least recently used policy made room for the last one
+	require.Len(t, keys, 3)
+	assert.Len(t, s.policies, 2)
+	assert.NotContains(t, s.policies, keys[0])
+}
+
+func TestValidateImageEffectiveTimeNow(t *testing.T) {
+	s, _ := testServer(t, Options{AllowRequestPolicy: true})
+
+	body := validationRequest(t, Request{Image: "registry.io/repository/image:tag"})
+	rec := request(t, s, http.MethodPost, "/v1/validate/image", body)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	require.Len(t, s.policies, 1)
+	for _, p := range s.policies {
+		// the current time is taken at each use of the prepared policy
+		prepared := p.policy.EffectiveTime()
+		assert.Eventually(t, func() bool {
+			return p.policy.EffectiveTime().After(prepared)
+		}, time.Second, time.Millisecond)
+	}
+}
+
+func TestValidateImagePolicyError(t *testing.T) {
+	s, calls := testServer(t, Options{AllowRequestPolicy: true})
+
+	body := validationRequest(t, Request{Image: "registry.io/repository/image:tag", Policy: `{"unknown": "field"}`})
+	rec := request(t, s, http.MethodPost, "/v1/validate/image", body)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), "additionalProperties 'unknown' not allowed")
+
+	// failed policies are not reused
+	assert.Empty(t, s.policies)
+	assert.Zero(t, calls.Load())
+}
+
+func TestValidateImageTooManyRequests(t *testing.T) {
+	s, calls := testServer(t, Options{AllowRequestPolicy: true, MaxConcurrency: 1, Timeout: 10 * time.Millisecond})
+
+	// occupy the only slot
+	s.slots <- struct{}{}
+	defer func() { <-s.slots }()
+
+	body := validationRequest(t, Request{Image: "registry.io/repository/image:tag"})
+	rec := request(t, s, http.MethodPost, "/v1/validate/image", body)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.JSONEq(t, `{"error": "too many concurrent validations"}`, rec.Body.String())
+	assert.Zero(t, calls.Load())
+}
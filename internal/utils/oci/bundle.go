@@ -0,0 +1,515 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	ssldsse "github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	cbundle "github.com/sigstore/cosign/v2/pkg/cosign/bundle"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+	cosignTypes "github.com/sigstore/cosign/v2/pkg/types"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature/dsse"
+	log "github.com/sirupsen/logrus"
+)
+
+// SigstoreBundleMediaType is the prefix of the media types used for Sigstore
+// bundles, e.g. application/vnd.dev.sigstore.bundle+json;version=0.2 or
+// application/vnd.dev.sigstore.bundle.v0.3+json
+const SigstoreBundleMediaType = "application/vnd.dev.sigstore.bundle"
+
+// sigstoreBundle holds the parts of the protobuf JSON encoded Sigstore bundle
+// needed to verify a DSSE envelope within it
+type sigstoreBundle struct {
+	MediaType            string `json:"mediaType"`
+	VerificationMaterial struct {
+		X509CertificateChain *struct {
+			Certificates []rawBytes `json:"certificates"`
+		} `json:"x509CertificateChain"`
+		Certificate *rawBytes   `json:"certificate"`
+		TlogEntries []tlogEntry `json:"tlogEntries"`
+	} `json:"verificationMaterial"`
+	DSSEEnvelope *ssldsse.Envelope `json:"dsseEnvelope"`
+}
+
+type rawBytes struct {
+	RawBytes []byte `json:"rawBytes"`
+}
+
+type tlogEntry struct {
+	LogIndex json.Number `json:"logIndex"`
+	LogID    struct {
+		KeyID []byte `json:"keyId"`
+	} `json:"logId"`
+	IntegratedTime   json.Number `json:"integratedTime"`
+	InclusionPromise *struct {
+		SignedEntryTimestamp []byte `json:"signedEntryTimestamp"`
+	} `json:"inclusionPromise"`
+	CanonicalizedBody []byte `json:"canonicalizedBody"`
+}
+
+// certificates returns the signing certificate and the rest of the certificate
+// chain, if any, from the bundle
+func (b sigstoreBundle) certificates() (*x509.Certificate, []*x509.Certificate, error) {
+	var raw []rawBytes
+	if b.VerificationMaterial.X509CertificateChain != nil {
+		raw = b.VerificationMaterial.X509CertificateChain.Certificates
+	} else if b.VerificationMaterial.Certificate != nil {
+		raw = []rawBytes{*b.VerificationMaterial.Certificate}
+	}
+
+	if len(raw) == 0 {
+		return nil, nil, nil
+	}
+
+	certs := make([]*x509.Certificate, 0, len(raw))
+	for _, r := range raw {
+		cert, err := x509.ParseCertificate(r.RawBytes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parsing certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+
+	return certs[0], certs[1:], nil
+}
+
+// SigstoreBundles returns the manifests of the Sigstore bundles attached to the
+// image with the digest via the OCI referrers API, or the referrers tag schema
+func (c *defaultClient) SigstoreBundles(digest name.Digest) ([]v1.Image, error) {
+	index, err := remote.Referrers(digest, c.opts...)
+	if err != nil {
+		return nil, fmt.Errorf("fetching referrers: %w", err)
+	}
+
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("fetching referrers: %w", err)
+	}
+
+	var bundles []v1.Image
+	for _, desc := range manifest.Manifests {
+		if !strings.HasPrefix(desc.ArtifactType, SigstoreBundleMediaType) {
+			continue
+		}
+
+		ref := digest.Context().Digest(desc.Digest.String())
+		img, err := remote.Image(ref, c.opts...)
+		if err != nil {
+			return nil, fmt.Errorf("fetching bundle %s: %w", ref, err)
+		}
+		bundles = append(bundles, img)
+	}
+
+	return bundles, nil
+}
+
+// withBundleAttestations adds the attestations from the Sigstore bundles of
+// the image with the digest h to the attestations verified by cosign, err is
+// the error cosign returned. The verified attestations are returned in the
+// same form as the attestations fetched via the cosign tag scheme.
+func withBundleAttestations(ctx context.Context, ref name.Reference, h v1.Hash, opts *cosign.CheckOpts, attestations []oci.Signature, verified bool, err error, bundles []v1.Image, berr error) ([]oci.Signature, bool, error) {
+	var noMatching *cosign.ErrNoMatchingAttestations
+	if err != nil && !errors.As(err, &noMatching) {
+		return nil, false, err
+	}
+
+	var verifiedBundles []oci.Signature
+	bundlesVerified := false
+	if berr == nil {
+		verifiedBundles, bundlesVerified, berr = verifyBundles(ctx, bundles, h, opts)
+	}
+
+	if len(verifiedBundles) == 0 {
+		if err != nil && berr != nil {
+			return nil, false, errors.Join(err, berr)
+		}
+		if berr != nil {
+			log.Debugf("Unable to verify Sigstore bundles attached to %q: %v", ref, berr)
+		}
+		return attestations, verified, err
+	}
+
+	return append(attestations, verifiedBundles...), verified || bundlesVerified, nil
+}
+
+// verifyBundles verifies the DSSE envelopes within the Sigstore bundles for
+// the image with the digest h. An error is returned only when bundles were
+// found but none of them could be verified.
+func verifyBundles(ctx context.Context, bundles []v1.Image, h v1.Hash, opts *cosign.CheckOpts) ([]oci.Signature, bool, error) {
+	var attestations []oci.Signature
+	var errs []error
+	verified := false
+	for _, img := range bundles {
+		d, err := img.Digest()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		b, err := readBundle(img)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("bundle %s: %w", d, err))
+			continue
+		}
+
+		if b.DSSEEnvelope == nil {
+			log.Debugf("Skipping Sigstore bundle %s without a DSSE envelope", d)
+			continue
+		}
+
+		att, bundleVerified, err := verifyBundle(ctx, b, h, opts)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("bundle %s: %w", d, err))
+			continue
+		}
+
+		log.Debugf("Verified Sigstore bundle %s", d)
+		attestations = append(attestations, att)
+		verified = verified || bundleVerified
+	}
+
+	if len(attestations) == 0 && len(errs) > 0 {
+		return nil, false, errors.Join(errs...)
+	}
+
+	return attestations, verified, nil
+}
+
+// readBundle reads the Sigstore bundle from the layer of the bundle manifest
+func readBundle(img v1.Image) (*sigstoreBundle, error) {
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, layer := range layers {
+		mediaType, err := layer.MediaType()
+		if err != nil {
+			return nil, err
+		}
+
+		if !strings.HasPrefix(string(mediaType), SigstoreBundleMediaType) {
+			continue
+		}
+
+		rc, err := layer.Uncompressed()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+
+		data, err := io.ReadAll(rc)
+		if err != nil {
+			return nil, err
+		}
+
+		var b sigstoreBundle
+		if err := json.Unmarshal(data, &b); err != nil {
+			return nil, fmt.Errorf("malformed Sigstore bundle: %w", err)
+		}
+
+		return &b, nil
+	}
+
+	return nil, errors.New("no Sigstore bundle layer found")
+}
+
+// verifyBundle verifies the DSSE envelope within the bundle following the
+// steps cosign performs for attestations: the signature is verified using the
+// provided key or the certificate from the bundle, the subject of the statement
+// is checked against the image digest and the claims with the ClaimVerifier,
+// which is required, and unless the transparency log is ignored, the inclusion
+// promise of the transparency log entry is verified and the entry time is used
+// to check the validity of the certificate. The attestation is returned in the
+// same form as cosign returns it.
+func verifyBundle(ctx context.Context, b *sigstoreBundle, h v1.Hash, co *cosign.CheckOpts) (oci.Signature, bool, error) {
+	if co.ClaimVerifier == nil {
+		return nil, false, errors.New("no claim verifier configured")
+	}
+
+	envelope := b.DSSEEnvelope
+	if envelope.PayloadType != cosignTypes.IntotoPayloadType {
+		return nil, false, fmt.Errorf("invalid payloadType %s on envelope, expected %s", envelope.PayloadType, cosignTypes.IntotoPayloadType)
+	}
+
+	cert, chain, err := b.certificates()
+	if err != nil {
+		return nil, false, err
+	}
+
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, false, err
+	}
+
+	attOpts := []static.Option{static.WithLayerMediaType(cosignTypes.DssePayloadType)}
+	if cert != nil {
+		certPEM, err := cryptoutils.MarshalCertificateToPEM(cert)
+		if err != nil {
+			return nil, false, err
+		}
+		chainPEM, err := cryptoutils.MarshalCertificatesToPEM(chain)
+		if err != nil {
+			return nil, false, err
+		}
+		attOpts = append(attOpts, static.WithCertChain(certPEM, chainPEM))
+	}
+
+	att, err := static.NewAttestation(payload, attOpts...)
+	if err != nil {
+		return nil, false, err
+	}
+
+	verifier := co.SigVerifier
+	if verifier == nil {
+		if cert == nil {
+			return nil, false, errors.New("no certificate found in bundle")
+		}
+
+		pool := co.IntermediateCerts
+		if pool == nil && len(chain) > 1 {
+			// The last certificate in the chain is the root
+			pool = x509.NewCertPool()
+			for _, c := range chain[:len(chain)-1] {
+				pool.AddCert(c)
+			}
+		}
+
+		verifier, err = cosign.ValidateAndUnpackCertWithIntermediates(cert, co, pool)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	envelopeVerifier, err := ssldsse.NewEnvelopeVerifier(&dsse.VerifierAdapter{SignatureVerifier: verifier})
+	if err != nil {
+		return nil, false, err
+	}
+
+	if _, err := envelopeVerifier.Verify(ctx, envelope); err != nil {
+		return nil, false, err
+	}
+
+	// The subject of the statement is always checked, a validly signed bundle
+	// for a different image must never be accepted
+	if err := cosign.IntotoSubjectClaimVerifier(att, h, nil); err != nil {
+		return nil, false, err
+	}
+
+	if err := co.ClaimVerifier(att, h, co.Annotations); err != nil {
+		return nil, false, err
+	}
+
+	verificationTime := time.Now()
+	bundleVerified := false
+	if !co.IgnoreTlog {
+		integratedTime, err := verifyTlogEntries(b, co)
+		if err != nil {
+			return nil, false, err
+		}
+		verificationTime = integratedTime
+		bundleVerified = true
+	}
+
+	if cert != nil {
+		if err := cosign.CheckExpiry(cert, verificationTime); err != nil {
+			return nil, false, err
+		}
+	}
+
+	return att, bundleVerified, nil
+}
+
+// verifyTlogEntries verifies the signed entry timestamp of the transparency
+// log entries in the bundle and that the entries were made for the DSSE
+// envelope in the bundle. Returns the time the entry was integrated into the
+// transparency log.
+func verifyTlogEntries(b *sigstoreBundle, co *cosign.CheckOpts) (time.Time, error) {
+	if len(b.VerificationMaterial.TlogEntries) == 0 {
+		return time.Time{}, errors.New("no transparency log entry found in bundle")
+	}
+
+	if co.RekorPubKeys == nil || len(co.RekorPubKeys.Keys) == 0 {
+		return time.Time{}, errors.New("no trusted Rekor public keys provided")
+	}
+
+	var errs []error
+	for _, entry := range b.VerificationMaterial.TlogEntries {
+		integratedTime, err := verifyTlogEntry(entry, b.DSSEEnvelope, co)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		return integratedTime, nil
+	}
+
+	return time.Time{}, errors.Join(errs...)
+}
+
+func verifyTlogEntry(entry tlogEntry, envelope *ssldsse.Envelope, co *cosign.CheckOpts) (time.Time, error) {
+	if entry.InclusionPromise == nil {
+		return time.Time{}, errors.New("transparency log entry has no inclusion promise")
+	}
+
+	logID := hex.EncodeToString(entry.LogID.KeyID)
+	key, ok := co.RekorPubKeys.Keys[logID]
+	if !ok {
+		return time.Time{}, fmt.Errorf("transparency log entry from untrusted log %q", logID)
+	}
+
+	pub, ok := key.PubKey.(*ecdsa.PublicKey)
+	if !ok {
+		return time.Time{}, fmt.Errorf("unsupported Rekor public key type %T", key.PubKey)
+	}
+
+	integratedTime, err := entry.IntegratedTime.Int64()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid integrated time: %w", err)
+	}
+
+	logIndex, err := entry.LogIndex.Int64()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid log index: %w", err)
+	}
+
+	payload := cbundle.RekorPayload{
+		Body:           base64.StdEncoding.EncodeToString(entry.CanonicalizedBody),
+		IntegratedTime: integratedTime,
+		LogIndex:       logIndex,
+		LogID:          logID,
+	}
+
+	if err := cosign.VerifySET(payload, entry.InclusionPromise.SignedEntryTimestamp, pub); err != nil {
+		return time.Time{}, err
+	}
+
+	if err := matchTlogEntry(entry.CanonicalizedBody, envelope); err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(integratedTime, 0), nil
+}
+
+// tlogEntryBody holds the parts of the dsse v0.0.1 and intoto v0.0.2 Rekor
+// entries needed to relate the entry to a DSSE envelope
+type tlogEntryBody struct {
+	Kind       string `json:"kind"`
+	APIVersion string `json:"apiVersion"`
+	Spec       struct {
+		// dsse v0.0.1
+		PayloadHash *hash `json:"payloadHash"`
+		Signatures  []struct {
+			Signature string `json:"signature"`
+		} `json:"signatures"`
+		// intoto v0.0.2
+		Content *struct {
+			PayloadHash *hash `json:"payloadHash"`
+			Envelope    struct {
+				Signatures []struct {
+					// base64 encoded base64 signature
+					Sig []byte `json:"sig"`
+				} `json:"signatures"`
+			} `json:"envelope"`
+		} `json:"content"`
+	} `json:"spec"`
+}
+
+type hash struct {
+	Algorithm string `json:"algorithm"`
+	Value     string `json:"value"`
+}
+
+// matchTlogEntry checks that the transparency log entry body records the
+// payload and a signature of the DSSE envelope
+func matchTlogEntry(body []byte, envelope *ssldsse.Envelope) error {
+	var entry tlogEntryBody
+	if err := json.Unmarshal(body, &entry); err != nil {
+		return fmt.Errorf("malformed transparency log entry: %w", err)
+	}
+
+	var payloadHash *hash
+	var signatures []string
+	switch {
+	case entry.Kind == "dsse" && entry.APIVersion == "0.0.1":
+		payloadHash = entry.Spec.PayloadHash
+		for _, s := range entry.Spec.Signatures {
+			signatures = append(signatures, s.Signature)
+		}
+	case entry.Kind == "intoto" && entry.APIVersion == "0.0.2" && entry.Spec.Content != nil:
+		payloadHash = entry.Spec.Content.PayloadHash
+		for _, s := range entry.Spec.Content.Envelope.Signatures {
+			signatures = append(signatures, string(s.Sig))
+		}
+	default:
+		return fmt.Errorf("unsupported transparency log entry %s/%s", entry.Kind, entry.APIVersion)
+	}
+
+	decoded, err := envelope.DecodeB64Payload()
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256(decoded)
+	if payloadHash == nil || payloadHash.Algorithm != "sha256" || payloadHash.Value != hex.EncodeToString(digest[:]) {
+		return errors.New("transparency log entry does not match the payload of the envelope")
+	}
+
+	for _, logged := range signatures {
+		for _, s := range envelope.Signatures {
+			if sigEqual(logged, s.Sig) {
+				return nil
+			}
+		}
+	}
+
+	return errors.New("transparency log entry does not match the signatures of the envelope")
+}
+
+// sigEqual compares the base64 encoded signatures, tolerating differences in
+// padding or alphabet
+func sigEqual(a, b string) bool {
+	decode := func(s string) []byte {
+		for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+			if d, err := enc.DecodeString(s); err == nil {
+				return d
+			}
+		}
+		return nil
+	}
+
+	da, db := decode(a), decode(b)
+	return da != nil && bytes.Equal(da, db)
+}
@@ -0,0 +1,350 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+	cosignTypes "github.com/sigstore/cosign/v2/pkg/types"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/dsse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testBundleMediaType = SigstoreBundleMediaType + ".v0.3+json"
+
+type bundleFixture struct {
+	signer      signature.SignerVerifier
+	rekorKey    *ecdsa.PrivateKey
+	rekorPubKey *cosign.TrustedTransparencyLogPubKeys
+}
+
+func newBundleFixture(t *testing.T) bundleFixture {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	signer, err := signature.LoadECDSASignerVerifier(key, crypto.SHA256)
+	require.NoError(t, err)
+
+	rekorKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	logID, err := cosign.GetTransparencyLogID(rekorKey.Public())
+	require.NoError(t, err)
+
+	return bundleFixture{
+		signer:   signer,
+		rekorKey: rekorKey,
+		rekorPubKey: &cosign.TrustedTransparencyLogPubKeys{
+			Keys: map[string]cosign.TransparencyLogPubKey{
+				logID: {PubKey: rekorKey.Public()},
+			},
+		},
+	}
+}
+
+// bundle creates a Sigstore bundle with a DSSE envelope holding an in-toto
+// statement about the given digest and a dsse v0.0.1 transparency log entry
+// for it, the body of the entry can be modified using the tamper function
+func (f bundleFixture) bundle(t *testing.T, digest v1.Hash, tamper func(map[string]any)) []byte {
+	statement := fmt.Sprintf(`{"_type":"https://in-toto.io/Statement/v0.1","predicateType":"https://example.com/predicate","subject":[{"name":"image","digest":{%q:%q}}],"predicate":{}}`, digest.Algorithm, digest.Hex)
+
+	envelopeJSON, err := dsse.WrapSigner(f.signer, cosignTypes.IntotoPayloadType).SignMessage(bytes.NewReader([]byte(statement)))
+	require.NoError(t, err)
+
+	var envelope map[string]any
+	require.NoError(t, json.Unmarshal(envelopeJSON, &envelope))
+	sig := envelope["signatures"].([]any)[0].(map[string]any)["sig"].(string)
+
+	pub, err := f.signer.PublicKey()
+	require.NoError(t, err)
+	pubPEM, err := cryptoutils.MarshalPublicKeyToPEM(pub)
+	require.NoError(t, err)
+
+	payloadHash := sha256.Sum256([]byte(statement))
+	entry := map[string]any{
+		"apiVersion": "0.0.1",
+		"kind":       "dsse",
+		"spec": map[string]any{
+			"payloadHash": map[string]any{"algorithm": "sha256", "value": hex.EncodeToString(payloadHash[:])},
+			"signatures":  []any{map[string]any{"signature": sig, "verifier": base64.StdEncoding.EncodeToString(pubPEM)}},
+		},
+	}
+	if tamper != nil {
+		tamper(entry)
+	}
+	body, err := json.Marshal(entry)
+	require.NoError(t, err)
+
+	logID, err := cosign.GetTransparencyLogID(f.rekorKey.Public())
+	require.NoError(t, err)
+	logIDBytes, err := hex.DecodeString(logID)
+	require.NoError(t, err)
+
+	integratedTime := time.Now().Unix()
+	b64Body := base64.StdEncoding.EncodeToString(body)
+	// canonical JSON of the cosign bundle.RekorPayload
+	canonical := fmt.Sprintf(`{"body":%q,"integratedTime":%d,"logID":%q,"logIndex":%d}`, b64Body, integratedTime, logID, 42)
+	setDigest := sha256.Sum256([]byte(canonical))
+	set, err := ecdsa.SignASN1(rand.Reader, f.rekorKey, setDigest[:])
+	require.NoError(t, err)
+
+	bundle := map[string]any{
+		"mediaType": testBundleMediaType,
+		"verificationMaterial": map[string]any{
+			"publicKey": map[string]any{"hint": ""},
+			"tlogEntries": []any{map[string]any{
+				"logIndex":          "42",
+				"logId":             map[string]any{"keyId": base64.StdEncoding.EncodeToString(logIDBytes)},
+				"kindVersion":       map[string]any{"kind": "dsse", "version": "0.0.1"},
+				"integratedTime":    fmt.Sprint(integratedTime),
+				"inclusionPromise":  map[string]any{"signedEntryTimestamp": base64.StdEncoding.EncodeToString(set)},
+				"canonicalizedBody": b64Body,
+			}},
+		},
+		"dsseEnvelope": envelope,
+	}
+
+	data, err := json.Marshal(bundle)
+	require.NoError(t, err)
+
+	return data
+}
+
+func (f bundleFixture) checkOpts() *cosign.CheckOpts {
+	return &cosign.CheckOpts{
+		SigVerifier:   f.signer,
+		RekorPubKeys:  f.rekorPubKey,
+		ClaimVerifier: cosign.IntotoSubjectClaimVerifier,
+	}
+}
+
+// pushReferrer pushes the Sigstore bundle as an artifact referring to the
+// image with the given digest
+func pushReferrer(t *testing.T, digest name.Digest, subject v1.Descriptor, bundle []byte) {
+	layer := static.NewLayer(bundle, types.MediaType(testBundleMediaType))
+	img, err := mutate.AppendLayers(empty.Image, layer)
+	require.NoError(t, err)
+	img = mutate.MediaType(img, types.OCIManifestSchema1)
+	img = mutate.ConfigMediaType(img, types.MediaType(testBundleMediaType))
+	img = mutate.Subject(img, subject).(v1.Image)
+
+	d, err := img.Digest()
+	require.NoError(t, err)
+
+	require.NoError(t, remote.Write(digest.Context().Digest(d.String()), img))
+}
+
+func TestVerifyImageAttestationsSigstoreBundle(t *testing.T) {
+	for _, referrers := range []bool{true, false} {
+		t.Run(fmt.Sprintf("referrers API: %v", referrers), func(t *testing.T) {
+			registry := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0)), registry.WithReferrersSupport(referrers)))
+			t.Cleanup(registry.Close)
+
+			u, err := url.Parse(registry.URL)
+			require.NoError(t, err)
+
+			img, err := random.Image(1024, 1)
+			require.NoError(t, err)
+			img = mutate.MediaType(img, types.OCIManifestSchema1)
+
+			ref, err := name.ParseReference(fmt.Sprintf("localhost:%s/repository/image:tag", u.Port()))
+			require.NoError(t, err)
+			require.NoError(t, remote.Write(ref, img))
+
+			imgDigest, err := img.Digest()
+			require.NoError(t, err)
+			digest := ref.Context().Digest(imgDigest.String())
+
+			f := newBundleFixture(t)
+			client := &defaultClient{ctx: context.Background()}
+
+			_, _, err = client.VerifyImageAttestations(ref, f.checkOpts())
+			var noMatching *cosign.ErrNoMatchingAttestations
+			require.ErrorAs(t, err, &noMatching)
+
+			desc, err := remote.Head(digest)
+			require.NoError(t, err)
+			pushReferrer(t, digest, *desc, f.bundle(t, imgDigest, nil))
+
+			attestations, bundleVerified, err := client.VerifyImageAttestations(ref, f.checkOpts())
+			require.NoError(t, err)
+			assert.True(t, bundleVerified)
+			require.Len(t, attestations, 1)
+
+			mediaType, err := attestations[0].MediaType()
+			require.NoError(t, err)
+			assert.Equal(t, types.MediaType(cosignTypes.DssePayloadType), mediaType)
+			assert.Equal(t, "https://example.com/predicate", predicateTypeOf(attestations[0]))
+		})
+	}
+}
+
+func TestVerifyBundle(t *testing.T) {
+	digest := v1.Hash{Algorithm: "sha256", Hex: "4e388ab32b10dc8dbc7e28144f552830adc74787c1e2c0824032078a79f227fb"}
+	other := newBundleFixture(t)
+
+	cases := []struct {
+		name           string
+		tamper         func(map[string]any)
+		digest         v1.Hash
+		opts           func(f bundleFixture) *cosign.CheckOpts
+		bundleVerified bool
+		err            string
+	}{
+		{
+			name:           "valid",
+			bundleVerified: true,
+		},
+		{
+			name: "ignored transparency log",
+			opts: func(f bundleFixture) *cosign.CheckOpts {
+				o := f.checkOpts()
+				o.IgnoreTlog = true
+				o.RekorPubKeys = nil
+				return o
+			},
+		},
+		{
+			name: "wrong key",
+			opts: func(f bundleFixture) *cosign.CheckOpts {
+				o := f.checkOpts()
+				o.SigVerifier = other.signer
+				return o
+			},
+			err: "accepted signatures do not match threshold",
+		},
+		{
+			name: "untrusted log",
+			opts: func(f bundleFixture) *cosign.CheckOpts {
+				o := f.checkOpts()
+				o.RekorPubKeys = other.rekorPubKey
+				return o
+			},
+			err: "transparency log entry from untrusted log",
+		},
+		{
+			name: "mismatched subject",
+			digest: v1.Hash{
+				Algorithm: "sha256",
+				Hex:       "0000000000000000000000000000000000000000000000000000000000000000",
+			},
+			err: "no matching subject digest found",
+		},
+		{
+			name: "mismatched subject with permissive claim verifier",
+			digest: v1.Hash{
+				Algorithm: "sha256",
+				Hex:       "0000000000000000000000000000000000000000000000000000000000000000",
+			},
+			opts: func(f bundleFixture) *cosign.CheckOpts {
+				o := f.checkOpts()
+				o.ClaimVerifier = func(oci.Signature, v1.Hash, map[string]any) error { return nil }
+				return o
+			},
+			err: "no matching subject digest found",
+		},
+		{
+			name: "no claim verifier",
+			opts: func(f bundleFixture) *cosign.CheckOpts {
+				o := f.checkOpts()
+				o.ClaimVerifier = nil
+				return o
+			},
+			err: "no claim verifier configured",
+		},
+		{
+			name: "entry for other payload",
+			tamper: func(entry map[string]any) {
+				entry["spec"].(map[string]any)["payloadHash"] = map[string]any{"algorithm": "sha256", "value": "0000"}
+			},
+			err: "transparency log entry does not match the payload of the envelope",
+		},
+		{
+			name: "entry for other signature",
+			tamper: func(entry map[string]any) {
+				entry["spec"].(map[string]any)["signatures"] = []any{map[string]any{"signature": base64.StdEncoding.EncodeToString([]byte("other"))}}
+			},
+			err: "transparency log entry does not match the signatures of the envelope",
+		},
+		{
+			name: "unsupported entry",
+			tamper: func(entry map[string]any) {
+				entry["kind"] = "rekord"
+			},
+			err: "unsupported transparency log entry rekord/0.0.1",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f := newBundleFixture(t)
+
+			var b sigstoreBundle
+			require.NoError(t, json.Unmarshal(f.bundle(t, digest, c.tamper), &b))
+
+			opts := f.checkOpts()
+			if c.opts != nil {
+				opts = c.opts(f)
+			}
+
+			h := digest
+			if c.digest.Hex != "" {
+				h = c.digest
+			}
+
+			att, bundleVerified, err := verifyBundle(context.Background(), &b, h, opts)
+			if c.err != "" {
+				assert.ErrorContains(t, err, c.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, c.bundleVerified, bundleVerified)
+			assert.Equal(t, "https://example.com/predicate", predicateTypeOf(att))
+		})
+	}
+}
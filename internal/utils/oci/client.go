@@ -18,6 +18,9 @@ package oci
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path"
@@ -32,6 +35,7 @@ import (
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/sigstore/cosign/v2/pkg/cosign"
 	"github.com/sigstore/cosign/v2/pkg/oci"
+	"github.com/sigstore/cosign/v2/pkg/oci/mutate"
 	ociremote "github.com/sigstore/cosign/v2/pkg/oci/remote"
 	log "github.com/sirupsen/logrus"
 
@@ -99,6 +103,9 @@ type Client interface {
 	Image(name.Reference) (v1.Image, error)
 	Layer(name.Digest) (v1.Layer, error)
 	Index(name.Reference) (v1.ImageIndex, error)
+	AttachAttestation(name.Digest, oci.Signature, string) error
+	SignedEntity(name.Reference) (oci.SignedEntity, error)
+	SigstoreBundles(name.Digest) ([]v1.Image, error)
 }
 
 func WithClient(ctx context.Context, client Client) context.Context {
@@ -133,6 +140,9 @@ func (c *defaultClient) VerifyImageSignatures(ref name.Reference, opts *cosign.C
 	}
 
 	opts.RegistryClientOpts = append(opts.RegistryClientOpts, ociremote.WithRemoteOptions(c.opts...))
+	// Look for signatures attached via the OCI 1.1 referrers API first, cosign
+	// falls back to the legacy tag scheme when none are found
+	opts.ExperimentalOCI11 = true
 	return cosign.VerifyImageSignatures(c.ctx, ref, opts)
 }
 
@@ -144,7 +154,27 @@ func (c *defaultClient) VerifyImageAttestations(ref name.Reference, opts *cosign
 	}
 
 	opts.RegistryClientOpts = append(opts.RegistryClientOpts, ociremote.WithRemoteOptions(c.opts...))
-	return cosign.VerifyImageAttestations(c.ctx, ref, opts)
+	attestations, bundleVerified, err := cosign.VerifyImageAttestations(c.ctx, ref, opts)
+	var noMatching *cosign.ErrNoMatchingAttestations
+	if err != nil && !errors.As(err, &noMatching) {
+		return nil, false, err
+	}
+
+	// In addition to the attestations attached using the legacy tag scheme,
+	// include the attestations from Sigstore bundles attached via the OCI 1.1
+	// referrers API
+	digest, derr := ociremote.ResolveDigest(ref, ociremote.WithRemoteOptions(c.opts...))
+	if derr != nil {
+		return nil, false, derr
+	}
+
+	h, herr := v1.NewHash(digest.DigestStr())
+	if herr != nil {
+		return nil, false, herr
+	}
+
+	bundles, berr := c.SigstoreBundles(digest)
+	return withBundleAttestations(c.ctx, ref, h, opts, attestations, bundleVerified, err, bundles, berr)
 }
 
 func (c *defaultClient) Head(ref name.Reference) (*v1.Descriptor, error) {
@@ -250,3 +280,108 @@ func (c *defaultClient) Index(ref name.Reference) (v1.ImageIndex, error) {
 
 	return index, nil
 }
+
+// AttachAttestation attaches the attestation to the image with the given digest
+// replacing any existing attestations with the same predicate type
+func (c *defaultClient) AttachAttestation(ref name.Digest, attestation oci.Signature, predicateType string) error {
+	if trace.IsEnabled() {
+		region := trace.StartRegion(c.ctx, "ec:oci-attach-attestation")
+		defer region.End()
+		trace.Logf(c.ctx, "", "image=%q", ref)
+	}
+
+	opts := []ociremote.Option{ociremote.WithRemoteOptions(c.opts...)}
+
+	// The remote entity is not needed to attach to it, so a placeholder is used
+	se := ociremote.SignedUnknown(ref, opts...)
+
+	se, err := mutate.AttachAttestationToEntity(se, attestation, mutate.WithReplaceOp(replacePredicate(predicateType)))
+	if err != nil {
+		return fmt.Errorf("attaching attestation: %w", err)
+	}
+
+	return ociremote.WriteAttestations(ref.Repository, se, opts...)
+}
+
+// SignedEntity returns the image or image index along with its signatures and
+// attestations attached using the cosign tag scheme
+func (c *defaultClient) SignedEntity(ref name.Reference) (oci.SignedEntity, error) {
+	if trace.IsEnabled() {
+		region := trace.StartRegion(c.ctx, "ec:oci-signed-entity")
+		defer region.End()
+		trace.Logf(c.ctx, "", "image=%q", ref)
+	}
+
+	se, err := ociremote.SignedEntity(ref, ociremote.WithRemoteOptions(c.opts...))
+	if err != nil {
+		return nil, fmt.Errorf("fetching signed entity: %w", err)
+	}
+
+	return se, nil
+}
+
+// replacePredicate is a mutate.ReplaceOp that drops the existing attestations
+// with the given predicate type. Unlike the cosign implementation, existing
+// attestations whose predicate type can't be determined are retained.
+type replacePredicate string
+
+type replacedAttestations struct {
+	oci.Signatures
+	attestations []oci.Signature
+}
+
+func (r *replacedAttestations) Get() ([]oci.Signature, error) {
+	return r.attestations, nil
+}
+
+func (r replacePredicate) Replace(existing oci.Signatures, attestation oci.Signature) (oci.Signatures, error) {
+	attestations, err := existing.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	replaced := &replacedAttestations{
+		Signatures:   existing,
+		attestations: []oci.Signature{attestation},
+	}
+	for _, a := range attestations {
+		if predicateTypeOf(a) == string(r) {
+			log.Debugf("Replacing attestation with predicate type %q", r)
+			continue
+		}
+		replaced.attestations = append(replaced.attestations, a)
+	}
+
+	return replaced, nil
+}
+
+// predicateTypeOf returns the predicate type of the in-toto statement within
+// the DSSE envelope of the attestation, or an empty string if it can't be
+// determined
+func predicateTypeOf(attestation oci.Signature) string {
+	payload, err := attestation.Payload()
+	if err != nil {
+		return ""
+	}
+
+	var envelope struct {
+		Payload string `json:"payload"`
+	}
+	if err := json.Unmarshal(payload, &envelope); err != nil {
+		return ""
+	}
+
+	statement, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return ""
+	}
+
+	var header struct {
+		PredicateType string `json:"predicateType"`
+	}
+	if err := json.Unmarshal(statement, &header); err != nil {
+		return ""
+	}
+
+	return header.PredicateType
+}
@@ -21,6 +21,8 @@ package oci
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
@@ -35,6 +37,9 @@ import (
 	"github.com/google/go-containerregistry/pkg/v1/random"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+	ociremote "github.com/sigstore/cosign/v2/pkg/oci/remote"
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -175,6 +180,65 @@ func TestLayer(t *testing.T) {
 	assert.Equal(t, fetchCount, blobDownloadCount)
 }
 
+func TestAttachAttestation(t *testing.T) {
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+
+	registry := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	t.Cleanup(registry.Close)
+
+	u, err := url.Parse(registry.URL)
+	require.NoError(t, err)
+
+	ref, err := name.ParseReference(fmt.Sprintf("localhost:%s/repository/image:tag", u.Port()))
+	require.NoError(t, err)
+	require.NoError(t, remote.Push(ref, img))
+
+	imgDigest, err := img.Digest()
+	require.NoError(t, err)
+	digest := ref.Context().Digest(imgDigest.String())
+
+	attestation := func(predicateType, value string) oci.Signature {
+		statement := fmt.Sprintf(`{"_type":"https://in-toto.io/Statement/v1","predicateType":%q,"predicate":{"value":%q}}`, predicateType, value)
+		envelope := fmt.Sprintf(`{"payloadType":"application/vnd.in-toto+json","payload":%q,"signatures":[]}`, base64.StdEncoding.EncodeToString([]byte(statement)))
+		a, err := static.NewAttestation([]byte(envelope))
+		require.NoError(t, err)
+		return a
+	}
+
+	client := defaultClient{}
+	require.NoError(t, client.AttachAttestation(digest, attestation("https://example.com/a", "first"), "https://example.com/a"))
+	require.NoError(t, client.AttachAttestation(digest, attestation("https://example.com/b", "other"), "https://example.com/b"))
+	require.NoError(t, client.AttachAttestation(digest, attestation("https://example.com/a", "second"), "https://example.com/a"))
+
+	se, err := ociremote.SignedEntity(digest)
+	require.NoError(t, err)
+	attestations, err := se.Attestations()
+	require.NoError(t, err)
+	got, err := attestations.Get()
+	require.NoError(t, err)
+
+	values := []string{}
+	for _, a := range got {
+		payload, err := a.Payload()
+		require.NoError(t, err)
+		var envelope struct {
+			Payload []byte `json:"payload"`
+		}
+		require.NoError(t, json.Unmarshal(payload, &envelope))
+		var statement struct {
+			PredicateType string `json:"predicateType"`
+			Predicate     struct {
+				Value string `json:"value"`
+			} `json:"predicate"`
+		}
+		require.NoError(t, json.Unmarshal(envelope.Payload, &statement))
+		values = append(values, statement.PredicateType+"="+statement.Predicate.Value)
+	}
+
+	assert.ElementsMatch(t, []string{"https://example.com/a=second", "https://example.com/b=other"}, values)
+}
+
 func TestScopedAuth(t *testing.T) {
 	cases := []struct {
 		repository string
@@ -143,3 +143,27 @@ func (m *FakeClient) Index(ref name.Reference) (v1.ImageIndex, error) {
 	}
 	return index, args.Error(1)
 }
+
+func (m *FakeClient) AttachAttestation(ref name.Digest, attestation cosignoci.Signature, predicateType string) error {
+	args := m.Called(ref, attestation, predicateType)
+
+	return args.Error(0)
+}
+
+func (m *FakeClient) SignedEntity(ref name.Reference) (cosignoci.SignedEntity, error) {
+	args := m.Called(ref)
+	var se cosignoci.SignedEntity
+	if maybeSE, ok := args.Get(0).(cosignoci.SignedEntity); ok {
+		se = maybeSE
+	}
+	return se, args.Error(1)
+}
+
+func (m *FakeClient) SigstoreBundles(ref name.Digest) ([]v1.Image, error) {
+	args := m.Called(ref)
+	var bundles []v1.Image
+	if maybeBundles, ok := args.Get(0).([]v1.Image); ok {
+		bundles = maybeBundles
+	}
+	return bundles, args.Error(1)
+}
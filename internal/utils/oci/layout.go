@@ -0,0 +1,584 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oci
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/trace"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	ggcrlayout "github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+	"github.com/sigstore/cosign/v2/pkg/oci/empty"
+	"github.com/sigstore/cosign/v2/pkg/oci/layout"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// annotations used by cosign to mark the content of the OCI layout it
+	// writes with `cosign save`
+	layoutKindAnnotation       = "kind"
+	layoutImageAnnotation      = "dev.cosignproject.cosign/image"
+	layoutImageIndexAnnotation = "dev.cosignproject.cosign/imageIndex"
+	// refNameAnnotation is the standard OCI annotation used for the reference
+	// of the image in the layout
+	refNameAnnotation = "org.opencontainers.image.ref.name"
+)
+
+// LayoutClient is a Client that provides the images, their signatures and
+// attestations from OCI image layouts, as written by `cosign save`, instead of
+// a registry. All verification is performed offline using the transparency
+// log bundles stored with the signatures and attestations.
+type LayoutClient struct {
+	ctx     context.Context
+	layouts []signedLayout
+	// temporary directories the tar archives were extracted to
+	extracted []string
+}
+
+// signedLayout is an OCI image layout holding a single image or image index
+// along with its signatures and attestations
+type signedLayout struct {
+	path    string
+	index   v1.ImageIndex
+	subject v1.Descriptor
+}
+
+// NewLayoutClient creates a LayoutClient from the given paths. Each path is
+// either an OCI image layout directory, a tar archive (optionally gzip
+// compressed) of one, or a directory containing OCI image layout directories.
+// Close should be called to remove any temporary files created for tar
+// archives.
+func NewLayoutClient(ctx context.Context, paths ...string) (*LayoutClient, error) {
+	c := &LayoutClient{ctx: ctx}
+
+	for _, p := range paths {
+		if err := c.add(p); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+
+	if len(c.layouts) == 0 {
+		c.Close()
+		return nil, fmt.Errorf("no signed images found in OCI layouts: %s", strings.Join(paths, ", "))
+	}
+
+	return c, nil
+}
+
+func (c *LayoutClient) add(p string) error {
+	info, err := os.Stat(p)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		dir, err := os.MkdirTemp("", "ec-oci-layout-")
+		if err != nil {
+			return err
+		}
+		c.extracted = append(c.extracted, dir)
+
+		if err := extractTar(p, dir); err != nil {
+			return fmt.Errorf("extracting OCI layout from %q: %w", p, err)
+		}
+		p = dir
+	}
+
+	if _, err := os.Stat(filepath.Join(p, "index.json")); err == nil {
+		return c.addLayout(p)
+	}
+
+	entries, err := os.ReadDir(p)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		dir := filepath.Join(p, e.Name())
+		if _, err := os.Stat(filepath.Join(dir, "index.json")); err != nil {
+			continue
+		}
+		if err := c.addLayout(dir); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *LayoutClient) addLayout(p string) error {
+	lp, err := ggcrlayout.FromPath(p)
+	if err != nil {
+		return fmt.Errorf("reading OCI layout %q: %w", p, err)
+	}
+
+	index, err := lp.ImageIndex()
+	if err != nil {
+		return fmt.Errorf("reading OCI layout %q: %w", p, err)
+	}
+
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return fmt.Errorf("reading OCI layout %q: %w", p, err)
+	}
+
+	for _, m := range manifest.Manifests {
+		switch m.Annotations[layoutKindAnnotation] {
+		case layoutImageAnnotation, layoutImageIndexAnnotation:
+			log.Debugf("Found image %s in OCI layout %q", m.Digest, p)
+			c.layouts = append(c.layouts, signedLayout{path: p, index: index, subject: m})
+			return nil
+		}
+	}
+
+	return fmt.Errorf("no signed image found in OCI layout %q", p)
+}
+
+// Close removes the temporary directories the tar archives were extracted to
+func (c *LayoutClient) Close() {
+	for _, dir := range c.extracted {
+		if err := os.RemoveAll(dir); err != nil {
+			log.Debugf("Ignoring error removing temporary directory %s: %v", dir, err)
+		}
+	}
+	c.extracted = nil
+}
+
+// extractTar extracts the, optionally gzip compressed, tar archive at path to
+// the dir directory
+func extractTar(path, dir string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = bufio.NewReader(f)
+	if magic, err := r.(*bufio.Reader).Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dir, hdr.Name) // #nosec G305 -- checked below
+		if !strings.HasPrefix(target, filepath.Clean(dir)+string(os.PathSeparator)) {
+			return fmt.Errorf("invalid path in archive: %q", hdr.Name)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+			if err != nil {
+				return err
+			}
+			// #nosec G110 -- the archive is provided by the user
+			if _, err := io.Copy(out, tr); err != nil {
+				out.Close()
+				return err
+			}
+			if err := out.Close(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// find returns the layout holding the image referenced. Digest references
+// are matched against the digest of the signed image, other references only
+// against the full reference name in the reference name annotation, so an
+// image is never validated under a name it wasn't stored with.
+func (c *LayoutClient) find(ref name.Reference) (*signedLayout, error) {
+	if d, ok := ref.(name.Digest); ok {
+		for i := range c.layouts {
+			if c.layouts[i].subject.Digest.String() == d.DigestStr() {
+				return &c.layouts[i], nil
+			}
+		}
+		return nil, fmt.Errorf("image %s not found in OCI layouts", ref)
+	}
+
+	for i := range c.layouts {
+		refName, err := name.ParseReference(c.layouts[i].subject.Annotations[refNameAnnotation])
+		if err != nil {
+			continue
+		}
+		if refName.Name() == ref.Name() {
+			return &c.layouts[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("image %s not found in OCI layouts, use a digest reference", ref)
+}
+
+func (c *LayoutClient) VerifyImageSignatures(ref name.Reference, opts *cosign.CheckOpts) ([]oci.Signature, bool, error) {
+	if trace.IsEnabled() {
+		region := trace.StartRegion(c.ctx, "ec:validate-image-signatures")
+		defer region.End()
+		trace.Logf(c.ctx, "", "image=%q", ref)
+	}
+
+	l, err := c.find(ref)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Never reach out to Rekor, the bundle stored with the signature is used
+	opts.Offline = true
+	return cosign.VerifyLocalImageSignatures(c.ctx, l.path, opts)
+}
+
+func (c *LayoutClient) VerifyImageAttestations(ref name.Reference, opts *cosign.CheckOpts) ([]oci.Signature, bool, error) {
+	if trace.IsEnabled() {
+		region := trace.StartRegion(c.ctx, "ec:validate-image-attestations")
+		defer region.End()
+		trace.Logf(c.ctx, "", "image=%q", ref)
+	}
+
+	l, err := c.find(ref)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Never reach out to Rekor, the bundle stored with the attestation is used
+	opts.Offline = true
+	attestations, bundleVerified, err := cosign.VerifyLocalImageAttestations(c.ctx, l.path, opts)
+
+	// Include the attestations from the Sigstore bundles stored in the layout
+	bundles, berr := l.sigstoreBundles()
+	return withBundleAttestations(c.ctx, ref, l.subject.Digest, opts, attestations, bundleVerified, err, bundles, berr)
+}
+
+// SigstoreBundles returns the manifests of the Sigstore bundles stored in the
+// layout of the image
+func (c *LayoutClient) SigstoreBundles(ref name.Digest) ([]v1.Image, error) {
+	l, err := c.find(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return l.sigstoreBundles()
+}
+
+// sigstoreBundles returns the manifests in the layout with the artifact type
+// of a Sigstore bundle
+func (l *signedLayout) sigstoreBundles() ([]v1.Image, error) {
+	manifest, err := l.index.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	var bundles []v1.Image
+	for _, desc := range manifest.Manifests {
+		if !strings.HasPrefix(desc.ArtifactType, SigstoreBundleMediaType) {
+			continue
+		}
+
+		img, err := l.index.Image(desc.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("reading bundle %s: %w", desc.Digest, err)
+		}
+		bundles = append(bundles, img)
+	}
+
+	return bundles, nil
+}
+
+func (c *LayoutClient) Head(ref name.Reference) (*v1.Descriptor, error) {
+	l, err := c.find(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	desc := l.subject
+	desc.Annotations = nil
+	return &desc, nil
+}
+
+func (c *LayoutClient) ResolveDigest(ref name.Reference) (string, error) {
+	l, err := c.find(ref)
+	if err != nil {
+		return "", err
+	}
+
+	return l.subject.Digest.String(), nil
+}
+
+// Image returns the image with the referenced digest from any of the layouts,
+// this includes the images within a signed image index
+func (c *LayoutClient) Image(ref name.Reference) (v1.Image, error) {
+	h, err := c.hash(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, l := range c.layouts {
+		if img, err := findImage(l.index, h); err == nil && img != nil {
+			return img, nil
+		}
+	}
+
+	return nil, fmt.Errorf("image %s not found in OCI layouts", ref)
+}
+
+// Layer returns the layer with the referenced digest from any of the images
+// in the layouts
+func (c *LayoutClient) Layer(ref name.Digest) (v1.Layer, error) {
+	h, err := v1.NewHash(ref.DigestStr())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, l := range c.layouts {
+		if layer, err := findLayer(l.index, h); err == nil && layer != nil {
+			return layer, nil
+		}
+	}
+
+	return nil, fmt.Errorf("layer %s not found in OCI layouts", ref)
+}
+
+// Index returns the image index with the referenced digest from any of the
+// layouts
+func (c *LayoutClient) Index(ref name.Reference) (v1.ImageIndex, error) {
+	h, err := c.hash(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, l := range c.layouts {
+		if ii, err := findIndex(l.index, h); err == nil && ii != nil {
+			return ii, nil
+		}
+	}
+
+	return nil, fmt.Errorf("image index %s not found in OCI layouts", ref)
+}
+
+func (c *LayoutClient) AttachAttestation(ref name.Digest, _ oci.Signature, _ string) error {
+	return fmt.Errorf("unable to attach attestation to %s: not supported for images in OCI layouts", ref)
+}
+
+// SignedEntity returns the image or image index stored in the layout along
+// with its signatures and attestations
+func (c *LayoutClient) SignedEntity(ref name.Reference) (oci.SignedEntity, error) {
+	l, err := c.find(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	se, err := layout.SignedImageIndex(l.path)
+	if err != nil {
+		return nil, err
+	}
+
+	sigs, err := se.Signatures()
+	if err != nil {
+		return nil, err
+	}
+
+	atts, err := se.Attestations()
+	if err != nil {
+		return nil, err
+	}
+
+	// The signatures and attestations are stored next to the image in the
+	// layout, not attached to it
+	if l.subject.Annotations[layoutKindAnnotation] == layoutImageIndexAnnotation {
+		ii, err := se.SignedImageIndex(v1.Hash{})
+		if err != nil {
+			return nil, err
+		}
+		return &signedLayoutIndex{SignedImageIndex: ii, signatures: sigs, attestations: atts}, nil
+	}
+
+	img, err := se.SignedImage(v1.Hash{})
+	if err != nil {
+		return nil, err
+	}
+	return &signedLayoutImage{SignedImage: img, signatures: sigs, attestations: atts}, nil
+}
+
+type signedLayoutImage struct {
+	oci.SignedImage
+	signatures   oci.Signatures
+	attestations oci.Signatures
+}
+
+func (s *signedLayoutImage) Signatures() (oci.Signatures, error) {
+	return orEmpty(s.signatures), nil
+}
+
+func (s *signedLayoutImage) Attestations() (oci.Signatures, error) {
+	return orEmpty(s.attestations), nil
+}
+
+type signedLayoutIndex struct {
+	oci.SignedImageIndex
+	signatures   oci.Signatures
+	attestations oci.Signatures
+}
+
+func (s *signedLayoutIndex) Signatures() (oci.Signatures, error) {
+	return orEmpty(s.signatures), nil
+}
+
+func (s *signedLayoutIndex) Attestations() (oci.Signatures, error) {
+	return orEmpty(s.attestations), nil
+}
+
+func orEmpty(s oci.Signatures) oci.Signatures {
+	if s == nil {
+		return empty.Signatures()
+	}
+	return s
+}
+
+// hash returns the digest of the referenced image, resolving tag references
+// via the layouts
+func (c *LayoutClient) hash(ref name.Reference) (v1.Hash, error) {
+	if d, ok := ref.(name.Digest); ok {
+		return v1.NewHash(d.DigestStr())
+	}
+
+	l, err := c.find(ref)
+	if err != nil {
+		return v1.Hash{}, err
+	}
+
+	return l.subject.Digest, nil
+}
+
+// findImage searches the index, and any nested indexes, for the image with
+// the given digest
+func findImage(index v1.ImageIndex, h v1.Hash) (v1.Image, error) {
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range manifest.Manifests {
+		if m.Digest == h && !m.MediaType.IsIndex() {
+			return index.Image(h)
+		}
+		if m.MediaType.IsIndex() {
+			nested, err := index.ImageIndex(m.Digest)
+			if err != nil {
+				continue
+			}
+			if img, err := findImage(nested, h); err == nil && img != nil {
+				return img, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// findIndex searches the index, and any nested indexes, for the image index
+// with the given digest
+func findIndex(index v1.ImageIndex, h v1.Hash) (v1.ImageIndex, error) {
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range manifest.Manifests {
+		if !m.MediaType.IsIndex() {
+			continue
+		}
+		nested, err := index.ImageIndex(m.Digest)
+		if err != nil {
+			continue
+		}
+		if m.Digest == h {
+			return nested, nil
+		}
+		if ii, err := findIndex(nested, h); err == nil && ii != nil {
+			return ii, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// findLayer searches the images in the index, and any nested indexes, for
+// the layer with the given digest
+func findLayer(index v1.ImageIndex, h v1.Hash) (v1.Layer, error) {
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range manifest.Manifests {
+		if m.MediaType.IsIndex() {
+			nested, err := index.ImageIndex(m.Digest)
+			if err != nil {
+				continue
+			}
+			if layer, err := findLayer(nested, h); err == nil && layer != nil {
+				return layer, nil
+			}
+			continue
+		}
+
+		img, err := index.Image(m.Digest)
+		if err != nil {
+			continue
+		}
+		if layer, err := img.LayerByDigest(h); err == nil {
+			return layer, nil
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,296 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/cosign/v2/pkg/oci/layout"
+	"github.com/sigstore/cosign/v2/pkg/oci/mutate"
+	"github.com/sigstore/cosign/v2/pkg/oci/signed"
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+	cosignTypes "github.com/sigstore/cosign/v2/pkg/types"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/dsse"
+	sigPayload "github.com/sigstore/sigstore/pkg/signature/payload"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeSignedLayout writes a random image, signed and attested with the
+// signer, to an OCI layout in dir the same way "cosign save" does
+func writeSignedLayout(t *testing.T, dir string, signer signature.SignerVerifier) name.Digest {
+	img, err := random.Image(1024, 2)
+	require.NoError(t, err)
+
+	h, err := img.Digest()
+	require.NoError(t, err)
+	digest, err := name.NewDigest("registry.local/image@" + h.String())
+	require.NoError(t, err)
+
+	payload, err := sigPayload.Cosign{Image: digest}.MarshalJSON()
+	require.NoError(t, err)
+	rawSig, err := signer.SignMessage(bytes.NewReader(payload))
+	require.NoError(t, err)
+	sig, err := static.NewSignature(payload, base64.StdEncoding.EncodeToString(rawSig))
+	require.NoError(t, err)
+
+	statement := fmt.Sprintf(`{"_type":"https://in-toto.io/Statement/v0.1","predicateType":"https://example.com/predicate","subject":[{"name":"image","digest":{%q:%q}}],"predicate":{}}`, h.Algorithm, h.Hex)
+	envelope, err := dsse.WrapSigner(signer, cosignTypes.IntotoPayloadType).SignMessage(bytes.NewReader([]byte(statement)))
+	require.NoError(t, err)
+	att, err := static.NewAttestation(envelope, static.WithLayerMediaType(cosignTypes.DssePayloadType))
+	require.NoError(t, err)
+
+	si := signed.Image(img)
+	si, err = mutate.AttachSignatureToImage(si, sig)
+	require.NoError(t, err)
+	si, err = mutate.AttachAttestationToImage(si, att)
+	require.NoError(t, err)
+
+	require.NoError(t, layout.WriteSignedImage(dir, si))
+
+	return digest
+}
+
+func newTestSigner(t *testing.T) signature.SignerVerifier {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	signer, err := signature.LoadECDSASignerVerifier(key, crypto.SHA256)
+	require.NoError(t, err)
+	return signer
+}
+
+// tarDir writes the content of dir to a, optionally gzip compressed, tar
+// archive
+func tarDir(t *testing.T, dir string, compress bool) string {
+	archive := filepath.Join(t.TempDir(), "layout.tar")
+	f, err := os.Create(archive)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var w io.Writer = f
+	if compress {
+		gz := gzip.NewWriter(f)
+		defer gz.Close()
+		w = gz
+	}
+
+	tw := tar.NewWriter(w)
+	defer tw.Close()
+	require.NoError(t, tw.AddFS(os.DirFS(dir)))
+
+	return archive
+}
+
+func TestLayoutClient(t *testing.T) {
+	signer := newTestSigner(t)
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "image")
+	digest := writeSignedLayout(t, dir, signer)
+
+	cases := []struct {
+		name string
+		path string
+	}{
+		{name: "directory", path: dir},
+		{name: "tar", path: tarDir(t, dir, false)},
+		{name: "tar.gz", path: tarDir(t, dir, true)},
+		{name: "directory of layouts", path: parent},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			client, err := NewLayoutClient(context.Background(), c.path)
+			require.NoError(t, err)
+			t.Cleanup(client.Close)
+
+			resolved, err := client.ResolveDigest(digest)
+			require.NoError(t, err)
+			assert.Equal(t, digest.DigestStr(), resolved)
+
+			// a tag reference is not resolved to the single image in the layout
+			// without a matching reference name
+			tag, err := name.ParseReference("registry.local/image:latest")
+			require.NoError(t, err)
+			_, err = client.ResolveDigest(tag)
+			assert.ErrorContains(t, err, "use a digest reference")
+
+			desc, err := client.Head(digest)
+			require.NoError(t, err)
+			assert.Equal(t, digest.DigestStr(), desc.Digest.String())
+
+			img, err := client.Image(digest)
+			require.NoError(t, err)
+			layers, err := img.Layers()
+			require.NoError(t, err)
+			require.Len(t, layers, 2)
+
+			layerDigest, err := layers[1].Digest()
+			require.NoError(t, err)
+			layer, err := client.Layer(digest.Context().Digest(layerDigest.String()))
+			require.NoError(t, err)
+			d, err := layer.Digest()
+			require.NoError(t, err)
+			assert.Equal(t, layerDigest, d)
+
+			opts := cosign.CheckOpts{SigVerifier: signer, IgnoreTlog: true}
+			signatures, _, err := client.VerifyImageSignatures(digest, &opts)
+			require.NoError(t, err)
+			assert.Len(t, signatures, 1)
+			assert.True(t, opts.Offline)
+
+			opts = cosign.CheckOpts{SigVerifier: signer, IgnoreTlog: true, ClaimVerifier: cosign.IntotoSubjectClaimVerifier}
+			attestations, _, err := client.VerifyImageAttestations(digest, &opts)
+			require.NoError(t, err)
+			require.Len(t, attestations, 1)
+			assert.Equal(t, "https://example.com/predicate", predicateTypeOf(attestations[0]))
+
+			opts = cosign.CheckOpts{SigVerifier: newTestSigner(t), IgnoreTlog: true}
+			_, _, err = client.VerifyImageSignatures(digest, &opts)
+			assert.Error(t, err)
+
+			se, err := client.SignedEntity(digest)
+			require.NoError(t, err)
+			sigs, err := se.Signatures()
+			require.NoError(t, err)
+			got, err := sigs.Get()
+			require.NoError(t, err)
+			assert.Len(t, got, 1)
+			atts, err := se.Attestations()
+			require.NoError(t, err)
+			got, err = atts.Get()
+			require.NoError(t, err)
+			assert.Len(t, got, 1)
+
+			assert.ErrorContains(t, client.AttachAttestation(digest, nil, ""), "not supported for images in OCI layouts")
+		})
+	}
+}
+
+func TestLayoutClientMultipleImages(t *testing.T) {
+	signer := newTestSigner(t)
+	first := writeSignedLayout(t, filepath.Join(t.TempDir(), "first"), signer)
+	parent := t.TempDir()
+	second := writeSignedLayout(t, filepath.Join(parent, "second"), signer)
+	third := writeSignedLayout(t, filepath.Join(parent, "third"), signer)
+
+	client, err := NewLayoutClient(context.Background(), parent)
+	require.NoError(t, err)
+	t.Cleanup(client.Close)
+
+	for _, d := range []name.Digest{second, third} {
+		resolved, err := client.ResolveDigest(d)
+		require.NoError(t, err)
+		assert.Equal(t, d.DigestStr(), resolved)
+	}
+
+	_, err = client.ResolveDigest(first)
+	assert.ErrorContains(t, err, "not found in OCI layouts")
+
+	tag, err := name.ParseReference("registry.local/image:latest")
+	require.NoError(t, err)
+	_, err = client.ResolveDigest(tag)
+	assert.ErrorContains(t, err, "use a digest reference")
+
+	_, err = client.Image(first)
+	assert.ErrorContains(t, err, "not found in OCI layouts")
+
+	_, err = client.Index(second)
+	assert.ErrorContains(t, err, "not found in OCI layouts")
+}
+
+func TestLayoutClientReferenceName(t *testing.T) {
+	signer := newTestSigner(t)
+	dir := t.TempDir()
+	digest := writeSignedLayout(t, dir, signer)
+	setRefName(t, dir, digest, "registry.local/image:v1")
+
+	client, err := NewLayoutClient(context.Background(), dir)
+	require.NoError(t, err)
+	t.Cleanup(client.Close)
+
+	tag, err := name.ParseReference("registry.local/image:v1")
+	require.NoError(t, err)
+	resolved, err := client.ResolveDigest(tag)
+	require.NoError(t, err)
+	assert.Equal(t, digest.DigestStr(), resolved)
+
+	for _, ref := range []string{"registry.local/image:v2", "registry.local/other:v1", "other.local/image:v1"} {
+		tag, err := name.ParseReference(ref)
+		require.NoError(t, err)
+		_, err = client.ResolveDigest(tag)
+		assert.ErrorContains(t, err, "not found in OCI layouts", ref)
+	}
+}
+
+// setRefName sets the reference name annotation of the image in the layout
+// in dir
+func setRefName(t *testing.T, dir string, digest name.Digest, refName string) {
+	path := filepath.Join(dir, "index.json")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var index v1.IndexManifest
+	require.NoError(t, json.Unmarshal(data, &index))
+	for i, m := range index.Manifests {
+		if m.Digest.String() != digest.DigestStr() {
+			continue
+		}
+		if index.Manifests[i].Annotations == nil {
+			index.Manifests[i].Annotations = map[string]string{}
+		}
+		index.Manifests[i].Annotations[refNameAnnotation] = refName
+	}
+
+	data, err = json.Marshal(index)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+}
+
+func TestNewLayoutClientErrors(t *testing.T) {
+	_, err := NewLayoutClient(context.Background(), filepath.Join(t.TempDir(), "missing"))
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = NewLayoutClient(context.Background(), t.TempDir())
+	assert.ErrorContains(t, err, "no signed images found in OCI layouts")
+
+	notTar := filepath.Join(t.TempDir(), "layout.tar")
+	require.NoError(t, os.WriteFile(notTar, []byte("not a tar archive"), 0o600))
+	_, err = NewLayoutClient(context.Background(), notTar)
+	assert.ErrorContains(t, err, "extracting OCI layout")
+}
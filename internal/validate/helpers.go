@@ -19,6 +19,8 @@ package validate
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"path/filepath"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
@@ -64,6 +66,25 @@ func GetPolicyConfig(ctx context.Context, policyConfiguration string) (string, e
 	return policyConfiguration, nil
 }
 
+// PolicyURI returns the URI of the policy configuration referenced as a file,
+// git or http URL, local files are returned as absolute file URLs. Inline
+// policy configuration and references to cluster resources have no URI, for
+// those an empty string is returned.
+func PolicyURI(policyConfiguration string) string {
+	if source.SourceIsGit(policyConfiguration) && !source.SourceIsFile(policyConfiguration) || source.SourceIsHttp(policyConfiguration) {
+		return policyConfiguration
+	}
+
+	if path, ok := source.LocalPath(policyConfiguration); ok && utils.HasJsonOrYamlExt(path) {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+	}
+
+	return ""
+}
+
 // Read file from the workspace and return its contents.
 func ReadFile(ctx context.Context, fileName string) (string, error) {
 	fs := utils.FS(ctx)
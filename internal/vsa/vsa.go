@@ -0,0 +1,237 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package vsa signs Verification Summary Attestations (VSA) and attaches them
+// to the validated images as cosign attestations.
+package vsa
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/rekor"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/sign"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	cbundle "github.com/sigstore/cosign/v2/pkg/cosign/bundle"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+	"github.com/sigstore/cosign/v2/pkg/types"
+	"github.com/sigstore/sigstore/pkg/signature/dsse"
+	signatureoptions "github.com/sigstore/sigstore/pkg/signature/options"
+
+	"github.com/enterprise-contract/ec-cli/internal/applicationsnapshot"
+	ecoci "github.com/enterprise-contract/ec-cli/internal/utils/oci"
+)
+
+// Options configure how the VSA is signed. When KeyRef is empty the VSA is
+// signed keyless, with a certificate issued by Fulcio for the OIDC identity.
+// The signature is recorded in the Rekor transparency log unless RekorURL is
+// empty.
+type Options struct {
+	// KeyRef is the path to the private key or a KMS URI
+	KeyRef     string
+	FulcioURL  string
+	RekorURL   string
+	OIDCIssuer string
+	// IdentityToken is the OIDC identity token used for keyless signing, if
+	// not provided cosign attempts to obtain one from the environment
+	IdentityToken string
+}
+
+// Signer signs a VSA, an in-toto statement with the given predicate type,
+// producing a cosign attestation
+type Signer interface {
+	Sign(ctx context.Context, predicateType string, statement any) (oci.Signature, error)
+}
+
+// Formats of the VSA attached to the images
+const (
+	// FormatEC is the VSA with the validation report as the predicate
+	FormatEC = "ec"
+	// FormatSLSA is the VSA with the SLSA Verification Summary predicate
+	FormatSLSA = "slsa"
+)
+
+// Formats lists the formats of the VSA attached to the images
+var Formats = []string{FormatEC, FormatSLSA}
+
+type contextKey string
+
+const signerContextKey contextKey = "ec.vsa.signer"
+
+// WithSigner returns a context with the Signer to use instead of the one
+// configured by the options, for example a local stand-in in tests
+func WithSigner(ctx context.Context, signer Signer) context.Context {
+	return context.WithValue(ctx, signerContextKey, signer)
+}
+
+type cosignSigner struct {
+	sv       *sign.SignerVerifier
+	rekorURL string
+}
+
+// NewSigner returns a Signer configured by the options. For keyless signing a
+// certificate is requested from Fulcio once, and used to sign all VSAs.
+func NewSigner(ctx context.Context, opts Options) (Signer, error) {
+	if signer, ok := ctx.Value(signerContextKey).(Signer); ok && signer != nil {
+		return signer, nil
+	}
+
+	ko := options.KeyOpts{
+		KeyRef:           opts.KeyRef,
+		FulcioURL:        opts.FulcioURL,
+		RekorURL:         opts.RekorURL,
+		OIDCIssuer:       opts.OIDCIssuer,
+		OIDCClientID:     "sigstore",
+		IDToken:          opts.IdentityToken,
+		SkipConfirmation: true,
+		PassFunc:         password,
+	}
+
+	sv, err := sign.SignerFromKeyOpts(ctx, "", "", ko)
+	if err != nil {
+		return nil, fmt.Errorf("getting the VSA signer: %w", err)
+	}
+
+	return &cosignSigner{sv: sv, rekorURL: opts.RekorURL}, nil
+}
+
+// password provides the password of an encrypted private key from the
+// COSIGN_PASSWORD environment variable, same as cosign does
+func password(_ bool) ([]byte, error) {
+	return []byte(os.Getenv("COSIGN_PASSWORD")), nil
+}
+
+func (s *cosignSigner) Sign(ctx context.Context, predicateType string, statement any) (oci.Signature, error) {
+	payload, err := json.Marshal(statement)
+	if err != nil {
+		return nil, err
+	}
+
+	envelope, err := dsse.WrapSigner(s.sv, types.IntotoPayloadType).SignMessage(bytes.NewReader(payload), signatureoptions.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("signing the VSA: %w", err)
+	}
+
+	opts := []static.Option{
+		static.WithLayerMediaType(types.DssePayloadType),
+		static.WithAnnotations(map[string]string{
+			"predicateType": predicateType,
+		}),
+	}
+
+	if s.sv.Cert != nil {
+		opts = append(opts, static.WithCertChain(s.sv.Cert, s.sv.Chain))
+	}
+
+	if s.rekorURL != "" {
+		bundle, err := s.uploadToRekor(ctx, envelope)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, static.WithBundle(bundle))
+	}
+
+	return static.NewAttestation(envelope, opts...)
+}
+
+// uploadToRekor records the signed VSA in the Rekor transparency log
+func (s *cosignSigner) uploadToRekor(ctx context.Context, envelope []byte) (*cbundle.RekorBundle, error) {
+	pemBytes, err := s.sv.Bytes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := rekor.NewClient(s.rekorURL)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := cosign.TLogUploadDSSEEnvelope(ctx, client, envelope, pemBytes)
+	if err != nil {
+		return nil, fmt.Errorf("uploading the VSA to Rekor: %w", err)
+	}
+
+	return cbundle.EntryToBundle(entry), nil
+}
+
+// ValidateFormat returns an error if the format is not one of the supported
+// VSA formats
+func ValidateFormat(format string) error {
+	if !slices.Contains(Formats, format) {
+		return fmt.Errorf("%q is not a valid VSA format, expected one of: %s", format, strings.Join(Formats, ", "))
+	}
+
+	return nil
+}
+
+// Attach signs the VSA in the given format of each component of the report and
+// attaches it to the image of the component, replacing any previously attached
+// VSA of the same format
+func Attach(ctx context.Context, signer Signer, report applicationsnapshot.Report, format string) (allErrors error) {
+	if err := ValidateFormat(format); err != nil {
+		return err
+	}
+
+	client := ecoci.NewClient(ctx)
+
+	for _, component := range report.AllComponents() {
+		if err := attach(ctx, client, signer, report, component, format); err != nil {
+			allErrors = errors.Join(allErrors, fmt.Errorf("attaching the VSA to image %s of component %s: %w", component.ContainerImage, component.Name, err))
+		}
+	}
+
+	return
+}
+
+func attach(ctx context.Context, client ecoci.Client, signer Signer, report applicationsnapshot.Report, component applicationsnapshot.Component, format string) error {
+	var predicateType string
+	var statement any
+	switch format {
+	case FormatSLSA:
+		vsa, err := applicationsnapshot.NewComponentSLSAVSA(report, component)
+		if err != nil {
+			return err
+		}
+		predicateType, statement = vsa.PredicateType, vsa
+	default:
+		vsa, err := applicationsnapshot.NewComponentVSA(report, component)
+		if err != nil {
+			return err
+		}
+		predicateType, statement = vsa.PredicateType, vsa
+	}
+
+	attestation, err := signer.Sign(ctx, predicateType, statement)
+	if err != nil {
+		return err
+	}
+
+	ref, err := name.NewDigest(component.ContainerImage)
+	if err != nil {
+		return err
+	}
+
+	return client.AttachAttestation(ref, attestation, predicateType)
+}
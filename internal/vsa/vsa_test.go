@@ -0,0 +1,210 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package vsa
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/json"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	app "github.com/konflux-ci/application-api/api/v1alpha1"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/dsse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/applicationsnapshot"
+	ecoci "github.com/enterprise-contract/ec-cli/internal/utils/oci"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci/fake"
+)
+
+const (
+	image1 = "registry.io/repository/image1@sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	image2 = "registry.io/repository/image2@sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+)
+
+func keySigner(t *testing.T) (Signer, *cosign.KeysBytes) {
+	keys, err := cosign.GenerateKeyPair(func(bool) ([]byte, error) {
+		return []byte("secret"), nil
+	})
+	require.NoError(t, err)
+
+	keyPath := path.Join(t.TempDir(), "cosign.key")
+	require.NoError(t, os.WriteFile(keyPath, keys.PrivateBytes, 0600))
+	t.Setenv("COSIGN_PASSWORD", "secret")
+
+	signer, err := NewSigner(context.Background(), Options{KeyRef: keyPath})
+	require.NoError(t, err)
+
+	return signer, keys
+}
+
+func TestAttach(t *testing.T) {
+	signer, keys := keySigner(t)
+
+	report := applicationsnapshot.Report{
+		Success: false,
+		Components: []applicationsnapshot.Component{
+			{
+				SnapshotComponent: app.SnapshotComponent{Name: "one", ContainerImage: image1},
+				Success:           true,
+			},
+			{
+				SnapshotComponent: app.SnapshotComponent{Name: "two", ContainerImage: image2},
+				Success:           false,
+			},
+		},
+	}
+
+	client := fake.FakeClient{}
+	client.On("AttachAttestation", mock.Anything, mock.Anything, applicationsnapshot.PredicateVSAProvenance).Return(nil)
+	ctx := ecoci.WithClient(context.Background(), &client)
+
+	require.NoError(t, Attach(ctx, signer, report, FormatEC))
+
+	pub, err := cosign.PemToECDSAKey(keys.PublicBytes)
+	require.NoError(t, err)
+	ecdsaVerifier, err := signature.LoadECDSAVerifier(pub, crypto.SHA256)
+	require.NoError(t, err)
+	verifier := dsse.WrapVerifier(ecdsaVerifier)
+
+	client.AssertNumberOfCalls(t, "AttachAttestation", 2)
+	for i, image := range []string{image1, image2} {
+		ref, err := name.NewDigest(image)
+		require.NoError(t, err)
+
+		call := client.Calls[i]
+		assert.Equal(t, ref, call.Arguments.Get(0))
+
+		attestation := call.Arguments.Get(1).(oci.Signature)
+		envelope, err := attestation.Payload()
+		require.NoError(t, err)
+		require.NoError(t, verifier.VerifySignature(bytes.NewReader(envelope), nil))
+
+		annotations, err := attestation.Annotations()
+		require.NoError(t, err)
+		assert.Equal(t, applicationsnapshot.PredicateVSAProvenance, annotations["predicateType"])
+
+		var signed struct {
+			Payload []byte `json:"payload"`
+		}
+		require.NoError(t, json.Unmarshal(envelope, &signed))
+
+		var vsa applicationsnapshot.ProvenanceStatementVSA
+		require.NoError(t, json.Unmarshal(signed.Payload, &vsa))
+		require.Len(t, vsa.Subject, 1)
+		assert.Equal(t, ref.Context().Name(), vsa.Subject[0].Name)
+		require.Len(t, vsa.Predicate.Components, 1)
+		assert.Equal(t, image, vsa.Predicate.Components[0].ContainerImage)
+		assert.Equal(t, vsa.Predicate.Components[0].Success, vsa.Predicate.Success)
+	}
+}
+
+func TestAttachNotPinned(t *testing.T) {
+	signer, _ := keySigner(t)
+
+	report := applicationsnapshot.Report{
+		Components: []applicationsnapshot.Component{
+			{
+				SnapshotComponent: app.SnapshotComponent{Name: "tagged", ContainerImage: "registry.io/repository/image:tag"},
+			},
+			{
+				SnapshotComponent: app.SnapshotComponent{Name: "one", ContainerImage: image1},
+			},
+		},
+	}
+
+	client := fake.FakeClient{}
+	client.On("AttachAttestation", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	ctx := ecoci.WithClient(context.Background(), &client)
+
+	err := Attach(ctx, signer, report, FormatEC)
+	assert.ErrorContains(t, err, `attaching the VSA to image registry.io/repository/image:tag of component tagged: image reference "registry.io/repository/image:tag" is not pinned to a digest`)
+	client.AssertNumberOfCalls(t, "AttachAttestation", 1)
+}
+
+func TestAttachSLSA(t *testing.T) {
+	signer, _ := keySigner(t)
+
+	report := applicationsnapshot.Report{
+		Components: []applicationsnapshot.Component{
+			{
+				SnapshotComponent: app.SnapshotComponent{Name: "one", ContainerImage: image1},
+				Success:           true,
+			},
+		},
+		VerifiedLevels: []string{"SLSA_BUILD_LEVEL_3"},
+		PolicyURI:      "github.com/org/repo//policy.yaml",
+	}
+
+	client := fake.FakeClient{}
+	client.On("AttachAttestation", mock.Anything, mock.Anything, applicationsnapshot.PredicateSLSAVSA).Return(nil)
+	ctx := ecoci.WithClient(context.Background(), &client)
+
+	require.NoError(t, Attach(ctx, signer, report, FormatSLSA))
+
+	client.AssertNumberOfCalls(t, "AttachAttestation", 1)
+	attestation := client.Calls[0].Arguments.Get(1).(oci.Signature)
+
+	annotations, err := attestation.Annotations()
+	require.NoError(t, err)
+	assert.Equal(t, applicationsnapshot.PredicateSLSAVSA, annotations["predicateType"])
+
+	envelope, err := attestation.Payload()
+	require.NoError(t, err)
+
+	var signed struct {
+		Payload []byte `json:"payload"`
+	}
+	require.NoError(t, json.Unmarshal(envelope, &signed))
+
+	var vsa applicationsnapshot.SLSAProvenanceStatementVSA
+	require.NoError(t, json.Unmarshal(signed.Payload, &vsa))
+	assert.Equal(t, applicationsnapshot.PredicateSLSAVSA, vsa.PredicateType)
+	assert.Equal(t, image1, vsa.Predicate.ResourceURI)
+	assert.Equal(t, applicationsnapshot.VerificationPassed, vsa.Predicate.VerificationResult)
+	assert.Equal(t, []string{"SLSA_BUILD_LEVEL_3"}, vsa.Predicate.VerifiedLevels)
+	assert.Equal(t, "github.com/org/repo//policy.yaml", vsa.Predicate.Policy.URI)
+}
+
+func TestAttachUnknownFormat(t *testing.T) {
+	err := Attach(context.Background(), stubSigner{}, applicationsnapshot.Report{}, "bogus")
+	assert.EqualError(t, err, `"bogus" is not a valid VSA format, expected one of: ec, slsa`)
+}
+
+type stubSigner struct{}
+
+func (stubSigner) Sign(context.Context, string, any) (oci.Signature, error) {
+	return nil, nil
+}
+
+func TestNewSignerFromContext(t *testing.T) {
+	stub := stubSigner{}
+	signer, err := NewSigner(WithSigner(context.Background(), stub), Options{})
+	require.NoError(t, err)
+	assert.Equal(t, stub, signer)
+}
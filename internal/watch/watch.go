@@ -0,0 +1,123 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package watch detects changes to files and directories by periodically
+// comparing the modification time and the size of the files within them. It
+// works with any afero file system and is not affected by the way editors
+// replace files when saving them.
+package watch
+
+import (
+	"context"
+	"maps"
+	"os"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// fileState is what is compared to detect a change of a file
+type fileState struct {
+	modTime int64
+	size    int64
+	mode    os.FileMode
+}
+
+// Watcher watches the paths, files or directories, for changes
+type Watcher struct {
+	fs       afero.Fs
+	interval time.Duration
+	paths    []string
+	states   map[string]map[string]fileState
+}
+
+// New returns a Watcher of the paths, checking for changes every interval.
+// Paths that don't exist are watched for being created.
+func New(fs afero.Fs, interval time.Duration, paths ...string) *Watcher {
+	w := &Watcher{
+		fs:       fs,
+		interval: interval,
+		paths:    paths,
+		states:   make(map[string]map[string]fileState, len(paths)),
+	}
+
+	for _, p := range paths {
+		w.states[p] = w.scan(p)
+	}
+
+	return w
+}
+
+// Wait blocks until any of the watched paths changes and returns the changed
+// paths, in the order they were provided to New. Changes made in quick
+// succession, e.g. when saving multiple files, are returned together once no
+// further changes are detected. An error is returned only when the context is
+// done.
+func (w *Watcher) Wait(ctx context.Context) ([]string, error) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	changed := map[string]bool{}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+
+		settled := true
+		for _, p := range w.paths {
+			state := w.scan(p)
+			if !maps.Equal(state, w.states[p]) {
+				w.states[p] = state
+				changed[p] = true
+				settled = false
+			}
+		}
+
+		if settled && len(changed) > 0 {
+			paths := make([]string, 0, len(changed))
+			for _, p := range w.paths {
+				if changed[p] {
+					paths = append(paths, p)
+				}
+			}
+			return paths, nil
+		}
+	}
+}
+
+// scan returns the state of the files at the path, within it if it is a
+// directory
+func (w *Watcher) scan(path string) map[string]fileState {
+	state := map[string]fileState{}
+
+	// errors, e.g. files removed while walking, are seen as changes on the
+	// next scan
+	_ = afero.Walk(w.fs, path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		state[p] = fileState{
+			modTime: info.ModTime().UnixNano(),
+			size:    info.Size(),
+			mode:    info.Mode(),
+		}
+		return nil
+	})
+
+	return state
+}
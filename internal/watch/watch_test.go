@@ -0,0 +1,95 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package watch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const interval = 5 * time.Millisecond
+
+func TestWait(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/input.yaml", []byte("a: 1"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/policy/main.rego", []byte("package main"), 0644))
+
+	w := New(fs, interval, "/input.yaml", "/policy", "/missing.yaml")
+
+	cases := []struct {
+		name     string
+		change   func()
+		expected []string
+	}{
+		{
+			name: "modified file",
+			change: func() {
+				require.NoError(t, afero.WriteFile(fs, "/input.yaml", []byte("a: 2"), 0644))
+			},
+			expected: []string{"/input.yaml"},
+		},
+		{
+			name: "file added to a directory",
+			change: func() {
+				require.NoError(t, afero.WriteFile(fs, "/policy/other.rego", []byte("package other"), 0644))
+			},
+			expected: []string{"/policy"},
+		},
+		{
+			name: "created file and removed file in a directory",
+			change: func() {
+				require.NoError(t, afero.WriteFile(fs, "/missing.yaml", []byte("b: 1"), 0644))
+				require.NoError(t, fs.Remove("/policy/other.rego"))
+			},
+			expected: []string{"/policy", "/missing.yaml"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.change()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			changed, err := w.Wait(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, changed)
+		})
+	}
+}
+
+func TestWaitCanceled(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/input.yaml", []byte("a: 1"), 0644))
+
+	w := New(fs, interval, "/input.yaml")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*interval)
+	defer cancel()
+
+	changed, err := w.Wait(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Nil(t, changed)
+}
@@ -0,0 +1,99 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/gkampitakis/go-snaps/snaps"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+var validCycloneDX = []byte(`{
+  "_type": "https://in-toto.io/Statement/v1",
+  "subject": [
+    {
+      "name": "subject_name",
+      "digest": {
+        "sha256": "abcdef0123456789"
+      }
+    }
+  ],
+  "predicateType": "https://cyclonedx.org/bom",
+  "predicate": {
+    "bomFormat": "CycloneDX",
+    "specVersion": "1.5",
+    "version": 1,
+    "components": [
+      {
+        "type": "library",
+        "name": "lodash",
+        "version": "4.17.21",
+        "purl": "pkg:npm/lodash@4.17.21"
+      }
+    ]
+  }
+}`)
+
+func checkSBOM(t *testing.T, schema *jsonschema.Schema, valid []byte, patches ...string) {
+	for i, patch := range patches {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			j, err := jsonpatch.MergePatch(valid, []byte(patch))
+			assert.NoError(t, err)
+
+			var v any
+			err = json.Unmarshal(j, &v)
+			assert.NoError(t, err)
+
+			err = schema.Validate(v)
+			snaps.MatchSnapshot(t, err)
+		})
+	}
+}
+
+func TestCycloneDXBOM(t *testing.T) {
+	checkSBOM(t, CycloneDX, validCycloneDX,
+		`{}`,
+		`{"_type": "https://in-toto.io/Statement/v0.1"}`,
+		`{"predicate": {"bomFormat": "SPDX"}}`,
+		`{"predicate": {"specVersion": "1.3"}}`,
+		`{"predicate": {"specVersion": "1.4"}}`,
+		`{"predicate": {"specVersion": "1.6"}}`,
+		`{"predicate": {"serialNumber": "not-a-urn"}}`,
+		`{"predicate": {"version": 0}}`,
+		`{"predicate": {"unknown": true}}`,
+		`{"subject": [{"name": "subject_name", "digest": {"unknown": "abcdef"}}]}`,
+	)
+}
+
+func TestCycloneDXComponents(t *testing.T) {
+	checkSBOM(t, CycloneDX, validCycloneDX,
+		`{"predicate": {"components": [{}]}}`,
+		`{"predicate": {"components": [{"type": "unknown", "name": "a"}]}}`,
+		`{"predicate": {"components": [{"type": "library", "name": "a", "licenses": [{"license": {"id": "MIT"}}, {"license": {"name": "Custom"}}]}]}}`,
+		`{"predicate": {"components": [{"type": "library", "name": "a", "licenses": [{"expression": "MIT OR Apache-2.0"}]}]}}`,
+		`{"predicate": {"components": [{"type": "library", "name": "a", "licenses": [{"license": {"id": "Not-A-License"}}]}]}}`,
+		`{"predicate": {"components": [{"type": "library", "name": "a", "licenses": [{"license": {}}]}]}}`,
+		`{"predicate": {"components": [{"type": "library", "name": "a", "components": [{"type": "library"}]}]}}`,
+	)
+}
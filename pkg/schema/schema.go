@@ -17,7 +17,10 @@
 package schema
 
 import (
-	_ "embed"
+	"bytes"
+	"embed"
+	"io/fs"
+	"path"
 	"strings"
 
 	"github.com/santhosh-tekuri/jsonschema/v5"
@@ -30,6 +33,29 @@ var SLSA_Provenance_v0_2 *jsonschema.Schema
 
 var SLSA_Provenance_v0_2_URI = "https://slsa.dev/provenance/v0.2"
 
+//go:embed slsa_provenance_v1.json
+var slsa_provenance_v1_json string
+
+var SLSA_Provenance_v1 *jsonschema.Schema
+
+var SLSA_Provenance_v1_URI = "https://slsa.dev/provenance/v1"
+
+//go:embed cyclonedx.json
+var cyclonedx_json string
+
+// The official CycloneDX schemas referenced by the CycloneDX statement schema
+//
+//go:embed cyclonedx/*.schema.json
+var cyclonedxSchemas embed.FS
+
+const cyclonedxSchemaBaseURI = "http://cyclonedx.org/schema/"
+
+// CycloneDX validates CycloneDX SBOM attestations, the predicate is validated
+// against the official schema of its spec version, 1.4 or newer
+var CycloneDX *jsonschema.Schema
+
+var CycloneDX_URI = "https://cyclonedx.org/bom"
+
 func init() {
 	compiler := jsonschema.NewCompiler()
 	compiler.AssertFormat = true
@@ -38,4 +64,28 @@ func init() {
 		panic(err)
 	}
 	SLSA_Provenance_v0_2 = compiler.MustCompile(SLSA_Provenance_v0_2_URI)
+
+	if err := compiler.AddResource(SLSA_Provenance_v1_URI, strings.NewReader(slsa_provenance_v1_json)); err != nil {
+		panic(err)
+	}
+	SLSA_Provenance_v1 = compiler.MustCompile(SLSA_Provenance_v1_URI)
+
+	schemas, err := fs.Glob(cyclonedxSchemas, "cyclonedx/*.schema.json")
+	if err != nil {
+		panic(err)
+	}
+	for _, file := range schemas {
+		data, err := cyclonedxSchemas.ReadFile(file)
+		if err != nil {
+			panic(err)
+		}
+		if err := compiler.AddResource(cyclonedxSchemaBaseURI+path.Base(file), bytes.NewReader(data)); err != nil {
+			panic(err)
+		}
+	}
+
+	if err := compiler.AddResource(CycloneDX_URI, strings.NewReader(cyclonedx_json)); err != nil {
+		panic(err)
+	}
+	CycloneDX = compiler.MustCompile(CycloneDX_URI)
 }
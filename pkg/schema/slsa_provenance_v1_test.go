@@ -0,0 +1,138 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/gkampitakis/go-snaps/snaps"
+	"github.com/stretchr/testify/assert"
+)
+
+var validV1 = []byte(`{
+  "_type": "https://in-toto.io/Statement/v1",
+  "subject": [
+    {
+      "name": "subject_name",
+      "digest": {
+        "sha256": "abcdef0123456789"
+      }
+    }
+  ],
+  "predicateType": "https://slsa.dev/provenance/v1",
+  "predicate": {
+    "buildDefinition": {
+      "buildType": "https://tekton.dev/chains/v2/slsa",
+      "externalParameters": {}
+    },
+    "runDetails": {
+      "builder": {
+        "id": "https://tekton.dev/chains/v2"
+      }
+    }
+  }
+}`)
+
+func checkV1(t *testing.T, patches ...string) {
+	for i, patch := range patches {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			j, err := jsonpatch.MergePatch(validV1, []byte(patch))
+			assert.NoError(t, err)
+
+			var v any
+			err = json.Unmarshal(j, &v)
+			assert.NoError(t, err)
+
+			err = SLSA_Provenance_v1.Validate(v)
+			snaps.MatchSnapshot(t, err)
+		})
+	}
+}
+
+func TestV1TypeMustBeInToto(t *testing.T) {
+	checkV1(t,
+		`{"_type": null}`,
+		`{"_type": "something else"}`,
+		`{"_type": "https://in-toto.io/Statement/v0.1"}`,
+		`{"_type": "https://in-toto.io/Statement/v1"}`,
+	)
+}
+
+func TestV1SubjectMustBeProvided(t *testing.T) {
+	checkV1(t,
+		`{"subject": null}`,
+		`{"subject": []}`,
+		`{"subject": [{"name": "a", "digest": {"foo": "abcdef0123456789"}}]}`,
+	)
+}
+
+func TestV1TypeMustBeSLSAProvenancev1(t *testing.T) {
+	checkV1(t,
+		`{"predicateType": null}`,
+		`{"predicateType": "https://slsa.dev/provenance/v0.2"}`,
+		`{"predicateType": "https://slsa.dev/provenance/v1"}`,
+	)
+}
+
+func TestV1BuildDefinition(t *testing.T) {
+	checkV1(t,
+		`{"predicate": {"buildDefinition": null}}`,
+		`{"predicate": {"buildDefinition": {"buildType": null}}}`,
+		`{"predicate": {"buildDefinition": {"buildType": "not_uri"}}}`,
+		`{"predicate": {"buildDefinition": {"externalParameters": null}}}`,
+		`{"predicate": {"buildDefinition": {"externalParameters": 1}}}`,
+		`{"predicate": {"buildDefinition": {"internalParameters": 1}}}`,
+		`{"predicate": {"buildDefinition": {"internalParameters": {"a": 1}}}}`,
+	)
+}
+
+func TestV1ResolvedDependencies(t *testing.T) {
+	checkV1(t,
+		`{"predicate": {"buildDefinition": {"resolvedDependencies": 1}}}`,
+		`{"predicate": {"buildDefinition": {"resolvedDependencies": [{}]}}}`,
+		`{"predicate": {"buildDefinition": {"resolvedDependencies": [{"name": "task"}]}}}`,
+		`{"predicate": {"buildDefinition": {"resolvedDependencies": [{"uri": "git+https://github.com/org/repo.git"}]}}}`,
+		`{"predicate": {"buildDefinition": {"resolvedDependencies": [{"digest": {"sha1": "g%-A"}}]}}}`,
+		`{"predicate": {"buildDefinition": {"resolvedDependencies": [{"name": "task", "digest": {"sha1": "abcdef"}}]}}}`,
+		`{"predicate": {"buildDefinition": {"resolvedDependencies": [{"content": "e30="}]}}}`,
+	)
+}
+
+func TestV1RunDetails(t *testing.T) {
+	checkV1(t,
+		`{"predicate": {"runDetails": null}}`,
+		`{"predicate": {"runDetails": {"builder": null}}}`,
+		`{"predicate": {"runDetails": {"builder": {"id": "not_uri"}}}}`,
+		`{"predicate": {"runDetails": {"builder": {"version": {"chains": 1}}}}}`,
+		`{"predicate": {"runDetails": {"builder": {"version": {"chains": "v0.20.0"}}}}}`,
+		`{"predicate": {"runDetails": {"byproducts": [{}]}}}`,
+	)
+}
+
+func TestV1RunDetailsMetadata(t *testing.T) {
+	checkV1(t,
+		`{"predicate": {"runDetails": {"metadata": 1}}}`,
+		`{"predicate": {"runDetails": {"metadata": {"invocationID": ""}}}}`,
+		`{"predicate": {"runDetails": {"metadata": {"startedOn": "1937-01-01T12:00:27.87+00:20"}}}}`,
+		`{"predicate": {"runDetails": {"metadata": {"startedOn": "1985-04-12T23:20:50.52Z", "finishedOn": "1985-04-12T23:21:50.52Z"}}}}`,
+	)
+}